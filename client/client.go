@@ -0,0 +1,318 @@
+// Package client controls a running go-overlay supervisor through its control
+// socket, as the go-overlay CLI does, so Go programs and sidecars can list,
+// restart and watch services without running the CLI.
+//
+//	c := client.New("")
+//	services, err := c.List(ctx)
+//	if client.Code(err) == client.ErrCodePermissionDenied {
+//		...
+//	}
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"time"
+)
+
+// operationPollInterval is how often Wait asks for the state of an operation
+const operationPollInterval = 500 * time.Millisecond
+
+// Client sends commands to the daemon. Each command opens its own connection.
+type Client struct {
+	// Address is the control socket: a socket path, @name or tcp://host:port
+	Address string
+
+	// Dial connects to the control socket; net.Dialer.DialContext when nil
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// New returns a client of the daemon at address; an empty address is
+// $GO_OVERLAY_SOCKET, which services get from the daemon, else DefaultAddress
+func New(address string) *Client {
+	if address == "" {
+		address = os.Getenv(EnvAddress)
+	}
+	if address == "" {
+		address = DefaultAddress
+	}
+	return &Client{Address: address}
+}
+
+// Conn is a connection to the daemon after the handshake, ready for a command
+type Conn struct {
+	net.Conn
+	encoder *json.Encoder
+	decoder *json.Decoder
+
+	// DaemonVersion is the version the daemon reported, empty for daemons
+	// older than the handshake
+	DaemonVersion string
+}
+
+// Send sends a command frame
+func (c *Conn) Send(cmd Command) error {
+	if err := c.encoder.Encode(cmd); err != nil {
+		return fmt.Errorf("error sending command: %w", err)
+	}
+	return nil
+}
+
+// Receive reads the next response frame into v
+func (c *Conn) Receive(v any) error {
+	if err := c.decoder.Decode(v); err != nil {
+		return fmt.Errorf("error receiving response: %w", err)
+	}
+	return nil
+}
+
+// Open connects to the daemon and negotiates the protocol for a command.
+// Daemons older than the handshake answer hello as an unknown command and
+// close the connection; the client then reconnects and speaks the original
+// protocol, which the current one extends. A daemon that doesn't know the
+// command is reported with an *UnsupportedError.
+func (c *Client) Open(ctx context.Context, cmdType CommandType) (*Conn, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Send(Command{Type: CmdHello, Version: ProtocolVersion}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	var hello Response
+	if err := conn.Receive(&hello); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if !hello.Success {
+		_ = conn.Close()
+		return c.dial(ctx)
+	}
+	if !slices.Contains(hello.Commands, cmdType) {
+		_ = conn.Close()
+		return nil, &UnsupportedError{Command: cmdType, DaemonVersion: hello.DaemonVersion}
+	}
+	conn.DaemonVersion = hello.DaemonVersion
+	return conn, nil
+}
+
+func (c *Client) dial(ctx context.Context) (*Conn, error) {
+	network, addr, err := SplitAddress(c.Address)
+	if err != nil {
+		return nil, err
+	}
+	dial := c.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn, encoder: json.NewEncoder(conn), decoder: json.NewDecoder(conn)}, nil
+}
+
+// Do sends a command answered with a single response. A failed command
+// returns its response along with an *Error.
+func (c *Client) Do(ctx context.Context, cmd Command) (*Response, error) {
+	conn, done, err := c.send(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var response Response
+	if err := conn.Receive(&response); err != nil {
+		return nil, contextError(ctx, err)
+	}
+	if !response.Success {
+		return &response, &Error{Code: response.Code, Message: response.Message}
+	}
+	return &response, nil
+}
+
+// Stream sends a command and calls handle for every frame until the daemon
+// signals the last one, ctx is done or handle fails. A failed frame ends the
+// stream with an *Error.
+func (c *Client) Stream(ctx context.Context, cmd Command, handle func(*Response) error) error {
+	conn, done, err := c.send(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	for {
+		var frame Response
+		if err := conn.Receive(&frame); err != nil {
+			return contextError(ctx, err)
+		}
+		if !frame.Success {
+			return &Error{Code: frame.Code, Message: frame.Message}
+		}
+		if err := handle(&frame); err != nil {
+			return err
+		}
+		if !frame.More {
+			return nil
+		}
+	}
+}
+
+// send opens a connection for cmd and sends it. The connection is closed when
+// ctx is done, to unblock a Receive, or by calling done.
+func (c *Client) send(ctx context.Context, cmd Command) (*Conn, func(), error) {
+	conn, err := c.Open(ctx, cmd.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	done := func() {
+		stop()
+		_ = conn.Close()
+	}
+
+	if err := conn.Send(cmd); err != nil {
+		done()
+		return nil, nil, contextError(ctx, err)
+	}
+	return conn, done, nil
+}
+
+// contextError returns the error of ctx when it is why a read or write on
+// the connection failed
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// List returns the state of every service
+func (c *Client) List(ctx context.Context) ([]Service, error) {
+	response, err := c.Do(ctx, Command{Type: CmdListServices})
+	if err != nil {
+		return nil, err
+	}
+	return response.Services, nil
+}
+
+// Status returns the summary of the supervisor, as `go-overlay status`
+// prints it
+func (c *Client) Status(ctx context.Context) (string, error) {
+	response, err := c.Do(ctx, Command{Type: CmdGetStatus})
+	if err != nil {
+		return "", err
+	}
+	return response.Message, nil
+}
+
+// Restart starts the restart of a service and returns the operation tracking
+// it, to pass to Wait
+func (c *Client) Restart(ctx context.Context, name string) (Operation, error) {
+	response, err := c.Do(ctx, Command{Type: CmdRestartService, ServiceName: name})
+	if err != nil {
+		return Operation{}, err
+	}
+	if response.Operation == nil {
+		return Operation{}, fmt.Errorf("the daemon answered no operation for the restart of '%s'", name)
+	}
+	return *response.Operation, nil
+}
+
+// Operation returns the progress of an operation
+func (c *Client) Operation(ctx context.Context, id string) (Operation, error) {
+	response, err := c.Do(ctx, Command{Type: CmdOperation, OperationID: id})
+	if err != nil {
+		return Operation{}, err
+	}
+	if response.Operation == nil {
+		return Operation{}, fmt.Errorf("the daemon answered no operation '%s'", id)
+	}
+	return *response.Operation, nil
+}
+
+// Wait waits until an operation finishes. A failed operation is returned
+// along with an *Error.
+func (c *Client) Wait(ctx context.Context, id string) (Operation, error) {
+	ticker := time.NewTicker(operationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		op, err := c.Operation(ctx, id)
+		if err != nil {
+			return op, err
+		}
+		if op.State == OperationFailed {
+			return op, &Error{Code: op.Code, Message: fmt.Sprintf("%s %s failed: %s", op.Type, op.Service, op.Error)}
+		}
+		if op.Done() {
+			return op, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return op, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops a service, or the services matching a glob pattern, and
+// returns the result for each of them
+func (c *Client) Stop(ctx context.Context, name string) ([]OperationResult, error) {
+	return c.bulk(ctx, CmdStopServices, name)
+}
+
+// Start starts a service, or the services matching a glob pattern, and
+// returns the result for each of them
+func (c *Client) Start(ctx context.Context, name string) ([]OperationResult, error) {
+	return c.bulk(ctx, CmdStartServices, name)
+}
+
+func (c *Client) bulk(ctx context.Context, cmdType CommandType, name string) ([]OperationResult, error) {
+	response, err := c.Do(ctx, Command{Type: cmdType, ServiceName: name})
+	if response == nil {
+		return nil, err
+	}
+	return response.Results, err
+}
+
+// Reload asks a service to reload its configuration with its reload_cmd or
+// reload_signal
+func (c *Client) Reload(ctx context.Context, name string) error {
+	_, err := c.Do(ctx, Command{Type: CmdReloadService, ServiceName: name})
+	return err
+}
+
+// Signal sends a signal, by name or number, to the main process of a service
+func (c *Client) Signal(ctx context.Context, name, signal string) error {
+	_, err := c.Do(ctx, Command{Type: CmdSignalService, ServiceName: name, Signal: signal})
+	return err
+}
+
+// NotifyReady marks a service ready, for services with readiness = "notify"
+func (c *Client) NotifyReady(ctx context.Context, name string) error {
+	_, err := c.Do(ctx, Command{Type: CmdNotifyReady, ServiceName: name})
+	return err
+}
+
+// Events calls handle for every lifecycle event of a service, or of every
+// service when name is empty, until ctx is done, handle fails or the daemon
+// shuts down. It returns nil when the daemon shuts down.
+func (c *Client) Events(ctx context.Context, name string, handle func(Event) error) error {
+	return c.Stream(ctx, Command{Type: CmdSubscribe, ServiceName: name}, func(frame *Response) error {
+		for _, event := range frame.Events {
+			if err := handle(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDaemon answers the handshake with commands, then each command with the
+// frames answer returns for it
+type fakeDaemon struct {
+	commands []CommandType
+	legacy   bool // Older than the handshake
+	answer   func(Command) []Response
+}
+
+// serve listens on a socket until the test ends and returns a client of it
+func (d *fakeDaemon) serve(t *testing.T) *Client {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "control.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go d.handle(conn)
+		}
+	}()
+	return New(path)
+}
+
+func (d *fakeDaemon) handle(conn net.Conn) {
+	defer conn.Close()
+	encoder, decoder := json.NewEncoder(conn), json.NewDecoder(conn)
+
+	var cmd Command
+	if decoder.Decode(&cmd) != nil {
+		return
+	}
+	if cmd.Type == CmdHello {
+		if d.legacy {
+			_ = encoder.Encode(Response{Message: "Unknown command type"})
+			return
+		}
+		_ = encoder.Encode(Response{Success: true, Version: ProtocolVersion, DaemonVersion: "v-test", Commands: d.commands})
+		if decoder.Decode(&cmd) != nil {
+			return
+		}
+	}
+	for _, frame := range d.answer(cmd) {
+		if encoder.Encode(frame) != nil {
+			return
+		}
+	}
+	// Followers hang up to stop
+	_, _ = conn.Read(make([]byte, 1))
+}
+
+var allCommands = []CommandType{CmdListServices, CmdGetStatus, CmdRestartService, CmdOperation, CmdStopServices, CmdSubscribe}
+
+func TestClientList(t *testing.T) {
+	c := (&fakeDaemon{commands: allCommands, answer: func(cmd Command) []Response {
+		if cmd.Type != CmdListServices {
+			return []Response{{Message: "unexpected " + string(cmd.Type)}}
+		}
+		return []Response{{Success: true, Services: []Service{{Name: "web", State: ServiceStateRunning, PID: 42}}}}
+	}}).serve(t)
+
+	services, err := c.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(services) != 1 || services[0].Name != "web" || services[0].State != ServiceStateRunning {
+		t.Errorf("List() = %+v", services)
+	}
+}
+
+// Test a failure keeps the code the daemon answered
+func TestClientErrorCode(t *testing.T) {
+	c := (&fakeDaemon{commands: allCommands, answer: func(cmd Command) []Response {
+		return []Response{{Message: "Service '" + cmd.ServiceName + "' not found", Code: ErrCodeServiceNotFound}}
+	}}).serve(t)
+
+	_, err := c.Restart(context.Background(), "missing")
+	var daemonErr *Error
+	if !errors.As(err, &daemonErr) || daemonErr.Message != "Service 'missing' not found" {
+		t.Fatalf("Restart() error = %v, want the daemon's failure", err)
+	}
+	if Code(err) != ErrCodeServiceNotFound {
+		t.Errorf("Code() = %q, want %s", Code(err), ErrCodeServiceNotFound)
+	}
+	if Code(errors.New("boom")) != "" {
+		t.Error("an error of another origin has no code")
+	}
+}
+
+// Test the results of a bulk command come back along with its failure
+func TestClientStopResults(t *testing.T) {
+	c := (&fakeDaemon{commands: allCommands, answer: func(Command) []Response {
+		return []Response{{Message: "stop: 1 service(s), 1 failed", Code: ErrCodeTimeout, Results: []OperationResult{
+			{Service: "web", Action: "stop", Status: ResultFailed, Code: ErrCodeTimeout},
+		}}}
+	}}).serve(t)
+
+	results, err := c.Stop(context.Background(), "web")
+	if Code(err) != ErrCodeTimeout || len(results) != 1 || results[0].Status != ResultFailed {
+		t.Errorf("Stop() = %+v, %v", results, err)
+	}
+}
+
+// Test commands are sent directly to a daemon older than the handshake
+func TestClientLegacyDaemon(t *testing.T) {
+	c := (&fakeDaemon{legacy: true, answer: func(cmd Command) []Response {
+		return []Response{{Success: true, Message: "Total: 1, Running: 1, Failed: 0"}}
+	}}).serve(t)
+
+	status, err := c.Status(context.Background())
+	if err != nil || status != "Total: 1, Running: 1, Failed: 0" {
+		t.Errorf("Status() = %q, %v", status, err)
+	}
+}
+
+func TestClientUnsupportedCommand(t *testing.T) {
+	c := (&fakeDaemon{commands: []CommandType{CmdListServices}, answer: func(Command) []Response {
+		return []Response{{Success: true}}
+	}}).serve(t)
+
+	_, err := c.Status(context.Background())
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Command != CmdGetStatus || unsupported.DaemonVersion != "v-test" {
+		t.Errorf("Status() error = %v, want an unsupported command", err)
+	}
+}
+
+func TestClientWait(t *testing.T) {
+	states := []OperationState{OperationStopping, OperationStarting, OperationCompleted}
+	var polls atomic.Int32
+	c := (&fakeDaemon{commands: allCommands, answer: func(cmd Command) []Response {
+		state := states[min(int(polls.Add(1))-1, len(states)-1)]
+		return []Response{{Success: true, Operation: &Operation{ID: cmd.OperationID, State: state}}}
+	}}).serve(t)
+
+	op, err := c.Wait(context.Background(), "op-1")
+	if err != nil || op.State != OperationCompleted || op.ID != "op-1" {
+		t.Errorf("Wait() = %+v, %v", op, err)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	c := (&fakeDaemon{commands: allCommands, answer: func(cmd Command) []Response {
+		return []Response{
+			{Success: true, More: true},
+			{Success: true, More: true, Events: []Event{{Type: EventState, Service: cmd.ServiceName, To: "RUNNING"}}},
+			{Success: true, More: true, Events: []Event{{Type: EventReady, Service: cmd.ServiceName}}},
+		}
+	}}).serve(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var types []string
+	err := c.Events(ctx, "web", func(event Event) error {
+		types = append(types, event.Type)
+		if len(types) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Events() error = %v, want the cancellation", err)
+	}
+	if !slices.Equal(types, []string{EventState, EventReady}) {
+		t.Errorf("Events() saw %v", types)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv(EnvAddress, "")
+	if got := New("").Address; got != DefaultAddress {
+		t.Errorf("New(\"\").Address = %s, want %s", got, DefaultAddress)
+	}
+	t.Setenv(EnvAddress, "@go-overlay")
+	if got := New("").Address; got != "@go-overlay" {
+		t.Errorf("New(\"\").Address = %s, want $%s", got, EnvAddress)
+	}
+	if got := New("tcp://127.0.0.1:7373").Address; got != "tcp://127.0.0.1:7373" {
+		t.Errorf("New() ignored its address: %s", got)
+	}
+}
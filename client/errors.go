@@ -0,0 +1,49 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode tells clients why a command failed, so they can branch on it
+// instead of parsing the message. Failures without a more precise cause have
+// no code.
+type ErrorCode string
+
+const (
+	ErrCodeServiceNotFound   ErrorCode = "SERVICE_NOT_FOUND"   // No service by that name
+	ErrCodeAlreadyRunning    ErrorCode = "ALREADY_RUNNING"     // Starting a service that runs
+	ErrCodeTimeout           ErrorCode = "TIMEOUT"             // A stop or reload took too long
+	ErrCodePermissionDenied  ErrorCode = "PERMISSION_DENIED"   // The client may not run the command
+	ErrCodeNotRunning        ErrorCode = "NOT_RUNNING"         // The service is configured but not running
+	ErrCodeOperationNotFound ErrorCode = "OPERATION_NOT_FOUND" // No operation by that ID
+)
+
+// Error is a failure answered by the daemon
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Code returns the code of a failure answered by the daemon, empty for any
+// other error
+func Code(err error) ErrorCode {
+	var daemonErr *Error
+	if errors.As(err, &daemonErr) {
+		return daemonErr.Code
+	}
+	return ""
+}
+
+// UnsupportedError is returned for a command the running daemon is too old
+// to understand
+type UnsupportedError struct {
+	Command       CommandType
+	DaemonVersion string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("the running daemon (%s) does not support '%s'", e.DaemonVersion, e.Command)
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is the version of the control socket protocol. It is bumped
+// when the meaning of existing commands or fields changes; adding a command or
+// an optional field doesn't need a new version, as clients check the command
+// list of the hello handshake instead.
+const ProtocolVersion = 1
+
+// DefaultAddress is the control socket of a daemon without [control] listen
+const DefaultAddress = "/tmp/go-overlay.sock"
+
+// EnvAddress is the variable the daemon sets in the environment of services
+// and scripts to the address of its control socket
+const EnvAddress = "GO_OVERLAY_SOCKET"
+
+// tcpPrefix starts the address of a control socket on TCP
+const tcpPrefix = "tcp://"
+
+// SplitAddress returns the network and address net.Listen and net.Dial take
+// for a control socket address: a socket path, an abstract socket name
+// starting with @, or tcp://host:port on a loopback address
+func SplitAddress(address string) (string, string, error) {
+	if hostPort, ok := strings.CutPrefix(address, tcpPrefix); ok {
+		host, _, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid control address '%s': %w", address, err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return "", "", fmt.Errorf("control address '%s' must be on a loopback address", address)
+		}
+		return "tcp", hostPort, nil
+	}
+	if strings.HasPrefix(address, "@") || filepath.IsAbs(address) {
+		return "unix", address, nil
+	}
+	return "", "", fmt.Errorf("invalid control address '%s' (use a socket path, @name or tcp://127.0.0.1:port)", address)
+}
+
+// CommandType represents the type of IPC command
+type CommandType string
+
+// IPC command type constants
+const (
+	CmdListServices   CommandType = "list_services"
+	CmdRestartService CommandType = "restart_service"
+	CmdGetStatus      CommandType = "get_status"
+	CmdServiceEnv     CommandType = "service_env"
+	CmdOperation      CommandType = "operation_status"
+	CmdStopServices   CommandType = "stop_services"
+	CmdStartServices  CommandType = "start_services"
+	CmdUpgrade        CommandType = "upgrade"
+	CmdGetConfig      CommandType = "get_config"
+	CmdApply          CommandType = "apply_config"
+	CmdReloadService  CommandType = "reload_service"
+	CmdServiceStats   CommandType = "service_stats"
+	CmdNotifyReady    CommandType = "notify_ready"
+	CmdServiceLogs    CommandType = "service_logs"
+	CmdHello          CommandType = "hello"
+	CmdSubscribe      CommandType = "subscribe"
+	CmdSignalService  CommandType = "signal_service"
+	CmdServiceExec    CommandType = "service_exec"
+	CmdScale          CommandType = "scale"
+	CmdAudit          CommandType = "audit"
+	CmdHistory        CommandType = "history"
+)
+
+// Command represents a command sent via IPC
+type Command struct {
+	Type        CommandType `json:"type"`
+	ServiceName string      `json:"service_name,omitempty"`
+	OperationID string      `json:"operation_id,omitempty"`
+	Pattern     string      `json:"pattern,omitempty"`     // Glob selecting services for bulk commands
+	Selector    string      `json:"selector,omitempty"`    // Label selector (key=value,...) for bulk commands
+	Tag         string      `json:"tag,omitempty"`         // Tag selecting services for bulk commands
+	Binary      string      `json:"binary,omitempty"`      // New supervisor binary for upgrade
+	ConfigData  string      `json:"config_data,omitempty"` // TOML document for apply
+	Offset      int         `json:"offset,omitempty"`      // First item of a paginated listing
+	Limit       int         `json:"limit,omitempty"`       // Max items of a paginated listing (0 = all)
+	All         bool        `json:"all,omitempty"`         // Select every configured service
+	Stream      bool        `json:"stream,omitempty"`      // Send the listing as a sequence of chunks
+	Tail        int         `json:"tail,omitempty"`        // Recent log lines to send (0 = all kept)
+	Follow      bool        `json:"follow,omitempty"`      // Keep streaming new log lines
+	Version     int         `json:"version,omitempty"`     // Protocol version of the client (hello)
+	Signal      string      `json:"signal,omitempty"`      // Signal name or number for signal_service
+	Group       bool        `json:"group,omitempty"`       // Signal the whole process group of the service
+	Instances   int         `json:"instances,omitempty"`   // Target number of instances for scale
+}
+
+// Response is a frame answered by the daemon, with the fields of the commands
+// this package runs. Other commands answer more fields, which it ignores.
+type Response struct {
+	Message   string            `json:"message,omitempty"`
+	Code      ErrorCode         `json:"code,omitempty"` // Why the command failed, when known
+	Services  []Service         `json:"services,omitempty"`
+	Operation *Operation        `json:"operation,omitempty"`
+	Results   []OperationResult `json:"results,omitempty"`
+	Lines     []string          `json:"lines,omitempty"` // Service output lines
+	Events    []Event           `json:"events,omitempty"`
+	Total     int               `json:"total,omitempty"` // Number of items before pagination
+	Success   bool              `json:"success"`
+	More      bool              `json:"more,omitempty"` // More chunks follow on the stream
+
+	// Hello handshake: protocol version, daemon version and supported commands
+	Version       int           `json:"version,omitempty"`
+	DaemonVersion string        `json:"daemon_version,omitempty"`
+	Commands      []CommandType `json:"commands,omitempty"`
+}
+
+// ServiceState represents the current state of a service
+type ServiceState int
+
+// Service state constants
+const (
+	ServiceStatePending ServiceState = iota
+	ServiceStateStarting
+	ServiceStateRunning
+	ServiceStateStopping
+	ServiceStateStopped
+	ServiceStateFailed
+	ServiceStateBackoff // Waiting to be restarted by its restart policy
+)
+
+func (s ServiceState) String() string {
+	switch s {
+	case ServiceStatePending:
+		return "PENDING"
+	case ServiceStateStarting:
+		return "STARTING"
+	case ServiceStateRunning:
+		return "RUNNING"
+	case ServiceStateStopping:
+		return "STOPPING"
+	case ServiceStateStopped:
+		return "STOPPED"
+	case ServiceStateFailed:
+		return "FAILED"
+	case ServiceStateBackoff:
+		return "BACKOFF"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// HealthState is the result of a service's health checks, tracked alongside
+// its ServiceState while the process runs
+type HealthState string
+
+// Health state constants; services without a health_check have no health
+const (
+	HealthNone      HealthState = ""
+	HealthStarting  HealthState = "starting"
+	HealthHealthy   HealthState = "healthy"
+	HealthUnhealthy HealthState = "unhealthy"
+)
+
+func (h HealthState) String() string {
+	return strings.ToUpper(string(h))
+}
+
+// CgroupUsage is the resource usage reported by the cgroup of a service
+type CgroupUsage struct {
+	Path          string `json:"path"`
+	MemoryCurrent uint64 `json:"memory_current"`           // Bytes, including the page cache
+	MemoryMax     string `json:"memory_max,omitempty"`     // As in memory.max
+	CPUUsageUsec  uint64 `json:"cpu_usage_usec"`           // Total CPU time consumed
+	PidsCurrent   uint64 `json:"pids_current"`             // Processes and threads
+	OOMKills      uint64 `json:"oom_kills,omitempty"`      // Processes killed for exceeding memory.max
+	Throttled     uint64 `json:"throttled_usec,omitempty"` // Time throttled by cpu.max
+}
+
+// Service contains information about a service
+type Service struct {
+	Name          string            `json:"name"`
+	LastError     string            `json:"last_error,omitempty"`
+	Uptime        time.Duration     `json:"uptime"`
+	State         ServiceState      `json:"state"`
+	PID           int               `json:"pid"`
+	Required      bool              `json:"required"`
+	DroppedLogs   uint64            `json:"dropped_logs,omitempty"` // Lines discarded by the log pipeline
+	Labels        map[string]string `json:"labels,omitempty"`
+	Tags          []string          `json:"tags,omitempty"`
+	Restarts      int               `json:"restarts,omitempty"`       // Automatic restarts since it last stayed up
+	TotalRestarts int               `json:"total_restarts,omitempty"` // Automatic restarts since the daemon started, or ever with a state_file
+	ExitCode      *int              `json:"exit_code,omitempty"`      // Last exit on its own, if any
+	Health        HealthState       `json:"health,omitempty"`         // Empty without a health_check
+	Ready         bool              `json:"ready,omitempty"`          // Passed its readiness probe
+	Cgroup        *CgroupUsage      `json:"cgroup,omitempty"`         // Usage of its cgroup, if it has one
+	CPUPercent    *float64          `json:"cpu_percent,omitempty"`    // Latest sample of its process tree
+	RSS           uint64            `json:"rss,omitempty"`            // Resident memory of its process tree in bytes
+
+	RecentOutput []string `json:"recent_output,omitempty"` // Last lines of output, kept after it exits
+}
+
+// OperationState is the progress of an asynchronous lifecycle operation
+type OperationState string
+
+// Operation state constants
+const (
+	OperationPending   OperationState = "pending"
+	OperationStopping  OperationState = "stopping"
+	OperationStarting  OperationState = "starting"
+	OperationCompleted OperationState = "completed"
+	OperationFailed    OperationState = "failed"
+)
+
+// Operation describes an asynchronous operation and its progress
+type Operation struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at,omitempty"`
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Service    string         `json:"service"`
+	State      OperationState `json:"state"`
+	Error      string         `json:"error,omitempty"`
+	Code       ErrorCode      `json:"code,omitempty"` // Why it failed, when known
+}
+
+// Done reports whether the operation has finished
+func (o Operation) Done() bool {
+	return o.State == OperationCompleted || o.State == OperationFailed
+}
+
+// Bulk result statuses
+const (
+	ResultOK      = "ok"
+	ResultSkipped = "skipped"
+	ResultFailed  = "failed"
+)
+
+// OperationResult reports the outcome of an action on one service
+type OperationResult struct {
+	Service string    `json:"service"`
+	Action  string    `json:"action"`
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Code    ErrorCode `json:"code,omitempty"` // Why it failed, when known
+}
+
+// Event types published on the event bus
+const (
+	EventState     = "state"      // State change (from/to)
+	EventExited    = "exited"     // Exited on its own with status 0
+	EventFailed    = "failed"     // Exited with an error or failed to start
+	EventRestart   = "restart"    // Restart scheduled by the restart policy or requested
+	EventCrashLoop = "crash_loop" // Restarts stopped by the crash loop breaker
+	EventHealth    = "health"     // Health check transition (from/to)
+	EventReady     = "ready"      // Readiness probe passed
+	EventWatchdog  = "watchdog"   // Missed a watchdog deadline, aborted
+	EventShutdown  = "shutdown"   // Required service failed, the supervisor shuts down
+)
+
+// Event is a service lifecycle event, streamed by `go-overlay events`
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Service string    `json:"service"`
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
@@ -0,0 +1,33 @@
+package client
+
+import "testing"
+
+func TestSplitAddress(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddr    string
+		wantErr     bool
+	}{
+		{"/tmp/go-overlay.sock", "unix", "/tmp/go-overlay.sock", false},
+		{"@go-overlay", "unix", "@go-overlay", false},
+		{"tcp://127.0.0.1:7373", "tcp", "127.0.0.1:7373", false},
+		{"tcp://localhost:7373", "tcp", "localhost:7373", false},
+		{"tcp://[::1]:7373", "tcp", "[::1]:7373", false},
+		{"tcp://0.0.0.0:7373", "", "", true},
+		{"tcp://127.0.0.1", "", "", true},
+		{"go-overlay.sock", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			network, addr, err := SplitAddress(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if network != tt.wantNetwork || addr != tt.wantAddr {
+				t.Errorf("SplitAddress() = %s %s, want %s %s", network, addr, tt.wantNetwork, tt.wantAddr)
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-module go-overlay
+module github.com/srelabz/go-overlay
 
 go 1.24.8
 
@@ -6,6 +6,7 @@ require (
 	github.com/creack/pty v1.1.24
 	github.com/pelletier/go-toml/v2 v2.2.3
 	github.com/spf13/cobra v1.8.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,298 @@
+package supervisor
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiShutdownTimeout bounds how long in-flight API requests may finish on shutdown
+const apiShutdownTimeout = 2 * time.Second
+
+// APIConfig configures the optional HTTP API ([api] in services.toml). It
+// exposes the operations of the control socket as JSON REST endpoints.
+type APIConfig struct {
+	Listen    string `toml:"listen,omitempty"`     // Bind address such as "0.0.0.0:9090" (empty = disabled)
+	Token     string `toml:"token,omitempty"`      // Bearer token required on every request
+	TokenFile string `toml:"token_file,omitempty"` // File holding the token, e.g. a mounted secret
+}
+
+// apiToken returns the configured bearer token ("" = none)
+func apiToken(cfg APIConfig) (string, error) {
+	if cfg.TokenFile == "" {
+		return cfg.Token, nil
+	}
+	data, err := os.ReadFile(cfg.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read token_file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token_file %s is empty", cfg.TokenFile)
+	}
+	return token, nil
+}
+
+// startAPIServer serves the HTTP API on cfg.Listen until the daemon stops
+func startAPIServer(cfg APIConfig) error {
+	token, err := apiToken(cfg)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.Listen, err)
+	}
+
+	server := &http.Server{
+		Handler:           newAPIHandler(token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("HTTP API server stopped: ", err)
+		}
+	}()
+	go func() {
+		<-shutdownContext().Done()
+		ctx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	logger.Info(fmt.Sprintf("HTTP API listening on %s", colorize(ColorCyan, listener.Addr().String())))
+	if token == "" {
+		logger.Warn("HTTP API has no token: anyone who can reach it can control services")
+	}
+	return nil
+}
+
+// newAPIHandler routes the REST endpoints, requiring token when it is set
+func newAPIHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIResponse(w, dispatchIPCCommand(IPCCommand{Type: CmdGetStatus}))
+	})
+	mux.HandleFunc("GET /v1/services", handleAPIListServices)
+	mux.HandleFunc("GET /v1/services/{name}", handleAPIGetService)
+	mux.HandleFunc("GET /v1/services/{name}/logs", handleAPIServiceLogs)
+	mux.HandleFunc("POST /v1/services/{name}/{action}", handleAPIServiceAction)
+	mux.HandleFunc("GET /v1/operations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIResponse(w, dispatchIPCCommand(IPCCommand{Type: CmdOperation, OperationID: r.PathValue("id")}))
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="go-overlay"`)
+				writeAPIJSON(w, http.StatusUnauthorized, IPCResponse{Message: "missing or invalid token", Code: ErrCodePermissionDenied})
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// writeAPIResponse answers with a response of the control socket: 200 when it
+// succeeded, the status of its error code when it failed, or 409
+func writeAPIResponse(w http.ResponseWriter, response IPCResponse) {
+	status := http.StatusOK
+	if !response.Success {
+		status = http.StatusConflict
+		if codeStatus, ok := errorHTTPStatus[response.Code]; ok {
+			status = codeStatus
+		}
+	}
+	writeAPIJSON(w, status, response)
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, response IPCResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// queryInt reads a non-negative integer query parameter (0 when absent)
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+// apiService resolves the {name} of a request, answering 404 when it's unknown
+func apiService(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.PathValue("name")
+	if _, ok := findServiceConfig(name); !ok {
+		writeAPIJSON(w, http.StatusNotFound, notFoundResponse(name))
+		return "", false
+	}
+	return name, true
+}
+
+func handleAPIListServices(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset")
+	if err == nil {
+		var limit int
+		if limit, err = queryInt(r, "limit"); err == nil {
+			writeAPIResponse(w, dispatchIPCCommand(IPCCommand{Type: CmdListServices, Offset: offset, Limit: limit}))
+			return
+		}
+	}
+	writeAPIJSON(w, http.StatusBadRequest, IPCResponse{Message: err.Error()})
+}
+
+func handleAPIGetService(w http.ResponseWriter, r *http.Request) {
+	name, ok := apiService(w, r)
+	if !ok {
+		return
+	}
+	response := dispatchIPCCommand(IPCCommand{Type: CmdListServices})
+	for _, info := range response.Services {
+		if info.Name == name {
+			writeAPIResponse(w, IPCResponse{Success: true, Services: []ServiceInfo{info}})
+			return
+		}
+	}
+	writeAPIJSON(w, http.StatusNotFound, notFoundResponse(name))
+}
+
+// apiActions maps POST /v1/services/{name}/{action} to control socket commands
+var apiActions = map[string]CommandType{
+	"restart": CmdRestartService,
+	"stop":    CmdStopServices,
+	"start":   CmdStartServices,
+	"reload":  CmdReloadService,
+	"signal":  CmdSignalService, // ?signal=HUP[&group=true]
+}
+
+func handleAPIServiceAction(w http.ResponseWriter, r *http.Request) {
+	action, ok := apiActions[r.PathValue("action")]
+	if !ok {
+		writeAPIJSON(w, http.StatusNotFound, IPCResponse{Message: fmt.Sprintf("Unknown action '%s'", r.PathValue("action"))})
+		return
+	}
+	name, ok := apiService(w, r)
+	if !ok {
+		return
+	}
+	cmd := IPCCommand{
+		Type:        action,
+		ServiceName: name,
+		Signal:      r.URL.Query().Get("signal"),
+		Group:       r.URL.Query().Get("group") == "true",
+	}
+	response := dispatchIPCCommand(cmd)
+	auditCommand(AuditEntry{Source: AuditSourceAPI, Remote: r.RemoteAddr}, cmd, response)
+	writeAPIResponse(w, response)
+}
+
+// handleAPIServiceLogs returns recent output, or with follow=true streams it as
+// newline-delimited JSON frames, like `go-overlay logs -f`
+func handleAPIServiceLogs(w http.ResponseWriter, r *http.Request) {
+	name, ok := apiService(w, r)
+	if !ok {
+		return
+	}
+	lines, err := queryInt(r, "lines")
+	if err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, IPCResponse{Message: err.Error()})
+		return
+	}
+
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+	if !follow {
+		writeAPIResponse(w, IPCResponse{Success: true, Lines: serviceLogs.tail(name, lines)})
+		return
+	}
+
+	history, followed, stop := serviceLogs.follow(name, lines)
+	defer stop()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	send := func(batch []string, more bool) error {
+		if err := encoder.Encode(IPCResponse{Success: true, Lines: batch, More: more}); err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+	if err := send(history, true); err != nil {
+		return
+	}
+	_ = relayLogLines(followed, r.Context().Done(), send)
+}
+
+// isLoopbackListen reports whether a listen address only accepts local connections
+func isLoopbackListen(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func validateAPI(cfg *APIConfig) ValidationErrors {
+	var errors ValidationErrors
+
+	if cfg.Listen == "" {
+		if cfg.Token != "" || cfg.TokenFile != "" {
+			errors = append(errors, ValidationError{
+				Field:   "api.listen",
+				Message: "listen is required to enable the HTTP API",
+			})
+		}
+		return errors
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Listen); err != nil {
+		errors = append(errors, ValidationError{
+			Field:   "api.listen",
+			Message: fmt.Sprintf("invalid listen address '%s': %v", cfg.Listen, err),
+		})
+	}
+
+	switch {
+	case cfg.Token != "" && cfg.TokenFile != "":
+		errors = append(errors, ValidationError{
+			Field:   "api.token",
+			Message: "token and token_file cannot both be set",
+		})
+	case cfg.TokenFile != "":
+		if _, err := os.Stat(rootPath(cfg.TokenFile)); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "api.token_file",
+				Message: fmt.Sprintf("token_file '%s' is not readable", cfg.TokenFile),
+			})
+		}
+	case cfg.Token == "" && !isLoopbackListen(cfg.Listen):
+		errors = append(errors, ValidationError{
+			Field:   "api.token",
+			Message: "a token or token_file is required when the API listens beyond loopback",
+		})
+	}
+
+	return errors
+}
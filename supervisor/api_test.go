@@ -0,0 +1,101 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Test API requests need the bearer token and map to the control socket operations
+func TestAPIHandler(t *testing.T) {
+	resetShutdown()
+	setConfig(&Config{Services: []Service{{Name: "api-web"}}})
+	saved := serviceLogs
+	serviceLogs = newLogRecorder(10)
+	defer func() {
+		serviceLogs = saved
+		setConfig(nil)
+		cancelShutdown()
+	}()
+	serviceLogs.record("api-web", "first")
+	serviceLogs.record("api-web", "second")
+
+	handler := newAPIHandler("secret")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"No token", http.MethodGet, "/v1/status", "", http.StatusUnauthorized},
+		{"Wrong token", http.MethodGet, "/v1/status", "wrong", http.StatusUnauthorized},
+		{"Status", http.MethodGet, "/v1/status", "secret", http.StatusOK},
+		{"List", http.MethodGet, "/v1/services?limit=1", "secret", http.StatusOK},
+		{"Bad query", http.MethodGet, "/v1/services?limit=x", "secret", http.StatusBadRequest},
+		{"Service", http.MethodGet, "/v1/services/api-web", "secret", http.StatusOK},
+		{"Unknown service", http.MethodGet, "/v1/services/missing", "secret", http.StatusNotFound},
+		{"Unknown action", http.MethodPost, "/v1/services/api-web/explode", "secret", http.StatusNotFound},
+		{"Action on unknown service", http.MethodPost, "/v1/services/missing/restart", "secret", http.StatusNotFound},
+		{"Wrong method", http.MethodDelete, "/v1/services/api-web", "secret", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("%s %s = %d, want %d (%s)", tt.method, tt.path, rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/services/api-web/logs?lines=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var response IPCResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("logs response is not JSON: %v", err)
+	}
+	if !response.Success || !reflect.DeepEqual(response.Lines, []string{"second"}) {
+		t.Errorf("logs response = %+v", response)
+	}
+}
+
+// Test [api] validation requires a token beyond loopback
+func TestValidateAPI(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "missing-token")
+
+	tests := []struct {
+		name    string
+		cfg     APIConfig
+		wantErr bool
+	}{
+		{"Disabled", APIConfig{}, false},
+		{"Loopback without token", APIConfig{Listen: "127.0.0.1:9090"}, false},
+		{"Public with token", APIConfig{Listen: "0.0.0.0:9090", Token: "secret"}, false},
+		{"Public without token", APIConfig{Listen: ":9090"}, true},
+		{"Token and token_file", APIConfig{Listen: ":9090", Token: "secret", TokenFile: "/run/token"}, true},
+		{"Missing token_file", APIConfig{Listen: ":9090", TokenFile: tokenFile}, true},
+		{"Invalid address", APIConfig{Listen: "9090", Token: "secret"}, true},
+		{"Token without listen", APIConfig{Token: "secret"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := validateAPI(&tt.cfg); (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateAPI() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
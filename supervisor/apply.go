@@ -0,0 +1,232 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// applyMutex serializes configuration changes (apply, reload)
+var applyMutex sync.Mutex
+
+// applyConfig reconciles the running services with desired: removed services
+// are stopped, added ones started and changed ones restarted. Only the
+// differences are touched; every other service keeps running.
+func applyConfig(desired *Config) []OperationResult {
+	applyMutex.Lock()
+	defer applyMutex.Unlock()
+
+	current := currentConfig()
+	if current == nil {
+		current = &Config{}
+	}
+	changes := diffConfigs(current, desired)
+
+	byChange := make(map[string]ConfigChange, len(changes))
+	for _, change := range changes {
+		byChange[change.Service] = change
+	}
+
+	var results []OperationResult
+	wasRunning := make(map[string]bool)
+
+	// Stop removed and changed services, dependents first
+	for _, name := range reversed(dependencyOrder(current.Services)) {
+		change, ok := byChange[name]
+		if !ok || change.Action == ChangeAdd {
+			continue
+		}
+		if _, running := getActiveService(name); !running {
+			if change.Action == ChangeRemove {
+				results = append(results, OperationResult{
+					Service: name, Action: ActionStop, Status: ResultSkipped, Message: "removed (not running)",
+				})
+			}
+			continue
+		}
+
+		wasRunning[name] = true
+		result := bulkStop(name)
+		if change.Action == ChangeRemove {
+			result.Message = joinMessages("removed", result.Message)
+			results = append(results, result)
+		} else if result.Status == ResultFailed {
+			result.Action = ActionRestart
+			results = append(results, result)
+			delete(byChange, name)
+		}
+	}
+
+	setConfig(desired)
+
+	// Start added services and restart changed ones, dependencies first
+	var added []Service
+	for _, name := range dependencyOrder(desired.Services) {
+		change, ok := byChange[name]
+		if !ok || change.Action == ChangeRemove {
+			continue
+		}
+		service, _ := findServiceConfig(name)
+		enabled := service.Enabled == nil || *service.Enabled
+
+		switch change.Action {
+		case ChangeAdd:
+			added = append(added, service)
+			writeServiceStatus(name, ServiceStatePending, 0)
+			if !enabled {
+				results = append(results, OperationResult{
+					Service: name, Action: ActionStart, Status: ResultSkipped, Message: "added (disabled)",
+				})
+				continue
+			}
+			result := bulkStart(name)
+			result.Message = joinMessages("added", result.Message)
+			results = append(results, result)
+		case ChangeUpdate:
+			reason := "changed: " + strings.Join(change.Fields, ", ")
+			switch {
+			case !wasRunning[name]:
+				results = append(results, OperationResult{
+					Service: name, Action: ActionRestart, Status: ResultSkipped, Message: reason + " (not running)",
+				})
+			case !enabled:
+				results = append(results, OperationResult{
+					Service: name, Action: ActionStop, Status: ResultOK, Message: reason,
+				})
+			default:
+				result := bulkStart(name)
+				result.Action = ActionRestart
+				result.Message = joinMessages(reason, result.Message)
+				results = append(results, result)
+			}
+		}
+	}
+
+	startControlFIFOs(added)
+	return results
+}
+
+func joinMessages(reason, detail string) string {
+	if detail == "" {
+		return reason
+	}
+	return reason + " (" + detail + ")"
+}
+
+func handleApply(data string) IPCResponse {
+	desired, err := parseConfig(strings.NewReader(data))
+	if err == nil && len(passthroughCmd) > 0 {
+		// The command of go-overlay run is not in the config file
+		err = addPassthroughService(&desired, passthroughCmd)
+	}
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Message: fmt.Sprintf("Invalid configuration: %v", err),
+		}
+	}
+	if err := validateConfig(&desired); err != nil {
+		return IPCResponse{
+			Success: false,
+			Message: fmt.Sprintf("Configuration validation failed: %v", err),
+		}
+	}
+
+	logger.Info("Applying new configuration")
+	results, failed := applyValidatedConfig(&desired)
+
+	return IPCResponse{
+		Success: failed == 0,
+		Message: applySummary("apply", results, failed),
+		Results: results,
+	}
+}
+
+// applyValidatedConfig applies a validated config, keeping the settings that
+// only take effect at daemon startup. It returns the results and the number
+// of failed operations.
+func applyValidatedConfig(desired *Config) ([]OperationResult, int) {
+	if current := currentConfig(); current != nil {
+		desired.StatusDir = current.StatusDir
+		desired.Logging = current.Logging
+		desired.API = current.API
+		desired.Control = current.Control
+		desired.AuditLog = current.AuditLog
+		desired.StateFile = current.StateFile
+	}
+
+	results := applyConfig(desired)
+
+	failed := 0
+	for _, res := range results {
+		if res.Status == ResultFailed {
+			failed++
+		}
+	}
+	return results, failed
+}
+
+func applySummary(prefix string, results []OperationResult, failed int) string {
+	if len(results) == 0 {
+		return prefix + ": no changes"
+	}
+	return fmt.Sprintf("%s: %d change(s), %d failed", prefix, len(results), failed)
+}
+
+// reloadConfigFile re-reads the config file the daemon was started with and
+// applies it, like `go-overlay apply` (SIGHUP). An invalid file is reported and
+// leaves the running configuration untouched.
+func reloadConfigFile(configFile string) {
+	if shutdownContext().Err() != nil {
+		return
+	}
+
+	desired, err := loadAndValidateConfig(configFile)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Config reload failed, keeping the running configuration: %v", err))
+		return
+	}
+
+	results, failed := applyValidatedConfig(&desired)
+	for _, res := range results {
+		line := fmt.Sprintf("%s %s: %s", res.Action, colorize(ColorCyan, res.Service), res.Message)
+		if res.Status == ResultFailed {
+			logger.Error(line)
+		} else {
+			logger.Info(line)
+		}
+	}
+
+	if failed > 0 {
+		logger.Warn(applySummary("Config reload", results, failed))
+	} else {
+		logger.Success(applySummary("Config reload", results, failed))
+	}
+}
+
+// applyConfigFile sends configFile (merged with the config dir, if any) to
+// the daemon and prints the actions taken
+func applyConfigFile(configFile string) error {
+	// Parse locally first so errors point at the file with line numbers
+	config, err := parseConfigSources(configFile, configDir)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if configDir == "" {
+		data, err = os.ReadFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config file %s: %w", configFile, err)
+		}
+	} else if data, err = marshalConfig(&config); err != nil {
+		return fmt.Errorf("error encoding configuration: %w", err)
+	}
+
+	response, err := sendIPCCommand(IPCCommand{Type: CmdApply, ConfigData: string(data)})
+	if err != nil {
+		return err
+	}
+	return printOperationResults(response)
+}
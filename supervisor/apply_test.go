@@ -0,0 +1,172 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test apply only touches added, removed and changed services
+func TestApplyConfig(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	resetShutdown()
+	current := mustParseConfig(t, `
+[timeouts]
+service_shutdown_timeout = 2
+
+[[services]]
+name = "keep"
+command = "/bin/sleep"
+args = ["30"]
+
+[[services]]
+name = "change"
+command = "/bin/sleep"
+args = ["30"]
+
+[[services]]
+name = "remove"
+command = "/bin/sleep"
+args = ["30"]
+`)
+	setConfig(current)
+	t.Cleanup(func() {
+		for _, name := range []string{"keep", "change", "remove", "add"} {
+			_ = stopService(name)
+		}
+		cancelShutdown()
+		setConfig(nil)
+	})
+
+	for _, name := range []string{"keep", "change", "remove"} {
+		if err := startService(name); err != nil {
+			t.Fatalf("startService(%s) error = %v", name, err)
+		}
+	}
+	kept, _ := getActiveService("keep")
+	keptPID := kept.GetPID()
+	changed, _ := getActiveService("change")
+	changedPID := changed.GetPID()
+
+	desired := mustParseConfig(t, `
+[timeouts]
+service_shutdown_timeout = 2
+
+[[services]]
+name = "keep"
+command = "/bin/sleep"
+args = ["30"]
+
+[[services]]
+name = "change"
+command = "/bin/sleep"
+args = ["31"]
+
+[[services]]
+name = "add"
+command = "/bin/sleep"
+args = ["30"]
+`)
+
+	results := applyConfig(desired)
+
+	want := map[string]string{"remove": ActionStop, "change": ActionRestart, "add": ActionStart}
+	if len(results) != len(want) {
+		t.Fatalf("applyConfig() = %+v, want %d results", results, len(want))
+	}
+	for _, res := range results {
+		if res.Status != ResultOK || want[res.Service] != res.Action {
+			t.Errorf("result %+v, want %s ok", res, want[res.Service])
+		}
+	}
+
+	if !waitFor(t, 5*time.Second, func() bool {
+		_, running := getActiveService("remove")
+		return !running
+	}) {
+		t.Error("removed service still running")
+	}
+	if _, running := getActiveService("add"); !running {
+		t.Error("added service not running")
+	}
+	if proc, _ := getActiveService("keep"); proc == nil || proc.GetPID() != keptPID {
+		t.Error("unchanged service was restarted")
+	}
+	if proc, _ := getActiveService("change"); proc == nil || proc.GetPID() == changedPID {
+		t.Error("changed service was not restarted")
+	}
+	if service, _ := findServiceConfig("change"); service.Args[0] != "31" {
+		t.Errorf("config not updated, args = %v", service.Args)
+	}
+}
+
+// Test SIGHUP reloads apply the config file and ignore invalid files
+func TestReloadConfigFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	resetShutdown()
+	setConfig(mustParseConfig(t, `
+[timeouts]
+service_shutdown_timeout = 2
+
+[[services]]
+name = "reload-keep"
+command = "/bin/sleep"
+args = ["30"]
+`))
+	t.Cleanup(func() {
+		for _, name := range []string{"reload-keep", "reload-add"} {
+			_ = stopService(name)
+		}
+		cancelShutdown()
+		setConfig(nil)
+	})
+
+	if err := startService("reload-keep"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	kept, _ := getActiveService("reload-keep")
+	keptPID := kept.GetPID()
+
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.toml")
+	if err := os.WriteFile(invalid, []byte("[[services]]\nname = \"reload-keep\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfigFile(invalid)
+	if _, ok := findServiceConfig("reload-keep"); !ok {
+		t.Fatal("invalid config replaced the running configuration")
+	}
+
+	valid := filepath.Join(dir, "services.toml")
+	if err := os.WriteFile(valid, []byte(`
+[timeouts]
+service_shutdown_timeout = 2
+
+[[services]]
+name = "reload-keep"
+command = "/bin/sleep"
+args = ["30"]
+
+[[services]]
+name = "reload-add"
+command = "/bin/sleep"
+args = ["30"]
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfigFile(valid)
+
+	if _, running := getActiveService("reload-add"); !running {
+		t.Error("added service not running after reload")
+	}
+	if proc, _ := getActiveService("reload-keep"); proc == nil || proc.GetPID() != keptPID {
+		t.Error("unchanged service was restarted by reload")
+	}
+}
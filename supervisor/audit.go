@@ -0,0 +1,312 @@
+package supervisor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuditEntry records a control operation: who asked, what, and how it went
+type AuditEntry struct {
+	Time    time.Time   `json:"time"`
+	Source  string      `json:"source"`           // "socket" or "api"
+	UID     *int        `json:"uid,omitempty"`    // Socket client, when the kernel told it
+	PID     int         `json:"pid,omitempty"`    // Socket client process
+	Remote  string      `json:"remote,omitempty"` // Address of an API client
+	Command CommandType `json:"command"`
+	Target  string      `json:"target,omitempty"` // Service, pattern or selector
+	Result  string      `json:"result"`           // "ok", "failed" or "denied"
+	Message string      `json:"message,omitempty"`
+}
+
+// Audit entry sources and results
+const (
+	AuditSourceSocket = "socket"
+	AuditSourceAPI    = "api"
+
+	AuditResultOK     = "ok"
+	AuditResultFailed = "failed"
+	AuditResultDenied = "denied"
+)
+
+// auditKeep is the number of entries kept in memory for `go-overlay audit`
+const auditKeep = 500
+
+// auditTailBytes is how much of an existing audit log is read back at startup,
+// so recent entries survive restarts and upgrades
+const auditTailBytes = 256 * 1024
+
+// auditedCommands change services or reveal their secrets; read-only
+// commands aren't recorded
+var auditedCommands = map[CommandType]bool{
+	CmdRestartService: true,
+	CmdReloadService:  true,
+	CmdSignalService:  true,
+	CmdStopServices:   true,
+	CmdStartServices:  true,
+	CmdUpgrade:        true,
+	CmdApply:          true,
+	CmdScale:          true,
+	CmdServiceExec:    true,
+	CmdServiceEnv:     true,
+	CmdGetConfig:      true,
+}
+
+// auditLog keeps the recent entries and appends every entry to the audit_log
+// file, when one is configured
+type auditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+	file    *os.File
+}
+
+var controlAudit = &auditLog{}
+
+// open appends the entries to path from now on, after reading back the last
+// ones it holds
+func (a *auditLog) open(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	previous, err := readAuditTail(path, auditKeep)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Could not read back the audit log: %v", err))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file != nil {
+		a.file.Close()
+	}
+	a.file = file
+	a.entries = append(previous, a.entries...)
+	a.trim()
+	return nil
+}
+
+// record keeps entry and writes it to the file. A failed write is logged but
+// doesn't fail the operation, which has already happened.
+func (a *auditLog) record(entry AuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entries = append(a.entries, entry)
+	a.trim()
+	if a.file == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		_, err = a.file.Write(append(data, '\n'))
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Could not write the audit log: %v", err))
+	}
+}
+
+func (a *auditLog) trim() {
+	if len(a.entries) > auditKeep {
+		a.entries = append([]AuditEntry(nil), a.entries[len(a.entries)-auditKeep:]...)
+	}
+}
+
+// recent returns the last n entries, oldest first (0 = all kept)
+func (a *auditLog) recent(n int) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries := a.entries
+	if n > 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return append([]AuditEntry(nil), entries...)
+}
+
+// readAuditTail returns the last n entries of an audit log file. Lines that
+// aren't entries, such as one cut by a crash, are skipped.
+func readAuditTail(path string, n int) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	partial := info.Size() > auditTailBytes
+	if partial {
+		if _, err := file.Seek(-auditTailBytes, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), auditTailBytes)
+	for scanner.Scan() {
+		if partial {
+			// The read started in the middle of a line
+			partial = false
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Command == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries, scanner.Err()
+}
+
+// socketAuditClient identifies a control socket client in audit entries
+func socketAuditClient(peer *peerCredentials) AuditEntry {
+	entry := AuditEntry{Source: AuditSourceSocket}
+	if peer != nil {
+		uid := peer.UID
+		entry.UID = &uid
+		entry.PID = peer.PID
+	}
+	return entry
+}
+
+// auditCommand records cmd, run by client, if it is a control operation
+func auditCommand(client AuditEntry, cmd IPCCommand, response IPCResponse) {
+	if !auditedCommands[cmd.Type] {
+		return
+	}
+	result := AuditResultOK
+	if !response.Success {
+		result = AuditResultFailed
+	}
+	auditEntry(client, cmd, result, response.Message)
+}
+
+// auditDenied records a command refused to client
+func auditDenied(client AuditEntry, cmd IPCCommand, err error) {
+	auditEntry(client, cmd, AuditResultDenied, err.Error())
+}
+
+func auditEntry(client AuditEntry, cmd IPCCommand, result, message string) {
+	client.Time = time.Now().UTC()
+	client.Command = cmd.Type
+	client.Target = auditTarget(cmd)
+	client.Result = result
+	client.Message = message
+	controlAudit.record(client)
+}
+
+// auditTarget describes what cmd acts on
+func auditTarget(cmd IPCCommand) string {
+	target := cmd.ServiceName
+	switch {
+	case cmd.All:
+		target = "--all"
+	case cmd.Pattern != "":
+		target = cmd.Pattern
+	case cmd.Selector != "":
+		target = "--selector " + cmd.Selector
+	case cmd.Tag != "":
+		target = "--tag " + cmd.Tag
+	}
+	if cmd.Pattern != "" && cmd.Selector != "" {
+		target += " --selector " + cmd.Selector
+	}
+
+	switch cmd.Type {
+	case CmdSignalService:
+		if cmd.Signal != "" {
+			target += " " + cmd.Signal
+		}
+	case CmdScale:
+		target += " " + strconv.Itoa(cmd.Instances)
+	case CmdUpgrade:
+		target = cmd.Binary
+	}
+	return target
+}
+
+func handleAudit(limit int) IPCResponse {
+	return IPCResponse{
+		Success: true,
+		Audit:   controlAudit.recent(limit),
+	}
+}
+
+// auditClientName shows who ran an audited command
+func auditClientName(entry AuditEntry) string {
+	switch {
+	case entry.UID != nil:
+		return fmt.Sprintf("uid %d", *entry.UID)
+	case entry.Remote != "":
+		return entry.Remote
+	}
+	return "unknown"
+}
+
+// showAudit prints the recent control operations the daemon recorded
+func showAudit(lines int, asJSON bool) error {
+	response, err := sendIPCCommand(IPCCommand{Type: CmdAudit, Limit: lines})
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return responseError(response)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, entry := range response.Audit {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(response.Audit) == 0 {
+		fmt.Println("No control operations recorded")
+		return nil
+	}
+	var rows [][]string
+	for _, entry := range response.Audit {
+		rows = append(rows, []string{
+			entry.Time.Local().Format(time.DateTime),
+			entry.Source,
+			auditClientName(entry),
+			string(entry.Command),
+			entry.Target,
+			entry.Result,
+			entry.Message,
+		})
+	}
+	fmt.Print(renderTable([]string{"TIME", "SOURCE", "CLIENT", "COMMAND", "TARGET", "RESULT", "MESSAGE"}, rows))
+	return nil
+}
+
+func validateAuditLog(path string) ValidationErrors {
+	var errors ValidationErrors
+
+	if path != "" && !filepath.IsAbs(path) {
+		errors = append(errors, ValidationError{
+			Field:   "audit_log",
+			Message: fmt.Sprintf("audit_log '%s' must be an absolute path", path),
+		})
+	}
+
+	return errors
+}
@@ -0,0 +1,128 @@
+package supervisor
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test audit entries are appended to the file and read back when it is opened
+// again, as after a restart
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+
+	log := &auditLog{}
+	if err := log.open(path); err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	uid := 1000
+	log.record(AuditEntry{Source: AuditSourceSocket, UID: &uid, Command: CmdRestartService, Target: "web", Result: AuditResultOK})
+	log.record(AuditEntry{Source: AuditSourceAPI, Remote: "10.0.0.1:4242", Command: CmdStopServices, Target: "db", Result: AuditResultFailed})
+	log.file.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("audit log mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"time":"2026-01-01T00:00:00Z","source":"socket","comm` + "\n")
+	file.Close()
+
+	var lines []AuditEntry
+	data, _ := os.Open(path)
+	defer data.Close()
+	scanner := bufio.NewScanner(data)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			lines = append(lines, entry)
+		}
+	}
+	if len(lines) != 2 || *lines[0].UID != 1000 || lines[1].Remote != "10.0.0.1:4242" {
+		t.Errorf("audit log = %+v, want the two entries", lines)
+	}
+
+	reopened := &auditLog{}
+	if err := reopened.open(path); err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	defer reopened.file.Close()
+	reopened.record(AuditEntry{Source: AuditSourceSocket, Command: CmdScale, Result: AuditResultDenied})
+
+	entries := reopened.recent(0)
+	if len(entries) != 3 || entries[0].Target != "web" || entries[2].Command != CmdScale {
+		t.Errorf("recent(0) = %+v, want both previous entries and the new one", entries)
+	}
+	if entries := reopened.recent(1); len(entries) != 1 || entries[0].Command != CmdScale {
+		t.Errorf("recent(1) = %+v, want the last entry", entries)
+	}
+}
+
+// Test the in-memory entries are bounded
+func TestAuditLogKeep(t *testing.T) {
+	log := &auditLog{}
+	for range auditKeep + 10 {
+		log.record(AuditEntry{Command: CmdRestartService})
+	}
+	if n := len(log.recent(0)); n != auditKeep {
+		t.Errorf("kept %d entries, want %d", n, auditKeep)
+	}
+}
+
+// Test only control operations are audited, with what they act on
+func TestAuditCommand(t *testing.T) {
+	previous := controlAudit
+	controlAudit = &auditLog{}
+	t.Cleanup(func() { controlAudit = previous })
+
+	peer := &peerCredentials{PID: 42, UID: 1000}
+	auditCommand(socketAuditClient(peer), IPCCommand{Type: CmdListServices}, IPCResponse{Success: true})
+	auditCommand(socketAuditClient(peer), IPCCommand{Type: CmdRestartService, ServiceName: "web"}, IPCResponse{Success: true})
+	auditCommand(socketAuditClient(nil), IPCCommand{Type: CmdApply}, IPCResponse{Message: "Invalid configuration"})
+	auditDenied(socketAuditClient(peer), IPCCommand{Type: CmdScale, ServiceName: "worker", Instances: 3},
+		authorizeIPCCommand(ControlConfig{AllowUIDs: []int{1}}, peer, IPCCommand{Type: CmdScale}))
+
+	entries := controlAudit.recent(0)
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v, want 3", entries)
+	}
+	if e := entries[0]; e.Command != CmdRestartService || e.Target != "web" || e.Result != AuditResultOK || *e.UID != 1000 || e.PID != 42 {
+		t.Errorf("restart entry = %+v", e)
+	}
+	if e := entries[1]; e.Result != AuditResultFailed || e.UID != nil || auditClientName(e) != "unknown" {
+		t.Errorf("apply entry = %+v", e)
+	}
+	if e := entries[2]; e.Result != AuditResultDenied || e.Target != "worker 3" || e.Message == "" {
+		t.Errorf("denied entry = %+v", e)
+	}
+
+	tests := []struct {
+		name string
+		cmd  IPCCommand
+		want string
+	}{
+		{"Service", IPCCommand{Type: CmdRestartService, ServiceName: "web"}, "web"},
+		{"All", IPCCommand{Type: CmdStopServices, All: true}, "--all"},
+		{"Pattern and selector", IPCCommand{Type: CmdStopServices, Pattern: "web-*", Selector: "tier=api"}, "web-* --selector tier=api"},
+		{"Selector", IPCCommand{Type: CmdStartServices, Selector: "tier=api"}, "--selector tier=api"},
+		{"Tag", IPCCommand{Type: CmdRestartService, Tag: "edge"}, "--tag edge"},
+		{"Signal", IPCCommand{Type: CmdSignalService, ServiceName: "web", Signal: "HUP"}, "web HUP"},
+		{"Upgrade", IPCCommand{Type: CmdUpgrade, Binary: "/usr/local/bin/go-overlay.new"}, "/usr/local/bin/go-overlay.new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditTarget(tt.cmd); got != tt.want {
+				t.Errorf("auditTarget() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package supervisor
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/srelabz/go-overlay/client"
+)
+
+// Bulk action names
+const (
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionRestart = "restart"
+)
+
+// Bulk result statuses
+const (
+	ResultOK      = client.ResultOK
+	ResultSkipped = client.ResultSkipped
+	ResultFailed  = client.ResultFailed
+)
+
+// OperationResult reports the outcome of an action on one service
+type OperationResult = client.OperationResult
+
+// isGlobPattern reports whether s contains glob metacharacters
+func isGlobPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// resolveTargets returns the configured services selected by a command, in
+// dependency order (dependencies before dependents).
+func resolveTargets(cmd IPCCommand) ([]string, error) {
+	config := currentConfig()
+	if config == nil {
+		return nil, fmt.Errorf("no configuration loaded")
+	}
+
+	pattern := cmd.ServiceName
+	if cmd.Pattern != "" {
+		pattern = cmd.Pattern
+	}
+	if !cmd.All && pattern == "" && cmd.Selector == "" && cmd.Tag == "" {
+		return nil, fmt.Errorf("no services selected: pass a service name, a pattern, a selector, a tag or --all")
+	}
+
+	var selector map[string]string
+	if cmd.Selector != "" {
+		var err error
+		if selector, err = parseSelector(cmd.Selector); err != nil {
+			return nil, err
+		}
+	}
+
+	byName := make(map[string]*Service, len(config.Services))
+	for i := range config.Services {
+		byName[config.Services[i].Name] = &config.Services[i]
+	}
+
+	var targets []string
+	for _, name := range dependencyOrder(config.Services) {
+		if selector != nil && !matchesSelector(byName[name].Labels, selector) {
+			continue
+		}
+		if cmd.Tag != "" && !hasTag(byName[name].Tags, cmd.Tag) {
+			continue
+		}
+		if cmd.All || pattern == "" {
+			targets = append(targets, name)
+			continue
+		}
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+		if matched {
+			targets = append(targets, name)
+		}
+	}
+
+	if len(targets) == 0 {
+		switch {
+		case selector != nil:
+			return nil, fmt.Errorf("no services match selector '%s'", cmd.Selector)
+		case cmd.Tag != "":
+			return nil, fmt.Errorf("no services tagged '%s'", cmd.Tag)
+		case isGlobPattern(pattern) || cmd.All:
+			return nil, fmt.Errorf("no services match '%s'", pattern)
+		}
+		return nil, errServiceNotFound(pattern)
+	}
+	return targets, nil
+}
+
+// dependencyOrder returns service names sorted so that every service comes
+// after its dependencies; ties keep alphabetical order for stable output.
+func dependencyOrder(services []Service) []string {
+	byName := make(map[string]*Service, len(services))
+	names := make([]string, 0, len(services))
+	for i := range services {
+		byName[services[i].Name] = &services[i]
+		names = append(names, services[i].Name)
+	}
+	sort.Strings(names)
+
+	visited := make(map[string]bool, len(services))
+	order := make([]string, 0, len(services))
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		if svc, ok := byName[name]; ok {
+			deps := append([]string(nil), svc.DependsOn...)
+			sort.Strings(deps)
+			for _, dep := range deps {
+				visit(dep)
+			}
+			order = append(order, name)
+		}
+	}
+
+	for _, name := range names {
+		visit(name)
+	}
+	return order
+}
+
+func reversed(names []string) []string {
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[len(names)-1-i] = name
+	}
+	return out
+}
+
+// runBulkAction applies action to targets in dependency-safe order: stops run
+// dependents first, starts run dependencies first, and restarts stop every
+// target before starting them again.
+func runBulkAction(action string, targets []string) []OperationResult {
+	var results []OperationResult
+
+	switch action {
+	case ActionStop:
+		for _, name := range reversed(targets) {
+			results = append(results, bulkStop(name))
+		}
+	case ActionStart:
+		for _, name := range targets {
+			results = append(results, bulkStart(name))
+		}
+	case ActionRestart:
+		stopped := make(map[string]OperationResult, len(targets))
+		for _, name := range reversed(targets) {
+			stopped[name] = bulkStop(name)
+		}
+		for _, name := range targets {
+			if res := stopped[name]; res.Status == ResultFailed {
+				res.Action = ActionRestart
+				results = append(results, res)
+				continue
+			}
+			res := bulkStart(name)
+			res.Action = ActionRestart
+			if res.Status == ResultSkipped {
+				res.Status = ResultOK
+			}
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+func bulkStop(name string) OperationResult {
+	result := OperationResult{Service: name, Action: ActionStop, Status: ResultOK}
+	if _, running := getActiveService(name); !running {
+		result.Status = ResultSkipped
+		result.Message = "not running"
+		return result
+	}
+	if err := stopService(name); err != nil {
+		result.Status = ResultFailed
+		result.Message = err.Error()
+		result.Code = errorCode(err)
+		return result
+	}
+	markStoppedByHand(name, true)
+	return result
+}
+
+func bulkStart(name string) OperationResult {
+	result := OperationResult{Service: name, Action: ActionStart, Status: ResultOK}
+	if _, running := getActiveService(name); running {
+		result.Status = ResultSkipped
+		result.Message = "already running"
+		return result
+	}
+	if err := startService(name); err != nil {
+		result.Status = ResultFailed
+		result.Message = err.Error()
+		result.Code = errorCode(err)
+		return result
+	}
+	markStoppedByHand(name, false)
+	return result
+}
+
+// handleBulkAction resolves the command's selection and runs action on it
+func handleBulkAction(action string, cmd IPCCommand) IPCResponse {
+	targets, err := resolveTargets(cmd)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	logger.Info(fmt.Sprintf("Bulk %s of %d service(s): %s", action, len(targets),
+		colorize(ColorCyan, strings.Join(targets, ", "))))
+	results := runBulkAction(action, targets)
+
+	failed := 0
+	for _, res := range results {
+		if res.Status == ResultFailed {
+			failed++
+		}
+	}
+
+	return IPCResponse{
+		Success: failed == 0,
+		Message: fmt.Sprintf("%s: %d service(s), %d failed", action, len(results), failed),
+		Code:    commonCode(results),
+		Results: results,
+	}
+}
+
+// bulkSelection holds the flags selecting several services for bulk commands
+type bulkSelection struct {
+	Selector string
+	Tag      string
+	All      bool
+}
+
+// addFlags registers the selection flags on cmd
+func (s *bulkSelection) addFlags(cmd *cobra.Command, verb string) {
+	cmd.Flags().BoolVar(&s.All, "all", false, verb+" all services")
+	cmd.Flags().StringVarP(&s.Selector, "selector", "l", "", verb+" services matching labels (key=value,...)")
+	cmd.Flags().StringVar(&s.Tag, "tag", "", verb+" services with this tag")
+}
+
+// isSet reports whether the flags select services without a positional argument
+func (s *bulkSelection) isSet() bool {
+	return s.All || s.Selector != "" || s.Tag != ""
+}
+
+// runBulkCommand sends a bulk action to the daemon and prints the consolidated report
+func runBulkCommand(action, target string, sel bulkSelection) error {
+	cmd := IPCCommand{Type: bulkCommandTypes[action], All: sel.All, Selector: sel.Selector, Tag: sel.Tag}
+	if isGlobPattern(target) {
+		cmd.Pattern = target
+	} else {
+		cmd.ServiceName = target
+	}
+
+	response, err := sendIPCCommand(cmd)
+	if err != nil {
+		return err
+	}
+	return printOperationResults(response)
+}
+
+// printOperationResults prints one line per service result followed by the summary
+func printOperationResults(response *IPCResponse) error {
+	for _, res := range response.Results {
+		mark, color := "✓", ColorGreen
+		switch res.Status {
+		case ResultSkipped:
+			mark, color = "-", ColorGray
+		case ResultFailed:
+			mark, color = "✗", ColorRed
+		}
+		line := fmt.Sprintf("%s %-8s %s", mark, res.Action, res.Service)
+		if res.Message != "" {
+			line += " (" + res.Message + ")"
+		}
+		fmt.Println(colorize(color, line))
+	}
+
+	if !response.Success {
+		return responseError(response)
+	}
+	fmt.Println(colorize(ColorGreen, response.Message))
+	return nil
+}
+
+// bulkCommandTypes maps bulk actions to their IPC command types
+var bulkCommandTypes = map[string]CommandType{
+	ActionStart:   CmdStartServices,
+	ActionStop:    CmdStopServices,
+	ActionRestart: CmdRestartService,
+}
+
+// bulkArgs accepts either exactly one service/pattern argument, or none with --all.
+// A pattern may be combined with --selector to narrow it down.
+func bulkArgs(sel *bulkSelection) cobra.PositionalArgs {
+	return func(_ *cobra.Command, args []string) error {
+		switch {
+		case sel.All && len(args) > 0:
+			return fmt.Errorf("--all does not accept a service argument")
+		case sel.isSet() && len(args) <= 1:
+			return nil
+		case len(args) != 1:
+			return fmt.Errorf("requires a service name or pattern (or --all/--selector/--tag)")
+		}
+		return nil
+	}
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
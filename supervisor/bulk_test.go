@@ -0,0 +1,123 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test services are ordered after their dependencies
+func TestDependencyOrder(t *testing.T) {
+	services := []Service{
+		{Name: "web", DependsOn: DependsOnField{"api"}},
+		{Name: "api", DependsOn: DependsOnField{"db", "cache"}},
+		{Name: "db"},
+		{Name: "cache"},
+		{Name: "worker", DependsOn: DependsOnField{"db"}},
+	}
+
+	got := strings.Join(dependencyOrder(services), ",")
+	want := "cache,db,api,web,worker"
+	if got != want {
+		t.Errorf("dependencyOrder() = %s, want %s", got, want)
+	}
+}
+
+// Test service selection by name, glob pattern and --all
+func TestResolveTargets(t *testing.T) {
+	useConfig(t, &Config{Services: []Service{
+		{Name: "worker-1"}, {Name: "worker-2"}, {Name: "web", DependsOn: DependsOnField{"worker-1"}},
+	}})
+
+	tests := []struct {
+		name    string
+		cmd     IPCCommand
+		want    string
+		wantErr bool
+	}{
+		{"All", IPCCommand{All: true}, "worker-1,web,worker-2", false},
+		{"Pattern", IPCCommand{Pattern: "worker-*"}, "worker-1,worker-2", false},
+		{"Name", IPCCommand{ServiceName: "web"}, "web", false},
+		{"Unknown name", IPCCommand{ServiceName: "db"}, "", true},
+		{"No match", IPCCommand{Pattern: "db-*"}, "", true},
+		{"Nothing selected", IPCCommand{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := resolveTargets(tt.cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Join(targets, ","); got != tt.want {
+				t.Errorf("resolveTargets() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test bulk stop/start over running processes
+func TestBulkStopStartAll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	resetShutdown()
+	useConfig(t, &Config{
+		Services: []Service{
+			{Name: "bulk-db", Command: "/bin/sleep", Args: []string{"30"}},
+			{Name: "bulk-app", Command: "/bin/sleep", Args: []string{"30"}, DependsOn: DependsOnField{"bulk-db"}},
+		},
+		Timeouts: Timeouts{ServiceShutdown: 2},
+	})
+	defer stopTestServices("bulk-db", "bulk-app")
+
+	response := handleBulkAction(ActionStart, IPCCommand{All: true})
+	if !response.Success || len(response.Results) != 2 {
+		t.Fatalf("start --all = %+v", response)
+	}
+	if response.Results[0].Service != "bulk-db" {
+		t.Errorf("start order = %v, want bulk-db first", response.Results)
+	}
+
+	response = handleBulkAction(ActionStart, IPCCommand{Pattern: "bulk-*"})
+	for _, res := range response.Results {
+		if res.Status != ResultSkipped {
+			t.Errorf("starting running service %s: status %s, want skipped", res.Service, res.Status)
+		}
+	}
+
+	response = handleBulkAction(ActionStop, IPCCommand{All: true})
+	if !response.Success || response.Results[0].Service != "bulk-app" {
+		t.Fatalf("stop --all = %+v, want bulk-app stopped first", response)
+	}
+	for _, name := range []string{"bulk-db", "bulk-app"} {
+		if _, running := getActiveService(name); running {
+			t.Errorf("service %s still running after stop --all", name)
+		}
+	}
+}
+
+// Test tag selection keeps dependency order and leaves untagged services out
+func TestResolveTargetsTag(t *testing.T) {
+	useConfig(t, &Config{Services: []Service{
+		{Name: "report", Tags: []string{"batch"}, DependsOn: DependsOnField{"queue"}},
+		{Name: "queue", Tags: []string{"batch", "critical"}, DependsOn: DependsOnField{"db"}},
+		{Name: "db", Tags: []string{"critical"}},
+		{Name: "web"},
+	}})
+
+	targets, err := resolveTargets(IPCCommand{Tag: "batch"})
+	if err != nil {
+		t.Fatalf("resolveTargets() error = %v", err)
+	}
+	if got := strings.Join(targets, ","); got != "queue,report" {
+		t.Errorf("resolveTargets() = %s, want queue,report", got)
+	}
+	if got := strings.Join(reversed(targets), ","); got != "report,queue" {
+		t.Errorf("stop order = %s, want report,queue", got)
+	}
+
+	if _, err := resolveTargets(IPCCommand{Tag: "frontend"}); err == nil {
+		t.Error("resolveTargets() with an unknown tag succeeded")
+	}
+}
@@ -0,0 +1,282 @@
+package supervisor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupSupervisorLeaf is the cgroup the supervisor moves its own processes
+// to, since cgroup v2 only allows controllers on cgroups without processes
+const cgroupSupervisorLeaf = "supervisor"
+
+// cgroupControllers are the controllers enabled for service cgroups
+var cgroupControllers = []string{"cpu", "memory", "pids"}
+
+// CgroupLimits are cgroup v2 controls of a service, written as-is to the
+// interface files of its cgroup
+type CgroupLimits struct {
+	CPUMax    string `toml:"cpu_max,omitempty"`    // cpu.max: "<quota> <period>" in microseconds, or "max"
+	MemoryMax string `toml:"memory_max,omitempty"` // memory.max: bytes with an optional K, M or G suffix, or "max"
+	PidsMax   int    `toml:"pids_max,omitempty"`   // pids.max: processes and threads
+}
+
+// CgroupUsage is the resource usage reported by the cgroup of a service
+type CgroupUsage = client.CgroupUsage
+
+// cgroupBase is the cgroup service cgroups are created under: the one the
+// supervisor was started in. It is prepared once, on first use.
+var cgroupBase struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+// serviceCgroupBase returns the parent of the service cgroups, preparing it
+// the first time: the supervisor's processes move to a leaf cgroup and the
+// controllers are enabled for its children
+func serviceCgroupBase() (string, error) {
+	cgroupBase.once.Do(func() {
+		cgroupBase.path, cgroupBase.err = prepareCgroupBase()
+	})
+	return cgroupBase.path, cgroupBase.err
+}
+
+func prepareCgroupBase() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("cgroup v2 is not mounted at %s", cgroupRoot)
+	}
+	if os.Geteuid() != 0 {
+		return "", fmt.Errorf("cgroups require running as root")
+	}
+
+	self, err := ownCgroup()
+	if err != nil {
+		return "", err
+	}
+	// After an upgrade the supervisor already runs in the leaf
+	if filepath.Base(self) == cgroupSupervisorLeaf {
+		self = filepath.Dir(self)
+	}
+	base := filepath.Join(cgroupRoot, self)
+
+	leaf := filepath.Join(base, cgroupSupervisorLeaf)
+	if err := os.MkdirAll(leaf, 0o755); err != nil {
+		return "", err
+	}
+	procs, err := os.ReadFile(filepath.Join(base, "cgroup.procs"))
+	if err != nil {
+		return "", err
+	}
+	for _, pid := range strings.Fields(string(procs)) {
+		// Processes may exit meanwhile; enabling controllers reports leftovers
+		_ = os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(pid), 0o644)
+	}
+
+	available, err := os.ReadFile(filepath.Join(base, "cgroup.controllers"))
+	if err != nil {
+		return "", err
+	}
+	var enable []string
+	for _, controller := range cgroupControllers {
+		if containsField(string(available), controller) {
+			enable = append(enable, "+"+controller)
+		}
+	}
+	if len(enable) > 0 {
+		control := filepath.Join(base, "cgroup.subtree_control")
+		if err := os.WriteFile(control, []byte(strings.Join(enable, " ")), 0o644); err != nil {
+			return "", fmt.Errorf("could not enable controllers in %s: %w", base, err)
+		}
+	}
+	return base, nil
+}
+
+// ownCgroup returns the cgroup v2 path of the supervisor from /proc/self/cgroup
+func ownCgroup() (string, error) {
+	file, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 entry in /proc/self/cgroup")
+}
+
+// containsField reports whether a space separated list contains field
+func containsField(list, field string) bool {
+	for _, f := range strings.Fields(list) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceCgroupPath returns the cgroup of a service under base
+func serviceCgroupPath(base, name string) string {
+	return filepath.Join(base, "service-"+name)
+}
+
+// openServiceCgroup creates the cgroup of a service, writes its limits and
+// opens it for starting the process directly inside it (CLONE_INTO_CGROUP).
+// The cgroup is kept across restarts; the limits are rewritten every start
+// so applied config changes take effect.
+func openServiceCgroup(service *Service) (*os.File, error) {
+	base, err := serviceCgroupBase()
+	if err != nil {
+		return nil, err
+	}
+	path := serviceCgroupPath(base, service.Name)
+	if err := os.Mkdir(path, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, err
+	}
+
+	limits := service.Cgroup
+	values := map[string]string{
+		"cpu.max":    "max",
+		"memory.max": "max",
+		"pids.max":   "max",
+	}
+	if limits.CPUMax != "" {
+		values["cpu.max"] = limits.CPUMax
+	}
+	if limits.MemoryMax != "" {
+		values["memory.max"] = limits.MemoryMax
+	}
+	if limits.PidsMax > 0 {
+		values["pids.max"] = strconv.Itoa(limits.PidsMax)
+	}
+	for file, value := range values {
+		if err := os.WriteFile(filepath.Join(path, file), []byte(value), 0o644); err != nil {
+			return nil, fmt.Errorf("could not set %s: %w", file, err)
+		}
+	}
+
+	return os.Open(path)
+}
+
+// readCgroupUsage reads the usage of the cgroup of a service, if it has one
+func readCgroupUsage(service *Service) *CgroupUsage {
+	if service.Cgroup == nil {
+		return nil
+	}
+	base, err := serviceCgroupBase()
+	if err != nil {
+		return nil
+	}
+	path := serviceCgroupPath(base, service.Name)
+	current, err := readCgroupValue(path, "memory.current")
+	if err != nil {
+		return nil
+	}
+
+	usage := &CgroupUsage{Path: strings.TrimPrefix(path, cgroupRoot), MemoryCurrent: current}
+	if data, err := os.ReadFile(filepath.Join(path, "memory.max")); err == nil {
+		usage.MemoryMax = strings.TrimSpace(string(data))
+	}
+	usage.PidsCurrent, _ = readCgroupValue(path, "pids.current")
+	cpu := readCgroupKeyed(path, "cpu.stat")
+	usage.CPUUsageUsec, usage.Throttled = cpu["usage_usec"], cpu["throttled_usec"]
+	usage.OOMKills = readCgroupKeyed(path, "memory.events")["oom_kill"]
+	return usage
+}
+
+// readCgroupValue reads a single number interface file
+func readCgroupValue(path, file string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(path, file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readCgroupKeyed reads a "key value" per line interface file
+func readCgroupKeyed(path, file string) map[string]uint64 {
+	values := make(map[string]uint64)
+	data, err := os.ReadFile(filepath.Join(path, file))
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			values[key] = n
+		}
+	}
+	return values
+}
+
+// describeCgroupUsage summarizes the usage for describe
+func describeCgroupUsage(u *CgroupUsage) string {
+	memory := formatBytes(float64(u.MemoryCurrent))
+	if u.MemoryMax != "" && u.MemoryMax != "max" {
+		if limit, err := strconv.ParseFloat(u.MemoryMax, 64); err == nil {
+			memory += " / " + formatBytes(limit)
+		}
+	}
+	summary := fmt.Sprintf("memory %s, cpu %s, %d pids", memory,
+		(time.Duration(u.CPUUsageUsec) * time.Microsecond).Round(time.Millisecond), u.PidsCurrent)
+	if u.OOMKills > 0 {
+		summary += fmt.Sprintf(", %d OOM kills", u.OOMKills)
+	}
+	return summary
+}
+
+var (
+	cgroupCPUMaxPattern    = regexp.MustCompile(`^(max|[0-9]+)( [0-9]+)?$`)
+	cgroupMemoryMaxPattern = regexp.MustCompile(`^(max|[0-9]+[KMGT]?)$`)
+)
+
+func validateCgroup(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	limits := service.Cgroup
+	if limits == nil {
+		return errors
+	}
+	if limits.CPUMax != "" && !cgroupCPUMaxPattern.MatchString(limits.CPUMax) {
+		errors = append(errors, ValidationError{
+			Field:   "cgroup.cpu_max",
+			Service: service.Name,
+			Message: fmt.Sprintf("invalid cpu_max '%s' (use \"<quota> <period>\" in microseconds or \"max\")", limits.CPUMax),
+		})
+	}
+	if limits.MemoryMax != "" && !cgroupMemoryMaxPattern.MatchString(limits.MemoryMax) {
+		errors = append(errors, ValidationError{
+			Field:   "cgroup.memory_max",
+			Service: service.Name,
+			Message: fmt.Sprintf("invalid memory_max '%s' (use bytes with an optional K, M, G or T suffix, or \"max\")", limits.MemoryMax),
+		})
+	}
+	if limits.PidsMax < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "cgroup.pids_max",
+			Service: service.Name,
+			Message: fmt.Sprintf("pids_max %d is negative", limits.PidsMax),
+		})
+	}
+
+	return errors
+}
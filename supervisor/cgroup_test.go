@@ -0,0 +1,123 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Test cgroup controls validation
+func TestValidateCgroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits CgroupLimits
+		errors int
+	}{
+		{"empty", CgroupLimits{}, 0},
+		{"all", CgroupLimits{CPUMax: "50000 100000", MemoryMax: "512M", PidsMax: 100}, 0},
+		{"max", CgroupLimits{CPUMax: "max", MemoryMax: "max"}, 0},
+		{"cpu quota only", CgroupLimits{CPUMax: "50000"}, 0},
+		{"cpu fraction", CgroupLimits{CPUMax: "0.5"}, 1},
+		{"memory unit", CgroupLimits{MemoryMax: "512MB"}, 1},
+		{"negative pids", CgroupLimits{PidsMax: -1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateCgroup(&Service{Name: "web", Cgroup: &tt.limits}); len(got) != tt.errors {
+				t.Errorf("validateCgroup() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test service cgroups are set up in a fake cgroup v2 tree: the supervisor
+// moves to a leaf, controllers are enabled and limits written
+func TestServiceCgroup(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("cgroups require root")
+	}
+	self, err := ownCgroup()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	root := t.TempDir()
+	base := filepath.Join(root, self)
+	files := map[string]string{
+		"cgroup.controllers":     "cpuset cpu io memory pids",
+		"cgroup.procs":           "1\n42\n",
+		"cgroup.subtree_control": "",
+	}
+	if err := os.MkdirAll(base, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(base, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	savedRoot := cgroupRoot
+	cgroupRoot = root
+	cgroupBase.once, cgroupBase.path, cgroupBase.err = sync.Once{}, "", nil
+	defer func() {
+		cgroupRoot = savedRoot
+		cgroupBase.once, cgroupBase.path, cgroupBase.err = sync.Once{}, "", nil
+	}()
+
+	service := &Service{Name: "web", Cgroup: &CgroupLimits{MemoryMax: "512M", PidsMax: 64}}
+	dir, err := openServiceCgroup(service)
+	if err != nil {
+		t.Fatalf("openServiceCgroup() error = %v", err)
+	}
+	dir.Close()
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read(filepath.Join(base, "cgroup.subtree_control")); got != "+cpu +memory +pids" {
+		t.Errorf("subtree_control = %q", got)
+	}
+	if got := read(filepath.Join(base, cgroupSupervisorLeaf, "cgroup.procs")); got != "42" {
+		t.Errorf("last pid moved to the supervisor leaf = %q, want 42", got)
+	}
+	path := serviceCgroupPath(base, "web")
+	for file, want := range map[string]string{"memory.max": "512M", "pids.max": "64", "cpu.max": "max"} {
+		if got := read(filepath.Join(path, file)); got != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+
+	// The kernel fills the usage files; fake them
+	usageFiles := map[string]string{
+		"memory.current": "1048576\n",
+		"memory.max":     "536870912\n",
+		"pids.current":   "3\n",
+		"cpu.stat":       "usage_usec 1500000\nuser_usec 1000000\nthrottled_usec 20\n",
+		"memory.events":  "low 0\nhigh 0\nmax 2\noom 1\noom_kill 1\n",
+	}
+	for file, content := range usageFiles {
+		if err := os.WriteFile(filepath.Join(path, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	usage := readCgroupUsage(service)
+	if usage == nil {
+		t.Fatal("readCgroupUsage() = nil")
+	}
+	if usage.MemoryCurrent != 1<<20 || usage.PidsCurrent != 3 || usage.CPUUsageUsec != 1500000 || usage.OOMKills != 1 {
+		t.Errorf("readCgroupUsage() = %+v", usage)
+	}
+	if got := describeCgroupUsage(usage); !strings.Contains(got, "memory 1.0 MiB / 512.0 MiB, cpu 1.5s, 3 pids, 1 OOM kills") {
+		t.Errorf("describeCgroupUsage() = %q", got)
+	}
+	if readCgroupUsage(&Service{Name: "web"}) != nil {
+		t.Error("readCgroupUsage() of a service without cgroup != nil")
+	}
+}
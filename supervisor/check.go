@@ -0,0 +1,58 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// checkConfig validates configFile, resolving paths and users against rootfs
+// when set (e.g. an image root in a build pipeline). With lint, warnings are
+// printed and make the check fail.
+func checkConfig(configFile, rootfs string, lint bool) error {
+	if rootfs != "" {
+		info, err := os.Stat(rootfs)
+		if err != nil {
+			return fmt.Errorf("invalid rootfs: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid rootfs: %s is not a directory", rootfs)
+		}
+		validationRoot = rootfs
+		defer func() { validationRoot = "" }()
+		logger.Info(fmt.Sprintf("Validating against rootfs %s", colorize(ColorCyan, rootfs)))
+	}
+
+	config, err := loadAndValidateConfig(configFile)
+	if err != nil {
+		return checkFailure(configFile, err)
+	}
+
+	if lint {
+		warnings := lintConfig(&config)
+		printLintWarnings(warnings)
+		if len(warnings) > 0 {
+			return fmt.Errorf("lint found %d warning(s) in %s", len(warnings), configSources(configFile, configDir))
+		}
+	}
+
+	logger.Success(fmt.Sprintf("%d services defined in %s", len(config.Services), colorize(ColorCyan, configSources(configFile, configDir))))
+	return nil
+}
+
+// checkFailure lists every validation problem on its own line, so CI logs
+// show all of them at once; parse errors already come one per line
+func checkFailure(configFile string, err error) error {
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	lines := make([]string, 0, len(validationErrs))
+	for _, validationErr := range validationErrs {
+		lines = append(lines, "  "+validationErr.Error())
+	}
+	return fmt.Errorf("%d problem(s) in %s:\n%s",
+		len(validationErrs), configSources(configFile, configDir), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,47 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test check reports every validation problem, one per line
+func TestCheckConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "services.toml")
+	content := `
+[[services]]
+name = "a"
+command = "/nonexistent/a"
+depends_on = "b"
+
+[[services]]
+name = "b"
+command = "/bin/sh"
+depends_on = "a"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := checkConfig(configFile, "", false)
+	if err == nil {
+		t.Fatal("checkConfig() error = nil for an invalid config")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "2 problem(s) in "+configFile) {
+		t.Fatalf("checkConfig() error = %q, want a header and one line per problem", err)
+	}
+	if !strings.Contains(lines[1], "/nonexistent/a") || !strings.Contains(lines[2], "circular dependency") {
+		t.Errorf("checkConfig() error = %q", err)
+	}
+
+	valid := filepath.Join(t.TempDir(), "services.toml")
+	if err := os.WriteFile(valid, []byte("[[services]]\nname = \"b\"\ncommand = \"/bin/sh\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkConfig(valid, "", false); err != nil {
+		t.Errorf("checkConfig() of a valid config error = %v", err)
+	}
+}
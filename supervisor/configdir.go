@@ -0,0 +1,163 @@
+package supervisor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// configDir is a directory of *.toml files adding services to the main
+// config file (--config-dir or GO_OVERLAY_CONFIG_DIR)
+var configDir string
+
+// configFileDefault returns the config file of GO_OVERLAY_CONFIG, if set
+func configFileDefault() string {
+	if path := os.Getenv(envConfigFile); path != "" {
+		return path
+	}
+	return defaultConfigFile
+}
+
+// configSources describes where the configuration is read from, for messages
+func configSources(configFile, dir string) string {
+	if dir == "" {
+		return configFile
+	}
+	return configFile + " and " + filepath.Join(dir, "*.toml")
+}
+
+// parseConfigSources parses configFile and merges in the services of every
+// *.toml file of dir, in lexical order. With a dir, the main file may be
+// missing. Files of dir may only define services: timeouts, logging and the
+// other top-level settings belong to the main file. Problems of every file
+// are reported together.
+func parseConfigSources(configFile, dir string) (Config, error) {
+	if dir == "" {
+		return parseConfigFile(configFile)
+	}
+
+	var errs ConfigErrors
+	addErr := func(err error) {
+		var configErrs ConfigErrors
+		if errors.As(err, &configErrs) {
+			errs = append(errs, configErrs...)
+		} else {
+			errs = append(errs, &ConfigError{File: configFile, Message: err.Error(), serviceIndex: -1})
+		}
+	}
+
+	var config Config
+	definedIn := make(map[string]string)
+	if _, err := os.Stat(configFile); err == nil {
+		main, err := parseConfigFile(configFile)
+		if err != nil {
+			addErr(err)
+		}
+		config = main
+		for _, name := range definedServiceNames(&main) {
+			definedIn[name] = configFile
+		}
+	} else if !os.IsNotExist(err) {
+		return Config{}, fmt.Errorf("error opening config file %s: %w", configFile, err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Config{}, fmt.Errorf("error opening config dir: %w", err)
+	}
+	if !info.IsDir() {
+		return Config{}, fmt.Errorf("config dir %s is not a directory", dir)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return Config{}, fmt.Errorf("error listing config dir %s: %w", dir, err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, &ConfigError{File: file, Message: err.Error(), serviceIndex: -1})
+			continue
+		}
+		part, err := parseConfig(bytes.NewReader(data))
+		if err != nil {
+			errs = append(errs, locateConfigErrors(file, data, err)...)
+			continue
+		}
+
+		if settings := topLevelSettings(&part); len(settings) > 0 {
+			errs = append(errs, &ConfigError{
+				File:         file,
+				Message:      fmt.Sprintf("only services can be defined in the config dir; move %s to %s", strings.Join(settings, ", "), configFile),
+				serviceIndex: -1,
+			})
+		}
+
+		lines := strings.Split(string(data), "\n")
+		for i, name := range definedServiceNames(&part) {
+			if other, ok := definedIn[name]; ok {
+				errs = append(errs, &ConfigError{
+					File:         file,
+					Line:         findServiceKeyLine(lines, i, "name"),
+					Key:          fmt.Sprintf("services[%d].name", i),
+					Message:      fmt.Sprintf("service '%s' is already defined in %s", name, other),
+					serviceIndex: i,
+				})
+				continue
+			}
+			definedIn[name] = file
+		}
+		config.Services = append(config.Services, part.Services...)
+		config.ServiceTemplates = append(config.ServiceTemplates, part.ServiceTemplates...)
+	}
+
+	if len(errs) > 0 {
+		return Config{}, errs
+	}
+	// Services may depend on a template of another file
+	resolveInstanceDependencies(&config)
+	return config, nil
+}
+
+// definedServiceNames returns the names of the [[services]] entries of a
+// config, where a service template stands for its instances
+func definedServiceNames(config *Config) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, service := range config.Services {
+		name := service.Name
+		if service.InstanceOf != "" {
+			name = service.InstanceOf
+		}
+		if !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	// Templates without instances
+	for _, template := range config.ServiceTemplates {
+		if !seen[template.Name] {
+			names = append(names, template.Name)
+		}
+	}
+	return names
+}
+
+// topLevelSettings returns the TOML keys of the settings other than services
+// that config sets
+func topLevelSettings(config *Config) []string {
+	var keys []string
+	value := reflect.ValueOf(*config)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Name == "Services" || field.Name == "ServiceTemplates" || value.Field(i).IsZero() {
+			continue
+		}
+		keys = append(keys, tomlKey(field))
+	}
+	return keys
+}
@@ -0,0 +1,144 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test the services of a config dir are merged into the main config file
+func TestParseConfigSources(t *testing.T) {
+	const mainConfig = `
+[timeouts]
+service_shutdown_timeout = 3
+
+[[services]]
+name = "app"
+command = "/bin/sh"
+`
+	tests := []struct {
+		name      string
+		main      string // Empty to leave the main file out
+		files     map[string]string
+		wantNames []string
+		wantErr   []string
+	}{
+		{
+			name: "Merged in lexical order",
+			main: mainConfig,
+			files: map[string]string{
+				"20-worker.toml": "[[services]]\nname = \"worker\"\ncommand = \"/bin/sh\"\n",
+				"10-cron.toml":   "[[services]]\nname = \"cron\"\ncommand = \"/bin/sh\"\n",
+				"README.md":      "not a config file",
+			},
+			wantNames: []string{"app", "cron", "worker"},
+		},
+		{
+			name:      "Without a main file",
+			files:     map[string]string{"web.toml": "[[services]]\nname = \"web\"\ncommand = \"/bin/sh\"\n"},
+			wantNames: []string{"web"},
+		},
+		{
+			name: "Duplicate service",
+			main: mainConfig,
+			files: map[string]string{
+				"app.toml": "[[services]]\nname = \"app\"\ncommand = \"/bin/sh\"\n",
+			},
+			wantErr: []string{"app.toml:2", "service 'app' is already defined in"},
+		},
+		{
+			name: "Service template",
+			main: mainConfig,
+			files: map[string]string{
+				"worker.toml": "[[services]]\nname = \"worker\"\ncommand = \"/bin/sh\"\ninstances = 2\n",
+			},
+			wantNames: []string{"app", "worker@1", "worker@2"},
+		},
+		{
+			name: "Duplicate service template",
+			main: mainConfig,
+			files: map[string]string{
+				"app.toml": "[[services]]\nname = \"app\"\ncommand = \"/bin/sh\"\ninstances = 2\n",
+			},
+			wantErr: []string{"app.toml:2", "service 'app' is already defined in"},
+		},
+		{
+			name: "Top-level settings in the dir",
+			main: mainConfig,
+			files: map[string]string{
+				"timeouts.toml": "[timeouts]\nglobal_shutdown_timeout = 5\n",
+			},
+			wantErr: []string{"timeouts.toml", "move timeouts to"},
+		},
+		{
+			name: "Errors of every file",
+			files: map[string]string{
+				"a.toml": "[[services]]\nname = \"a\"\ncommand = \"/bin/sh\"\nwait_after = \"soon\"\n",
+				"b.toml": "[[services\n",
+			},
+			wantErr: []string{"a.toml:4", "b.toml:1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "services.d")
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			configFile := filepath.Join(root, "services.toml")
+			if tt.main != "" {
+				if err := os.WriteFile(configFile, []byte(tt.main), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			config, err := parseConfigSources(configFile, dir)
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatal("parseConfigSources() error = nil")
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("parseConfigSources() error = %v, want %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfigSources() error = %v", err)
+			}
+
+			var names []string
+			for _, service := range config.Services {
+				names = append(names, service.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("services = %v, want %v", names, tt.wantNames)
+			}
+			if tt.main != "" && config.Timeouts.ServiceShutdown != 3 {
+				t.Errorf("service_shutdown_timeout = %d, want 3 from the main file", config.Timeouts.ServiceShutdown)
+			}
+		})
+	}
+}
+
+// Test GO_OVERLAY_CONFIG overrides the default config file
+func TestConfigFileDefault(t *testing.T) {
+	t.Setenv(envConfigFile, "")
+	if got := configFileDefault(); got != defaultConfigFile {
+		t.Errorf("configFileDefault() = %q, want %q", got, defaultConfigFile)
+	}
+
+	t.Setenv(envConfigFile, "/etc/go-overlay/services.toml")
+	if got := configFileDefault(); got != "/etc/go-overlay/services.toml" {
+		t.Errorf("configFileDefault() = %q, want the path of %s", got, envConfigFile)
+	}
+}
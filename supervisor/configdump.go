@@ -0,0 +1,123 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Output formats of `go-overlay config dump`
+const (
+	DumpFormatTOML = "toml"
+	DumpFormatJSON = "json"
+)
+
+// resolvedConfig returns a validated config with the defaults the supervisor
+// applies at runtime written out, so a dump shows what will actually happen
+func resolvedConfig(config Config) Config {
+	config.Services = slices.Clone(config.Services)
+
+	if config.Logging.Overflow == "" {
+		config.Logging.Overflow = LogOverflowBlock
+	}
+	if config.Logging.BufferSize <= 0 {
+		config.Logging.BufferSize = defaultLogBufferSize
+	}
+	if config.ValidateCommands == nil {
+		config.ValidateCommands = new(bool)
+		*config.ValidateCommands = true
+	}
+
+	for i := range config.Services {
+		service := &config.Services[i]
+		if service.Restart == "" {
+			service.Restart = RestartNever
+		}
+		if service.RestartBackoff <= 0 {
+			service.RestartBackoff = defaultRestartBackoff
+		}
+		if service.RestartBackoffMax <= 0 {
+			service.RestartBackoffMax = defaultRestartBackoffMax
+		}
+		if service.LogBufferLines <= 0 {
+			service.LogBufferLines = defaultLogBufferLines
+		}
+		if service.DependsOnCondition == "" {
+			service.DependsOnCondition = DependsOnStarted
+		}
+		if service.StopSignal == "" {
+			service.StopSignal = "SIGTERM"
+		}
+		if service.StopTimeout <= 0 {
+			service.StopTimeout = config.Timeouts.ServiceShutdown
+		}
+		if service.KillTimeout <= 0 {
+			service.KillTimeout = defaultKillTimeout
+		}
+		if service.ValidateCommands == nil {
+			service.ValidateCommands = config.ValidateCommands
+		}
+		if service.LogOutput != nil {
+			output := *service.LogOutput
+			if output.MaxSize <= 0 {
+				output.MaxSize = defaultLogOutputMaxSize
+			}
+			if output.MaxFiles <= 0 {
+				output.MaxFiles = defaultLogOutputMaxFiles
+			}
+			service.LogOutput = &output
+		}
+	}
+
+	return config
+}
+
+// renderConfig encodes config as TOML, or as JSON with the same keys
+func renderConfig(config *Config, format string) ([]byte, error) {
+	data, err := marshalConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case DumpFormatTOML:
+		return data, nil
+	case DumpFormatJSON:
+		var document map[string]any
+		if err := toml.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		out, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	}
+	return nil, fmt.Errorf("unknown format '%s' (use %s or %s)", format, DumpFormatTOML, DumpFormatJSON)
+}
+
+// dumpConfig prints the resolved configuration of configFile (merged with the
+// config dir, if any) without starting anything
+func dumpConfig(configFile, format string) error {
+	config, err := parseConfigSources(configFile, configDir)
+	if err != nil {
+		return err
+	}
+	if err := validateConfig(&config); err != nil {
+		return checkFailure(configFile, err)
+	}
+
+	resolved := resolvedConfig(config)
+	data, err := renderConfig(&resolved, format)
+	if err != nil {
+		return err
+	}
+
+	if format == DumpFormatTOML {
+		fmt.Printf("# Resolved by go-overlay %s from %s\n", version, configSources(configFile, configDir))
+	}
+	fmt.Print(string(data))
+	return nil
+}
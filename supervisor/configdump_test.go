@@ -0,0 +1,78 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Test the runtime defaults are written out and explicit settings kept
+func TestResolvedConfig(t *testing.T) {
+	config := mustParseConfig(t, `
+[timeouts]
+service_shutdown_timeout = 4
+
+[[services]]
+name = "web"
+command = "/bin/sh"
+
+[[services]]
+name = "worker"
+command = "/bin/sh"
+restart = "always"
+stop_signal = "SIGQUIT"
+log_output = { path = "/tmp/worker.log" }
+`)
+
+	resolved := resolvedConfig(*config)
+	web, worker := resolved.Services[0], resolved.Services[1]
+
+	if web.Restart != RestartNever || web.RestartBackoff != defaultRestartBackoff || web.LogBufferLines != defaultLogBufferLines {
+		t.Errorf("web restart/log defaults = %q, %d, %d", web.Restart, web.RestartBackoff, web.LogBufferLines)
+	}
+	if web.StopSignal != "SIGTERM" || web.StopTimeout != 4 {
+		t.Errorf("web stop defaults = %q, %d, want SIGTERM after service_shutdown_timeout", web.StopSignal, web.StopTimeout)
+	}
+	if worker.Restart != RestartAlways || worker.StopSignal != "SIGQUIT" {
+		t.Errorf("worker explicit settings changed: %q, %q", worker.Restart, worker.StopSignal)
+	}
+	if worker.LogOutput.MaxFiles != defaultLogOutputMaxFiles {
+		t.Errorf("worker log_output.max_files = %d, want %d", worker.LogOutput.MaxFiles, defaultLogOutputMaxFiles)
+	}
+	if config.Services[1].LogOutput.MaxFiles != 0 || config.Services[0].Restart != "" {
+		t.Error("resolvedConfig() modified the config it was given")
+	}
+}
+
+// Test a dump can be loaded again, and JSON uses the TOML keys
+func TestRenderConfig(t *testing.T) {
+	resolved := resolvedConfig(*mustParseConfig(t, "[[services]]\nname = \"web\"\ncommand = \"/bin/sh\"\n"))
+
+	data, err := renderConfig(&resolved, DumpFormatTOML)
+	if err != nil {
+		t.Fatalf("renderConfig(toml) error = %v", err)
+	}
+	reloaded := mustParseConfig(t, string(data))
+	if changes := diffConfigs(&resolved, reloaded); len(changes) > 0 {
+		t.Errorf("reloaded dump differs: %+v", changes)
+	}
+	if reloaded.Timeouts != resolved.Timeouts || reloaded.Logging != resolved.Logging {
+		t.Errorf("reloaded dump settings = %+v, %+v", reloaded.Timeouts, reloaded.Logging)
+	}
+
+	data, err = renderConfig(&resolved, DumpFormatJSON)
+	if err != nil {
+		t.Fatalf("renderConfig(json) error = %v", err)
+	}
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !strings.Contains(string(data), `"log_buffer_lines": 500`) {
+		t.Errorf("JSON dump misses log_buffer_lines:\n%s", data)
+	}
+
+	if _, err := renderConfig(&resolved, "yaml"); err == nil {
+		t.Error("renderConfig(yaml) error = nil")
+	}
+}
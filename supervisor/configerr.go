@@ -0,0 +1,178 @@
+package supervisor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// ConfigError locates a problem in a configuration file
+type ConfigError struct {
+	File    string
+	Key     string // Offending key, e.g. services[1].depends_on
+	Message string
+	Line    int // 1-based, 0 when unknown
+	Column  int
+
+	serviceIndex int // Index of the [[services]] entry, -1 when not service-specific
+	field        string
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d", e.Line)
+			if e.Column > 0 {
+				fmt.Fprintf(&b, ":%d", e.Column)
+			}
+		}
+		b.WriteString(": ")
+	}
+	if e.Key != "" {
+		fmt.Fprintf(&b, "key '%s': ", e.Key)
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ConfigErrors collects every problem found while parsing one or more files
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// serviceFieldError reports an invalid field of the index-th [[services]] entry
+func serviceFieldError(index int, field, message string) *ConfigError {
+	return &ConfigError{
+		Key:          fmt.Sprintf("services[%d].%s", index, field),
+		Message:      message,
+		serviceIndex: index,
+		field:        field,
+	}
+}
+
+// structFieldPattern matches the Go type details go-toml puts in decode errors
+var structFieldPattern = regexp.MustCompile(`struct field \S+ of type `)
+
+// decodeErrorMessage turns a go-toml error into a message about the TOML value
+func decodeErrorMessage(err error) string {
+	return structFieldPattern.ReplaceAllString(strings.TrimPrefix(err.Error(), "toml: "), "")
+}
+
+// parseConfigFile parses path, reporting errors with file, line and key context
+func parseConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("error opening config file %s: %w", path, err)
+	}
+
+	config, err := parseConfig(bytes.NewReader(data))
+	if err != nil {
+		return Config{}, locateConfigErrors(path, data, err)
+	}
+	return config, nil
+}
+
+// locateConfigErrors attaches file and position information to parse errors
+func locateConfigErrors(path string, data []byte, err error) ConfigErrors {
+	lines := strings.Split(string(data), "\n")
+
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		row, col := decodeErr.Position()
+		return ConfigErrors{{
+			File:    path,
+			Line:    row,
+			Column:  col,
+			Key:     keyAtLine(lines, row),
+			Message: decodeErrorMessage(decodeErr),
+		}}
+	}
+
+	var configErrs ConfigErrors
+	if !errors.As(err, &configErrs) {
+		return ConfigErrors{{File: path, Message: decodeErrorMessage(err)}}
+	}
+
+	for _, configErr := range configErrs {
+		configErr.File = path
+		if configErr.Line == 0 && configErr.serviceIndex >= 0 {
+			configErr.Line = findServiceKeyLine(lines, configErr.serviceIndex, configErr.field)
+		}
+	}
+	return configErrs
+}
+
+// keyAtLine returns the dotted key (prefixed with its table) assigned on the 1-based line row
+func keyAtLine(lines []string, row int) string {
+	if row < 1 || row > len(lines) {
+		return ""
+	}
+
+	table := ""
+	arrayCounts := make(map[string]int)
+	for i := 0; i < row; i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(line, "[["):
+			name := strings.TrimSpace(strings.Trim(line, "[]"))
+			table = fmt.Sprintf("%s[%d]", name, arrayCounts[name])
+			arrayCounts[name]++
+		case strings.HasPrefix(line, "["):
+			table = strings.TrimSpace(strings.Trim(line, "[]"))
+			// Sub-tables of an array entry, e.g. [services.register]
+			if parent, rest, ok := strings.Cut(table, "."); ok && arrayCounts[parent] > 0 {
+				table = fmt.Sprintf("%s[%d].%s", parent, arrayCounts[parent]-1, rest)
+			}
+		}
+	}
+
+	line := strings.TrimSpace(lines[row-1])
+	if strings.HasPrefix(line, "[") {
+		return table
+	}
+	key, _, ok := strings.Cut(line, "=")
+	if !ok {
+		return table
+	}
+	key = strings.TrimSpace(key)
+	if table == "" {
+		return key
+	}
+	return table + "." + key
+}
+
+// findServiceKeyLine returns the line assigning field in the index-th [[services]] entry
+func findServiceKeyLine(lines []string, index int, field string) int {
+	current := -1
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[[") && strings.TrimSpace(strings.Trim(line, "[]")) == "services" {
+				current++
+			} else if current == index && !strings.HasPrefix(line, "[services.") {
+				return 0
+			}
+			continue
+		}
+		if current != index {
+			continue
+		}
+		if key, _, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == field {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package supervisor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test parse errors carry file, line and key context
+func TestParseConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want []string
+	}{
+		{
+			name: "Type mismatch",
+			toml: "[[services]]\nname = \"web\"\nrequired = \"yes\"\n",
+			want: []string{"services.toml:3:12: key 'services[0].required': cannot decode TOML string into bool"},
+		},
+		{
+			name: "Syntax error",
+			toml: "[timeouts]\npost_script_timeout = \n",
+			want: []string{"services.toml:2:"},
+		},
+		{
+			name: "Every bad service is reported",
+			toml: `[[services]]
+name = "a"
+command = "/bin/true"
+depends_on = 5
+
+[[services]]
+name = "b"
+command = "/bin/true"
+wait_after = "soon"
+`,
+			want: []string{
+				"services.toml:4: key 'services[0].depends_on'",
+				"services.toml:9: key 'services[1].wait_after'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "services.toml")
+			if err := os.WriteFile(path, []byte(tt.toml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := parseConfigFile(path)
+			var configErrs ConfigErrors
+			if !errors.As(err, &configErrs) {
+				t.Fatalf("parseConfigFile() error = %v, want ConfigErrors", err)
+			}
+			if len(configErrs) != len(tt.want) {
+				t.Fatalf("parseConfigFile() returned %d errors, want %d: %v", len(configErrs), len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				got := strings.TrimPrefix(configErrs[i].Error(), filepath.Dir(path)+string(filepath.Separator))
+				if !strings.HasPrefix(got, want) {
+					t.Errorf("error %d = %q, want prefix %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// Test keys are resolved relative to their table
+func TestKeyAtLine(t *testing.T) {
+	lines := strings.Split("status_dir = \"/run\"\n[timeouts]\npost_script_timeout = 1\n[[services]]\nname = \"a\"\n[[services]]\nname = \"b\"\n[services.register]\nprovider = 1", "\n")
+
+	tests := []struct {
+		row  int
+		want string
+	}{
+		{1, "status_dir"},
+		{3, "timeouts.post_script_timeout"},
+		{5, "services[0].name"},
+		{7, "services[1].name"},
+		{9, "services[1].register.provider"},
+		{0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := keyAtLine(lines, tt.row); got != tt.want {
+			t.Errorf("keyAtLine(%d) = %q, want %q", tt.row, got, tt.want)
+		}
+	}
+}
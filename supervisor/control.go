@@ -0,0 +1,124 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// controlFIFOName is the per-service control FIFO under the status directory
+const controlFIFOName = "control"
+
+// controlSignals maps s6-svc style command letters to signals
+var controlSignals = map[byte]syscall.Signal{
+	'a': syscall.SIGALRM,
+	'b': syscall.SIGABRT,
+	'c': syscall.SIGCONT,
+	'h': syscall.SIGHUP,
+	'i': syscall.SIGINT,
+	'k': syscall.SIGKILL,
+	'p': syscall.SIGSTOP,
+	'q': syscall.SIGQUIT,
+	't': syscall.SIGTERM,
+	'w': syscall.SIGWINCH,
+	'1': syscall.SIGUSR1,
+	'2': syscall.SIGUSR2,
+}
+
+// startControlFIFOs creates and serves the control FIFO of every service that enables it
+func startControlFIFOs(services []Service) {
+	for i := range services {
+		if !services[i].ControlFIFO {
+			continue
+		}
+		if statusDir == "" {
+			logger.Warn(fmt.Sprintf("Control FIFO for service '%s' requires the status directory, skipping",
+				colorize(ColorCyan, services[i].Name)))
+			continue
+		}
+
+		fifo, err := openControlFIFO(services[i].Name)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Could not create control FIFO for service '%s': %v",
+				colorize(ColorCyan, services[i].Name), err))
+			continue
+		}
+		go serveControlFIFO(services[i].Name, fifo)
+	}
+}
+
+// openControlFIFO creates <statusDir>/<service>/control and opens it for reading
+func openControlFIFO(name string) (*os.File, error) {
+	dir := serviceStatusDir(name)
+	if err := os.MkdirAll(dir, 0o755); err != nil { // #nosec G301 - status files are meant to be world-readable
+		return nil, err
+	}
+
+	path := filepath.Join(dir, controlFIFOName)
+	_ = os.Remove(path)
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		return nil, err
+	}
+
+	// Opening read-write keeps a writer attached, so reads block instead of
+	// returning EOF each time a client closes its end.
+	return os.OpenFile(path, os.O_RDWR, os.ModeNamedPipe)
+}
+
+// serveControlFIFO executes commands written to the FIFO until shutdown
+func serveControlFIFO(name string, fifo *os.File) {
+	go func() {
+		<-shutdownContext().Done()
+		_ = fifo.Close()
+	}()
+
+	buf := make([]byte, 64)
+	for {
+		n, err := fifo.Read(buf)
+		for _, c := range buf[:n] {
+			if err := runControlCommand(name, c); err != nil {
+				logger.Warn(fmt.Sprintf("Control command '%c' for service '%s' failed: %v",
+					c, colorize(ColorCyan, name), err))
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				logger.Error(fmt.Sprintf("Error reading control FIFO for service '%s': %v",
+					colorize(ColorCyan, name), err))
+			}
+			return
+		}
+	}
+}
+
+// runControlCommand executes a single control letter:
+// u (up), d (down), r (restart) or a signal letter (see controlSignals)
+func runControlCommand(name string, c byte) error {
+	switch c {
+	case ' ', '\n', '\r', '\t':
+		return nil
+	case 'u':
+		if err := startService(name); err != nil {
+			return err
+		}
+		markStoppedByHand(name, false)
+		return nil
+	case 'd':
+		if err := stopService(name); err != nil {
+			return err
+		}
+		markStoppedByHand(name, true)
+		return nil
+	case 'r':
+		_, err := requestRestart(name)
+		return err
+	}
+
+	if sig, ok := controlSignals[c]; ok {
+		return signalService(name, sig, false)
+	}
+	return fmt.Errorf("unknown control command")
+}
@@ -0,0 +1,81 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it returns true or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return cond()
+}
+
+// Test control letters validation
+func TestRunControlCommandUnknown(t *testing.T) {
+	if err := runControlCommand("svc", '\n'); err != nil {
+		t.Errorf("whitespace should be ignored, got %v", err)
+	}
+	if err := runControlCommand("svc", 'z'); err == nil {
+		t.Error("expected error for unknown control command")
+	}
+	if err := runControlCommand("svc", 'h'); err == nil {
+		t.Error("expected error signaling a service that is not running")
+	}
+}
+
+// Test down/up through the control FIFO
+func TestControlFIFODownUp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	resetShutdown()
+	defer stopTestServices("sleeper")
+	statusDir = t.TempDir()
+	defer func() { statusDir = "" }()
+
+	config := &Config{
+		Services: []Service{{Name: "sleeper", Command: "/bin/sleep", Args: []string{"30"}, ControlFIFO: true}},
+		Timeouts: Timeouts{ServiceShutdown: 2},
+	}
+	useConfig(t, config)
+
+	startControlFIFOs(config.Services)
+	fifoPath := filepath.Join(statusDir, "sleeper", controlFIFOName)
+
+	if err := startService("sleeper"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	running := func() bool { _, ok := getActiveService("sleeper"); return ok }
+	if !waitFor(t, 5*time.Second, running) {
+		t.Fatal("service did not start")
+	}
+
+	if err := os.WriteFile(fifoPath, []byte("d"), 0o600); err != nil {
+		t.Fatalf("writing control FIFO: %v", err)
+	}
+	if !waitFor(t, 5*time.Second, func() bool { return !running() }) {
+		t.Fatal("service still running after 'd'")
+	}
+
+	if err := os.WriteFile(fifoPath, []byte("u"), 0o600); err != nil {
+		t.Fatalf("writing control FIFO: %v", err)
+	}
+	if !waitFor(t, 5*time.Second, running) {
+		t.Fatal("service not running after 'u'")
+	}
+
+	if err := stopService("sleeper"); err != nil {
+		t.Errorf("stopService() error = %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// serviceCredential resolves the user and group a service runs as, without
+// relying on su being installed. The group defaults to the primary group of
+// the user, whose supplementary groups are kept. It also returns the HOME,
+// USER and LOGNAME of the user, as su would set them. A nil credential means
+// the service runs as the supervisor.
+func serviceCredential(service *Service) (*syscall.Credential, map[string]string, error) {
+	if service.User == "" && service.Group == "" {
+		return nil, nil, nil
+	}
+
+	cred := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+	var env map[string]string
+
+	if service.User != "" {
+		u, err := lookupUser(service.User)
+		if err != nil {
+			return nil, nil, err
+		}
+		uid, err := parseID(u.Uid)
+		if err != nil {
+			return nil, nil, err
+		}
+		gid, err := parseID(u.Gid)
+		if err != nil {
+			return nil, nil, err
+		}
+		cred.Uid, cred.Gid = uid, gid
+
+		if groups, err := u.GroupIds(); err == nil {
+			for _, group := range groups {
+				if id, err := parseID(group); err == nil {
+					cred.Groups = append(cred.Groups, id)
+				}
+			}
+		}
+
+		env = map[string]string{"USER": u.Username, "LOGNAME": u.Username}
+		if u.HomeDir != "" {
+			env["HOME"] = u.HomeDir
+		}
+	}
+
+	if service.Group != "" {
+		gid, err := lookupGroupID(service.Group)
+		if err != nil {
+			return nil, nil, err
+		}
+		cred.Gid = gid
+	}
+
+	return cred, env, nil
+}
+
+// serviceProcessEnv returns the environment of the process of a service and
+// the credential it runs with: the variables of its user are added, unless
+// the service sets them itself
+func serviceProcessEnv(service *Service) ([]string, *syscall.Credential, error) {
+	env := buildServiceEnv(service)
+	cred, userEnv, err := serviceCredential(service)
+	if err != nil || cred == nil {
+		return env, nil, err
+	}
+	for key := range service.Env {
+		delete(userEnv, key)
+	}
+	return mergeEnv(env, userEnv), cred, nil
+}
+
+// lookupUser finds a user by name or uid. A numeric uid missing from
+// /etc/passwd is used as is, with the same gid.
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, numeric := strconv.ParseUint(name, 10, 32); numeric != nil {
+		return nil, fmt.Errorf("user '%s' does not exist", name)
+	}
+	if u, err := user.LookupId(name); err == nil {
+		return u, nil
+	}
+	return &user.User{Uid: name, Gid: name, Username: name}, nil
+}
+
+// lookupGroupID finds a group by name or gid; numeric gids need not exist
+func lookupGroupID(name string) (uint32, error) {
+	if id, err := parseID(name); err == nil {
+		return id, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		var unknown user.UnknownGroupError
+		if errors.As(err, &unknown) {
+			return 0, fmt.Errorf("group '%s' does not exist", name)
+		}
+		return 0, err
+	}
+	return parseID(g.Gid)
+}
+
+func parseID(id string) (uint32, error) {
+	value, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id '%s'", id)
+	}
+	return uint32(value), nil
+}
+
+func validateUser(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.User != "" {
+		if validationRoot != "" {
+			if !existsInRootDB("/etc/passwd", service.User) {
+				errors = append(errors, ValidationError{
+					Field:   "user",
+					Service: service.Name,
+					Message: fmt.Sprintf("user '%s' does not exist in rootfs %s", service.User, validationRoot),
+				})
+			}
+		} else if _, err := lookupUser(service.User); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "user",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	if service.Group != "" {
+		if validationRoot != "" {
+			if !existsInRootDB("/etc/group", service.Group) {
+				errors = append(errors, ValidationError{
+					Field:   "group",
+					Service: service.Name,
+					Message: fmt.Sprintf("group '%s' does not exist in rootfs %s", service.Group, validationRoot),
+				})
+			}
+		} else if _, err := lookupGroupID(service.Group); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "group",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,75 @@
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// Test user and group resolve to a credential without su
+func TestServiceCredential(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		uid     uint32
+		gid     uint32
+		wantErr bool
+	}{
+		{"User by name", Service{Name: "web", User: "root"}, 0, 0, false},
+		{"Numeric user not in passwd", Service{Name: "web", User: "4242"}, 4242, 4242, false},
+		{"Group overrides primary group", Service{Name: "web", User: "4242", Group: "100"}, 4242, 100, false},
+		{"Group by name", Service{Name: "web", User: "4242", Group: "root"}, 4242, 0, false},
+		{"Unknown user", Service{Name: "web", User: "no-such-user"}, 0, 0, true},
+		{"Unknown group", Service{Name: "web", Group: "no-such-group"}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, _, err := serviceCredential(&tt.service)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("serviceCredential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cred.Uid != tt.uid || cred.Gid != tt.gid {
+				t.Errorf("credential = %d:%d, want %d:%d", cred.Uid, cred.Gid, tt.uid, tt.gid)
+			}
+		})
+	}
+
+	if cred, env, err := serviceCredential(&Service{Name: "web"}); cred != nil || env != nil || err != nil {
+		t.Errorf("serviceCredential() without user = %v, %v, %v, want nothing", cred, env, err)
+	}
+}
+
+// Test a service started with the credential runs as that user
+func TestServiceCredentialExec(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	if os.Getuid() != 0 {
+		t.Skip("Switching users requires root")
+	}
+
+	cred, env, err := serviceCredential(&Service{Name: "web", User: "4242", Group: "4343"})
+	if err != nil {
+		t.Fatalf("serviceCredential() error = %v", err)
+	}
+	if !reflect.DeepEqual(env, map[string]string{"USER": "4242", "LOGNAME": "4242"}) {
+		t.Errorf("user env = %v", env)
+	}
+
+	cmd := exec.Command("sh", "-c", "id -u; id -g")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("id error = %v", err)
+	}
+	if got := strings.Fields(string(out)); !reflect.DeepEqual(got, []string{"4242", "4343"}) {
+		t.Errorf("id output = %v", got)
+	}
+}
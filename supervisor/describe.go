@@ -0,0 +1,218 @@
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// describeService prints the definition of a service together with its runtime state
+func describeService(name string) error {
+	config, err := fetchRunningConfig()
+	if err != nil {
+		return err
+	}
+
+	var service *Service
+	for i := range config.Services {
+		if config.Services[i].Name == name {
+			service = &config.Services[i]
+			break
+		}
+	}
+	if service == nil {
+		return fmt.Errorf("service '%s' not found", name)
+	}
+
+	var info *ServiceInfo
+	err = sendIPCStream(IPCCommand{Type: CmdListServices}, func(frame *IPCResponse) error {
+		for i := range frame.Services {
+			if frame.Services[i].Name == name {
+				info = &frame.Services[i]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{
+		{"Name", colorize(ColorCyan, service.Name)},
+		{"Command", strings.TrimSpace(service.Command + " " + strings.Join(service.Args, " "))},
+	}
+	if service.InstanceOf != "" {
+		rows = append(rows, []string{"Instance of", fmt.Sprintf("%s (instance %s)", service.InstanceOf, service.Instance)})
+	}
+	if info != nil {
+		rows = append(rows, []string{"State", colorize(getStateColor(info.State), info.State.String())})
+		if info.PID > 0 {
+			rows = append(rows,
+				[]string{"PID", fmt.Sprint(info.PID)},
+				[]string{"Uptime", info.Uptime.Round(time.Second).String()},
+			)
+		}
+		if info.Health != HealthNone {
+			rows = append(rows, []string{"Health", colorize(getHealthColor(info.Health), info.Health.String())})
+		}
+		if info.Cgroup != nil {
+			rows = append(rows, []string{"Cgroup", info.Cgroup.Path + ": " + describeCgroupUsage(info.Cgroup)})
+		}
+		if info.TotalRestarts > 0 {
+			rows = append(rows, []string{"Restarts", fmt.Sprintf("%d in a row, %d in total", info.Restarts, info.TotalRestarts)})
+		}
+		if info.ExitCode != nil {
+			rows = append(rows, []string{"Last exit code", fmt.Sprint(*info.ExitCode)})
+		}
+		if info.LastError != "" {
+			rows = append(rows, []string{"Last error", colorize(ColorRed, info.LastError)})
+		}
+	} else {
+		rows = append(rows, []string{"State", colorize(ColorGray, "not started")})
+	}
+	if service.HealthCheck != nil {
+		rows = append(rows, []string{"Health check", service.HealthCheck.String()})
+	}
+	if service.Readiness != nil {
+		readiness := service.Readiness.String()
+		if info != nil {
+			readiness += fmt.Sprintf(" (ready: %v)", info.Ready)
+		}
+		rows = append(rows, []string{"Readiness", readiness})
+	}
+	if service.Watchdog != nil {
+		rows = append(rows, []string{"Watchdog", service.Watchdog.String()})
+	}
+	if notifiers := serviceNotifiers(config, service); len(notifiers) > 0 {
+		var names []string
+		for i := range notifiers {
+			names = append(names, notifiers[i].String())
+		}
+		rows = append(rows, []string{"Notifiers", strings.Join(names, ", ")})
+	}
+	if len(service.DependsOn) > 0 {
+		dependsOn := strings.Join(service.DependsOn, ", ")
+		if service.DependsOnCondition == DependsOnHealthy || service.DependsOnCondition == DependsOnReady {
+			dependsOn += " (" + service.DependsOnCondition + ")"
+		}
+		rows = append(rows, []string{"Depends on", dependsOn})
+	}
+	if service.ShutdownExitCode != nil {
+		rows = append(rows, []string{"Shutdown exit code", fmt.Sprint(*service.ShutdownExitCode)})
+	}
+	if service.User != "" {
+		rows = append(rows, []string{"User", service.User})
+	}
+	if service.Group != "" {
+		rows = append(rows, []string{"Group", service.Group})
+	}
+	if service.Limits != nil {
+		rows = append(rows, []string{"Limits", service.Limits.String()})
+	}
+	if service.Nice != 0 {
+		rows = append(rows, []string{"Nice", fmt.Sprint(service.Nice)})
+	}
+	if service.IONice != nil {
+		rows = append(rows, []string{"IO priority", service.IONice.String()})
+	}
+	for i, t := range service.Templates {
+		label := ""
+		if i == 0 {
+			label = "Templates"
+		}
+		rows = append(rows, []string{label, t.Source + " -> " + t.Target})
+	}
+	for i, secret := range service.Secrets {
+		label := ""
+		if i == 0 {
+			label = "Secrets"
+		}
+		var exposed []string
+		if secret.Env != "" {
+			exposed = append(exposed, "$"+secret.Env)
+		}
+		if secret.Target != "" {
+			exposed = append(exposed, secret.Target)
+		}
+		rows = append(rows, []string{label, secret.Source + " -> " + strings.Join(exposed, ", ")})
+	}
+	rows = append(rows,
+		[]string{"Enabled", fmt.Sprint(service.Enabled == nil || *service.Enabled)},
+		[]string{"Required", fmt.Sprint(service.Required)},
+		[]string{"Restart", describeRestart(service)},
+	)
+	if (service.PTY != nil && !*service.PTY) || service.StderrLevel != "" || service.StderrLogOutput != nil {
+		stderr := "logged at " + serviceStderrLevel(service).String() + " without a PTY"
+		if service.StderrLogOutput != nil {
+			stderr += ", written to " + service.StderrLogOutput.Path
+		}
+		rows = append(rows, []string{"Stderr", stderr})
+	}
+	if filter := describeLogFilter(service); filter != "" {
+		rows = append(rows, []string{"Console filter", filter})
+	}
+	if service.Multiline != nil {
+		rows = append(rows, []string{"Multiline", describeMultiline(service.Multiline)})
+	}
+	if service.LogRateLimit > 0 {
+		rows = append(rows, []string{"Log rate limit", fmt.Sprintf("%d lines/s", service.LogRateLimit)})
+	}
+	if service.StartDelay > 0 {
+		rows = append(rows, []string{"Start delay", fmt.Sprintf("%ds", service.StartDelay)})
+	}
+	if service.PreStop != "" {
+		rows = append(rows, []string{"Pre-stop", fmt.Sprintf("%s (timeout %s)", service.PreStop, preStopTimeout(service))})
+	}
+	if service.OnExit != "" {
+		rows = append(rows, []string{"On exit", fmt.Sprintf("%s (timeout %s)", service.OnExit, onExitTimeout(service))})
+	}
+	if service.StopSignal != "" {
+		rows = append(rows, []string{"Stop signal", service.StopSignal})
+	}
+	if len(service.Tags) > 0 {
+		rows = append(rows, []string{"Tags", strings.Join(service.Tags, ", ")})
+	}
+	if len(service.Labels) > 0 {
+		rows = append(rows, []string{"Labels", formatLabels(service.Labels)})
+	}
+	if info != nil && len(info.RecentOutput) > 0 {
+		for i, line := range info.RecentOutput {
+			label := ""
+			if i == 0 {
+				label = "Output"
+			}
+			rows = append(rows, []string{label, colorize(ColorGray, line)})
+		}
+	}
+
+	for _, row := range rows {
+		label := row[0]
+		if label != "" {
+			label += ":"
+		}
+		fmt.Printf("%s %s\n", colorize(ColorBoldWhite, padRight(label, 12)), row[1])
+	}
+	return nil
+}
+
+// describeRestart shows the restart policy with its exit code overrides
+func describeRestart(service *Service) string {
+	policy := restartPolicy(service)
+	if len(service.RestartOnExitCodes) > 0 {
+		policy += fmt.Sprintf(", always on exit codes %v", service.RestartOnExitCodes)
+	}
+	if len(service.NoRestartExitCodes) > 0 {
+		policy += fmt.Sprintf(", never on exit codes %v", service.NoRestartExitCodes)
+	}
+	if service.RestartEvery > 0 {
+		policy += fmt.Sprintf(", every %s of uptime", time.Duration(service.RestartEvery)*time.Second)
+	}
+	if service.CrashLoopRestarts > 0 {
+		window := service.CrashLoopWindow
+		if window <= 0 {
+			window = defaultCrashLoopWindow
+		}
+		policy += fmt.Sprintf(", at most %d in %s", service.CrashLoopRestarts, time.Duration(window)*time.Second)
+	}
+	return policy
+}
@@ -0,0 +1,80 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	defaultDaemonWaitTimeout = 30 * time.Second
+	daemonRetryInterval      = 250 * time.Millisecond
+)
+
+// Client-side options for reaching the daemon (set by root flags)
+var (
+	waitForDaemon     bool
+	daemonWaitTimeout = defaultDaemonWaitTimeout
+)
+
+// clientControlAddress returns the control socket clients connect to:
+// GO_OVERLAY_SOCKET, which services and scripts get from the daemon, else
+// [control] listen in the config file, else the default socket
+func clientControlAddress() string {
+	if address := os.Getenv(EnvSocket); address != "" {
+		return address
+	}
+
+	// Only [control] is read: the rest of the config may be invalid or
+	// unreadable without keeping clients from reaching the daemon
+	var file struct {
+		Control struct {
+			Listen string `toml:"listen"`
+		} `toml:"control"`
+	}
+	if data, err := os.ReadFile(daemonConfigFile); err == nil {
+		if toml.Unmarshal(data, &file) == nil && file.Control.Listen != "" {
+			return file.Control.Listen
+		}
+	}
+	return socketPath
+}
+
+// daemonDialer returns the dial function of clients of the daemon at
+// address. With --wait-for-daemon it keeps retrying while the socket is
+// missing or refusing connections (early boot).
+func daemonDialer(address string) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var dialer net.Dialer
+		deadline := time.Now().Add(daemonWaitTimeout)
+		for {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err == nil {
+				return conn, nil
+			}
+
+			if !isDaemonDown(err) {
+				return nil, fmt.Errorf("could not connect to Go Overlay daemon at %s: %w", address, err)
+			}
+			if !waitForDaemon {
+				return nil, fmt.Errorf("daemon not running at %s (use --wait-for-daemon to wait for it)", address)
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("daemon not running at %s after waiting %s", address, daemonWaitTimeout)
+			}
+			time.Sleep(daemonRetryInterval)
+		}
+	}
+}
+
+// isDaemonDown reports whether a dial error means nobody is listening yet
+func isDaemonDown(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,65 @@
+package supervisor
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test dial errors are classified as "daemon not running"
+func TestIsDaemonDown(t *testing.T) {
+	dir := t.TempDir()
+
+	// Missing socket file
+	_, err := net.Dial("unix", filepath.Join(dir, "missing.sock"))
+	if err == nil || !isDaemonDown(err) {
+		t.Errorf("isDaemonDown(%v) = false, want true for a missing socket", err)
+	}
+
+	// Stale socket file with no listener
+	stale := filepath.Join(dir, "stale.sock")
+	listener, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("stale socket missing: %v", err)
+	}
+	_, err = net.Dial("unix", stale)
+	if err == nil || !isDaemonDown(err) {
+		t.Errorf("isDaemonDown(%v) = false, want true for a stale socket", err)
+	}
+
+	// Permission problems are reported as-is
+	if isDaemonDown(os.ErrPermission) {
+		t.Error("isDaemonDown(ErrPermission) = true, want false")
+	}
+}
+
+// Test clients use GO_OVERLAY_SOCKET, else [control] listen of the config file
+func TestClientControlAddress(t *testing.T) {
+	previous := daemonConfigFile
+	t.Cleanup(func() { daemonConfigFile = previous })
+	t.Setenv(EnvSocket, "")
+
+	daemonConfigFile = filepath.Join(t.TempDir(), "services.toml")
+	if got := clientControlAddress(); got != socketPath {
+		t.Errorf("without config, clientControlAddress() = %s, want %s", got, socketPath)
+	}
+
+	config := "[control]\nlisten = \"@go-overlay\"\n\n[[services]]\nname = \"web\"\n"
+	if err := os.WriteFile(daemonConfigFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := clientControlAddress(); got != "@go-overlay" {
+		t.Errorf("clientControlAddress() = %s, want the [control] listen of the config", got)
+	}
+
+	t.Setenv(EnvSocket, "tcp://127.0.0.1:7373")
+	if got := clientControlAddress(); got != "tcp://127.0.0.1:7373" {
+		t.Errorf("clientControlAddress() = %s, want %s", got, EnvSocket)
+	}
+}
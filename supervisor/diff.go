@@ -0,0 +1,159 @@
+package supervisor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Config change actions reported by diff/apply
+const (
+	ChangeAdd    = "add"
+	ChangeRemove = "remove"
+	ChangeUpdate = "change"
+)
+
+// ConfigChange describes how a service differs between two configs
+type ConfigChange struct {
+	Service string   `json:"service"`
+	Action  string   `json:"action"`
+	Fields  []string `json:"fields,omitempty"` // TOML keys that differ (ChangeUpdate only)
+}
+
+// diffConfigs returns the changes turning current into desired, removals first
+// and then in the order services appear in desired
+func diffConfigs(current, desired *Config) []ConfigChange {
+	var changes []ConfigChange
+
+	wanted := make(map[string]*Service, len(desired.Services))
+	for i := range desired.Services {
+		wanted[desired.Services[i].Name] = &desired.Services[i]
+	}
+	existing := make(map[string]*Service, len(current.Services))
+	for i := range current.Services {
+		existing[current.Services[i].Name] = &current.Services[i]
+	}
+
+	for _, service := range current.Services {
+		if _, ok := wanted[service.Name]; !ok {
+			changes = append(changes, ConfigChange{Service: service.Name, Action: ChangeRemove})
+		}
+	}
+
+	for i := range desired.Services {
+		service := &desired.Services[i]
+		old, ok := existing[service.Name]
+		if !ok {
+			changes = append(changes, ConfigChange{Service: service.Name, Action: ChangeAdd})
+			continue
+		}
+		if fields := changedFields(old, service); len(fields) > 0 {
+			changes = append(changes, ConfigChange{Service: service.Name, Action: ChangeUpdate, Fields: fields})
+		}
+	}
+
+	return changes
+}
+
+// changedFields returns the TOML keys whose values differ between a and b
+func changedFields(a, b *Service) []string {
+	var fields []string
+
+	va, vb := reflect.ValueOf(*a), reflect.ValueOf(*b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if !valuesEqual(va.Field(i), vb.Field(i)) {
+			fields = append(fields, tomlKey(field))
+		}
+	}
+	return fields
+}
+
+// valuesEqual compares two config values, treating nil and empty collections as equal
+func valuesEqual(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Slice, reflect.Map:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func tomlKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// fetchRunningConfig returns the configuration the daemon is running with
+func fetchRunningConfig() (*Config, error) {
+	response, err := sendIPCCommand(IPCCommand{Type: CmdGetConfig})
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, responseError(response)
+	}
+	if response.Config == nil {
+		return nil, fmt.Errorf("daemon did not return its configuration")
+	}
+	return response.Config, nil
+}
+
+// diffConfig prints what applying configFile to the running daemon would change
+func diffConfig(configFile string) error {
+	desired, err := loadAndValidateConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	current, err := fetchRunningConfig()
+	if err != nil {
+		return err
+	}
+
+	changes := diffConfigs(current, &desired)
+	if len(changes) == 0 {
+		logger.Success("No changes: the daemon is running this configuration")
+		return nil
+	}
+
+	printConfigChanges(changes)
+	return nil
+}
+
+func printConfigChanges(changes []ConfigChange) {
+	for _, change := range changes {
+		switch change.Action {
+		case ChangeAdd:
+			fmt.Printf("%s %s\n", colorize(ColorGreen, "+"), colorize(ColorCyan, change.Service))
+		case ChangeRemove:
+			fmt.Printf("%s %s\n", colorize(ColorRed, "-"), colorize(ColorCyan, change.Service))
+		case ChangeUpdate:
+			fmt.Printf("%s %s %s\n", colorize(ColorYellow, "~"), colorize(ColorCyan, change.Service),
+				colorize(ColorGray, "("+strings.Join(change.Fields, ", ")+")"))
+		}
+	}
+}
+
+func handleGetConfig() IPCResponse {
+	config := currentConfig()
+	if config == nil {
+		return IPCResponse{
+			Success: false,
+			Message: "Configuration not loaded yet",
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Config:  config,
+	}
+}
@@ -0,0 +1,88 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const diffBaseConfig = `
+[[services]]
+name = "db"
+command = "/bin/sleep"
+args = ["60"]
+
+[[services]]
+name = "api"
+command = "/bin/sleep"
+args = ["60"]
+depends_on = "db"
+wait_after = { db = 2 }
+
+[[services]]
+name = "legacy"
+command = "/bin/sleep"
+`
+
+func mustParseConfig(t *testing.T, content string) *Config {
+	t.Helper()
+	config, err := parseConfig(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	if err := validateConfig(&config); err != nil {
+		t.Fatalf("validateConfig() error = %v", err)
+	}
+	return &config
+}
+
+// Test added, removed and changed services are detected
+func TestDiffConfigs(t *testing.T) {
+	current := mustParseConfig(t, diffBaseConfig)
+	desired := mustParseConfig(t, `
+[[services]]
+name = "db"
+command = "/bin/sleep"
+args = ["60"]
+
+[[services]]
+name = "api"
+command = "/bin/sleep"
+args = ["120"]
+depends_on = "db"
+wait_after = { db = 2 }
+required = true
+
+[[services]]
+name = "worker"
+command = "/bin/sleep"
+`)
+
+	want := []ConfigChange{
+		{Service: "legacy", Action: ChangeRemove},
+		{Service: "api", Action: ChangeUpdate, Fields: []string{"args", "required"}},
+		{Service: "worker", Action: ChangeAdd},
+	}
+	if got := diffConfigs(current, desired); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffConfigs() = %+v, want %+v", got, want)
+	}
+}
+
+// Test a config sent over IPC compares equal to the file it came from
+func TestDiffConfigsAfterIPCRoundTrip(t *testing.T) {
+	current := mustParseConfig(t, diffBaseConfig)
+
+	payload, err := json.Marshal(IPCResponse{Success: true, Config: current})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response IPCResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := diffConfigs(response.Config, mustParseConfig(t, diffBaseConfig)); len(changes) != 0 {
+		t.Errorf("diffConfigs() = %+v, want no changes", changes)
+	}
+}
@@ -0,0 +1,94 @@
+package supervisor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultPublishHost is used when a published service does not declare a host;
+// all services share the container network namespace.
+const defaultPublishHost = "127.0.0.1"
+
+// PublishField describes how dependents can reach a service
+type PublishField struct {
+	Host   string `toml:"host,omitempty"`
+	Socket string `toml:"socket,omitempty"`
+	Port   int    `toml:"port,omitempty"`
+}
+
+// dependencyEnv returns <DEP>_HOST/<DEP>_PORT (and <DEP>_SOCKET) variables for
+// every dependency of service that publishes connection info.
+func dependencyEnv(service *Service, services []Service) map[string]string {
+	env := make(map[string]string)
+	if len(service.DependsOn) == 0 {
+		return env
+	}
+
+	byName := make(map[string]*Service, len(services))
+	for i := range services {
+		byName[services[i].Name] = &services[i]
+	}
+
+	for _, depName := range service.DependsOn {
+		dep, ok := byName[depName]
+		if !ok || dep.Publish == nil {
+			continue
+		}
+
+		prefix := envPrefix(dep.Name)
+		if dep.Publish.Port > 0 {
+			host := dep.Publish.Host
+			if host == "" {
+				host = defaultPublishHost
+			}
+			env[prefix+"_HOST"] = host
+			env[prefix+"_PORT"] = strconv.Itoa(dep.Publish.Port)
+		}
+		if dep.Publish.Socket != "" {
+			env[prefix+"_SOCKET"] = dep.Publish.Socket
+		}
+	}
+	return env
+}
+
+// envPrefix converts a service name into an environment variable prefix
+// (e.g. "my-db" becomes "MY_DB").
+func envPrefix(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func validatePublish(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.Publish == nil {
+		return errors
+	}
+
+	if service.Publish.Port == 0 && service.Publish.Socket == "" {
+		errors = append(errors, ValidationError{
+			Field:   "publish",
+			Service: service.Name,
+			Message: "publish must declare a port or a socket",
+		})
+	}
+
+	if service.Publish.Port < 0 || service.Publish.Port > 65535 {
+		errors = append(errors, ValidationError{
+			Field:   "publish",
+			Service: service.Name,
+			Message: fmt.Sprintf("publish port %d must be between 1 and 65535", service.Publish.Port),
+		})
+	}
+
+	return errors
+}
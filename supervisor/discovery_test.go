@@ -0,0 +1,74 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test dependency connection info injection
+func TestDependencyEnv(t *testing.T) {
+	services := []Service{
+		{Name: "postgres", Publish: &PublishField{Port: 5432}},
+		{Name: "redis-cache", Publish: &PublishField{Host: "10.0.0.5", Port: 6379, Socket: "/run/redis.sock"}},
+		{Name: "worker"},
+		{Name: "app", DependsOn: DependsOnField{"postgres", "redis-cache", "worker"}},
+	}
+
+	env := dependencyEnv(&services[3], services)
+	expected := map[string]string{
+		"POSTGRES_HOST":      defaultPublishHost,
+		"POSTGRES_PORT":      "5432",
+		"REDIS_CACHE_HOST":   "10.0.0.5",
+		"REDIS_CACHE_PORT":   "6379",
+		"REDIS_CACHE_SOCKET": "/run/redis.sock",
+	}
+
+	if len(env) != len(expected) {
+		t.Errorf("dependencyEnv() returned %d vars, want %d: %v", len(env), len(expected), env)
+	}
+	for key, want := range expected {
+		if env[key] != want {
+			t.Errorf("%s = %q, want %q", key, env[key], want)
+		}
+	}
+}
+
+// Test publish validation
+func TestValidatePublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish *PublishField
+		wantErr bool
+	}{
+		{"No publish", nil, false},
+		{"Port", &PublishField{Port: 8080}, false},
+		{"Socket", &PublishField{Socket: "/run/app.sock"}, false},
+		{"Empty", &PublishField{}, true},
+		{"Port out of range", &PublishField{Port: 70000}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validatePublish(&Service{Name: "svc", Publish: tt.publish})
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validatePublish() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Test publish is decoded from TOML inline tables
+func TestParseConfigPublish(t *testing.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+[[services]]
+name = "db"
+command = "/bin/true"
+publish = { port = 5432 }
+`))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	if cfg.Services[0].Publish == nil || cfg.Services[0].Publish.Port != 5432 {
+		t.Errorf("Publish = %+v, want port 5432", cfg.Services[0].Publish)
+	}
+}
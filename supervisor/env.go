@@ -0,0 +1,382 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// Environment variables injected into every child process
+const (
+	EnvServiceName = "GO_OVERLAY_SERVICE"
+	EnvInstance    = "GO_OVERLAY_INSTANCE"
+	EnvSocket      = client.EnvAddress
+	EnvVersion     = "GO_OVERLAY_VERSION"
+)
+
+// s6ContainerEnvDir is where s6-overlay stores the container environment
+const s6ContainerEnvDir = "/run/s6/container_environment"
+
+// importedEnv holds variables imported at startup (e.g. from s6-overlay's
+// container_environment) that are applied on top of the supervisor environment
+var importedEnv map[string]string
+
+// importS6Environment loads dir into the base environment applied to services
+func importS6Environment(dir string) {
+	if _, err := os.Stat(dir); err != nil {
+		logger.Debug("No s6 container environment at ", dir)
+		return
+	}
+
+	values, err := loadEnvDir(dir)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Could not import s6 container environment from %s: %v", dir, err))
+		return
+	}
+
+	importedEnv = values
+	logger.Info(fmt.Sprintf("Imported %d variables from %s", len(values), colorize(ColorCyan, dir)))
+}
+
+// baseEnvironment returns the supervisor environment with imported variables applied
+func baseEnvironment() []string {
+	return mergeEnv(os.Environ(), importedEnv)
+}
+
+// cleanEnvKeys are the supervisor variables kept by clean_env services
+var cleanEnvKeys = []string{"PATH", "HOME", "TERM", "LANG", "TZ"}
+
+// buildServiceEnv returns the environment a service (and its scripts) is started with:
+// the supervisor environment (or a minimal one with clean_env), dependency info,
+// env_file, env and finally the GO_OVERLAY_* metadata
+func buildServiceEnv(service *Service) []string {
+	env := baseEnvironment()
+	if service.CleanEnv {
+		env = filterEnv(env, cleanEnvKeys)
+	}
+	if config := currentConfig(); config != nil {
+		env = mergeEnv(env, dependencyEnv(service, config.Services))
+	}
+	if service.EnvFile != "" {
+		values, err := loadEnvFile(service.EnvFile)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Could not load env_file of service '%s': %v",
+				colorize(ColorCyan, service.Name), err))
+		}
+		env = mergeEnv(env, values)
+	}
+	env = mergeEnv(env, service.Env)
+	return mergeEnv(env, supervisorMetadataEnv(service))
+}
+
+// filterEnv keeps the entries of env whose key is in keys
+func filterEnv(env []string, keys []string) []string {
+	var out []string
+	for _, entry := range env {
+		if slices.Contains(keys, envKey(entry)) {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// loadEnvFile reads KEY=VALUE lines, skipping blank lines and # comments. An
+// "export " prefix and quotes around the value are removed, as in shell files.
+func loadEnvFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isValidEnvName(key) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, i+1)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// expandConfigVars replaces ${VAR} and ${VAR:-default} in a config value with
+// the supervisor environment; the default is used when VAR is unset or empty.
+// A bare $VAR is left alone so shell commands keep working.
+func expandConfigVars(value string) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			out.WriteString(value)
+			return out.String(), nil
+		}
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in '%s'", value)
+		}
+		end += start
+
+		name, fallback, hasDefault := strings.Cut(value[start+2:end], ":-")
+		if !isValidEnvName(name) {
+			return "", fmt.Errorf("invalid variable name '%s' in '%s'", name, value)
+		}
+		expanded := os.Getenv(name)
+		if expanded == "" && hasDefault {
+			expanded = fallback
+		}
+
+		out.WriteString(value[:start])
+		out.WriteString(expanded)
+		value = value[end+1:]
+	}
+}
+
+// expandServiceVars expands ${VAR} in the command, args, log_file and scripts
+// of a service, reporting the first bad reference of each field
+func expandServiceVars(index int, svc *Service) ConfigErrors {
+	var errs ConfigErrors
+
+	expand := func(field string, value *string) {
+		expanded, err := expandConfigVars(*value)
+		if err != nil {
+			errs = append(errs, serviceFieldError(index, field, err.Error()))
+			return
+		}
+		*value = expanded
+	}
+
+	expand("command", &svc.Command)
+	for i := range svc.Args {
+		expand(fmt.Sprintf("args[%d]", i), &svc.Args[i])
+	}
+	expand("log_file", &svc.LogFile)
+	expand("pre_script", &svc.PreScript)
+	expand("pos_script", &svc.PosScript)
+	expand("pre_stop", &svc.PreStop)
+	expand("on_exit", &svc.OnExit)
+
+	return errs
+}
+
+func validateEnv(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	for _, key := range sortedKeys(service.Env) {
+		if !isValidEnvName(key) {
+			errors = append(errors, ValidationError{
+				Field:   "env",
+				Service: service.Name,
+				Message: fmt.Sprintf("invalid environment variable name '%s'", key),
+			})
+		}
+	}
+
+	// Like commands, the file may only exist once a pre_script or volume provides it
+	if service.EnvFile != "" && shouldValidateCommands(service) {
+		if _, err := loadEnvFile(rootPath(service.EnvFile)); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "env_file",
+				Service: service.Name,
+				Message: fmt.Sprintf("invalid env_file: %v", err),
+			})
+		}
+	}
+
+	return errors
+}
+
+// supervisorMetadataEnv returns the GO_OVERLAY_* variables describing the supervisor
+// and the service, so children can call back into the control API or tag telemetry.
+func supervisorMetadataEnv(service *Service) map[string]string {
+	return map[string]string{
+		EnvServiceName: service.Name,
+		EnvInstance:    serviceInstance(service),
+		EnvSocket:      controlAddress,
+		EnvVersion:     version,
+	}
+}
+
+// serviceInstance returns the instance identifier of a service: the number
+// of an instance of a service template, or the name of other services
+func serviceInstance(service *Service) string {
+	if service.Instance != "" {
+		return service.Instance
+	}
+	return service.Name
+}
+
+// mergeEnv overrides or appends KEY=VALUE entries in env with the given values
+func mergeEnv(env []string, values map[string]string) []string {
+	if len(values) == 0 {
+		return env
+	}
+
+	out := make([]string, 0, len(env)+len(values))
+	seen := make(map[string]bool, len(values))
+	for _, entry := range env {
+		key := envKey(entry)
+		if val, ok := values[key]; ok {
+			if !seen[key] {
+				out = append(out, key+"="+val)
+				seen[key] = true
+			}
+			continue
+		}
+		out = append(out, entry)
+	}
+
+	for _, key := range sortedKeys(values) {
+		if !seen[key] {
+			out = append(out, key+"="+values[key])
+		}
+	}
+	return out
+}
+
+func envKey(entry string) string {
+	key, _, _ := strings.Cut(entry, "=")
+	return key
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containerEnvDirName is the directory (under the status dir) holding the
+// supervisor's environment captured at startup, one file per variable.
+const containerEnvDirName = "container_environment"
+
+// containerEnvDir returns the location of the saved container environment
+func containerEnvDir() string {
+	dir := statusDir
+	if dir == "" {
+		dir = defaultStatusDir
+	}
+	return filepath.Join(dir, containerEnvDirName)
+}
+
+// saveContainerEnvironment writes env into dir as one file per variable,
+// the same layout as s6-overlay's /run/s6/container_environment.
+func saveContainerEnvironment(dir string, env []string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	for _, entry := range env {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !isValidEnvName(key) {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, key), []byte(value), 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadEnvDir reads an envdir (one file per variable, content is the value)
+func loadEnvDir(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !isValidEnvName(entry.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		values[entry.Name()] = string(content)
+	}
+	return values, nil
+}
+
+// isValidEnvName reports whether name is usable as an environment variable name
+func isValidEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// withEnv execs command with the saved container environment (and, when
+// serviceName is set, the service's resolved environment) applied on top of
+// the current one, mirroring s6-overlay's with-contenv.
+func withEnv(envDir, serviceName string, command []string) error {
+	env := os.Environ()
+
+	containerEnv, err := loadEnvDir(envDir)
+	if err != nil {
+		return fmt.Errorf("could not load container environment from %s: %w", envDir, err)
+	}
+	env = mergeEnv(env, containerEnv)
+
+	if serviceName != "" {
+		response, err := sendIPCCommand(IPCCommand{Type: CmdServiceEnv, ServiceName: serviceName})
+		if err != nil {
+			return err
+		}
+		if !response.Success {
+			return responseError(response)
+		}
+		serviceEnv := make(map[string]string, len(response.Env))
+		for _, entry := range response.Env {
+			if key, value, ok := strings.Cut(entry, "="); ok {
+				serviceEnv[key] = value
+			}
+		}
+		env = mergeEnv(env, serviceEnv)
+	}
+
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf("command '%s' not found: %w", command[0], err)
+	}
+
+	return syscall.Exec(path, command, env) // #nosec G204 - executing the user-provided command is the purpose of with-env
+}
+
+func handleServiceEnv(serviceName string) IPCResponse {
+	service, ok := findServiceConfig(serviceName)
+	if !ok {
+		return notFoundResponse(serviceName)
+	}
+
+	return IPCResponse{
+		Success: true,
+		Env:     buildServiceEnv(&service),
+	}
+}
@@ -0,0 +1,269 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test supervisor metadata injected into service environments
+func TestBuildServiceEnvMetadata(t *testing.T) {
+	service := &Service{Name: "web", Command: "/bin/true"}
+	env := buildServiceEnv(service)
+
+	expected := map[string]string{
+		EnvServiceName: "web",
+		EnvInstance:    "web",
+		EnvSocket:      socketPath,
+		EnvVersion:     version,
+	}
+
+	for key, want := range expected {
+		found := false
+		for _, entry := range env {
+			if strings.HasPrefix(entry, key+"=") {
+				found = true
+				if got := strings.TrimPrefix(entry, key+"="); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s not present in service environment", key)
+		}
+	}
+}
+
+// Test mergeEnv overrides existing keys and appends new ones
+func TestMergeEnv(t *testing.T) {
+	env := []string{"A=1", "B=2", "A=3"}
+	got := mergeEnv(env, map[string]string{"A": "x", "C": "y"})
+	want := []string{"A=x", "B=2", "C=y"}
+
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("mergeEnv() = %v, want %v", got, want)
+	}
+}
+
+// Test the container environment round-trips through an envdir
+func TestContainerEnvironmentRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	env := []string{"APP_MODE=production", "MULTI=line1\nline2", "EMPTY=", "1INVALID=x", "NOEQUALS"}
+
+	if err := saveContainerEnvironment(dir, env); err != nil {
+		t.Fatalf("saveContainerEnvironment() error = %v", err)
+	}
+
+	values, err := loadEnvDir(dir)
+	if err != nil {
+		t.Fatalf("loadEnvDir() error = %v", err)
+	}
+
+	expected := map[string]string{"APP_MODE": "production", "MULTI": "line1\nline2", "EMPTY": ""}
+	if len(values) != len(expected) {
+		t.Errorf("loadEnvDir() = %v, want %v", values, expected)
+	}
+	for key, want := range expected {
+		if got, ok := values[key]; !ok || got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+// Test the service_env IPC handler
+func TestHandleServiceEnv(t *testing.T) {
+	useConfig(t, &Config{Services: []Service{{Name: "api", Command: "/bin/true"}}})
+
+	response := handleServiceEnv("api")
+	if !response.Success || len(response.Env) == 0 {
+		t.Fatalf("handleServiceEnv() = %+v, want env", response)
+	}
+	if response := handleServiceEnv("missing"); response.Success {
+		t.Error("handleServiceEnv() should fail for unknown service")
+	}
+}
+
+// Test variables imported from an s6 container_environment reach services
+func TestImportS6Environment(t *testing.T) {
+	defer func() { importedEnv = nil }()
+
+	dir := t.TempDir()
+	if err := saveContainerEnvironment(dir, []string{"S6_IMPORTED=yes"}); err != nil {
+		t.Fatalf("saveContainerEnvironment() error = %v", err)
+	}
+
+	importS6Environment(dir)
+
+	env := buildServiceEnv(&Service{Name: "web"})
+	found := false
+	for _, entry := range env {
+		if entry == "S6_IMPORTED=yes" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("imported variable missing from service environment")
+	}
+
+	importedEnv = nil
+	importS6Environment(filepath.Join(dir, "missing"))
+	if importedEnv != nil {
+		t.Error("importS6Environment() should ignore a missing directory")
+	}
+}
+
+// Test env_file and env layer over the supervisor environment, and clean_env drops it
+func TestBuildServiceEnvPerService(t *testing.T) {
+	t.Setenv("SUPERVISOR_ONLY", "1")
+	t.Setenv("SHARED", "supervisor")
+
+	envFile := filepath.Join(t.TempDir(), "web.env")
+	content := "# comment\n\nexport FROM_FILE=\"quoted value\"\nSHARED=file\n"
+	if err := os.WriteFile(envFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		service Service
+		want    map[string]string
+		absent  []string
+	}{
+		{
+			"Merged",
+			Service{Name: "web", EnvFile: envFile, Env: map[string]string{"FROM_ENV": "x"}},
+			map[string]string{"SUPERVISOR_ONLY": "1", "SHARED": "file", "FROM_FILE": "quoted value", "FROM_ENV": "x"},
+			nil,
+		},
+		{
+			"Env overrides env_file",
+			Service{Name: "web", EnvFile: envFile, Env: map[string]string{"SHARED": "env"}},
+			map[string]string{"SHARED": "env"},
+			nil,
+		},
+		{
+			"Clean",
+			Service{Name: "web", CleanEnv: true, Env: map[string]string{"FROM_ENV": "x"}},
+			map[string]string{"FROM_ENV": "x", "PATH": os.Getenv("PATH"), EnvServiceName: "web"},
+			[]string{"SUPERVISOR_ONLY", "SHARED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := make(map[string]string)
+			for _, entry := range buildServiceEnv(&tt.service) {
+				key, value, _ := strings.Cut(entry, "=")
+				env[key] = value
+			}
+			for key, want := range tt.want {
+				if got, ok := env[key]; !ok || got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			for _, key := range tt.absent {
+				if _, ok := env[key]; ok {
+					t.Errorf("%s should not be set", key)
+				}
+			}
+		})
+	}
+}
+
+// Test env names and env_file syntax are validated
+func TestValidateEnv(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.env")
+	invalid := filepath.Join(dir, "invalid.env")
+	if err := os.WriteFile(valid, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, []byte("not a variable\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	skip := false
+
+	tests := []struct {
+		name    string
+		service Service
+		wantErr bool
+	}{
+		{"Valid", Service{Name: "web", Env: map[string]string{"A": "1"}, EnvFile: valid}, false},
+		{"Invalid name", Service{Name: "web", Env: map[string]string{"1A": "1"}}, true},
+		{"Invalid file", Service{Name: "web", EnvFile: invalid}, true},
+		{"Missing file", Service{Name: "web", EnvFile: filepath.Join(dir, "missing.env")}, true},
+		{"Missing file not validated", Service{Name: "web", EnvFile: filepath.Join(dir, "missing.env"), ValidateCommands: &skip}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateEnv(&tt.service)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateEnv() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Test ${VAR} and ${VAR:-default} expansion of config values
+func TestExpandConfigVars(t *testing.T) {
+	t.Setenv("APP_PORT", "8080")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"--port=${APP_PORT}", "--port=8080", false},
+		{"${UNSET_VAR_FOR_TEST:-9090}", "9090", false},
+		{"${EMPTY:-fallback}", "fallback", false},
+		{"${APP_PORT:-9090}", "8080", false},
+		{"${UNSET_VAR_FOR_TEST}", "", false},
+		{"${APP_PORT}/${APP_PORT}", "8080/8080", false},
+		{"echo $HOME $$", "echo $HOME $$", false},
+		{"${APP_PORT", "", true},
+		{"${1BAD}", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := expandConfigVars(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandConfigVars(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expandConfigVars(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// Test parseConfig expands variables in service commands and reports bad references
+func TestParseConfigExpandsVars(t *testing.T) {
+	t.Setenv("APP_BIN", "/bin/sh")
+
+	cfg := mustParseConfig(t, `
+[[services]]
+name = "app"
+command = "${APP_BIN}"
+args = ["--env", "${APP_ENV:-production}"]
+`)
+	if got := cfg.Services[0].Command; got != "/bin/sh" {
+		t.Errorf("Command = %q", got)
+	}
+	if got := cfg.Services[0].Args[1]; got != "production" {
+		t.Errorf("Args[1] = %q", got)
+	}
+
+	_, err := parseConfig(strings.NewReader(`
+[[services]]
+name = "app"
+command = "/bin/app"
+pre_script = "${BROKEN"
+`))
+	if err == nil || !strings.Contains(err.Error(), "pre_script") {
+		t.Errorf("parseConfig() error = %v, want a pre_script error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// Event types published on the event bus
+const (
+	EventState     = client.EventState
+	EventExited    = client.EventExited
+	EventFailed    = client.EventFailed
+	EventRestart   = client.EventRestart
+	EventCrashLoop = client.EventCrashLoop
+	EventHealth    = client.EventHealth
+	EventReady     = client.EventReady
+	EventWatchdog  = client.EventWatchdog
+	EventShutdown  = client.EventShutdown
+)
+
+// eventFollowBuffer is the number of events queued per subscriber before it misses events
+const eventFollowBuffer = 256
+
+// Event is a service lifecycle event, streamed by `go-overlay events`
+type Event = client.Event
+
+// eventBus fans lifecycle events out to subscribers. Like log followers,
+// subscribers that can't keep up miss events rather than slowing services down.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+var events = &eventBus{subscribers: make(map[chan Event]struct{})}
+
+// publish sends an event of a service to every subscriber
+func (b *eventBus) publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// subscribe returns the channel of the next events; stop unsubscribes
+func (b *eventBus) subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscriber := make(chan Event, eventFollowBuffer)
+	b.subscribers[subscriber] = struct{}{}
+	return subscriber, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, subscriber)
+	}
+}
+
+// streamEvents sends events (of cmd.ServiceName only, if set) as they happen
+// until the client disconnects or the daemon stops
+func streamEvents(conn net.Conn, encoder *json.Encoder, cmd IPCCommand) error {
+	if cmd.ServiceName != "" {
+		if _, ok := findServiceConfig(cmd.ServiceName); !ok {
+			return encoder.Encode(notFoundResponse(cmd.ServiceName))
+		}
+	}
+
+	subscription, stop := events.subscribe()
+	defer stop()
+
+	// An empty frame tells the client it is subscribed
+	if err := encoder.Encode(IPCResponse{Success: true, More: true}); err != nil {
+		return err
+	}
+
+	disconnected := make(chan struct{})
+	go func() {
+		_, _ = conn.Read(make([]byte, 1))
+		close(disconnected)
+	}()
+
+	for {
+		select {
+		case event := <-subscription:
+			if cmd.ServiceName != "" && event.Service != cmd.ServiceName {
+				continue
+			}
+			if err := encoder.Encode(IPCResponse{Success: true, Events: []Event{event}, More: true}); err != nil {
+				return err
+			}
+		case <-disconnected:
+			return nil
+		case <-shutdownContext().Done():
+			return encoder.Encode(IPCResponse{Success: true})
+		}
+	}
+}
+
+// showEvents prints events as JSON lines until interrupted or the daemon stops
+func showEvents(serviceName string) error {
+	encoder := json.NewEncoder(os.Stdout)
+	return sendIPCStream(IPCCommand{Type: CmdSubscribe, ServiceName: serviceName}, func(frame *IPCResponse) error {
+		for _, event := range frame.Events {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,87 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// Test state changes are published to subscribers, and slow subscribers never block
+func TestEventBus(t *testing.T) {
+	subscription, stop := events.subscribe()
+	defer stop()
+
+	sp := &ServiceProcess{Name: "events-web", State: ServiceStatePending}
+	sp.SetState(ServiceStateRunning)
+
+	select {
+	case event := <-subscription:
+		want := Event{Type: EventState, Service: "events-web", From: "PENDING", To: "RUNNING"}
+		event.Time = time.Time{}
+		if event != want {
+			t.Errorf("event = %+v, want %+v", event, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("state change not published")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < eventFollowBuffer*2; i++ {
+			events.publish(Event{Type: EventReady, Service: "flood"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a slow subscriber")
+	}
+}
+
+// Test subscribe streams the events of the requested service until the client hangs up
+func TestStreamEvents(t *testing.T) {
+	resetShutdown()
+	setConfig(&Config{Services: []Service{{Name: "events-web"}, {Name: "events-db"}}})
+	defer func() {
+		setConfig(nil)
+		cancelShutdown()
+	}()
+
+	server, client := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- streamEvents(server, json.NewEncoder(server), IPCCommand{Type: CmdSubscribe, ServiceName: "events-web"})
+	}()
+
+	decoder := json.NewDecoder(client)
+	var frame IPCResponse
+	if err := decoder.Decode(&frame); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !frame.Success || !frame.More {
+		t.Fatalf("subscribed frame = %+v", frame)
+	}
+
+	events.publish(Event{Type: EventFailed, Service: "events-db"})
+	events.publish(Event{Type: EventReady, Service: "events-web"})
+
+	frame = IPCResponse{}
+	if err := decoder.Decode(&frame); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(frame.Events) != 1 || frame.Events[0].Type != EventReady || frame.Events[0].Service != "events-web" {
+		t.Errorf("event frame = %+v, want the ready event of events-web only", frame)
+	}
+
+	_ = client.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("streamEvents() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("streamEvents() did not return after the client hung up")
+	}
+}
@@ -0,0 +1,99 @@
+package supervisor
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// ExecContext is what `go-overlay exec` needs to run a command the way the
+// daemon runs a service
+type ExecContext struct {
+	Env   []string   `json:"env"`
+	Dir   string     `json:"dir,omitempty"`   // Working directory of the service processes
+	Setup url.Values `json:"setup,omitempty"` // Exec helper settings: limits, priorities, user and cgroup
+}
+
+// serviceExecContext resolves the context a command run for a service gets:
+// its environment (secrets included) and user, the settings the exec helper
+// applies, and its cgroup once the service created it
+func serviceExecContext(service *Service) (*ExecContext, error) {
+	env, cred, err := serviceProcessEnv(service)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve its user: %w", err)
+	}
+	secrets, err := secretEnv(service)
+	if err != nil {
+		return nil, err
+	}
+	env = mergeEnv(env, secrets)
+	setup := serviceExecSetup(service)
+	if cred != nil {
+		setup.Set("credential", encodeCredential(cred))
+	}
+	if service.Cgroup != nil {
+		if base, err := serviceCgroupBase(); err == nil {
+			path := serviceCgroupPath(base, service.Name)
+			if _, err := os.Stat(path); err == nil {
+				setup.Set("cgroup", path)
+			}
+		}
+	}
+	// Services inherit the working directory of the supervisor
+	dir, _ := os.Getwd()
+	return &ExecContext{Env: env, Dir: dir, Setup: setup}, nil
+}
+
+func handleServiceExec(serviceName string) IPCResponse {
+	service, ok := findServiceConfig(serviceName)
+	if !ok {
+		return notFoundResponse(serviceName)
+	}
+
+	execContext, err := serviceExecContext(&service)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Message: fmt.Sprintf("Could not prepare the context of service '%s': %v", serviceName, err),
+		}
+	}
+	return IPCResponse{Success: true, Exec: execContext}
+}
+
+// execInService replaces the client with command, run in the context of a
+// service: for maintenance commands that must see the same environment,
+// files and limits as the service. The command keeps the terminal and its
+// exit code is the one of the client.
+func execInService(serviceName string, command []string) error {
+	response, err := sendIPCCommand(IPCCommand{Type: CmdServiceExec, ServiceName: serviceName})
+	if err != nil {
+		return err
+	}
+	if !response.Success || response.Exec == nil {
+		return responseError(response)
+	}
+	execContext := response.Exec
+
+	if execContext.Dir != "" {
+		if err := os.Chdir(execContext.Dir); err != nil {
+			return fmt.Errorf("could not change to the directory of service '%s': %w", serviceName, err)
+		}
+	}
+	// Look the command up in the PATH of the service
+	for _, entry := range execContext.Env {
+		if envKey(entry) == "PATH" {
+			_ = os.Setenv("PATH", entry[len("PATH="):])
+		}
+	}
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf("command '%s' not found: %w", command[0], err)
+	}
+
+	if err := applyExecSetup(execContext.Setup); err != nil {
+		return err
+	}
+	return syscall.Exec(path, command, execContext.Env) // #nosec G204 - executing the user-provided command is the purpose of exec
+}
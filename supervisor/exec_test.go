@@ -0,0 +1,64 @@
+package supervisor
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Test the exec context of a service has its environment, user and settings
+func TestHandleServiceExec(t *testing.T) {
+	setConfig(&Config{Services: []Service{{
+		Name:    "api",
+		Command: "/bin/true",
+		User:    "65534",
+		Nice:    5,
+		Env:     map[string]string{"FOO": "bar", "HOME": "/srv/api"},
+	}}})
+	defer setConfig(nil)
+
+	response := handleServiceExec("api")
+	if !response.Success || response.Exec == nil {
+		t.Fatalf("handleServiceExec() = %+v, want a context", response)
+	}
+	execContext := response.Exec
+	for _, want := range []string{"FOO=bar", "HOME=/srv/api", "GO_OVERLAY_SERVICE=api"} {
+		if !slices.Contains(execContext.Env, want) {
+			t.Errorf("env misses %s: %v", want, execContext.Env)
+		}
+	}
+	if wd, _ := os.Getwd(); execContext.Dir != wd {
+		t.Errorf("dir = %q, want the supervisor's %q", execContext.Dir, wd)
+	}
+	if got := execContext.Setup.Get("nice"); got != "5" {
+		t.Errorf("nice = %q, want 5", got)
+	}
+	if got := execContext.Setup.Get("credential"); !strings.HasPrefix(got, "65534:65534:") {
+		t.Errorf("credential = %q, want uid and gid 65534", got)
+	}
+
+	if response := handleServiceExec("missing"); response.Success {
+		t.Error("handleServiceExec() should fail for unknown service")
+	}
+}
+
+// Test the exec setup moves the process into the cgroup it names
+func TestApplyExecSetupCgroup(t *testing.T) {
+	cgroup := t.TempDir()
+	if err := applyExecSetup(url.Values{"cgroup": {cgroup}}); err != nil {
+		t.Fatalf("applyExecSetup() error = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(cgroup, "cgroup.procs"))
+	if string(data) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("cgroup.procs = %q, want our pid", data)
+	}
+
+	missing := filepath.Join(cgroup, "missing")
+	if err := applyExecSetup(url.Values{"cgroup": {missing}}); err == nil {
+		t.Error("applyExecSetup() should fail for a missing cgroup")
+	}
+}
@@ -0,0 +1,176 @@
+package supervisor
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// envExecSetup carries the settings of the exec helper: go-overlay started
+// as a service process applies them and then executes the service command
+const envExecSetup = "GO_OVERLAY_EXEC_SETUP"
+
+// serviceExecSetup returns the settings a service needs applied right before
+// its command is executed, if any
+func serviceExecSetup(service *Service) url.Values {
+	setup := url.Values{}
+	if service.Limits != nil {
+		if spec := service.Limits.encode(); spec != "" {
+			setup.Set("limits", spec)
+		}
+	}
+	if service.Nice != 0 {
+		setup.Set("nice", strconv.Itoa(service.Nice))
+	}
+	if service.IONice != nil {
+		setup.Set("ionice", strconv.Itoa(service.IONice.value()))
+	}
+	return setup
+}
+
+// RunHelper runs the exec helper when wrapExec started the process as one, to
+// prepare and exec a service command, and never returns then. Programs
+// embedding a Supervisor call it first thing in main, as wrapExec starts
+// their own executable.
+func RunHelper() {
+	if spec := os.Getenv(envExecSetup); spec != "" {
+		err := runExecHelper(spec, os.Args[1:])
+		fmt.Fprintf(os.Stderr, "go-overlay: %v\n", err)
+		os.Exit(126)
+	}
+}
+
+// wrapExec makes cmd run through the exec helper: Go can't run code between
+// fork and exec, and changing the supervisor itself would leak into every
+// other child. Our own binary is started instead, applies setup and replaces
+// itself with the command, keeping the PID. The user is switched last, so a
+// supervisor running as root can raise limits and priorities of unprivileged
+// services.
+func wrapExec(cmd *exec.Cmd, setup url.Values) error {
+	if len(setup) == 0 || cmd.Err != nil {
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Credential != nil {
+		setup.Set("credential", encodeCredential(cmd.SysProcAttr.Credential))
+		cmd.SysProcAttr.Credential = nil
+	}
+
+	cmd.Args = append([]string{"go-overlay", cmd.Path}, cmd.Args...)
+	cmd.Path = self
+	cmd.Env = mergeEnv(cmd.Env, map[string]string{envExecSetup: setup.Encode()})
+	return nil
+}
+
+// runExecHelper is run by main in the process started by wrapExec: it applies
+// the settings in spec and executes args[0] with argv args[1:]. It only
+// returns on failure.
+func runExecHelper(spec string, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%s: missing command", envExecSetup)
+	}
+	setup, err := url.ParseQuery(spec)
+	if err != nil {
+		return fmt.Errorf("%s: %w", envExecSetup, err)
+	}
+	if err := applyExecSetup(setup); err != nil {
+		return err
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, entry := range os.Environ() {
+		if envKey(entry) != envExecSetup {
+			env = append(env, entry)
+		}
+	}
+	return syscall.Exec(args[0], args[1:], env) // #nosec G204 - the configured service command
+}
+
+// applyExecSetup applies the settings of the exec helper to the current
+// process, which is expected to exec right after
+func applyExecSetup(setup url.Values) error {
+	// Priorities are per thread on Linux; exec from the thread they were set on
+	runtime.LockOSThread()
+
+	if cgroup := setup.Get("cgroup"); cgroup != "" {
+		procs := filepath.Join(cgroup, "cgroup.procs")
+		if err := os.WriteFile(procs, []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+			return fmt.Errorf("could not join cgroup %s: %w", cgroup, err)
+		}
+	}
+	if limits := setup.Get("limits"); limits != "" {
+		if err := setLimits(limits); err != nil {
+			return err
+		}
+	}
+	if nice := setup.Get("nice"); nice != "" {
+		if err := setNice(nice); err != nil {
+			return err
+		}
+	}
+	if ionice := setup.Get("ionice"); ionice != "" {
+		if err := setIOPriority(ionice); err != nil {
+			return err
+		}
+	}
+	if credential := setup.Get("credential"); credential != "" {
+		if err := switchCredential(credential); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeCredential renders cred for the exec helper, e.g. "33:33:33,100"
+func encodeCredential(cred *syscall.Credential) string {
+	groups := make([]string, len(cred.Groups))
+	for i, group := range cred.Groups {
+		groups[i] = strconv.FormatUint(uint64(group), 10)
+	}
+	return fmt.Sprintf("%d:%d:%s", cred.Uid, cred.Gid, strings.Join(groups, ","))
+}
+
+// switchCredential switches to the user encoded by encodeCredential
+func switchCredential(spec string) error {
+	invalid := fmt.Errorf("%s: invalid credential '%s'", envExecSetup, spec)
+	fields := strings.Split(spec, ":")
+	if len(fields) != 3 {
+		return invalid
+	}
+	uid, errUID := strconv.Atoi(fields[0])
+	gid, errGID := strconv.Atoi(fields[1])
+	if errUID != nil || errGID != nil {
+		return invalid
+	}
+	var groups []int
+	if fields[2] != "" {
+		for _, field := range strings.Split(fields[2], ",") {
+			group, err := strconv.Atoi(field)
+			if err != nil {
+				return invalid
+			}
+			groups = append(groups, group)
+		}
+	}
+
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("could not set groups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("could not set gid %d: %w", gid, err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("could not set uid %d: %w", uid, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// Test the settings a service needs from the exec helper
+func TestServiceExecSetup(t *testing.T) {
+	nofile, level := int64(64), 7
+	service := &Service{
+		Name:   "backup",
+		Limits: &ResourceLimits{NoFile: &nofile},
+		Nice:   10,
+		IONice: &IOPriority{Class: IOClassBestEffort, Level: &level},
+	}
+	setup := serviceExecSetup(service)
+	if got := setup.Encode(); got != "ionice=16391&limits=nofile%3D64&nice=10" {
+		t.Errorf("serviceExecSetup() = %q", got)
+	}
+	if setup := serviceExecSetup(&Service{Name: "web"}); len(setup) != 0 {
+		t.Errorf("serviceExecSetup() of a plain service = %v", setup)
+	}
+
+	if got := encodeCredential(&syscall.Credential{Uid: 33, Gid: 33, Groups: []uint32{33, 100}}); got != "33:33:33,100" {
+		t.Errorf("encodeCredential() = %q", got)
+	}
+}
+
+// Test the exec helper applies limits and priorities before running the
+// command, by running it in a copy of the test binary
+func TestExecHelper(t *testing.T) {
+	if spec := os.Getenv(envExecSetup); spec != "" {
+		err := runExecHelper(spec, []string{"/bin/sh", "sh", "-c",
+			"ulimit -n; ulimit -c; nice; ionice; echo ${" + envExecSetup + ":-unset}"})
+		t.Fatalf("runExecHelper() error = %v", err)
+	}
+	if _, err := exec.LookPath("ionice"); err != nil {
+		t.Skip("ionice is not installed")
+	}
+
+	nofile, core := int64(64), int64(0)
+	setup := serviceExecSetup(&Service{
+		Limits: &ResourceLimits{NoFile: &nofile, Core: &core},
+		Nice:   5,
+		IONice: &IOPriority{Class: IOClassIdle},
+	})
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExecHelper$") // #nosec G204 - the test binary
+	cmd.Env = append(os.Environ(), envExecSetup+"="+setup.Encode())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("helper error = %v: %s", err, out)
+	}
+	if got := strings.Join(strings.Fields(string(out)), " "); got != "64 0 5 idle unset" {
+		t.Errorf("settings seen by the command = %q, want 64 0 5 idle unset", got)
+	}
+}
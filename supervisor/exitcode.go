@@ -0,0 +1,108 @@
+package supervisor
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// shutdownExit holds the exit code of the supervisor, set by the service
+// whose end shut it down. Without one the supervisor exits 0.
+var shutdownExit struct {
+	mu   sync.Mutex
+	code *int
+}
+
+// shutdownForService shuts the supervisor down because service ended with
+// exitErr, exiting with the exit code of the service (or its
+// shutdown_exit_code) instead of 0, so container restart policies and
+// alerting see the failure
+func shutdownForService(service *Service, exitErr error) {
+	setShutdownExitCode(serviceShutdownExitCode(service, exitErr))
+	gracefulShutdown()
+}
+
+// serviceShutdownExitCode returns the exit code the supervisor exits with
+// when service ends with exitErr. A failure that isn't an exit status, e.g. a
+// command that could not start, is 1.
+func serviceShutdownExitCode(service *Service, exitErr error) int {
+	if service.ShutdownExitCode != nil {
+		return *service.ShutdownExitCode
+	}
+	code := exitStatus(exitErr)
+	if code < 0 || (code == 0 && exitErr != nil) {
+		return 1
+	}
+	return code
+}
+
+// setShutdownExitCode records the exit code of the supervisor; the first
+// service to end the supervisor wins
+func setShutdownExitCode(code int) {
+	shutdownExit.mu.Lock()
+	defer shutdownExit.mu.Unlock()
+	if shutdownExit.code == nil {
+		shutdownExit.code = &code
+	}
+}
+
+// supervisorExitCode returns the exit code of the supervisor
+func supervisorExitCode() int {
+	shutdownExit.mu.Lock()
+	defer shutdownExit.mu.Unlock()
+	if shutdownExit.code == nil {
+		return 0
+	}
+	return *shutdownExit.code
+}
+
+// isExitCodeFrom reports whether the supervisor ends with the service name,
+// as set by exit_code_from
+func isExitCodeFrom(name string) bool {
+	config := currentConfig()
+	return config != nil && config.ExitCodeFrom != "" && config.ExitCodeFrom == name
+}
+
+// exitCodeFromService returns the exit_code_from service of config, nil if
+// there is none
+func exitCodeFromService(config *Config) *Service {
+	for i := range config.Services {
+		if config.ExitCodeFrom != "" && config.Services[i].Name == config.ExitCodeFrom {
+			return &config.Services[i]
+		}
+	}
+	return nil
+}
+
+func validateExitCodeFrom(config *Config) ValidationErrors {
+	var errors ValidationErrors
+
+	if config.ExitCodeFrom == "" {
+		return errors
+	}
+	exists := slices.ContainsFunc(config.Services, func(service Service) bool {
+		return service.Name == config.ExitCodeFrom
+	})
+	if !exists {
+		errors = append(errors, ValidationError{
+			Field:   "exit_code_from",
+			Message: fmt.Sprintf("exit_code_from references unknown service '%s'", config.ExitCodeFrom),
+		})
+	}
+
+	return errors
+}
+
+func validateShutdownExitCode(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if code := service.ShutdownExitCode; code != nil && (*code < 0 || *code > 255) {
+		errors = append(errors, ValidationError{
+			Field:   "shutdown_exit_code",
+			Service: service.Name,
+			Message: fmt.Sprintf("shutdown_exit_code must be between 0 and 255 (got %d)", *code),
+		})
+	}
+
+	return errors
+}
@@ -0,0 +1,81 @@
+package supervisor
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+// Test the supervisor exits with the exit code of the service that shut it
+// down, or its shutdown_exit_code
+func TestServiceShutdownExitCode(t *testing.T) {
+	exitErr := func(script string) error {
+		return exec.Command("/bin/sh", "-c", script).Run()
+	}
+	three := 3
+
+	tests := []struct {
+		name    string
+		service Service
+		err     error
+		want    int
+	}{
+		{"Clean exit", Service{Name: "app"}, nil, 0},
+		{"Exit code", Service{Name: "app"}, exitErr("exit 4"), 4},
+		{"Killed", Service{Name: "app"}, exitErr("kill -KILL $$"), 137},
+		{"Not an exit status", Service{Name: "app"}, errors.New("command not found"), 1},
+		{"Mapped", Service{Name: "app", ShutdownExitCode: &three}, exitErr("exit 4"), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceShutdownExitCode(&tt.service, tt.err); got != tt.want {
+				t.Errorf("serviceShutdownExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test the first service to shut the supervisor down sets its exit code
+func TestSetShutdownExitCode(t *testing.T) {
+	t.Cleanup(func() { shutdownExit.code = nil })
+
+	if got := supervisorExitCode(); got != 0 {
+		t.Errorf("supervisorExitCode() = %d before any failure, want 0", got)
+	}
+	setShutdownExitCode(2)
+	setShutdownExitCode(5)
+	if got := supervisorExitCode(); got != 2 {
+		t.Errorf("supervisorExitCode() = %d, want 2", got)
+	}
+}
+
+// Test exit_code_from and shutdown_exit_code validation
+func TestValidateExitCodes(t *testing.T) {
+	negative, tooLarge, valid := -1, 256, 0
+
+	tests := []struct {
+		name   string
+		config Config
+		errors int
+	}{
+		{"None", Config{Services: []Service{{Name: "app"}}}, 0},
+		{"Known service", Config{ExitCodeFrom: "app", Services: []Service{{Name: "app"}}}, 0},
+		{"Unknown service", Config{ExitCodeFrom: "web", Services: []Service{{Name: "app"}}}, 1},
+		{"Valid code", Config{Services: []Service{{Name: "app", ShutdownExitCode: &valid}}}, 0},
+		{"Negative code", Config{Services: []Service{{Name: "app", ShutdownExitCode: &negative}}}, 1},
+		{"Code too large", Config{Services: []Service{{Name: "app", ShutdownExitCode: &tooLarge}}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateExitCodeFrom(&tt.config)
+			for i := range tt.config.Services {
+				errs = append(errs, validateShutdownExitCode(&tt.config.Services[i])...)
+			}
+			if len(errs) != tt.errors {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.errors, errs)
+			}
+		})
+	}
+}
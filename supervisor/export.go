@@ -0,0 +1,190 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// toRawConfig converts a config back into its TOML representation, writing
+// depends_on and wait_after in their compact forms and service templates
+// instead of their instances
+func toRawConfig(config *Config) configRaw {
+	raw := configRaw{
+		StatusDir:        config.StatusDir,
+		Timeouts:         config.Timeouts,
+		Logging:          config.Logging,
+		API:              config.API,
+		Control:          config.Control,
+		ValidateCommands: config.ValidateCommands,
+
+		InitDir:             config.InitDir,
+		PreShutdownScript:   config.PreShutdownScript,
+		FinishDir:           config.FinishDir,
+		AuditLog:            config.AuditLog,
+		StateFile:           config.StateFile,
+		MaxConcurrentStarts: config.MaxConcurrentStarts,
+		ExitCodeFrom:        config.ExitCodeFrom,
+		Notifiers:           config.Notifiers,
+	}
+
+	for _, service := range exportedServices(config) {
+		sr := serviceRaw{
+			Name:        service.Name,
+			Command:     service.Command,
+			Args:        service.Args,
+			LogFile:     service.LogFile,
+			PreScript:   service.PreScript,
+			PosScript:   service.PosScript,
+			Enabled:     service.Enabled,
+			User:        service.User,
+			Group:       service.Group,
+			Required:    service.Required,
+			Publish:     service.Publish,
+			Register:    service.Register,
+			WaitFor:     service.WaitFor,
+			ControlFIFO: service.ControlFIFO,
+
+			Labels:           service.Labels,
+			Tags:             service.Tags,
+			ShutdownExitCode: service.ShutdownExitCode,
+			ShutdownPriority: service.ShutdownPriority,
+			Stage:            service.Stage,
+			LogBufferLines:   service.LogBufferLines,
+			Timestamps:       service.Timestamps,
+			LogOutput:        service.LogOutput,
+			PTY:              service.PTY,
+			StderrLevel:      service.StderrLevel,
+			StderrLogOutput:  service.StderrLogOutput,
+			LogLevel:         service.LogLevel,
+			LogInclude:       service.LogInclude,
+			LogExclude:       service.LogExclude,
+			LogSample:        service.LogSample,
+			LogRateLimit:     service.LogRateLimit,
+			Multiline:        service.Multiline,
+			Env:              service.Env,
+			EnvFile:          service.EnvFile,
+			CleanEnv:         service.CleanEnv,
+			Limits:           service.Limits,
+			Cgroup:           service.Cgroup,
+			Nice:             service.Nice,
+			IONice:           service.IONice,
+			Templates:        service.Templates,
+			Secrets:          service.Secrets,
+			ReloadSignal:     service.ReloadSignal,
+			ReloadCmd:        service.ReloadCmd,
+			PreStop:          service.PreStop,
+			PreStopTimeout:   service.PreStopTimeout,
+			OnExit:           service.OnExit,
+			OnExitTimeout:    service.OnExitTimeout,
+			StopSignal:       service.StopSignal,
+			StopTimeout:      service.StopTimeout,
+			KillTimeout:      service.KillTimeout,
+			ValidateCommands: service.ValidateCommands,
+
+			Restart:           service.Restart,
+			RestartBackoff:    service.RestartBackoff,
+			RestartBackoffMax: service.RestartBackoffMax,
+			RestartMaxRetries: service.RestartMaxRetries,
+
+			CrashLoopRestarts: service.CrashLoopRestarts,
+			CrashLoopWindow:   service.CrashLoopWindow,
+
+			RestartOnExitCodes: service.RestartOnExitCodes,
+			NoRestartExitCodes: service.NoRestartExitCodes,
+
+			StartDelay:   service.StartDelay,
+			RestartEvery: service.RestartEvery,
+
+			HealthCheck:        service.HealthCheck,
+			Readiness:          service.Readiness,
+			DependsOnCondition: service.DependsOnCondition,
+			Watchdog:           service.Watchdog,
+			Notifiers:          service.Notifiers,
+		}
+
+		if _, ok := findServiceTemplate(config, service.Name); ok {
+			instances := service.Instances
+			sr.Instances = &instances
+		}
+
+		deps := foldInstanceDependencies(service.DependsOn, config)
+		switch len(deps) {
+		case 0:
+		case 1:
+			sr.DependsOn = deps[0]
+		default:
+			sr.DependsOn = deps
+		}
+
+		if wa := service.WaitAfter; wa != nil {
+			if wa.IsPerDep {
+				sr.WaitAfter = foldInstanceWaits(wa.PerDep, config)
+			} else {
+				sr.WaitAfter = wa.Global
+			}
+		}
+
+		raw.Services = append(raw.Services, sr)
+	}
+	return raw
+}
+
+// exportedServices returns the services of config in order, with the first
+// instance of a template replaced by the template and the others left out.
+// Templates without instances come last.
+func exportedServices(config *Config) []*Service {
+	var services []*Service
+	exported := make(map[string]bool)
+	for i := range config.Services {
+		service := &config.Services[i]
+		if service.InstanceOf != "" {
+			template, ok := findServiceTemplate(config, service.InstanceOf)
+			if ok && exported[template.Name] {
+				continue
+			}
+			if ok {
+				exported[template.Name] = true
+				service = template
+			}
+		}
+		services = append(services, service)
+	}
+	for i := range config.ServiceTemplates {
+		if !exported[config.ServiceTemplates[i].Name] {
+			services = append(services, &config.ServiceTemplates[i])
+		}
+	}
+	return services
+}
+
+// marshalConfig renders config as a services.toml document
+func marshalConfig(config *Config) ([]byte, error) {
+	return toml.Marshal(toRawConfig(config))
+}
+
+// exportConfig writes the daemon's effective configuration as TOML to output ("-" for stdout)
+func exportConfig(output string) error {
+	config, err := fetchRunningConfig()
+	if err != nil {
+		return err
+	}
+
+	data, err := marshalConfig(config)
+	if err != nil {
+		return fmt.Errorf("error encoding configuration: %w", err)
+	}
+
+	header := fmt.Sprintf("# Exported from go-overlay %s\n", version)
+	if output == "" || output == "-" {
+		fmt.Print(header + string(data))
+		return nil
+	}
+
+	if err := os.WriteFile(output, []byte(header+string(data)), 0o644); err != nil { // #nosec G306 - config files are not secret
+		return fmt.Errorf("error writing %s: %w", output, err)
+	}
+	logger.Success(fmt.Sprintf("Configuration exported to %s", colorize(ColorCyan, output)))
+	return nil
+}
@@ -0,0 +1,65 @@
+package supervisor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Test exported TOML parses back into an identical configuration
+func TestMarshalConfigRoundTrip(t *testing.T) {
+	original := mustParseConfig(t, `
+validate_commands = false
+
+[timeouts]
+service_shutdown_timeout = 5
+
+[[services]]
+name = "db"
+command = "/bin/sleep"
+args = ["60"]
+publish = { port = 5432 }
+
+[[services]]
+name = "cache"
+command = "/bin/sleep"
+enabled = false
+
+[[services]]
+name = "api"
+command = "/bin/sleep"
+depends_on = ["db", "cache"]
+wait_after = { db = 2, cache = 1 }
+wait_for = [{ dns = "db.internal", timeout = 10 }]
+required = true
+
+[[services]]
+name = "web"
+command = "/bin/sleep"
+depends_on = "api"
+wait_after = 3
+`)
+
+	data, err := marshalConfig(original)
+	if err != nil {
+		t.Fatalf("marshalConfig() error = %v", err)
+	}
+
+	exported, err := parseConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("parseConfig(exported) error = %v\n%s", err, data)
+	}
+	if err := validateConfig(&exported); err != nil {
+		t.Fatalf("validateConfig(exported) error = %v", err)
+	}
+
+	if changes := diffConfigs(original, &exported); len(changes) != 0 {
+		t.Errorf("round trip changed services: %+v\n%s", changes, data)
+	}
+	if exported.Timeouts != original.Timeouts {
+		t.Errorf("timeouts = %+v, want %+v", exported.Timeouts, original.Timeouts)
+	}
+	if !strings.Contains(string(data), "depends_on = 'api'") {
+		t.Errorf("single dependency not written in compact form:\n%s", data)
+	}
+}
@@ -0,0 +1,104 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// s6FinishDir is where s6-overlay images keep their finish scripts, used as
+// finish_dir with --s6-compat
+const s6FinishDir = "/etc/cont-finish.d"
+
+// finishDir returns the finish scripts directory of config: finish_dir, or
+// the one of s6-overlay with --s6-compat when it exists
+func finishDir(config *Config) string {
+	if config.FinishDir != "" {
+		return config.FinishDir
+	}
+	if s6Compat {
+		if info, err := os.Stat(s6FinishDir); err == nil && info.IsDir() {
+			return s6FinishDir
+		}
+	}
+	return ""
+}
+
+// runFinishScripts runs the scripts of the finish directory one after the
+// other once every service has stopped, like cont-finish.d. Each is killed
+// after finish_script_timeout; failures are logged and the next one runs.
+func runFinishScripts() {
+	config := currentConfig()
+	if config == nil {
+		return
+	}
+	dir := finishDir(config)
+	if dir == "" {
+		return
+	}
+
+	scripts, err := listScripts(dir)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Could not read finish_dir: %v", err))
+		return
+	}
+	if len(scripts) == 0 {
+		return
+	}
+
+	timeout := time.Duration(config.Timeouts.FinishScript) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	logger.Info(fmt.Sprintf("Running %d finish scripts from %s", len(scripts), colorize(ColorCyan, dir)))
+	env := mergeEnv(baseEnvironment(), map[string]string{
+		EnvVersion: version,
+	})
+	for _, script := range scripts {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := runScriptContext(ctx, script, env); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				logger.Warn(fmt.Sprintf("Finish script %s timed out after %s", filepath.Base(script), timeout))
+			} else {
+				logger.Warn(fmt.Sprintf("Finish script %s failed: %v", filepath.Base(script), err))
+			}
+		}
+		cancel()
+	}
+}
+
+func validateFinishDir(config *Config) ValidationErrors {
+	var errors ValidationErrors
+
+	if config.Timeouts.FinishScript < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "finish_script_timeout",
+			Message: fmt.Sprintf("finish_script_timeout must not be negative (got %d)", config.Timeouts.FinishScript),
+		})
+	}
+	if config.FinishDir == "" || (config.ValidateCommands != nil && !*config.ValidateCommands) {
+		return errors
+	}
+
+	scripts, err := listScripts(rootPath(config.FinishDir))
+	if err != nil {
+		errors = append(errors, ValidationError{
+			Field:   "finish_dir",
+			Message: fmt.Sprintf("finish_dir '%s' can't be read: %v", config.FinishDir, err),
+		})
+		return errors
+	}
+	for _, script := range scripts {
+		if !isExecutableFile(script) {
+			errors = append(errors, ValidationError{
+				Field:   "finish_dir",
+				Message: fmt.Sprintf("finish script '%s' is not executable", filepath.Join(config.FinishDir, filepath.Base(script))),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,55 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test finish scripts run in order, and a failing or hung script doesn't keep
+// the next ones from running
+func TestRunFinishScripts(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	dir := writeScripts(t, map[string]string{
+		"10-fails": "echo fails >> " + log + "; exit 1",
+		"20-hangs": "sleep 30",
+		"30-last":  "echo last >> " + log,
+	})
+	useConfig(t, &Config{FinishDir: dir, Timeouts: Timeouts{FinishScript: 1}})
+
+	start := time.Now()
+	runFinishScripts()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("finish scripts took %s, the hung one wasn't killed", elapsed)
+	}
+
+	data, _ := os.ReadFile(log)
+	if string(data) != "fails\nlast\n" {
+		t.Errorf("scripts wrote %q, want %q", data, "fails\nlast\n")
+	}
+}
+
+// Test finish_dir validation
+func TestValidateFinishDir(t *testing.T) {
+	dir := writeScripts(t, map[string]string{"10-cleanup": "true"})
+
+	tests := []struct {
+		name   string
+		config Config
+		errors int
+	}{
+		{"None", Config{}, 0},
+		{"Valid", Config{FinishDir: dir}, 0},
+		{"Missing", Config{FinishDir: filepath.Join(dir, "missing")}, 1},
+		{"Negative timeout", Config{Timeouts: Timeouts{FinishScript: -1}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateFinishDir(&tt.config); len(got) != tt.errors {
+				t.Errorf("validateFinishDir() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
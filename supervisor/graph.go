@@ -0,0 +1,163 @@
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Output formats of `go-overlay graph`
+const (
+	GraphFormatDOT     = "dot"
+	GraphFormatMermaid = "mermaid"
+)
+
+// graphEdgeLabel labels a dependency edge with the condition the dependent
+// waits for, unless it is the default "started"
+func graphEdgeLabel(service *Service) string {
+	if service.DependsOnCondition == "" || service.DependsOnCondition == DependsOnStarted {
+		return ""
+	}
+	return service.DependsOnCondition
+}
+
+// graphStages returns the stages of the config, and whether there is more
+// than one and they are worth drawing
+func graphStages(config *Config) ([]int, map[int][]*Service, bool) {
+	stages, groups := serviceStages(config.Services)
+	return stages, groups, len(stages) > 1
+}
+
+// renderDOT renders the dependency graph in Graphviz DOT. Edges point from a
+// dependency to the services waiting for it, in startup order; stages are
+// drawn as clusters.
+func renderDOT(config *Config) string {
+	var b strings.Builder
+	b.WriteString("digraph services {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	node := func(indent string, service *Service) {
+		var attrs []string
+		if service.Enabled != nil && !*service.Enabled {
+			attrs = append(attrs, "style=dashed")
+		} else if service.Required {
+			attrs = append(attrs, "style=bold")
+		}
+		fmt.Fprintf(&b, "%s%q", indent, service.Name)
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+
+	stages, groups, clustered := graphStages(config)
+	for _, stage := range stages {
+		if !clustered {
+			for _, service := range groups[stage] {
+				node("  ", service)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "  subgraph cluster_stage_%d {\n", stage)
+		fmt.Fprintf(&b, "    label=%q;\n", fmt.Sprintf("stage %d", stage))
+		for _, service := range groups[stage] {
+			node("    ", service)
+		}
+		b.WriteString("  }\n")
+	}
+
+	for i := range config.Services {
+		service := &config.Services[i]
+		for _, dep := range service.DependsOn {
+			fmt.Fprintf(&b, "  %q -> %q", dep, service.Name)
+			if label := graphEdgeLabel(service); label != "" {
+				fmt.Fprintf(&b, " [label=%q]", label)
+			}
+			b.WriteString(";\n")
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// renderMermaid renders the dependency graph as a Mermaid flowchart, for
+// Markdown documentation. Service names are labels; node ids are generated
+// since names may contain characters Mermaid doesn't accept in ids.
+func renderMermaid(config *Config) string {
+	ids := make(map[string]string, len(config.Services))
+	for i := range config.Services {
+		ids[config.Services[i].Name] = fmt.Sprintf("s%d", i)
+	}
+	id := func(name string) string {
+		if id, ok := ids[name]; ok {
+			return id
+		}
+		// A dependency that isn't defined: draw it anyway so the mistake shows
+		ids[name] = fmt.Sprintf("s%d", len(ids))
+		return fmt.Sprintf("%s[%q]", ids[name], name)
+	}
+
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+
+	node := func(indent string, service *Service) {
+		fmt.Fprintf(&b, "%s%s[%q]", indent, id(service.Name), service.Name)
+		if service.Enabled != nil && !*service.Enabled {
+			b.WriteString(":::disabled")
+		} else if service.Required {
+			b.WriteString(":::required")
+		}
+		b.WriteString("\n")
+	}
+
+	stages, groups, clustered := graphStages(config)
+	for _, stage := range stages {
+		if !clustered {
+			for _, service := range groups[stage] {
+				node("  ", service)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "  subgraph stage_%d [\"stage %d\"]\n", stage, stage)
+		for _, service := range groups[stage] {
+			node("    ", service)
+		}
+		b.WriteString("  end\n")
+	}
+
+	for i := range config.Services {
+		service := &config.Services[i]
+		for _, dep := range service.DependsOn {
+			arrow := "-->"
+			if label := graphEdgeLabel(service); label != "" {
+				arrow = "-->|" + label + "|"
+			}
+			fmt.Fprintf(&b, "  %s %s %s\n", id(dep), arrow, id(service.Name))
+		}
+	}
+
+	b.WriteString("  classDef disabled stroke-dasharray: 5 5\n")
+	b.WriteString("  classDef required stroke-width:3px\n")
+	return b.String()
+}
+
+// showGraph prints the dependency graph of configFile (merged with the config
+// dir, if any). The config is parsed but not validated, so graphs can be drawn
+// on machines without the services installed, and of configs with a cycle.
+func showGraph(configFile, format string) error {
+	config, err := parseConfigSources(configFile, configDir)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case GraphFormatDOT:
+		fmt.Print(renderDOT(&config))
+	case GraphFormatMermaid:
+		fmt.Print(renderMermaid(&config))
+	default:
+		return fmt.Errorf("unknown format '%s' (use %s or %s)", format, GraphFormatDOT, GraphFormatMermaid)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+)
+
+const graphTestConfig = `
+[[services]]
+name = "postgres"
+command = "/usr/bin/postgres"
+required = true
+
+[[services]]
+name = "api"
+command = "/app/api"
+depends_on = "postgres"
+depends_on_condition = "healthy"
+stage = 1
+
+[[services]]
+name = "worker"
+command = "/app/worker"
+depends_on = ["postgres", "redis"]
+enabled = false
+stage = 1
+`
+
+// Test the DOT graph draws stages, node styles and labeled dependency edges
+func TestRenderDOT(t *testing.T) {
+	config, err := parseConfig(strings.NewReader(graphTestConfig))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	want := `digraph services {
+  rankdir=LR;
+  node [shape=box];
+  subgraph cluster_stage_0 {
+    label="stage 0";
+    "postgres" [style=bold];
+  }
+  subgraph cluster_stage_1 {
+    label="stage 1";
+    "api";
+    "worker" [style=dashed];
+  }
+  "postgres" -> "api" [label="healthy"];
+  "postgres" -> "worker";
+  "redis" -> "worker";
+}
+`
+	if got := renderDOT(&config); got != want {
+		t.Errorf("renderDOT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// Test the Mermaid graph, including a dependency that isn't defined
+func TestRenderMermaid(t *testing.T) {
+	config, err := parseConfig(strings.NewReader(graphTestConfig))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	got := renderMermaid(&config)
+	for _, want := range []string{
+		"graph LR\n",
+		`  subgraph stage_1 ["stage 1"]` + "\n",
+		`    s0["postgres"]:::required` + "\n",
+		`    s2["worker"]:::disabled` + "\n",
+		"  s0 -->|healthy| s1\n",
+		`  s3["redis"] --> s2` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("renderMermaid() misses %q:\n%s", want, got)
+		}
+	}
+
+	// A single stage isn't drawn as a subgraph
+	config.Services[1].Stage, config.Services[2].Stage = 0, 0
+	if got := renderMermaid(&config); strings.Contains(got, "subgraph") {
+		t.Errorf("renderMermaid() of one stage =\n%s", got)
+	}
+}
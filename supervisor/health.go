@@ -0,0 +1,330 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// HealthState is the result of a service's health checks, tracked alongside
+// its ServiceState while the process runs
+type HealthState = client.HealthState
+
+// Health state constants; services without a health_check have no health
+const (
+	HealthNone      = client.HealthNone
+	HealthStarting  = client.HealthStarting
+	HealthHealthy   = client.HealthHealthy
+	HealthUnhealthy = client.HealthUnhealthy
+)
+
+// Dependency conditions for depends_on_condition
+const (
+	DependsOnStarted = "started" // The dependency process was spawned (the default)
+	DependsOnHealthy = "healthy" // The dependency passed its health check
+	DependsOnReady   = "ready"   // The dependency passed its readiness probe
+)
+
+// Health check defaults, in seconds
+const (
+	defaultHealthInterval = 10
+	defaultHealthTimeout  = 5
+	defaultHealthRetries  = 3
+)
+
+// HealthCheck probes a running service: exactly one of Exec, TCP or HTTP is set
+type HealthCheck struct {
+	Exec        string `toml:"exec,omitempty"`         // Shell command, healthy on exit code 0
+	TCP         string `toml:"tcp,omitempty"`          // host:port, healthy when a connection succeeds
+	HTTP        string `toml:"http,omitempty"`         // URL, healthy on a 2xx/3xx response to GET
+	Interval    int    `toml:"interval,omitempty"`     // Seconds between checks
+	Timeout     int    `toml:"timeout,omitempty"`      // Seconds before a check counts as failed
+	Retries     int    `toml:"retries,omitempty"`      // Consecutive failures before unhealthy
+	StartPeriod int    `toml:"start_period,omitempty"` // Seconds to wait before the first check
+}
+
+func (h *HealthCheck) String() string {
+	switch {
+	case h.Exec != "":
+		return "exec " + h.Exec
+	case h.TCP != "":
+		return "tcp " + h.TCP
+	default:
+		return "http " + h.HTTP
+	}
+}
+
+func (h *HealthCheck) interval() time.Duration {
+	return secondsOr(h.Interval, defaultHealthInterval)
+}
+
+func (h *HealthCheck) timeout() time.Duration {
+	return secondsOr(h.Timeout, defaultHealthTimeout)
+}
+
+func (h *HealthCheck) retries() int {
+	if h.Retries <= 0 {
+		return defaultHealthRetries
+	}
+	return h.Retries
+}
+
+func secondsOr(value, fallback int) time.Duration {
+	if value <= 0 {
+		value = fallback
+	}
+	return time.Duration(value) * time.Second
+}
+
+// SetHealth updates the health of the service, logging transitions
+func (sp *ServiceProcess) SetHealth(health HealthState) {
+	sp.StateMu.Lock()
+	old := sp.Health
+	sp.Health = health
+	sp.StateMu.Unlock()
+
+	if old == health {
+		return
+	}
+	events.publish(Event{Type: EventHealth, Service: sp.Name, From: string(old), To: string(health)})
+	switch health {
+	case HealthHealthy:
+		logger.Success(fmt.Sprintf("Service '%s' is %s", colorize(ColorCyan, sp.Name), colorize(ColorGreen, "healthy")))
+	case HealthUnhealthy:
+		logger.Warn(fmt.Sprintf("Service '%s' is %s", colorize(ColorCyan, sp.Name), colorize(ColorRed, "unhealthy")))
+	}
+}
+
+// GetHealth returns the current health of the service
+func (sp *ServiceProcess) GetHealth() HealthState {
+	sp.StateMu.RLock()
+	defer sp.StateMu.RUnlock()
+	return sp.Health
+}
+
+// getHealthColor returns the color used to display a health state
+func getHealthColor(health HealthState) string {
+	switch health {
+	case HealthHealthy:
+		return ColorGreen
+	case HealthUnhealthy:
+		return ColorRed
+	default:
+		return ColorYellow
+	}
+}
+
+// monitorHealth runs the health check of a service until ctx is done. The
+// service is unhealthy after `retries` consecutive failures and healthy again
+// after the next success.
+func monitorHealth(ctx context.Context, sp *ServiceProcess) {
+	check := sp.Config.HealthCheck
+	sp.SetHealth(HealthStarting)
+
+	wait := time.Duration(check.StartPeriod) * time.Second
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = check.interval()
+
+		probeCtx, cancel := context.WithTimeout(ctx, check.timeout())
+		err := runHealthProbe(probeCtx, check, buildServiceEnv(&sp.Config))
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			failures = 0
+			sp.SetHealth(HealthHealthy)
+			continue
+		}
+
+		failures++
+		logger.Debug(fmt.Sprintf("Health check %s of service '%s' failed (%d/%d): %v",
+			check, sp.Name, failures, check.retries(), err))
+		if failures >= check.retries() {
+			if sp.GetHealth() != HealthUnhealthy {
+				sp.SetError(fmt.Errorf("health check failed: %w", err))
+			}
+			sp.SetHealth(HealthUnhealthy)
+		}
+	}
+}
+
+// runHealthProbe runs a single check, returning nil when it passed
+func runHealthProbe(ctx context.Context, check *HealthCheck, env []string) error {
+	switch {
+	case check.Exec != "":
+		return execProbe(ctx, check.Exec, env)
+	case check.TCP != "":
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", check.TCP)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		return httpProbe(ctx, check.HTTP)
+	}
+}
+
+// execProbe runs command through the shell, discarding its output unless it fails
+func execProbe(ctx context.Context, command string, env []string) error {
+	shell := "sh"
+	if isBashAvailable() {
+		shell = "bash"
+	}
+
+	cmd := exec.CommandContext(ctx, shell, "-c", command)
+	cmd.Env = env
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
+
+func httpProbe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// dependencyMet reports whether a started dependency satisfies the
+// depends_on_condition of its dependent
+func dependencyMet(depName, condition string) bool {
+	switch condition {
+	case DependsOnHealthy:
+		return serviceHealth(depName) == HealthHealthy
+	case DependsOnReady:
+		return serviceReady(depName)
+	}
+	return true
+}
+
+// serviceHealth returns the health of a running service
+func serviceHealth(name string) HealthState {
+	serviceProc, exists := getActiveService(name)
+	if !exists {
+		return HealthNone
+	}
+	return serviceProc.GetHealth()
+}
+
+func validateHealthCheck(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if check := service.HealthCheck; check != nil {
+		probes := 0
+		for _, probe := range []string{check.Exec, check.TCP, check.HTTP} {
+			if probe != "" {
+				probes++
+			}
+		}
+		if probes != 1 {
+			errors = append(errors, ValidationError{
+				Field:   "health_check",
+				Service: service.Name,
+				Message: "health_check must set exactly one of exec, tcp or http",
+			})
+		}
+
+		if check.TCP != "" {
+			if _, _, err := net.SplitHostPort(check.TCP); err != nil {
+				errors = append(errors, ValidationError{
+					Field:   "health_check.tcp",
+					Service: service.Name,
+					Message: fmt.Sprintf("invalid address '%s': %v", check.TCP, err),
+				})
+			}
+		}
+
+		if check.HTTP != "" && !strings.HasPrefix(check.HTTP, "http://") && !strings.HasPrefix(check.HTTP, "https://") {
+			errors = append(errors, ValidationError{
+				Field:   "health_check.http",
+				Service: service.Name,
+				Message: fmt.Sprintf("'%s' must be an http:// or https:// URL", check.HTTP),
+			})
+		}
+
+		if check.Interval < 0 || check.Timeout < 0 || check.Retries < 0 || check.StartPeriod < 0 {
+			errors = append(errors, ValidationError{
+				Field:   "health_check",
+				Service: service.Name,
+				Message: "interval, timeout, retries and start_period cannot be negative",
+			})
+		}
+	}
+
+	switch service.DependsOnCondition {
+	case "", DependsOnStarted, DependsOnHealthy, DependsOnReady:
+	default:
+		errors = append(errors, ValidationError{
+			Field:   "depends_on_condition",
+			Service: service.Name,
+			Message: fmt.Sprintf("invalid condition '%s' (use %s, %s or %s)",
+				service.DependsOnCondition, DependsOnStarted, DependsOnHealthy, DependsOnReady),
+		})
+	}
+
+	return errors
+}
+
+// validateHealthyDependencies checks that services waiting for healthy
+// dependencies only depend on services that define a health check
+func validateHealthyDependencies(services []Service) ValidationErrors {
+	var errors ValidationErrors
+
+	hasCheck := make(map[string]bool, len(services))
+	for _, service := range services {
+		hasCheck[service.Name] = service.HealthCheck != nil
+	}
+
+	for _, service := range services {
+		if service.DependsOnCondition != DependsOnHealthy {
+			continue
+		}
+		for _, dep := range service.DependsOn {
+			if checked, ok := hasCheck[dep]; ok && !checked {
+				errors = append(errors, ValidationError{
+					Field:   "depends_on_condition",
+					Service: service.Name,
+					Message: fmt.Sprintf("dependency '%s' has no health_check to become healthy", dep),
+				})
+			}
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,156 @@
+package supervisor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test health check settings validation
+func TestValidateHealthCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"none", Service{Name: "web"}, 0},
+		{"exec", Service{Name: "web", HealthCheck: &HealthCheck{Exec: "true"}}, 0},
+		{"tcp", Service{Name: "web", HealthCheck: &HealthCheck{TCP: "127.0.0.1:80", Interval: 5}}, 0},
+		{"http", Service{Name: "web", HealthCheck: &HealthCheck{HTTP: "http://127.0.0.1/healthz"}}, 0},
+		{"no probe", Service{Name: "web", HealthCheck: &HealthCheck{Interval: 5}}, 1},
+		{"two probes", Service{Name: "web", HealthCheck: &HealthCheck{Exec: "true", TCP: "127.0.0.1:80"}}, 1},
+		{"bad tcp address", Service{Name: "web", HealthCheck: &HealthCheck{TCP: "localhost"}}, 1},
+		{"bad url", Service{Name: "web", HealthCheck: &HealthCheck{HTTP: "localhost/healthz"}}, 1},
+		{"negative retries", Service{Name: "web", HealthCheck: &HealthCheck{Exec: "true", Retries: -1}}, 1},
+		{"unknown condition", Service{Name: "web", DependsOnCondition: "listening"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateHealthCheck(&tt.service); len(got) != tt.errors {
+				t.Errorf("validateHealthCheck() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test waiting for healthy dependencies requires them to define a health check
+func TestValidateHealthyDependencies(t *testing.T) {
+	services := []Service{
+		{Name: "db", HealthCheck: &HealthCheck{TCP: "127.0.0.1:5432"}},
+		{Name: "cache"},
+		{Name: "api", DependsOn: DependsOnField{"db"}, DependsOnCondition: DependsOnHealthy},
+		{Name: "web", DependsOn: DependsOnField{"db", "cache"}, DependsOnCondition: DependsOnHealthy},
+		{Name: "worker", DependsOn: DependsOnField{"cache"}},
+	}
+
+	errs := validateHealthyDependencies(services)
+	if len(errs) != 1 || errs[0].Service != "web" {
+		t.Errorf("validateHealthyDependencies() = %v, want one error for service web", errs)
+	}
+}
+
+// Test each probe type against a passing and a failing target
+func TestRunHealthProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		check   HealthCheck
+		healthy bool
+	}{
+		{"exec success", HealthCheck{Exec: "exit 0"}, true},
+		{"exec failure", HealthCheck{Exec: "echo not ready; exit 1"}, false},
+		{"tcp open", HealthCheck{TCP: listener.Addr().String()}, true},
+		{"tcp closed", HealthCheck{TCP: closedAddr}, false},
+		{"http ok", HealthCheck{HTTP: server.URL + "/healthz"}, true},
+		{"http error status", HealthCheck{HTTP: server.URL + "/other"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := runHealthProbe(ctx, &tt.check, os.Environ())
+			if (err == nil) != tt.healthy {
+				t.Errorf("runHealthProbe() error = %v, want healthy %v", err, tt.healthy)
+			}
+		})
+	}
+}
+
+// Test a probe that hangs is stopped by its timeout
+func TestRunHealthProbeTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := runHealthProbe(ctx, &HealthCheck{Exec: "sleep 5"}, os.Environ()); err == nil {
+		t.Error("runHealthProbe() of a hanging command succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("runHealthProbe() took %s, want it bounded by the timeout", elapsed)
+	}
+}
+
+// Test the monitor turns unhealthy after the configured retries and recovers
+func TestMonitorHealth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping slow health check test in short mode")
+	}
+	flag := filepath.Join(t.TempDir(), "healthy")
+	if err := os.WriteFile(flag, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sp := &ServiceProcess{
+		Name: "checked",
+		Config: Service{Name: "checked", HealthCheck: &HealthCheck{
+			Exec: "test -f " + flag, Interval: 1, Timeout: 1, Retries: 2,
+		}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitorHealth(ctx, sp)
+
+	if !waitFor(t, 3*time.Second, func() bool { return sp.GetHealth() == HealthHealthy }) {
+		t.Fatalf("health = %s, want healthy", sp.GetHealth())
+	}
+
+	if err := os.Remove(flag); err != nil {
+		t.Fatal(err)
+	}
+	// One failure is tolerated, the second one marks it unhealthy
+	if !waitFor(t, 4*time.Second, func() bool { return sp.GetHealth() == HealthUnhealthy }) {
+		t.Fatalf("health = %s, want unhealthy", sp.GetHealth())
+	}
+
+	if err := os.WriteFile(flag, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, 3*time.Second, func() bool { return sp.GetHealth() == HealthHealthy }) {
+		t.Fatalf("health = %s, want healthy again", sp.GetHealth())
+	}
+}
@@ -0,0 +1,182 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Lifecycle events recorded in the history of a service
+const (
+	LifecycleStart = "start" // The process was spawned
+	LifecycleExit  = "exit"  // It exited on its own with code 0
+	LifecycleCrash = "crash" // It exited on its own with an error or a signal
+	LifecycleStop  = "stop"  // It was stopped by the supervisor
+)
+
+// lifecycleKeep is the number of events kept per service
+const lifecycleKeep = 50
+
+// LifecycleEvent is an entry of the history of a service
+type LifecycleEvent struct {
+	Time     time.Time     `json:"time"`
+	Event    string        `json:"event"`
+	PID      int           `json:"pid,omitempty"`
+	ExitCode *int          `json:"exit_code,omitempty"` // On exit, crash and stop
+	Duration time.Duration `json:"duration,omitempty"`  // How long the process ran, on exit, crash and stop
+	Message  string        `json:"message,omitempty"`
+}
+
+var (
+	lifecycleHistory = make(map[string][]LifecycleEvent)
+	lifecycleMu      sync.Mutex
+)
+
+// recordLifecycle appends an event to the history of a service, dropping the
+// oldest ones past lifecycleKeep, and saves the state
+func recordLifecycle(name string, event LifecycleEvent) {
+	event.Time = time.Now().UTC()
+
+	lifecycleMu.Lock()
+	history := append(lifecycleHistory[name], event)
+	if len(history) > lifecycleKeep {
+		history = append([]LifecycleEvent(nil), history[len(history)-lifecycleKeep:]...)
+	}
+	lifecycleHistory[name] = history
+	lifecycleMu.Unlock()
+
+	saveState()
+}
+
+// recordProcessEnd records how the process of a service ended after running
+// for uptime: on its own or stopped by the supervisor
+func recordProcessEnd(serviceProcess *ServiceProcess, exitedOnOwn bool, waitErr error, uptime time.Duration) {
+	code := exitStatus(waitErr)
+	event := LifecycleEvent{Event: LifecycleStop, PID: serviceProcess.GetPID(), Duration: uptime}
+	if code >= 0 {
+		event.ExitCode = &code
+	}
+	if exitedOnOwn {
+		event.Event = LifecycleExit
+		if waitErr != nil {
+			event.Event = LifecycleCrash
+			event.Message = waitErr.Error()
+		}
+	}
+	recordLifecycle(serviceProcess.Name, event)
+}
+
+// lifecycleEvents returns the history of a service, oldest first
+func lifecycleEvents(name string) []LifecycleEvent {
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+	return append([]LifecycleEvent(nil), lifecycleHistory[name]...)
+}
+
+// restoreLifecycleEvents puts saved events before the ones recorded since the
+// supervisor started
+func restoreLifecycleEvents(name string, events []LifecycleEvent) {
+	if len(events) == 0 {
+		return
+	}
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+
+	history := append(append([]LifecycleEvent(nil), events...), lifecycleHistory[name]...)
+	if len(history) > lifecycleKeep {
+		history = history[len(history)-lifecycleKeep:]
+	}
+	lifecycleHistory[name] = history
+}
+
+// handleHistory answers the records of every service, without their events,
+// or the record of one service with its events
+func handleHistory(name string) IPCResponse {
+	records := serviceRecords()
+	if name == "" {
+		for i := range records {
+			records[i].Events = nil
+		}
+		return IPCResponse{Success: true, History: records}
+	}
+
+	for _, record := range records {
+		if record.Name == name {
+			return IPCResponse{Success: true, History: []ServiceRecord{record}}
+		}
+	}
+	return notFoundResponse(name)
+}
+
+// showHistory prints the restart counts, last exit codes and runtime stops
+// the daemon remembers, or the lifecycle events of one service
+func showHistory(name string, asJSON bool) error {
+	response, err := sendIPCCommand(IPCCommand{Type: CmdHistory, ServiceName: name})
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return responseError(response)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if name != "" && len(response.History) == 1 {
+			return encoder.Encode(response.History[0])
+		}
+		return encoder.Encode(response.History)
+	}
+
+	if name != "" && len(response.History) == 1 {
+		printLifecycleEvents(response.History[0])
+		return nil
+	}
+
+	var rows [][]string
+	for _, record := range response.History {
+		exitCode, stopped := "-", ""
+		if record.ExitCode != nil {
+			exitCode = fmt.Sprint(*record.ExitCode)
+		}
+		if record.StoppedByHand {
+			stopped = "yes"
+		}
+		rows = append(rows, []string{record.Name, fmt.Sprint(record.TotalRestarts), exitCode, stopped})
+	}
+	fmt.Print(renderTable([]string{"NAME", "RESTARTS", "LAST EXIT", "STOPPED BY HAND"}, rows))
+	return nil
+}
+
+// printLifecycleEvents prints the history of a service as a table
+func printLifecycleEvents(record ServiceRecord) {
+	if len(record.Events) == 0 {
+		fmt.Printf("No lifecycle events recorded for %s\n", record.Name)
+		return
+	}
+
+	var rows [][]string
+	for _, event := range record.Events {
+		pid, exitCode, ran := "-", "-", "-"
+		if event.PID > 0 {
+			pid = fmt.Sprint(event.PID)
+		}
+		if event.ExitCode != nil {
+			exitCode = fmt.Sprint(*event.ExitCode)
+		}
+		if event.Event != LifecycleStart {
+			ran = event.Duration.Round(time.Second).String()
+		}
+		rows = append(rows, []string{
+			event.Time.Local().Format(time.DateTime),
+			event.Event,
+			pid,
+			exitCode,
+			ran,
+			event.Message,
+		})
+	}
+	fmt.Print(renderTable([]string{"TIME", "EVENT", "PID", "EXIT CODE", "RAN FOR", "MESSAGE"}, rows))
+}
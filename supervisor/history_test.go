@@ -0,0 +1,124 @@
+package supervisor
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// forgetLifecycle clears the history of a service
+func forgetLifecycle(t *testing.T, name string) {
+	t.Helper()
+	cleanup := func() {
+		lifecycleMu.Lock()
+		delete(lifecycleHistory, name)
+		lifecycleMu.Unlock()
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+}
+
+// Test the history keeps the last lifecycleKeep events, oldest first
+func TestRecordLifecycleBounded(t *testing.T) {
+	const name = "history-bounded"
+	forgetLifecycle(t, name)
+
+	for pid := 1; pid <= lifecycleKeep+5; pid++ {
+		recordLifecycle(name, LifecycleEvent{Event: LifecycleStart, PID: pid})
+	}
+
+	events := lifecycleEvents(name)
+	if len(events) != lifecycleKeep {
+		t.Fatalf("kept %d events, want %d", len(events), lifecycleKeep)
+	}
+	if events[0].PID != 6 || events[len(events)-1].PID != lifecycleKeep+5 {
+		t.Errorf("kept PIDs %d..%d, want 6..%d", events[0].PID, events[len(events)-1].PID, lifecycleKeep+5)
+	}
+	if events[0].Time.IsZero() {
+		t.Error("events should be timestamped")
+	}
+}
+
+func TestRecordProcessEnd(t *testing.T) {
+	const name = "history-end"
+	forgetLifecycle(t, name)
+
+	failed := exec.Command("/bin/sh", "-c", "exit 3").Run()
+	var exitErr *exec.ExitError
+	if !errors.As(failed, &exitErr) {
+		t.Fatalf("expected an exit error, got %v", failed)
+	}
+
+	tests := []struct {
+		exitedOnOwn bool
+		waitErr     error
+		wantEvent   string
+		wantCode    int
+	}{
+		{true, nil, LifecycleExit, 0},
+		{true, failed, LifecycleCrash, 3},
+		{false, failed, LifecycleStop, 3},
+	}
+	for _, tt := range tests {
+		recordProcessEnd(&ServiceProcess{Name: name}, tt.exitedOnOwn, tt.waitErr, 90*time.Second)
+	}
+
+	events := lifecycleEvents(name)
+	if len(events) != len(tests) {
+		t.Fatalf("recorded %d events, want %d", len(events), len(tests))
+	}
+	for i, tt := range tests {
+		event := events[i]
+		if event.Event != tt.wantEvent || event.ExitCode == nil || *event.ExitCode != tt.wantCode {
+			t.Errorf("event %d = %+v, want %s with exit code %d", i, event, tt.wantEvent, tt.wantCode)
+		}
+		if event.Duration != 90*time.Second {
+			t.Errorf("event %d duration = %s, want 1m30s", i, event.Duration)
+		}
+	}
+	if events[1].Message == "" {
+		t.Error("a crash should keep the error")
+	}
+}
+
+// Test saved events come before the ones recorded since the supervisor started
+func TestRestoreLifecycleEvents(t *testing.T) {
+	const name = "history-restore"
+	forgetLifecycle(t, name)
+
+	recordLifecycle(name, LifecycleEvent{Event: LifecycleStart, PID: 3})
+	restoreLifecycleEvents(name, []LifecycleEvent{
+		{Event: LifecycleStart, PID: 1},
+		{Event: LifecycleCrash, PID: 1},
+	})
+
+	events := lifecycleEvents(name)
+	if len(events) != 3 || events[0].PID != 1 || events[2].PID != 3 {
+		t.Errorf("events = %+v, want the saved ones first", events)
+	}
+}
+
+func TestHandleHistory(t *testing.T) {
+	const name = "history-handle"
+	forgetLifecycle(t, name)
+	recordLifecycle(name, LifecycleEvent{Event: LifecycleStart, PID: 42})
+
+	response := handleHistory(name)
+	if !response.Success || len(response.History) != 1 || len(response.History[0].Events) != 1 {
+		t.Errorf("handleHistory(%s) = %+v, want its record with its events", name, response)
+	}
+
+	response = handleHistory("")
+	record, ok := findRecord(response.History, name)
+	if !response.Success || !ok {
+		t.Fatalf("handleHistory() = %+v, want every record", response)
+	}
+	if len(record.Events) != 0 {
+		t.Error("the summary should leave the events out")
+	}
+
+	if response := handleHistory("history-unknown"); response.Success {
+		t.Error("an unknown service should fail")
+	}
+}
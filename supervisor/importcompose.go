@@ -0,0 +1,325 @@
+package supervisor
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeFile is the part of a Docker Compose file the converter reads
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+// composeService is a compose service definition. Fields accepting several
+// forms (a string or a list, a map or a list) are decoded as any.
+type composeService struct {
+	Command         any                 `yaml:"command"`
+	Entrypoint      any                 `yaml:"entrypoint"`
+	Environment     any                 `yaml:"environment"`
+	EnvFile         any                 `yaml:"env_file"`
+	DependsOn       any                 `yaml:"depends_on"`
+	Healthcheck     *composeHealthcheck `yaml:"healthcheck"`
+	Restart         string              `yaml:"restart"`
+	User            string              `yaml:"user"`
+	Labels          any                 `yaml:"labels"`
+	StopSignal      string              `yaml:"stop_signal"`
+	StopGracePeriod string              `yaml:"stop_grace_period"`
+
+	// Everything else, reported as not converted
+	Other map[string]any `yaml:",inline"`
+}
+
+type composeHealthcheck struct {
+	Test        any    `yaml:"test"`
+	Interval    string `yaml:"interval"`
+	Timeout     string `yaml:"timeout"`
+	Retries     int    `yaml:"retries"`
+	StartPeriod string `yaml:"start_period"`
+	Disable     bool   `yaml:"disable"`
+}
+
+// composeIgnoredKeys are compose keys that describe the container rather
+// than the process, and have no meaning once services share one container
+var composeIgnoredKeys = map[string]bool{
+	"image": true, "build": true, "container_name": true, "hostname": true,
+	"ports": true, "expose": true, "networks": true, "volumes": true,
+}
+
+// importComposeServices converts the services of a Docker Compose file into
+// go-overlay services, in name order
+func importComposeServices(data []byte) (Config, []string, error) {
+	var file composeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Config{}, nil, fmt.Errorf("error parsing compose file: %w", err)
+	}
+
+	names := make([]string, 0, len(file.Services))
+	for name := range file.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var config Config
+	var warnings []string
+	for _, name := range names {
+		service, serviceWarnings, ok := importComposeService(name, file.Services[name])
+		warnings = append(warnings, serviceWarnings...)
+		if ok {
+			config.Services = append(config.Services, service)
+		}
+	}
+	return config, warnings, nil
+}
+
+// importComposeService converts one compose service. It reports false for a
+// service without entrypoint or command, which runs the default of its image.
+func importComposeService(name string, cs composeService) (Service, []string, bool) {
+	var warnings []string
+	warn := func(format string, a ...any) {
+		warnings = append(warnings, name+": "+fmt.Sprintf(format, a...))
+	}
+
+	entrypoint, err := composeCommand(cs.Entrypoint)
+	if err != nil {
+		warn("invalid entrypoint: %v", err)
+	}
+	command, err := composeCommand(cs.Command)
+	if err != nil {
+		warn("invalid command: %v", err)
+	}
+	argv := append(entrypoint, command...)
+	if len(argv) == 0 {
+		warn("skipped, it has no command or entrypoint and runs the default of its image")
+		return Service{}, warnings, false
+	}
+
+	service := Service{
+		Name:       name,
+		Command:    argv[0],
+		Args:       argv[1:],
+		StopSignal: cs.StopSignal,
+	}
+
+	if service.Env, err = composeMap(cs.Environment); err != nil {
+		warn("invalid environment: %v", err)
+	}
+	if service.Labels, err = composeMap(cs.Labels); err != nil {
+		warn("invalid labels: %v", err)
+	}
+
+	envFiles, err := composeList(cs.EnvFile)
+	if err != nil {
+		warn("invalid env_file: %v", err)
+	}
+	if len(envFiles) > 0 {
+		service.EnvFile = envFiles[0]
+		if len(envFiles) > 1 {
+			warn("only the first env_file is kept, merge %s into it", strings.Join(envFiles[1:], ", "))
+		}
+	}
+
+	if cs.User != "" {
+		service.User, service.Group, _ = strings.Cut(cs.User, ":")
+	}
+
+	switch restart, retries, _ := strings.Cut(cs.Restart, ":"); restart {
+	case "", "no":
+	case "always", "unless-stopped":
+		service.Restart = RestartAlways
+	case "on-failure":
+		service.Restart = RestartOnFailure
+		if retries != "" {
+			service.RestartMaxRetries, _ = strconv.Atoi(retries)
+		}
+	default:
+		warn("unknown restart policy '%s'", cs.Restart)
+	}
+
+	if cs.StopGracePeriod != "" {
+		if service.StopTimeout, err = composeSeconds(cs.StopGracePeriod); err != nil {
+			warn("invalid stop_grace_period: %v", err)
+		}
+	}
+
+	if deps, condition, err := composeDependsOn(cs.DependsOn); err != nil {
+		warn("invalid depends_on: %v", err)
+	} else {
+		service.DependsOn = deps
+		service.DependsOnCondition = condition
+	}
+
+	if hc := cs.Healthcheck; hc != nil && !hc.Disable {
+		check, err := composeHealthCheck(hc)
+		if err != nil {
+			warn("healthcheck not converted: %v", err)
+		}
+		service.HealthCheck = check
+	}
+
+	var ignored []string
+	for key := range cs.Other {
+		if !composeIgnoredKeys[key] {
+			ignored = append(ignored, key)
+		}
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		warn("not converted: %s", strings.Join(ignored, ", "))
+	}
+
+	return service, warnings, true
+}
+
+// composeCommand returns a command given as a list, or as a string split
+// like compose does
+func composeCommand(value any) ([]string, error) {
+	if line, ok := value.(string); ok {
+		return splitCommandLine(line)
+	}
+	return composeList(value)
+}
+
+// composeList returns a string or a list of strings as a list
+func composeList(value any) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("expected a string or a list, got %T", value)
+	}
+}
+
+// composeMap returns a map, or a list of KEY=value items, as a map. Keys
+// listed without a value are left out, as they take the value of the host.
+func composeMap(value any) (map[string]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		m := make(map[string]string, len(v))
+		for key, val := range v {
+			if val != nil {
+				m[key] = fmt.Sprint(val)
+			}
+		}
+		return m, nil
+	case []any:
+		m := make(map[string]string, len(v))
+		for _, item := range v {
+			if key, val, ok := strings.Cut(fmt.Sprint(item), "="); ok {
+				m[key] = val
+			}
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("expected a map or a list, got %T", value)
+	}
+}
+
+// composeDependsOn returns the dependencies of a compose service and the
+// depends_on_condition they need: healthy if any waits for service_healthy
+func composeDependsOn(value any) ([]string, string, error) {
+	if m, ok := value.(map[string]any); ok {
+		var deps []string
+		condition := ""
+		for dep, options := range m {
+			deps = append(deps, dep)
+			if opts, ok := options.(map[string]any); ok && opts["condition"] == "service_healthy" {
+				condition = DependsOnHealthy
+			}
+		}
+		sort.Strings(deps)
+		return deps, condition, nil
+	}
+	deps, err := composeList(value)
+	return deps, "", err
+}
+
+// composeHealthCheck converts a compose healthcheck into an exec health check
+func composeHealthCheck(hc *composeHealthcheck) (*HealthCheck, error) {
+	var exec string
+	switch test := hc.Test.(type) {
+	case string:
+		exec = test
+	case []any:
+		words, _ := composeList(test)
+		if len(words) > 0 {
+			switch words[0] {
+			case "NONE":
+				return nil, nil
+			case "CMD-SHELL":
+				exec = strings.Join(words[1:], " ")
+			case "CMD":
+				exec = shellJoin(words[1:])
+			}
+		}
+	}
+	if exec == "" {
+		return nil, fmt.Errorf("unsupported test %v", hc.Test)
+	}
+
+	check := &HealthCheck{Exec: exec, Retries: hc.Retries}
+	var err error
+	for _, d := range []struct {
+		value string
+		field *int
+	}{{hc.Interval, &check.Interval}, {hc.Timeout, &check.Timeout}, {hc.StartPeriod, &check.StartPeriod}} {
+		if d.value != "" {
+			if *d.field, err = composeSeconds(d.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return check, nil
+}
+
+// composeSeconds converts a compose duration (1m30s) into whole seconds,
+// rounded up
+func composeSeconds(value string) (int, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Ceil(d.Seconds())), nil
+}
+
+// shellJoin quotes words for a shell command line
+func shellJoin(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		if word != "" && !strings.ContainsAny(word, " \t\n'\"\\$`;&|<>()*?[]{}!#~") {
+			quoted[i] = word
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// importCompose converts the services of a compose file and writes them to output
+func importCompose(path, output string) error {
+	data, err := os.ReadFile(path) // #nosec G304 - reading the file being imported
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	config, warnings, err := importComposeServices(data)
+	if err != nil {
+		return err
+	}
+	return writeImportedConfig(&config, warnings, path, output)
+}
@@ -0,0 +1,75 @@
+package supervisor
+
+import (
+	"slices"
+	"testing"
+)
+
+// Test compose services convert into equivalent services
+func TestImportComposeServices(t *testing.T) {
+	data := []byte(`
+services:
+  db:
+    image: postgres:16
+    command: postgres -c 'max_connections=200'
+    environment:
+      POSTGRES_PASSWORD: secret
+    healthcheck:
+      test: ["CMD", "pg_isready", "-U", "postgres"]
+      interval: 10s
+      timeout: 1500ms
+      retries: 5
+    ports: ["5432:5432"]
+  api:
+    entrypoint: ["node"]
+    command: ["server.js"]
+    environment:
+      - NODE_ENV=production
+      - FROM_HOST
+    depends_on:
+      db:
+        condition: service_healthy
+    restart: on-failure:3
+    user: "app:app"
+    stop_grace_period: 1m30s
+    deploy: {}
+  nginx:
+    image: nginx
+`)
+
+	config, warnings, err := importComposeServices(data)
+	if err != nil {
+		t.Fatalf("importComposeServices() error = %v", err)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("got warnings %v, want deploy not converted and nginx skipped", warnings)
+	}
+	if len(config.Services) != 2 {
+		t.Fatalf("got %d services, want api and db", len(config.Services))
+	}
+
+	api, db := config.Services[0], config.Services[1]
+	if api.Command != "node" || !slices.Equal(api.Args, []string{"server.js"}) {
+		t.Errorf("api command = %q %q, want node server.js", api.Command, api.Args)
+	}
+	if len(api.Env) != 1 || api.Env["NODE_ENV"] != "production" {
+		t.Errorf("api env = %v, want NODE_ENV only", api.Env)
+	}
+	if !slices.Equal(api.DependsOn, []string{"db"}) || api.DependsOnCondition != DependsOnHealthy {
+		t.Errorf("api depends_on = %v (%s), want db (healthy)", api.DependsOn, api.DependsOnCondition)
+	}
+	if api.Restart != RestartOnFailure || api.RestartMaxRetries != 3 {
+		t.Errorf("api restart = %s (%d retries), want on-failure (3)", api.Restart, api.RestartMaxRetries)
+	}
+	if api.User != "app" || api.Group != "app" || api.StopTimeout != 90 {
+		t.Errorf("api user = %s:%s, stop_timeout = %d", api.User, api.Group, api.StopTimeout)
+	}
+
+	if db.Command != "postgres" || !slices.Equal(db.Args, []string{"-c", "max_connections=200"}) {
+		t.Errorf("db command = %q %q", db.Command, db.Args)
+	}
+	want := HealthCheck{Exec: "pg_isready -U postgres", Interval: 10, Timeout: 2, Retries: 5}
+	if db.HealthCheck == nil || *db.HealthCheck != want {
+		t.Errorf("db health_check = %+v, want %+v", db.HealthCheck, want)
+	}
+}
@@ -0,0 +1,95 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// writeImportedConfig writes the services converted from source as a
+// services.toml document to output ("-" for stdout). Warnings about what
+// could not be converted are kept out of the document, on stderr when it is
+// written to stdout.
+func writeImportedConfig(config *Config, warnings []string, source, output string) error {
+	toStdout := output == "" || output == "-"
+	for _, warning := range warnings {
+		if toStdout {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		} else {
+			logger.Warn(warning)
+		}
+	}
+
+	data, err := marshalConfig(config)
+	if err != nil {
+		return fmt.Errorf("error encoding configuration: %w", err)
+	}
+
+	header := fmt.Sprintf("# Imported from %s by go-overlay %s\n", source, version)
+	if toStdout {
+		fmt.Print(header + string(data))
+		return nil
+	}
+
+	if err := os.WriteFile(output, []byte(header+string(data)), 0o644); err != nil { // #nosec G306 - config files are not secret
+		return fmt.Errorf("error writing %s: %w", output, err)
+	}
+	logger.Success(fmt.Sprintf("Imported %d services to %s", len(config.Services), colorize(ColorCyan, output)))
+	return nil
+}
+
+// splitCommandLine splits a command line into words like a shell does,
+// honoring single and double quotes and backslash escapes, without
+// expanding anything
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && quote != '\'':
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("trailing backslash in %q", line)
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
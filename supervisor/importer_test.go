@@ -0,0 +1,34 @@
+package supervisor
+
+import (
+	"slices"
+	"testing"
+)
+
+// Test command lines split into words like a shell does
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"nginx -g 'daemon off;'", []string{"nginx", "-g", "daemon off;"}, false},
+		{`echo "a \"b\"" c\ d`, []string{"echo", `a "b"`, "c d"}, false},
+		{"  spaced   out  ", []string{"spaced", "out"}, false},
+		{`empty ''`, []string{"empty", ""}, false},
+		{"", nil, false},
+		{"unterminated 'quote", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := splitCommandLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommandLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitCommandLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
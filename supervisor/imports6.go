@@ -0,0 +1,139 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultS6ServicesDir is where s6-overlay v2 images keep their services
+const defaultS6ServicesDir = "/etc/services.d"
+
+// s6BaseBundle is the bundle of s6-overlay itself, which s6-rc services
+// depend on and go-overlay has no equivalent of
+const s6BaseBundle = "base"
+
+// importS6Services converts the s6-overlay service directories of dir, either
+// services.d (a run script per service) or s6-rc.d (with a type file), into
+// go-overlay services. Longruns are restarted like s6-supervise does.
+func importS6Services(dir string) (Config, []string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("error reading %s: %w", dir, err)
+	}
+
+	var config Config
+	var warnings []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		service, warning, ok := importS6Service(filepath.Join(dir, entry.Name()))
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+		if ok {
+			config.Services = append(config.Services, service)
+		}
+	}
+	return config, warnings, nil
+}
+
+// importS6Service converts one service directory. It reports false, with a
+// warning, for directories that are not a service go-overlay can run.
+func importS6Service(dir string) (Service, string, bool) {
+	name := filepath.Base(dir)
+	service := Service{Name: name}
+
+	switch kind := readS6File(dir, "type"); kind {
+	case "", "longrun":
+		run := filepath.Join(dir, "run")
+		if _, err := os.Stat(run); err != nil {
+			return Service{}, fmt.Sprintf("Skipping %s: no run script", name), false
+		}
+		service.Command = run
+		service.Restart = RestartAlways
+	case "oneshot":
+		// The up file is an execline command line; only a plain command converts
+		up := readS6File(dir, "up")
+		fields := strings.Fields(up)
+		if len(fields) == 0 || strings.ContainsAny(up, "\n{};") {
+			return Service{}, fmt.Sprintf("Skipping oneshot %s: convert its up script to a command by hand", name), false
+		}
+		service.Command = fields[0]
+		service.Args = fields[1:]
+	case "bundle":
+		return Service{}, fmt.Sprintf("Skipping bundle %s: go-overlay has no bundles, use tags instead", name), false
+	default:
+		return Service{}, fmt.Sprintf("Skipping %s: unknown type '%s'", name, kind), false
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "finish")); err == nil {
+		service.OnExit = filepath.Join(dir, "finish")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "down")); err == nil {
+		service.Enabled = new(bool)
+	}
+	if signal := readS6File(dir, "down-signal"); signal != "" {
+		service.StopSignal = signal
+	}
+
+	var warning string
+	if fd := readS6File(dir, "notification-fd"); fd != "" {
+		if n, err := strconv.Atoi(fd); err == nil && n > 0 {
+			service.Readiness = &ReadinessProbe{NotificationFD: n}
+		} else {
+			warning = fmt.Sprintf("Ignoring notification-fd of %s: '%s' is not a file descriptor", name, fd)
+		}
+	}
+
+	service.DependsOn = s6Dependencies(dir)
+	return service, warning, true
+}
+
+// s6Dependencies returns the dependencies of a service directory: the files
+// of dependencies.d (s6-rc) or the lines of dependencies, without the base
+// bundle of s6-overlay
+func s6Dependencies(dir string) []string {
+	var names []string
+	if entries, err := os.ReadDir(filepath.Join(dir, "dependencies.d")); err == nil {
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+	} else {
+		names = strings.Fields(readS6File(dir, "dependencies"))
+	}
+
+	var deps []string
+	for _, name := range names {
+		if name != s6BaseBundle && !strings.HasPrefix(name, ".") {
+			deps = append(deps, name)
+		}
+	}
+	return deps
+}
+
+// readS6File returns the trimmed content of a file of a service directory,
+// empty if it doesn't exist
+func readS6File(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name)) // #nosec G304 - reading the directory being imported
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// importS6 converts the s6-overlay services of dir and writes them to output
+func importS6(dir, output string) error {
+	config, warnings, err := importS6Services(dir)
+	if err != nil {
+		return err
+	}
+	return writeImportedConfig(&config, warnings, dir, output)
+}
@@ -0,0 +1,76 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Test s6-overlay service directories convert into equivalent services
+func TestImportS6Services(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"web/run":                 "#!/bin/sh\nexec nginx\n",
+		"web/finish":              "#!/bin/sh\n",
+		"web/notification-fd":     "3\n",
+		"web/down-signal":         "SIGQUIT\n",
+		"web/dependencies.d/db":   "",
+		"web/dependencies.d/base": "",
+		"db/run":                  "#!/bin/sh\nexec postgres\n",
+		"db/down":                 "",
+		"migrate/type":            "oneshot\n",
+		"migrate/up":              "/scripts/migrate.sh --all\n",
+		"complex/type":            "oneshot\n",
+		"complex/up":              "if { true } echo hi\n",
+		"user/type":               "bundle\n",
+		"empty/.keep":             "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, warnings, err := importS6Services(dir)
+	if err != nil {
+		t.Fatalf("importS6Services() error = %v", err)
+	}
+	if len(warnings) != 3 {
+		t.Errorf("got warnings %v, want complex, empty and user skipped", warnings)
+	}
+
+	services := make(map[string]Service)
+	for _, service := range config.Services {
+		services[service.Name] = service
+	}
+	if len(services) != 3 {
+		t.Fatalf("got %d services, want db, migrate and web", len(services))
+	}
+
+	web := services["web"]
+	if web.Command != filepath.Join(dir, "web", "run") || web.Restart != RestartAlways {
+		t.Errorf("web = %+v, want its run script restarted always", web)
+	}
+	if web.OnExit != filepath.Join(dir, "web", "finish") || web.StopSignal != "SIGQUIT" {
+		t.Errorf("web on_exit = %q, stop_signal = %q", web.OnExit, web.StopSignal)
+	}
+	if web.Readiness == nil || web.Readiness.NotificationFD != 3 {
+		t.Errorf("web readiness = %v, want notification_fd 3", web.Readiness)
+	}
+	if !slices.Equal(web.DependsOn, []string{"db"}) {
+		t.Errorf("web depends_on = %v, want [db]", web.DependsOn)
+	}
+
+	if db := services["db"]; db.Enabled == nil || *db.Enabled {
+		t.Error("db has a down file but is enabled")
+	}
+	migrate := services["migrate"]
+	if migrate.Command != "/scripts/migrate.sh" || !slices.Equal(migrate.Args, []string{"--all"}) || migrate.Restart != "" {
+		t.Errorf("migrate = %+v, want a oneshot running its up command", migrate)
+	}
+}
@@ -0,0 +1,350 @@
+package supervisor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// supervisordProgramPrefix starts the sections defining a program
+const supervisordProgramPrefix = "program:"
+
+// defaultSupervisordPriority is the priority of a program without one
+const defaultSupervisordPriority = 999
+
+// supervisordExpansion matches the %(name)s expressions of supervisord
+var supervisordExpansion = regexp.MustCompile(`%\(([A-Za-z0-9_]+)\)s`)
+
+// supervisordConvertedKeys are the program settings the converter handles
+var supervisordConvertedKeys = map[string]bool{
+	"command": true, "autostart": true, "autorestart": true, "exitcodes": true,
+	"startretries": true, "user": true, "priority": true, "environment": true,
+	"stopsignal": true, "stopwaitsecs": true, "redirect_stderr": true,
+	"stdout_logfile": true, "stdout_logfile_maxbytes": true, "stdout_logfile_backups": true,
+	"stderr_logfile": true, "stderr_logfile_maxbytes": true, "stderr_logfile_backups": true,
+}
+
+// parseSupervisordConfig parses a supervisord.conf into its sections. Indented
+// lines continue the value of the previous key, and " ;" starts a comment.
+func parseSupervisordConfig(data []byte) (map[string]map[string]string, []string, error) {
+	sections := make(map[string]map[string]string)
+	var order []string
+	var section, key string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		if i := strings.Index(text, " ;"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" || text[0] == ';' || text[0] == '#' {
+			continue
+		}
+
+		switch {
+		case raw[0] == ' ' || raw[0] == '\t':
+			if key == "" {
+				return nil, nil, fmt.Errorf("line %d: continuation without a key", n)
+			}
+			sections[section][key] += "\n" + text
+		case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+			section, key = text[1:len(text)-1], ""
+			if sections[section] == nil {
+				sections[section] = make(map[string]string)
+				order = append(order, section)
+			}
+		case section == "":
+			return nil, nil, fmt.Errorf("line %d: setting outside of a section", n)
+		default:
+			k, value, ok := strings.Cut(text, "=")
+			if !ok {
+				return nil, nil, fmt.Errorf("line %d: expected key=value", n)
+			}
+			key = strings.TrimSpace(k)
+			sections[section][key] = strings.TrimSpace(value)
+		}
+	}
+	return sections, order, scanner.Err()
+}
+
+// importSupervisordPrograms converts the [program:x] sections of a
+// supervisord config into go-overlay services. here is the directory of the
+// config, for %(here)s.
+func importSupervisordPrograms(data []byte, here string) (Config, []string, error) {
+	sections, order, err := parseSupervisordConfig(data)
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	// Programs start by priority: each distinct priority becomes a stage
+	priorities := make(map[int]bool)
+	for _, section := range order {
+		if strings.HasPrefix(section, supervisordProgramPrefix) {
+			priorities[supervisordPriority(sections[section])] = true
+		}
+	}
+	stages := make([]int, 0, len(priorities))
+	for priority := range priorities {
+		stages = append(stages, priority)
+	}
+	sort.Ints(stages)
+
+	var config Config
+	var warnings []string
+	for _, section := range order {
+		name, ok := strings.CutPrefix(section, supervisordProgramPrefix)
+		if !ok {
+			if strings.HasPrefix(section, "group:") || strings.HasPrefix(section, "eventlistener:") {
+				warnings = append(warnings, fmt.Sprintf("[%s] not converted", section))
+			}
+			continue
+		}
+		service, serviceWarnings, ok := importSupervisordProgram(name, sections[section], here)
+		warnings = append(warnings, serviceWarnings...)
+		if ok {
+			if len(stages) > 1 {
+				service.Stage = sort.SearchInts(stages, supervisordPriority(sections[section]))
+			}
+			config.Services = append(config.Services, service)
+		}
+	}
+	return config, warnings, nil
+}
+
+// importSupervisordProgram converts one program. It reports false for a
+// program without command.
+func importSupervisordProgram(name string, program map[string]string, here string) (Service, []string, bool) {
+	var warnings []string
+	warn := func(format string, a ...any) {
+		warnings = append(warnings, name+": "+fmt.Sprintf(format, a...))
+	}
+	expand := func(value string) string {
+		return expandSupervisord(value, name, here)
+	}
+
+	argv, err := splitCommandLine(expand(program["command"]))
+	if err != nil || len(argv) == 0 {
+		warn("skipped, no command: %v", err)
+		return Service{}, warnings, false
+	}
+
+	service := Service{
+		Name:    name,
+		Command: argv[0],
+		Args:    argv[1:],
+		User:    program["user"],
+	}
+	if program["autostart"] == "false" {
+		service.Enabled = new(bool)
+	}
+	if signal := program["stopsignal"]; signal != "" {
+		service.StopSignal = "SIG" + strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	}
+	if value := program["stopwaitsecs"]; value != "" {
+		if service.StopTimeout, err = strconv.Atoi(value); err != nil {
+			warn("invalid stopwaitsecs: %v", err)
+		}
+	}
+
+	// Programs with a higher priority stop first, as with supervisord
+	service.ShutdownPriority = supervisordPriority(program)
+
+	// autorestart defaults to unexpected: restart unless the exit code is in exitcodes
+	switch restart := program["autorestart"]; restart {
+	case "true":
+		service.Restart = RestartAlways
+	case "false":
+	case "", "unexpected":
+		service.Restart = RestartOnFailure
+		if codes := program["exitcodes"]; codes != "" && codes != "0" {
+			service.Restart = RestartAlways
+			for _, code := range strings.Split(codes, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(code))
+				if err != nil {
+					warn("invalid exitcodes: %v", err)
+					continue
+				}
+				service.NoRestartExitCodes = append(service.NoRestartExitCodes, n)
+			}
+		}
+	default:
+		warn("unknown autorestart '%s'", restart)
+	}
+	if value := program["startretries"]; value != "" && service.Restart != "" {
+		if service.RestartMaxRetries, err = strconv.Atoi(value); err != nil {
+			warn("invalid startretries: %v", err)
+		}
+	}
+
+	if env := program["environment"]; env != "" {
+		service.Env, err = supervisordEnvironment(expand(env))
+		if err != nil {
+			warn("invalid environment: %v", err)
+		}
+	}
+
+	service.LogOutput = supervisordLogFile(program, "stdout", expand, warn)
+	if program["redirect_stderr"] != "true" {
+		if output := supervisordLogFile(program, "stderr", expand, warn); output != nil {
+			service.StderrLogOutput = output
+			service.PTY = new(bool)
+		}
+	}
+
+	var ignored []string
+	for key := range program {
+		if !supervisordConvertedKeys[key] {
+			ignored = append(ignored, key)
+		}
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		warn("not converted: %s", strings.Join(ignored, ", "))
+	}
+
+	return service, warnings, true
+}
+
+// supervisordPriority returns the priority of a program
+func supervisordPriority(program map[string]string) int {
+	if priority, err := strconv.Atoi(program["priority"]); err == nil {
+		return priority
+	}
+	return defaultSupervisordPriority
+}
+
+// supervisordLogFile converts the <stream>_logfile of a program into a log
+// output. Files supervisord manages itself (AUTO) or no file (NONE, or the
+// container's own output) give nil.
+func supervisordLogFile(program map[string]string, stream string, expand func(string) string, warn func(string, ...any)) *LogOutput {
+	path := expand(program[stream+"_logfile"])
+	switch path {
+	case "", "NONE", "AUTO", "/dev/stdout", "/dev/stderr", "/dev/fd/1", "/dev/fd/2":
+		return nil
+	}
+
+	output := &LogOutput{Path: path}
+	if value := program[stream+"_logfile_maxbytes"]; value != "" {
+		size, err := supervisordMegabytes(value)
+		if err != nil {
+			warn("invalid %s_logfile_maxbytes: %v", stream, err)
+		}
+		output.MaxSize = size
+	}
+	if value := program[stream+"_logfile_backups"]; value != "" {
+		backups, err := strconv.Atoi(value)
+		if err != nil {
+			warn("invalid %s_logfile_backups: %v", stream, err)
+		}
+		output.MaxFiles = backups
+	}
+	return output
+}
+
+// supervisordMegabytes converts a supervisord size (50MB, 1GB, 1024KB or
+// bytes) into megabytes, rounded up
+func supervisordMegabytes(value string) (int, error) {
+	multiplier := int64(1)
+	upper := strings.ToUpper(value)
+	for _, unit := range []struct {
+		suffix string
+		bytes  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if number, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			upper, multiplier = number, unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int((n*multiplier + 1<<20 - 1) >> 20), nil
+}
+
+// supervisordEnvironment parses KEY="value",KEY2=value2: assignments are
+// separated by commas outside of quotes
+func supervisordEnvironment(value string) (map[string]string, error) {
+	env := make(map[string]string)
+	var assignment strings.Builder
+	var quote rune
+
+	add := func() error {
+		text := strings.TrimSpace(assignment.String())
+		assignment.Reset()
+		if text == "" {
+			return nil
+		}
+		key, val, ok := strings.Cut(text, "=")
+		if !ok {
+			return fmt.Errorf("expected KEY=value, got %q", text)
+		}
+		env[strings.TrimSpace(key)] = val
+		return nil
+	}
+
+	for _, r := range value {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == ',':
+			if err := add(); err != nil {
+				return nil, err
+			}
+		case quote == 0 && r == '\n':
+		default:
+			assignment.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if err := add(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// expandSupervisord replaces the %(name)s expressions supervisord expands:
+// %(ENV_X)s becomes ${X}, expanded by go-overlay when it loads the config
+func expandSupervisord(value, program, here string) string {
+	return supervisordExpansion.ReplaceAllStringFunc(value, func(match string) string {
+		name := supervisordExpansion.FindStringSubmatch(match)[1]
+		switch {
+		case strings.HasPrefix(name, "ENV_"):
+			return "${" + strings.TrimPrefix(name, "ENV_") + "}"
+		case name == "program_name":
+			return program
+		case name == "here":
+			return here
+		}
+		return match
+	})
+}
+
+// importSupervisord converts the programs of a supervisord config and writes
+// them to output
+func importSupervisord(path, output string) error {
+	data, err := os.ReadFile(path) // #nosec G304 - reading the file being imported
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	here, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	config, warnings, err := importSupervisordPrograms(data, here)
+	if err != nil {
+		return err
+	}
+	return writeImportedConfig(&config, warnings, path, output)
+}
@@ -0,0 +1,85 @@
+package supervisor
+
+import (
+	"slices"
+	"testing"
+)
+
+// Test supervisord programs convert into equivalent services
+func TestImportSupervisordPrograms(t *testing.T) {
+	data := []byte(`[supervisord]
+nodaemon=true
+
+[program:nginx]
+command=/usr/sbin/nginx -g "daemon off;"
+priority=10
+autorestart=true
+stdout_logfile=/dev/stdout
+stopsignal=QUIT
+
+[program:app]
+command=%(ENV_HOME)s/bin/app --name %(program_name)s ; comment
+user=www-data
+priority=20
+environment=A="1,2",B=3,
+  C='x y'
+stdout_logfile=%(here)s/app.log
+stdout_logfile_maxbytes=50MB
+stdout_logfile_backups=3
+stderr_logfile=/var/log/app.err
+exitcodes=0,2
+startretries=5
+directory=/srv
+
+[program:worker]
+command=/usr/bin/worker
+autostart=false
+autorestart=false
+
+[group:all]
+programs=nginx,app
+`)
+
+	config, warnings, err := importSupervisordPrograms(data, "/etc/supervisor")
+	if err != nil {
+		t.Fatalf("importSupervisordPrograms() error = %v", err)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("got warnings %v, want directory and the group not converted", warnings)
+	}
+	if len(config.Services) != 3 {
+		t.Fatalf("got %d services, want nginx, app and worker", len(config.Services))
+	}
+
+	nginx, app, worker := config.Services[0], config.Services[1], config.Services[2]
+	if !slices.Equal(nginx.Args, []string{"-g", "daemon off;"}) || nginx.StopSignal != "SIGQUIT" || nginx.Restart != RestartAlways {
+		t.Errorf("nginx = %+v", nginx)
+	}
+	if nginx.LogOutput != nil {
+		t.Errorf("nginx log_output = %+v, want none for /dev/stdout", nginx.LogOutput)
+	}
+	if nginx.Stage != 0 || app.Stage != 1 || worker.Stage != 2 || app.ShutdownPriority != 20 {
+		t.Errorf("stages = %d, %d, %d, want the priority order", nginx.Stage, app.Stage, worker.Stage)
+	}
+
+	if app.Command != "${HOME}/bin/app" || !slices.Equal(app.Args, []string{"--name", "app"}) || app.User != "www-data" {
+		t.Errorf("app command = %q %q, user = %q", app.Command, app.Args, app.User)
+	}
+	if app.Env["A"] != "1,2" || app.Env["B"] != "3" || app.Env["C"] != "x y" {
+		t.Errorf("app env = %v", app.Env)
+	}
+	if app.Restart != RestartAlways || !slices.Equal(app.NoRestartExitCodes, []int{0, 2}) || app.RestartMaxRetries != 5 {
+		t.Errorf("app restart = %s, no_restart_exit_codes = %v, retries = %d", app.Restart, app.NoRestartExitCodes, app.RestartMaxRetries)
+	}
+	want := LogOutput{Path: "/etc/supervisor/app.log", MaxSize: 50, MaxFiles: 3}
+	if app.LogOutput == nil || *app.LogOutput != want {
+		t.Errorf("app log_output = %+v, want %+v", app.LogOutput, want)
+	}
+	if app.StderrLogOutput == nil || app.PTY == nil || *app.PTY {
+		t.Errorf("app stderr_log_output = %+v, pty = %v", app.StderrLogOutput, app.PTY)
+	}
+
+	if worker.Enabled == nil || *worker.Enabled || worker.Restart != "" {
+		t.Errorf("worker = %+v, want disabled and never restarted", worker)
+	}
+}
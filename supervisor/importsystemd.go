@@ -0,0 +1,266 @@
+package supervisor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// systemdUnit holds the settings of a unit file by section, every value of
+// a key in order
+type systemdUnit map[string]map[string][]string
+
+// get returns the last value of key in section, like systemd for single
+// value settings
+func (u systemdUnit) get(section, key string) string {
+	values := u[section][key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// systemdConvertedKeys are the [Service] settings the converter handles
+var systemdConvertedKeys = map[string]bool{
+	"Type": true, "ExecStart": true, "ExecStartPre": true, "ExecReload": true,
+	"User": true, "Group": true, "Restart": true, "RestartSec": true,
+	"Environment": true, "EnvironmentFile": true, "KillSignal": true,
+	"TimeoutStopSec": true, "Nice": true,
+}
+
+// parseSystemdUnit parses a unit file: sections, key=value lines continued
+// with a trailing backslash, and comments
+func parseSystemdUnit(data []byte) (systemdUnit, error) {
+	unit := make(systemdUnit)
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var line string
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if line == "" && (text == "" || text[0] == '#' || text[0] == ';') {
+			continue
+		}
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+		line += text
+
+		switch {
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line[1 : len(line)-1]
+			if unit[section] == nil {
+				unit[section] = make(map[string][]string)
+			}
+		case section == "":
+			return nil, fmt.Errorf("line %d: setting outside of a section", n)
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value", n)
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if value == "" {
+				// An empty assignment resets the list of values
+				delete(unit[section], key)
+			} else {
+				unit[section][key] = append(unit[section][key], value)
+			}
+		}
+		line = ""
+	}
+	return unit, scanner.Err()
+}
+
+// importSystemdUnits converts .service unit files into go-overlay services
+// named after the units. Dependencies are kept between the imported units.
+func importSystemdUnits(paths []string) (Config, []string, error) {
+	units := make(map[string]systemdUnit)
+	var names []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path) // #nosec G304 - reading the files being imported
+		if err != nil {
+			return Config{}, nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		unit, err := parseSystemdUnit(data)
+		if err != nil {
+			return Config{}, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".service")
+		units[name] = unit
+		names = append(names, name)
+	}
+
+	var config Config
+	var warnings []string
+	for _, name := range names {
+		service, serviceWarnings, ok := importSystemdUnit(name, units[name], units)
+		warnings = append(warnings, serviceWarnings...)
+		if ok {
+			config.Services = append(config.Services, service)
+		}
+	}
+	return config, warnings, nil
+}
+
+// importSystemdUnit converts one unit. It reports false for a unit without
+// ExecStart.
+func importSystemdUnit(name string, unit systemdUnit, units map[string]systemdUnit) (Service, []string, bool) {
+	var warnings []string
+	warn := func(format string, a ...any) {
+		warnings = append(warnings, name+": "+fmt.Sprintf(format, a...))
+	}
+
+	execStart := unit["Service"]["ExecStart"]
+	if len(execStart) == 0 {
+		warn("skipped, it has no ExecStart")
+		return Service{}, warnings, false
+	}
+	if len(execStart) > 1 {
+		warn("only the first ExecStart is kept")
+	}
+	argv, err := splitCommandLine(strings.TrimLeft(execStart[0], "-@:+!"))
+	if err != nil || len(argv) == 0 {
+		warn("skipped, ExecStart can't be parsed: %v", err)
+		return Service{}, warnings, false
+	}
+
+	service := Service{
+		Name:       name,
+		Command:    argv[0],
+		Args:       argv[1:],
+		User:       unit.get("Service", "User"),
+		Group:      unit.get("Service", "Group"),
+		StopSignal: unit.get("Service", "KillSignal"),
+	}
+
+	// pre_script is a script file: only a lone ExecStartPre without arguments converts
+	if pre := unit["Service"]["ExecStartPre"]; len(pre) == 1 && len(strings.Fields(pre[0])) == 1 && !strings.HasPrefix(pre[0], "-") {
+		service.PreScript = strings.TrimLeft(pre[0], "@:+!")
+	} else if len(pre) > 0 {
+		warn("ExecStartPre not converted, write a pre_script running: %s", strings.Join(pre, "; "))
+	}
+	service.ReloadCmd = strings.TrimLeft(unit.get("Service", "ExecReload"), "-@:+!")
+
+	switch restart := unit.get("Service", "Restart"); restart {
+	case "", "no":
+	case "always":
+		service.Restart = RestartAlways
+	case "on-failure", "on-abnormal", "on-abort", "on-watchdog":
+		service.Restart = RestartOnFailure
+	default:
+		warn("Restart=%s is not supported", restart)
+	}
+	if value := unit.get("Service", "RestartSec"); value != "" {
+		if service.RestartBackoff, err = systemdSeconds(value); err != nil {
+			warn("invalid RestartSec: %v", err)
+		}
+	}
+	if value := unit.get("Service", "TimeoutStopSec"); value != "" {
+		if service.StopTimeout, err = systemdSeconds(value); err != nil {
+			warn("invalid TimeoutStopSec: %v", err)
+		}
+	}
+	if value := unit.get("Service", "Nice"); value != "" {
+		if service.Nice, err = strconv.Atoi(value); err != nil {
+			warn("invalid Nice: %v", err)
+		}
+	}
+
+	switch kind := unit.get("Service", "Type"); kind {
+	case "", "simple", "exec", "oneshot":
+	case "notify":
+		service.Readiness = &ReadinessProbe{Notify: true}
+	default:
+		warn("Type=%s is not supported, the service must not fork", kind)
+	}
+
+	for _, line := range unit["Service"]["Environment"] {
+		assignments, err := splitCommandLine(line)
+		if err != nil {
+			warn("invalid Environment: %v", err)
+			continue
+		}
+		for _, assignment := range assignments {
+			if key, value, ok := strings.Cut(assignment, "="); ok {
+				if service.Env == nil {
+					service.Env = make(map[string]string)
+				}
+				service.Env[key] = value
+			}
+		}
+	}
+	if files := unit["Service"]["EnvironmentFile"]; len(files) > 0 {
+		service.EnvFile = strings.TrimPrefix(files[0], "-")
+		if len(files) > 1 {
+			warn("only the first EnvironmentFile is kept")
+		}
+	}
+
+	service.DependsOn = systemdDependencies(unit, units)
+
+	var ignored []string
+	for key := range unit["Service"] {
+		if !systemdConvertedKeys[key] {
+			ignored = append(ignored, key)
+		}
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		warn("not converted: %s", strings.Join(ignored, ", "))
+	}
+
+	return service, warnings, true
+}
+
+// systemdDependencies returns the imported units a unit is ordered after or
+// requires. Targets and units that weren't imported are left out.
+func systemdDependencies(unit systemdUnit, units map[string]systemdUnit) []string {
+	seen := make(map[string]bool)
+	var deps []string
+	for _, key := range []string{"Requires", "BindsTo", "Wants", "After"} {
+		for _, line := range unit["Unit"][key] {
+			for _, dep := range strings.Fields(line) {
+				dep = strings.TrimSuffix(dep, ".service")
+				if _, ok := units[dep]; ok && !seen[dep] {
+					seen[dep] = true
+					deps = append(deps, dep)
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// systemdSeconds converts a systemd time span (90, 5s, 1min 30s) into whole
+// seconds, rounded up
+func systemdSeconds(value string) (int, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n, nil
+	}
+	span := strings.ReplaceAll(value, " ", "")
+	for _, unit := range []struct{ from, to string }{{"min", "m"}, {"sec", "s"}} {
+		span = strings.ReplaceAll(span, unit.from, unit.to)
+	}
+	d, err := time.ParseDuration(span)
+	if err != nil {
+		return 0, err
+	}
+	return int((d + time.Second - 1) / time.Second), nil
+}
+
+// importSystemd converts the unit files at paths and writes them to output
+func importSystemd(paths []string, output string) error {
+	config, warnings, err := importSystemdUnits(paths)
+	if err != nil {
+		return err
+	}
+	return writeImportedConfig(&config, warnings, strings.Join(paths, ", "), output)
+}
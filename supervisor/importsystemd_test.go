@@ -0,0 +1,92 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Test systemd units convert into equivalent services
+func TestImportSystemdUnits(t *testing.T) {
+	dir := t.TempDir()
+	units := map[string]string{
+		"api.service": `[Unit]
+Description=API
+After=network.target db.service cache.service
+
+[Service]
+Type=notify
+User=app
+ExecStartPre=/usr/bin/api-setup
+ExecStart=/usr/bin/api serve \
+    --name "my api"
+Environment="A=1 2" B=2
+Environment=C=3
+EnvironmentFile=-/etc/default/api
+Restart=on-failure
+RestartSec=5
+TimeoutStopSec=1min 30s
+# Not converted
+WorkingDirectory=/srv
+`,
+		"db.service":  "[Service]\nExecStart=/usr/bin/postgres\nKillSignal=SIGINT\n",
+		"tmp.service": "[Service]\nExecStartPre=/bin/true\n",
+	}
+	var paths []string
+	for name, content := range units {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	config, warnings, err := importSystemdUnits(paths)
+	if err != nil {
+		t.Fatalf("importSystemdUnits() error = %v", err)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("got warnings %v, want WorkingDirectory not converted and tmp skipped", warnings)
+	}
+	if len(config.Services) != 2 {
+		t.Fatalf("got %d services, want api and db", len(config.Services))
+	}
+
+	api, db := config.Services[0], config.Services[1]
+	if api.Command != "/usr/bin/api" || !slices.Equal(api.Args, []string{"serve", "--name", "my api"}) {
+		t.Errorf("api command = %q %q", api.Command, api.Args)
+	}
+	if api.PreScript != "/usr/bin/api-setup" || api.User != "app" || api.EnvFile != "/etc/default/api" {
+		t.Errorf("api pre_script = %q, user = %q, env_file = %q", api.PreScript, api.User, api.EnvFile)
+	}
+	if api.Env["A"] != "1 2" || api.Env["B"] != "2" || api.Env["C"] != "3" {
+		t.Errorf("api env = %v", api.Env)
+	}
+	if api.Restart != RestartOnFailure || api.RestartBackoff != 5 || api.StopTimeout != 90 {
+		t.Errorf("api restart = %s, restart_backoff = %d, stop_timeout = %d", api.Restart, api.RestartBackoff, api.StopTimeout)
+	}
+	if api.Readiness == nil || !api.Readiness.Notify {
+		t.Errorf("api readiness = %v, want notify", api.Readiness)
+	}
+	if !slices.Equal(api.DependsOn, []string{"db"}) {
+		t.Errorf("api depends_on = %v, want [db]", api.DependsOn)
+	}
+	if db.StopSignal != "SIGINT" {
+		t.Errorf("db stop_signal = %q, want SIGINT", db.StopSignal)
+	}
+}
+
+// Test systemd time spans convert into seconds
+func TestSystemdSeconds(t *testing.T) {
+	tests := map[string]int{"90": 90, "5s": 5, "1min 30s": 90, "500ms": 1, "2min": 120}
+	for value, want := range tests {
+		if got, err := systemdSeconds(value); err != nil || got != want {
+			t.Errorf("systemdSeconds(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+	if _, err := systemdSeconds("forever"); err == nil {
+		t.Error("systemdSeconds(\"forever\") expected an error")
+	}
+}
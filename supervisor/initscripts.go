@@ -0,0 +1,111 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// s6InitDir is where s6-overlay images keep their init scripts, used as
+// init_dir with --s6-compat
+const s6InitDir = "/etc/cont-init.d"
+
+// listScripts returns the scripts of dir in lexical order, skipping
+// directories and hidden files
+func listScripts(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		scripts = append(scripts, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(scripts)
+	return scripts, nil
+}
+
+// initDir returns the init scripts directory of config: init_dir, or the one
+// of s6-overlay with --s6-compat when it exists
+func initDir(config *Config) string {
+	if config.InitDir != "" {
+		return config.InitDir
+	}
+	if s6Compat {
+		if info, err := os.Stat(s6InitDir); err == nil && info.IsDir() {
+			return s6InitDir
+		}
+	}
+	return ""
+}
+
+// runInitScripts runs the scripts of the init directory one after the other
+// before any service starts, like cont-init.d. The first failing script
+// aborts the boot.
+func runInitScripts(config *Config) error {
+	dir := initDir(config)
+	if dir == "" {
+		return nil
+	}
+
+	scripts, err := listScripts(dir)
+	if err != nil {
+		return fmt.Errorf("could not read init_dir: %w", err)
+	}
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	logger.Info(fmt.Sprintf("Running %d init scripts from %s", len(scripts), colorize(ColorCyan, dir)))
+	env := mergeEnv(baseEnvironment(), map[string]string{
+		EnvSocket:  controlAddress,
+		EnvVersion: version,
+	})
+	for _, script := range scripts {
+		logger.Info(fmt.Sprintf("Running init script %s", colorize(ColorCyan, filepath.Base(script))))
+		if err := runScriptContext(context.Background(), script, env); err != nil {
+			return fmt.Errorf("init script %s failed: %w", script, err)
+		}
+	}
+	logger.Success("Init scripts completed")
+
+	// Like cont-init.d, scripts may export variables for the services
+	if s6Compat {
+		importS6Environment(s6ContainerEnvDir)
+	}
+	return nil
+}
+
+func validateInitDir(config *Config) ValidationErrors {
+	var errors ValidationErrors
+
+	if config.InitDir == "" || (config.ValidateCommands != nil && !*config.ValidateCommands) {
+		return errors
+	}
+
+	scripts, err := listScripts(rootPath(config.InitDir))
+	if err != nil {
+		errors = append(errors, ValidationError{
+			Field:   "init_dir",
+			Message: fmt.Sprintf("init_dir '%s' can't be read: %v", config.InitDir, err),
+		})
+		return errors
+	}
+	for _, script := range scripts {
+		if !isExecutableFile(script) {
+			errors = append(errors, ValidationError{
+				Field:   "init_dir",
+				Message: fmt.Sprintf("init script '%s' is not executable", filepath.Join(config.InitDir, filepath.Base(script))),
+			})
+		}
+	}
+
+	return errors
+}
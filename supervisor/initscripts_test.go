@@ -0,0 +1,75 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeScripts writes executable scripts named after the keys of scripts into
+// a new directory
+func writeScripts(t *testing.T, scripts map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// Test init scripts run in lexical order and the first failure aborts the boot
+func TestRunInitScripts(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+
+	tests := []struct {
+		name    string
+		scripts map[string]string
+		wantErr bool
+		want    string
+	}{
+		{"In order", map[string]string{
+			"20-second": "echo second >> " + log,
+			"10-first":  "echo first >> " + log,
+			".hidden":   "echo hidden >> " + log,
+		}, false, "first\nsecond\n"},
+		{"Failure aborts", map[string]string{
+			"10-fails": "echo fails >> " + log + "; exit 2",
+			"20-never": "echo never >> " + log,
+		}, true, "fails\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(log)
+			config := &Config{InitDir: writeScripts(t, tt.scripts)}
+
+			err := runInitScripts(config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runInitScripts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			data, _ := os.ReadFile(log)
+			if string(data) != tt.want {
+				t.Errorf("scripts wrote %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+// Test init_dir validation
+func TestValidateInitDir(t *testing.T) {
+	dir := writeScripts(t, map[string]string{"10-setup": "true"})
+	if err := os.WriteFile(filepath.Join(dir, "20-not-executable"), []byte("true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := validateInitDir(&Config{InitDir: dir})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "20-not-executable") {
+		t.Errorf("validateInitDir() = %v, want the non-executable script", errs)
+	}
+	if errs := validateInitDir(&Config{InitDir: filepath.Join(dir, "missing")}); len(errs) != 1 {
+		t.Errorf("validateInitDir() = %v, want a missing directory error", errs)
+	}
+}
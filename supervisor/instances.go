@@ -0,0 +1,260 @@
+package supervisor
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// instanceSeparator separates the name of a service template from the
+// identifier of an instance, as in worker@1
+const instanceSeparator = "@"
+
+// instanceName returns the name of an instance of a service template
+func instanceName(template string, id int) string {
+	return template + instanceSeparator + strconv.Itoa(id)
+}
+
+// expandInstances returns the instances worker@1 to worker@<instances> of a
+// service template, like systemd template units. In their settings, %i and
+// {{.Instance}} are replaced by the identifier of the instance (%% is a
+// literal %).
+func expandInstances(template *Service) []Service {
+	instances := make([]Service, 0, template.Instances)
+	for id := 1; id <= template.Instances; id++ {
+		instances = append(instances, instantiate(template, id))
+	}
+	return instances
+}
+
+// instantiate copies a service template into its instance id. Settings that
+// take a placeholder are copied; the others are shared read-only.
+func instantiate(template *Service, id int) Service {
+	instance := *template
+	instance.Name = instanceName(template.Name, id)
+	instance.Instances = 0
+	instance.InstanceOf = template.Name
+	instance.Instance = strconv.Itoa(id)
+
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%i", instance.Instance,
+		"{{.Instance}}", instance.Instance,
+		"{{ .Instance }}", instance.Instance,
+	)
+	replace := replacer.Replace
+
+	instance.Command = replace(instance.Command)
+	instance.Args = slices.Clone(instance.Args)
+	for i := range instance.Args {
+		instance.Args[i] = replace(instance.Args[i])
+	}
+	instance.LogFile = replace(instance.LogFile)
+	instance.PreScript = replace(instance.PreScript)
+	instance.PosScript = replace(instance.PosScript)
+	instance.PreStop = replace(instance.PreStop)
+	instance.OnExit = replace(instance.OnExit)
+	instance.EnvFile = replace(instance.EnvFile)
+	instance.Env = replaceValues(instance.Env, replace)
+	instance.Labels = replaceValues(instance.Labels, replace)
+	instance.DependsOn = slices.Clone(instance.DependsOn)
+	if wa := instance.WaitAfter; wa != nil {
+		instance.WaitAfter = &WaitAfterField{Global: wa.Global, PerDep: maps.Clone(wa.PerDep), IsPerDep: wa.IsPerDep}
+	}
+
+	if output := instance.LogOutput; output != nil {
+		instance.LogOutput = &LogOutput{}
+		*instance.LogOutput = *output
+		instance.LogOutput.Path = replace(output.Path)
+	}
+	if output := instance.StderrLogOutput; output != nil {
+		instance.StderrLogOutput = &LogOutput{}
+		*instance.StderrLogOutput = *output
+		instance.StderrLogOutput.Path = replace(output.Path)
+	}
+	if publish := instance.Publish; publish != nil {
+		instance.Publish = &PublishField{}
+		*instance.Publish = *publish
+		instance.Publish.Host = replace(publish.Host)
+		instance.Publish.Socket = replace(publish.Socket)
+	}
+	if check := instance.HealthCheck; check != nil {
+		instance.HealthCheck = &HealthCheck{}
+		*instance.HealthCheck = *check
+		instance.HealthCheck.Exec = replace(check.Exec)
+		instance.HealthCheck.TCP = replace(check.TCP)
+		instance.HealthCheck.HTTP = replace(check.HTTP)
+	}
+	if probe := instance.Readiness; probe != nil {
+		instance.Readiness = &ReadinessProbe{}
+		*instance.Readiness = *probe
+		instance.Readiness.TCP = replace(probe.TCP)
+		instance.Readiness.File = replace(probe.File)
+	}
+	if watchdog := instance.Watchdog; watchdog != nil {
+		instance.Watchdog = &Watchdog{}
+		*instance.Watchdog = *watchdog
+		instance.Watchdog.File = replace(watchdog.File)
+		instance.Watchdog.Exec = replace(watchdog.Exec)
+	}
+
+	instance.Templates = slices.Clone(instance.Templates)
+	for i := range instance.Templates {
+		instance.Templates[i].Source = replace(instance.Templates[i].Source)
+		instance.Templates[i].Target = replace(instance.Templates[i].Target)
+	}
+	instance.Secrets = slices.Clone(instance.Secrets)
+	for i := range instance.Secrets {
+		instance.Secrets[i].Source = replace(instance.Secrets[i].Source)
+		instance.Secrets[i].Target = replace(instance.Secrets[i].Target)
+	}
+
+	return instance
+}
+
+// replaceValues returns a copy of values with replace applied to every value
+func replaceValues(values map[string]string, replace func(string) string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	out := make(map[string]string, len(values))
+	for key, value := range values {
+		out[key] = replace(value)
+	}
+	return out
+}
+
+// templateInstances returns the names of the instances of a service template
+// in services
+func templateInstances(services []Service, template string) []string {
+	var names []string
+	for i := range services {
+		if services[i].InstanceOf == template {
+			names = append(names, services[i].Name)
+		}
+	}
+	return names
+}
+
+// resolveInstanceDependencies replaces the name of a service template in
+// depends_on and wait_after by the names of its instances, so depending on a
+// template waits for all of them
+func resolveInstanceDependencies(config *Config) {
+	if len(config.ServiceTemplates) == 0 {
+		return
+	}
+	instances := make(map[string][]string, len(config.ServiceTemplates))
+	for _, template := range config.ServiceTemplates {
+		instances[template.Name] = templateInstances(config.Services, template.Name)
+	}
+
+	for i := range config.Services {
+		service := &config.Services[i]
+		var deps DependsOnField
+		for _, dep := range service.DependsOn {
+			if names, ok := instances[dep]; ok {
+				deps = append(deps, names...)
+			} else {
+				deps = append(deps, dep)
+			}
+		}
+		service.DependsOn = deps
+
+		if wa := service.WaitAfter; wa != nil && wa.IsPerDep {
+			for dep, wait := range wa.PerDep {
+				names, ok := instances[dep]
+				if !ok {
+					continue
+				}
+				delete(wa.PerDep, dep)
+				for _, name := range names {
+					wa.PerDep[name] = wait
+				}
+			}
+		}
+	}
+}
+
+// foldInstanceDependencies is the reverse of resolveInstanceDependencies for
+// exports: a dependency on every instance of a template is written as a
+// dependency on the template
+func foldInstanceDependencies(deps []string, config *Config) []string {
+	folded := slices.Clone(deps)
+	for _, template := range config.ServiceTemplates {
+		names := templateInstances(config.Services, template.Name)
+		if len(names) == 0 || !containsAll(folded, names) {
+			continue
+		}
+		at := slices.Index(folded, names[0])
+		folded = slices.DeleteFunc(folded, func(dep string) bool { return slices.Contains(names, dep) })
+		folded = slices.Insert(folded, min(at, len(folded)), template.Name)
+	}
+	return folded
+}
+
+// foldInstanceWaits is foldInstanceDependencies for wait_after: the same
+// wait for every instance of a template is written as a wait for the template
+func foldInstanceWaits(waits map[string]int, config *Config) map[string]int {
+	folded := maps.Clone(waits)
+	for _, template := range config.ServiceTemplates {
+		names := templateInstances(config.Services, template.Name)
+		if len(names) == 0 {
+			continue
+		}
+		wait, ok := folded[names[0]]
+		for _, name := range names[1:] {
+			if other, set := folded[name]; !set || other != wait {
+				ok = false
+			}
+		}
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			delete(folded, name)
+		}
+		folded[template.Name] = wait
+	}
+	return folded
+}
+
+func containsAll(items, wanted []string) bool {
+	for _, item := range wanted {
+		if !slices.Contains(items, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// findServiceTemplate returns the service template named name
+func findServiceTemplate(config *Config, name string) (*Service, bool) {
+	for i := range config.ServiceTemplates {
+		if config.ServiceTemplates[i].Name == name {
+			return &config.ServiceTemplates[i], true
+		}
+	}
+	return nil, false
+}
+
+// validateServiceTemplates checks that the name of a service template is not
+// also the name of a service, which would make depending on it ambiguous
+func validateServiceTemplates(config *Config) ValidationErrors {
+	var errors ValidationErrors
+
+	for _, template := range config.ServiceTemplates {
+		for i := range config.Services {
+			if config.Services[i].Name == template.Name {
+				errors = append(errors, ValidationError{
+					Field:   "name",
+					Service: template.Name,
+					Message: "a service template and a service have the same name",
+				})
+				break
+			}
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,191 @@
+package supervisor
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Test a service with instances runs as one service per instance, with the
+// placeholders of its settings replaced
+func TestExpandInstances(t *testing.T) {
+	config := mustParseConfig(t, `
+validate_commands = false
+
+[[services]]
+name = "worker"
+command = "/usr/bin/worker"
+args = ["--id", "%i", "--queue={{.Instance}}", "--rate=50%%"]
+instances = 3
+env = { WORKER_ID = "{{ .Instance }}" }
+log_output = { path = "/var/log/worker-%i.log" }
+readiness = { tcp = "127.0.0.1:900%i" }
+`)
+
+	var names []string
+	for _, service := range config.Services {
+		names = append(names, service.Name)
+	}
+	if want := []string{"worker@1", "worker@2", "worker@3"}; !slices.Equal(names, want) {
+		t.Fatalf("services = %v, want %v", names, want)
+	}
+
+	second := config.Services[1]
+	if want := []string{"--id", "2", "--queue=2", "--rate=50%"}; !slices.Equal(second.Args, want) {
+		t.Errorf("args = %q, want %q", second.Args, want)
+	}
+	if second.Env["WORKER_ID"] != "2" {
+		t.Errorf("env WORKER_ID = %q, want 2", second.Env["WORKER_ID"])
+	}
+	if second.LogOutput.Path != "/var/log/worker-2.log" || second.Readiness.TCP != "127.0.0.1:9002" {
+		t.Errorf("log_output = %q, readiness = %q", second.LogOutput.Path, second.Readiness.TCP)
+	}
+	if second.InstanceOf != "worker" || serviceInstance(&second) != "2" {
+		t.Errorf("instance = %q of %q, want 2 of worker", serviceInstance(&second), second.InstanceOf)
+	}
+	if config.Services[0].Args[1] != "1" || config.Services[0].LogOutput.Path != "/var/log/worker-1.log" {
+		t.Error("instances share their settings")
+	}
+
+	if len(config.ServiceTemplates) != 1 || config.ServiceTemplates[0].Args[1] != "%i" {
+		t.Errorf("templates = %+v, want worker kept as is", config.ServiceTemplates)
+	}
+	if err := validateConfig(config); err != nil {
+		t.Errorf("validateConfig() error = %v", err)
+	}
+}
+
+// Test depending on a template waits for all of its instances
+func TestInstanceDependencies(t *testing.T) {
+	config := mustParseConfig(t, `
+validate_commands = false
+
+[[services]]
+name = "worker"
+command = "/usr/bin/worker"
+instances = 2
+
+[[services]]
+name = "db"
+command = "/usr/bin/db"
+
+[[services]]
+name = "api"
+command = "/usr/bin/api"
+depends_on = ["db", "worker"]
+wait_after = { worker = 3 }
+`)
+
+	api := config.Services[3]
+	if want := []string{"db", "worker@1", "worker@2"}; !slices.Equal(api.DependsOn, want) {
+		t.Errorf("depends_on = %v, want %v", api.DependsOn, want)
+	}
+	if want := map[string]int{"worker@1": 3, "worker@2": 3}; !reflect.DeepEqual(api.WaitAfter.PerDep, want) {
+		t.Errorf("wait_after = %v, want %v", api.WaitAfter.PerDep, want)
+	}
+	if err := validateConfig(config); err != nil {
+		t.Errorf("validateConfig() error = %v", err)
+	}
+}
+
+// Test exports write service templates instead of their instances
+func TestInstancesRoundTrip(t *testing.T) {
+	original := mustParseConfig(t, `
+validate_commands = false
+
+[[services]]
+name = "worker"
+command = "/usr/bin/worker"
+args = ["%i"]
+instances = 2
+
+[[services]]
+name = "idle"
+command = "/usr/bin/worker"
+instances = 0
+
+[[services]]
+name = "api"
+command = "/usr/bin/api"
+depends_on = "worker"
+wait_after = { worker = 3 }
+`)
+
+	data, err := marshalConfig(original)
+	if err != nil {
+		t.Fatalf("marshalConfig() error = %v", err)
+	}
+	for _, want := range []string{"name = 'worker'", "instances = 2", "instances = 0", "'%i'", "depends_on = 'worker'"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("export misses %s:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "worker@") {
+		t.Errorf("export contains instances:\n%s", data)
+	}
+
+	exported, err := parseConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("parseConfig(exported) error = %v\n%s", err, data)
+	}
+	if changes := diffConfigs(original, &exported); len(changes) != 0 {
+		t.Errorf("round trip changed services: %+v\n%s", changes, data)
+	}
+	if len(exported.ServiceTemplates) != 2 {
+		t.Errorf("templates = %d, want 2", len(exported.ServiceTemplates))
+	}
+}
+
+// Test instance and template validation
+func TestValidateInstances(t *testing.T) {
+	if _, err := parseConfig(strings.NewReader(`
+[[services]]
+name = "worker"
+command = "/usr/bin/worker"
+instances = -1
+`)); err == nil || !strings.Contains(err.Error(), "instances must not be negative") {
+		t.Errorf("parseConfig() error = %v, want negative instances", err)
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{
+			name:   "instances",
+			config: "[[services]]\nname = \"worker\"\ncommand = \"/bin/true\"\ninstances = 2\n",
+		},
+		{
+			name:    "template named like an instance",
+			config:  "[[services]]\nname = \"worker@1\"\ncommand = \"/bin/true\"\ninstances = 2\n",
+			wantErr: true,
+		},
+		{
+			name:    "service named like an instance",
+			config:  "[[services]]\nname = \"worker@1\"\ncommand = \"/bin/true\"\n",
+			wantErr: true,
+		},
+		{
+			name: "template named like a service",
+			config: "[[services]]\nname = \"worker\"\ncommand = \"/bin/true\"\ninstances = 2\n" +
+				"[[services]]\nname = \"worker\"\ncommand = \"/bin/true\"\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseConfig(strings.NewReader(tt.config))
+			if err != nil {
+				t.Fatalf("parseConfig() error = %v", err)
+			}
+			err = validateConfig(&config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
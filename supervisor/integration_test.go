@@ -2,10 +2,9 @@
 //go:build integration
 // +build integration
 
-package main
+package supervisor
 
 import (
-	"context"
 	"os"
 	"path/filepath"
 	"sync"
@@ -250,8 +249,8 @@ func TestIntegrationServiceLifecycle(t *testing.T) {
 	}
 
 	// Initialize shutdown context for the test
-	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
-	defer shutdownCancel()
+	resetShutdown()
+	defer cancelShutdown()
 
 	// Create a simple service that exits quickly
 	service := Service{
@@ -306,7 +305,7 @@ func TestIntegrationPreScript(t *testing.T) {
 	}
 
 	// Execute the script
-	err = runScript(scriptPath)
+	err = runScript(scriptPath, os.Environ())
 	if err != nil {
 		t.Errorf("runScript() failed: %v", err)
 	}
@@ -359,8 +358,8 @@ func TestIntegrationTimeouts(t *testing.T) {
 // Integration test for wait_after with dependencies
 func TestIntegrationWaitAfterDependencies(t *testing.T) {
 	// Initialize shutdown context
-	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
-	defer shutdownCancel()
+	resetShutdown()
+	defer cancelShutdown()
 
 	startedServices := make(map[string]bool)
 	var mu sync.Mutex
@@ -373,7 +372,7 @@ func TestIntegrationWaitAfterDependencies(t *testing.T) {
 	// Test waiting for dependency with wait_after
 	done := make(chan bool)
 	go func() {
-		result := waitForDependency("dep-service", 1, &mu, startedServices, 10)
+		result := waitForDependency("dep-service", 1, &mu, startedServices, 10, DependsOnStarted)
 		done <- result
 	}()
 
@@ -0,0 +1,217 @@
+package supervisor
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// ControlConfig restricts the commands of the control socket that change or
+// reveal services ([control] in services.toml) to some users and groups.
+// Without allow_uids and allow_gids, everyone who can open the socket may
+// run every command.
+type ControlConfig struct {
+	Listen    string `toml:"listen,omitempty"`     // Socket path, @abstract name or tcp://127.0.0.1:port
+	AllowUIDs []int  `toml:"allow_uids,omitempty"` // Users allowed besides root and the supervisor's user
+	AllowGIDs []int  `toml:"allow_gids,omitempty"` // Groups allowed, primary or supplementary
+}
+
+func (c ControlConfig) restricted() bool {
+	return len(c.AllowUIDs) > 0 || len(c.AllowGIDs) > 0
+}
+
+// controlSocketMode lets every user connect to a restricted socket, for the
+// read-only commands
+const controlSocketMode = 0o666
+
+// readOnlyCommands may be run by everyone. Commands returning the
+// environment or the configuration reveal secrets, so they are restricted.
+// notify-ready is sent from inside services, which may run as any user: it is
+// also accepted from the service it names (see servicePeer).
+var readOnlyCommands = map[CommandType]bool{
+	CmdHello:        true,
+	CmdListServices: true,
+	CmdGetStatus:    true,
+	CmdOperation:    true,
+	CmdServiceStats: true,
+	CmdHistory:      true,
+	CmdServiceLogs:  true,
+	CmdSubscribe:    true,
+}
+
+// peerCredentials holds the identity of the process at the other end of the
+// control socket
+type peerCredentials struct {
+	PID    int
+	UID    int
+	GID    int
+	Groups []int // Supplementary groups, when /proc tells them
+}
+
+// socketPeerCredentials reads the credentials of the client of a Unix socket
+// with SO_PEERCRED, as they were when it connected
+func socketPeerCredentials(conn net.Conn) (*peerCredentials, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("not a Unix socket")
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var ucred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	if credErr != nil {
+		return nil, credErr
+	}
+
+	peer := &peerCredentials{PID: int(ucred.Pid), UID: int(ucred.Uid), GID: int(ucred.Gid)}
+	peer.Groups, _ = processGroups(peer.PID)
+	return peer, nil
+}
+
+// processGroups returns the supplementary groups of a process
+func processGroups(pid int) ([]int, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "Groups:")
+		if !found {
+			continue
+		}
+		var groups []int
+		for _, field := range strings.Fields(value) {
+			if gid, err := strconv.Atoi(field); err == nil {
+				groups = append(groups, gid)
+			}
+		}
+		return groups, nil
+	}
+	return nil, scanner.Err()
+}
+
+// authorizeIPCCommand returns an error when peer may not run cmd
+func authorizeIPCCommand(cfg ControlConfig, peer *peerCredentials, cmd IPCCommand) error {
+	if !cfg.restricted() || readOnlyCommands[cmd.Type] {
+		return nil
+	}
+	if peer == nil {
+		return withCode(ErrCodePermissionDenied, fmt.Errorf("permission denied: could not identify the client"))
+	}
+	if peer.UID == 0 || peer.UID == os.Getuid() || slices.Contains(cfg.AllowUIDs, peer.UID) {
+		return nil
+	}
+	if slices.Contains(cfg.AllowGIDs, peer.GID) {
+		return nil
+	}
+	for _, gid := range peer.Groups {
+		if slices.Contains(cfg.AllowGIDs, gid) {
+			return nil
+		}
+	}
+	if cmd.Type == CmdNotifyReady && servicePeer(peer, cmd.ServiceName) {
+		return nil
+	}
+	return withCode(ErrCodePermissionDenied, fmt.Errorf("permission denied: uid %d may not run %s", peer.UID, cmd.Type))
+}
+
+// servicePeer reports whether peer belongs to a running service: it is one
+// of the processes of the service, or runs as the user of the service
+func servicePeer(peer *peerCredentials, name string) bool {
+	serviceProc, running := getActiveService(name)
+	if !running {
+		return false
+	}
+	if cred, _, err := serviceCredential(&serviceProc.Config); err == nil && cred != nil && int(cred.Uid) == peer.UID {
+		return true
+	}
+	return descendsFrom(peer.PID, serviceProc.GetPID())
+}
+
+// descendsFrom reports whether pid is ancestor or one of its descendants
+func descendsFrom(pid, ancestor int) bool {
+	if ancestor <= 0 {
+		return false
+	}
+	for pid > 1 {
+		if pid == ancestor {
+			return true
+		}
+		stat, err := readProcStat(pid)
+		if err != nil {
+			return false
+		}
+		pid = stat.ppid
+	}
+	return false
+}
+
+// currentControlConfig returns the access rules of the control socket
+func currentControlConfig() ControlConfig {
+	if config := currentConfig(); config != nil {
+		return config.Control
+	}
+	return ControlConfig{}
+}
+
+// openControlSocket lets every user connect to a restricted control socket
+// file; the commands they may run are checked per connection. Abstract and
+// TCP sockets have no permissions.
+func openControlSocket(address string, cfg ControlConfig) {
+	if !cfg.restricted() || noIPC || !isSocketFile(address) {
+		return
+	}
+	if err := os.Chmod(address, controlSocketMode); err != nil {
+		logger.Warn(fmt.Sprintf("Could not open the control socket to every user: %v", err))
+	}
+}
+
+func validateControl(cfg *ControlConfig) ValidationErrors {
+	var errors ValidationErrors
+
+	if cfg.Listen != "" {
+		if _, _, err := client.SplitAddress(cfg.Listen); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "control.listen",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	for _, uid := range cfg.AllowUIDs {
+		if uid < 0 {
+			errors = append(errors, ValidationError{
+				Field:   "control.allow_uids",
+				Message: fmt.Sprintf("invalid uid %d", uid),
+			})
+		}
+	}
+	for _, gid := range cfg.AllowGIDs {
+		if gid < 0 {
+			errors = append(errors, ValidationError{
+				Field:   "control.allow_gids",
+				Message: fmt.Sprintf("invalid gid %d", gid),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,121 @@
+package supervisor
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Test control commands are limited to the allowed users and groups, and
+// read-only commands are open to everyone
+func TestAuthorizeIPCCommand(t *testing.T) {
+	restricted := ControlConfig{AllowUIDs: []int{1000}, AllowGIDs: []int{50}}
+	stranger := &peerCredentials{UID: 4242, GID: 4242, Groups: []int{4242}}
+
+	tests := []struct {
+		name    string
+		cfg     ControlConfig
+		peer    *peerCredentials
+		cmd     CommandType
+		wantErr bool
+	}{
+		{"No restrictions", ControlConfig{}, stranger, CmdRestartService, false},
+		{"Read-only command", restricted, stranger, CmdListServices, false},
+		{"Logs", restricted, stranger, CmdServiceLogs, false},
+		{"Restart by a stranger", restricted, stranger, CmdRestartService, true},
+		{"Config reveals secrets", restricted, stranger, CmdGetConfig, true},
+		{"Exec", restricted, stranger, CmdServiceExec, true},
+		{"Root", restricted, &peerCredentials{UID: 0}, CmdStopServices, false},
+		{"Supervisor user", restricted, &peerCredentials{UID: os.Getuid()}, CmdStopServices, false},
+		{"Allowed uid", restricted, &peerCredentials{UID: 1000, GID: 1000}, CmdSignalService, false},
+		{"Allowed primary group", restricted, &peerCredentials{UID: 4242, GID: 50}, CmdApply, false},
+		{"Allowed supplementary group", restricted, &peerCredentials{UID: 4242, GID: 4242, Groups: []int{10, 50}}, CmdScale, false},
+		{"Unknown client", restricted, nil, CmdRestartService, true},
+		{"Notify-ready of a service not running", restricted, stranger, CmdNotifyReady, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authorizeIPCCommand(tt.cfg, tt.peer, IPCCommand{Type: tt.cmd})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("authorizeIPCCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && errorCode(err) != ErrCodePermissionDenied {
+				t.Errorf("authorizeIPCCommand() code = %q, want %s", errorCode(err), ErrCodePermissionDenied)
+			}
+		})
+	}
+}
+
+// Test notify-ready is accepted from the processes of the service it names
+// only, unless the peer may run every command
+func TestAuthorizeNotifyReady(t *testing.T) {
+	restricted := ControlConfig{AllowUIDs: []int{1000}}
+	servicesMutex.Lock()
+	activeServices["notify-auth"] = &ServiceProcess{
+		Name:    "notify-auth",
+		Process: &exec.Cmd{Process: &os.Process{Pid: os.Getppid()}},
+	}
+	servicesMutex.Unlock()
+	t.Cleanup(func() {
+		servicesMutex.Lock()
+		delete(activeServices, "notify-auth")
+		servicesMutex.Unlock()
+	})
+
+	// The test process descends from the one of the service
+	child := &peerCredentials{PID: os.Getpid(), UID: 4242, GID: 4242}
+	if err := authorizeIPCCommand(restricted, child, IPCCommand{Type: CmdNotifyReady, ServiceName: "notify-auth"}); err != nil {
+		t.Errorf("notify-ready from a process of the service: %v", err)
+	}
+	if err := authorizeIPCCommand(restricted, child, IPCCommand{Type: CmdRestartService, ServiceName: "notify-auth"}); err == nil {
+		t.Error("a process of a service may restart it")
+	}
+
+	stranger := &peerCredentials{PID: 1, UID: 4242, GID: 4242}
+	if err := authorizeIPCCommand(restricted, stranger, IPCCommand{Type: CmdNotifyReady, ServiceName: "notify-auth"}); err == nil {
+		t.Error("notify-ready accepted from a process outside the service")
+	}
+}
+
+// Test the credentials of a socket client are read from the kernel
+func TestSocketPeerCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	peer, err := socketPeerCredentials(conn)
+	if err != nil {
+		t.Fatalf("socketPeerCredentials() error = %v", err)
+	}
+	if peer.PID != os.Getpid() || peer.UID != os.Getuid() || peer.GID != os.Getgid() {
+		t.Errorf("peer = %+v, want pid %d, uid %d, gid %d", peer, os.Getpid(), os.Getuid(), os.Getgid())
+	}
+	groups, _ := os.Getgroups()
+	if len(peer.Groups) != len(groups) {
+		t.Errorf("groups = %v, want %v", peer.Groups, groups)
+	}
+
+	server, other := net.Pipe()
+	defer server.Close()
+	defer other.Close()
+	if _, err := socketPeerCredentials(server); err == nil {
+		t.Error("socketPeerCredentials() should fail on a pipe")
+	}
+}
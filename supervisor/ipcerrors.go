@@ -0,0 +1,137 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// ErrorCode tells clients why a command failed, so they can branch on it
+// instead of parsing the message. Failures without a more precise cause have
+// no code.
+type ErrorCode = client.ErrorCode
+
+const (
+	ErrCodeServiceNotFound   = client.ErrCodeServiceNotFound
+	ErrCodeAlreadyRunning    = client.ErrCodeAlreadyRunning
+	ErrCodeTimeout           = client.ErrCodeTimeout
+	ErrCodePermissionDenied  = client.ErrCodePermissionDenied
+	ErrCodeNotRunning        = client.ErrCodeNotRunning
+	ErrCodeOperationNotFound = client.ErrCodeOperationNotFound
+)
+
+// errorExitCodes are the exit codes of the CLI for each error code; other
+// failures exit 1
+var errorExitCodes = map[ErrorCode]int{
+	ErrCodeServiceNotFound:   3,
+	ErrCodeAlreadyRunning:    4,
+	ErrCodeTimeout:           5,
+	ErrCodePermissionDenied:  6,
+	ErrCodeNotRunning:        7,
+	ErrCodeOperationNotFound: 8,
+}
+
+// errorHTTPStatus are the HTTP API statuses for each error code; other
+// failures answer 409
+var errorHTTPStatus = map[ErrorCode]int{
+	ErrCodeServiceNotFound:   http.StatusNotFound,
+	ErrCodeAlreadyRunning:    http.StatusConflict,
+	ErrCodeTimeout:           http.StatusGatewayTimeout,
+	ErrCodePermissionDenied:  http.StatusForbidden,
+	ErrCodeNotRunning:        http.StatusConflict,
+	ErrCodeOperationNotFound: http.StatusNotFound,
+}
+
+// codedError is an error of the daemon carrying the code answered to clients
+type codedError struct {
+	code ErrorCode
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// withCode attaches an error code to err
+func withCode(code ErrorCode, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// errServiceNotFound is the error of a command naming an unknown service
+func errServiceNotFound(name string) error {
+	return withCode(ErrCodeServiceNotFound, fmt.Errorf("service '%s' not found", name))
+}
+
+// errServiceNotRunning is the error of a command needing a service that is
+// configured but not running
+func errServiceNotRunning(name string) error {
+	return withCode(ErrCodeNotRunning, fmt.Errorf("service '%s' is not running", name))
+}
+
+// IPCError is a failure answered by the daemon, as the CLI returns it
+type IPCError = client.Error
+
+// responseError returns the failure of a response as an error keeping its code
+func responseError(response *IPCResponse) error {
+	return &IPCError{Code: response.Code, Message: response.Message}
+}
+
+// errorCode returns the code of err, empty when it has none. A control
+// socket the user may not open is a denied permission too.
+func errorCode(err error) ErrorCode {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	if code := client.Code(err); code != "" {
+		return code
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return ErrCodePermissionDenied
+	}
+	return ""
+}
+
+// errorResponse answers a failed command with the message and code of err
+func errorResponse(err error) IPCResponse {
+	return IPCResponse{
+		Success: false,
+		Message: err.Error(),
+		Code:    errorCode(err),
+	}
+}
+
+// notFoundResponse answers a command naming an unknown service
+func notFoundResponse(name string) IPCResponse {
+	return IPCResponse{
+		Success: false,
+		Message: fmt.Sprintf("Service '%s' not found", name),
+		Code:    ErrCodeServiceNotFound,
+	}
+}
+
+// cliExitCode returns the exit code of the CLI for a failed command
+func cliExitCode(err error) int {
+	if code, ok := errorExitCodes[errorCode(err)]; ok {
+		return code
+	}
+	return 1
+}
+
+// commonCode returns the code shared by every failed result, empty when they
+// failed for different reasons
+func commonCode(results []OperationResult) ErrorCode {
+	var code ErrorCode
+	for _, res := range results {
+		if res.Status != ResultFailed {
+			continue
+		}
+		if res.Code == "" || (code != "" && res.Code != code) {
+			return ""
+		}
+		code = res.Code
+	}
+	return code
+}
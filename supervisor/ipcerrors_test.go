@@ -0,0 +1,117 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"Coded", errServiceNotFound("web"), ErrCodeServiceNotFound},
+		{"Wrapped", fmt.Errorf("restart: %w", withCode(ErrCodeTimeout, errors.New("too slow"))), ErrCodeTimeout},
+		{"Answered by the daemon", responseError(&IPCResponse{Message: "running", Code: ErrCodeAlreadyRunning}), ErrCodeAlreadyRunning},
+		{"Socket not allowed", &os.PathError{Op: "dial", Path: socketPath, Err: os.ErrPermission}, ErrCodePermissionDenied},
+		{"Plain", errors.New("boom"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(tt.err); got != tt.want {
+				t.Errorf("errorCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLIExitCode(t *testing.T) {
+	if got := cliExitCode(errors.New("boom")); got != 1 {
+		t.Errorf("cliExitCode() of an error without code = %d, want 1", got)
+	}
+
+	seen := make(map[int]ErrorCode)
+	for code := range errorExitCodes {
+		exit := cliExitCode(responseError(&IPCResponse{Code: code}))
+		if exit <= 1 {
+			t.Errorf("%s exits %d, want a code of its own", code, exit)
+		}
+		if other, ok := seen[exit]; ok {
+			t.Errorf("%s and %s both exit %d", code, other, exit)
+		}
+		seen[exit] = code
+	}
+}
+
+// Test a bulk response carries the code its failures share
+func TestCommonCode(t *testing.T) {
+	notFound := OperationResult{Status: ResultFailed, Code: ErrCodeServiceNotFound}
+	timeout := OperationResult{Status: ResultFailed, Code: ErrCodeTimeout}
+	ok := OperationResult{Status: ResultOK}
+
+	tests := []struct {
+		name    string
+		results []OperationResult
+		want    ErrorCode
+	}{
+		{"No failure", []OperationResult{ok}, ""},
+		{"Same code", []OperationResult{timeout, ok, timeout}, ErrCodeTimeout},
+		{"Different codes", []OperationResult{timeout, notFound}, ""},
+		{"Failure without code", []OperationResult{timeout, {Status: ResultFailed}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commonCode(tt.results); got != tt.want {
+				t.Errorf("commonCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test handlers answer an unknown service with its code
+func TestUnknownServiceCode(t *testing.T) {
+	setConfig(&Config{Services: []Service{{Name: "code-web"}}})
+	defer setConfig(nil)
+
+	for _, cmd := range []IPCCommand{
+		{Type: CmdRestartService, ServiceName: "missing"},
+		{Type: CmdStopServices, ServiceName: "missing"},
+		{Type: CmdReloadService, ServiceName: "missing"},
+		{Type: CmdSignalService, ServiceName: "missing", Signal: "HUP"},
+		{Type: CmdHistory, ServiceName: "missing"},
+		{Type: CmdServiceEnv, ServiceName: "missing"},
+	} {
+		response := dispatchIPCCommand(cmd)
+		if response.Success || response.Code != ErrCodeServiceNotFound {
+			t.Errorf("%s = %+v, want %s", cmd.Type, response, ErrCodeServiceNotFound)
+		}
+	}
+}
+
+func TestWriteAPIResponseStatus(t *testing.T) {
+	tests := []struct {
+		response IPCResponse
+		want     int
+	}{
+		{IPCResponse{Success: true}, http.StatusOK},
+		{IPCResponse{Code: ErrCodeServiceNotFound}, http.StatusNotFound},
+		{IPCResponse{Code: ErrCodeTimeout}, http.StatusGatewayTimeout},
+		{IPCResponse{Code: ErrCodePermissionDenied}, http.StatusForbidden},
+		{IPCResponse{Message: "failed"}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeAPIResponse(rec, tt.response)
+		if rec.Code != tt.want {
+			t.Errorf("writeAPIResponse(%+v) status = %d, want %d", tt.response, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// ipcSocketCheckInterval is how often the control socket file is checked,
+// to recreate it when something like a /tmp cleaner removed it
+const ipcSocketCheckInterval = 5 * time.Second
+
+// ipcAcceptBackoff is the pause after a failed accept, such as one running
+// out of file descriptors, so the loop doesn't spin
+const ipcAcceptBackoff = 100 * time.Millisecond
+
+// noIPC disables the control socket, for minimal setups that never run a
+// client command
+var noIPC bool
+
+// controlAddress is where the daemon serves the control socket: a socket
+// path, an abstract socket name starting with @, or tcp://host:port on a
+// loopback address ([control] listen)
+var controlAddress = socketPath
+
+// ipcServerMu guards ipcServer, replaced when the control socket is recreated
+var ipcServerMu sync.Mutex
+
+// isSocketFile reports whether a control address is a socket file, which can
+// be removed from under the daemon and has permissions
+func isSocketFile(address string) bool {
+	return filepath.IsAbs(address)
+}
+
+// startIPCServer serves the control socket at address, taking over the
+// listener of a previous supervisor after an upgrade, and keeps it up until
+// shutdown
+func startIPCServer(address string) error {
+	listener := inheritedListener()
+	if listener != nil {
+		// The previous supervisor listened elsewhere: [control] listen changed
+		if _, addr, err := client.SplitAddress(address); err == nil && listener.Addr().String() != addr {
+			_ = listener.Close()
+			listener = nil
+		}
+	}
+	if listener == nil {
+		var err error
+		if listener, err = listenControl(address); err != nil {
+			return err
+		}
+	}
+
+	ipcServerMu.Lock()
+	ipcServer = listener
+	ipcServerMu.Unlock()
+	go acceptIPC(shutdownContext(), address, listener)
+	go watchControlSocket(shutdownContext(), address, ipcSocketCheckInterval)
+
+	logger.Success(fmt.Sprintf("IPC server started at %s", colorize(ColorCyan, address)))
+	return nil
+}
+
+// listenControl creates the control socket at address, replacing a stale
+// socket file
+func listenControl(address string) (net.Listener, error) {
+	network, addr, err := client.SplitAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if isSocketFile(address) {
+		_ = os.Remove(address)
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create control socket: %w", err)
+	}
+	return listener, nil
+}
+
+// currentIPCServer returns the listener of the control socket, nil if it is down
+func currentIPCServer() net.Listener {
+	ipcServerMu.Lock()
+	defer ipcServerMu.Unlock()
+	return ipcServer
+}
+
+// closeIPCServer closes the control socket for good, at shutdown
+func closeIPCServer() {
+	ipcServerMu.Lock()
+	defer ipcServerMu.Unlock()
+	if ipcServer != nil {
+		_ = ipcServer.Close()
+	}
+}
+
+// acceptIPC serves the connections of listener until it is closed. A
+// listener closed before ctx is done is recreated.
+func acceptIPC(ctx context.Context, address string, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return // Shutting down
+			}
+			if errors.Is(err, net.ErrClosed) {
+				respawnIPCServer(ctx, address, listener, "listener closed")
+				return
+			}
+			logger.Warn("Error accepting IPC connection: ", err)
+			time.Sleep(ipcAcceptBackoff)
+			continue
+		}
+
+		go handleIPCConnection(conn)
+	}
+}
+
+// watchControlSocket recreates the control socket when its file disappears:
+// the listener still works, but clients can no longer reach it. It also
+// retries after a failed respawn.
+func watchControlSocket(ctx context.Context, address string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		listener := currentIPCServer()
+		if listener == nil {
+			respawnIPCServer(ctx, address, nil, "is down")
+		} else if !isSocketFile(address) {
+			continue
+		} else if _, err := os.Stat(address); errors.Is(err, os.ErrNotExist) {
+			respawnIPCServer(ctx, address, listener, "was removed")
+		}
+	}
+}
+
+// respawnIPCServer replaces old, the listener that failed, with a new control
+// socket. It does nothing if old was already replaced. When the socket can't
+// be created, the watcher tries again on its next check.
+func respawnIPCServer(ctx context.Context, address string, old net.Listener, reason string) {
+	ipcServerMu.Lock()
+	defer ipcServerMu.Unlock()
+	if ipcServer != old || ctx.Err() != nil {
+		return
+	}
+
+	logger.Warn(fmt.Sprintf("Control socket %s %s, recreating it", colorize(ColorCyan, address), reason))
+	if old != nil {
+		// Closing would otherwise unlink the path the new socket is created at
+		if unixListener, ok := old.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+		_ = old.Close()
+		ipcServer = nil
+	}
+
+	listener, err := listenControl(address)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Could not recreate the control socket: %v", err))
+		return
+	}
+	ipcServer = listener
+	openControlSocket(address, currentControlConfig())
+	go acceptIPC(ctx, address, listener)
+	logger.Success(fmt.Sprintf("IPC server restarted at %s", colorize(ColorCyan, address)))
+}
@@ -0,0 +1,155 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// serveTestSocket serves a control socket at a temporary path, as
+// startIPCServer does, until the test ends
+func serveTestSocket(t *testing.T, interval time.Duration) (string, net.Listener) {
+	t.Helper()
+	resetShutdown()
+	path := filepath.Join(t.TempDir(), "control.sock")
+
+	listener, err := listenControl(path)
+	if err != nil {
+		t.Fatalf("listenControl() error = %v", err)
+	}
+	ipcServerMu.Lock()
+	ipcServer = listener
+	ipcServerMu.Unlock()
+	t.Cleanup(func() {
+		cancelShutdown()
+		closeIPCServer()
+		ipcServerMu.Lock()
+		ipcServer = nil
+		ipcServerMu.Unlock()
+	})
+
+	go acceptIPC(shutdownContext(), path, listener)
+	go watchControlSocket(shutdownContext(), path, interval)
+	return path, listener
+}
+
+// helloSocket reports whether a daemon answers the handshake at path
+func helloSocket(path string) bool {
+	return hello("unix", path)
+}
+
+// hello reports whether a daemon answers the handshake at a network address
+func hello(network, address string) bool {
+	conn, err := net.DialTimeout(network, address, time.Second)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+
+	if err := json.NewEncoder(conn).Encode(IPCCommand{Type: CmdHello, Version: ipcProtocolVersion}); err != nil {
+		return false
+	}
+	var response IPCResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return false
+	}
+	return response.Success && response.Version == ipcProtocolVersion
+}
+
+// waitForSocket waits until a daemon answers at path again
+func waitForSocket(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !helloSocket(path) {
+		if time.Now().After(deadline) {
+			t.Fatal("control socket was not recreated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Test the control socket is recreated once a cleaner removed its file
+func TestControlSocketRecreatedWhenRemoved(t *testing.T) {
+	path, listener := serveTestSocket(t, 20*time.Millisecond)
+	if !helloSocket(path) {
+		t.Fatal("control socket should answer")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitForSocket(t, path)
+	if currentIPCServer() == listener {
+		t.Error("the listener should have been replaced")
+	}
+}
+
+// Test a listener that stops accepting is replaced right away
+func TestControlSocketRecreatedWhenClosed(t *testing.T) {
+	path, listener := serveTestSocket(t, time.Hour)
+
+	_ = listener.Close()
+	waitForSocket(t, path)
+	if currentIPCServer() == listener {
+		t.Error("the listener should have been replaced")
+	}
+}
+
+// Test the socket stays down once the supervisor is shutting down
+func TestControlSocketNotRecreatedOnShutdown(t *testing.T) {
+	path, _ := serveTestSocket(t, 20*time.Millisecond)
+
+	cancelShutdown()
+	closeIPCServer()
+	time.Sleep(100 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("control socket should be gone after shutdown, stat error = %v", err)
+	}
+}
+
+// Test the control socket can be served on an abstract socket and on TCP
+func TestListenControl(t *testing.T) {
+	resetShutdown()
+	defer cancelShutdown()
+
+	for _, address := range []string{"@go-overlay-test-" + strconv.Itoa(os.Getpid()), "tcp://127.0.0.1:0"} {
+		listener, err := listenControl(address)
+		if err != nil {
+			t.Fatalf("listenControl(%s) error = %v", address, err)
+		}
+		go acceptIPC(shutdownContext(), address, listener)
+
+		addr := listener.Addr()
+		if !hello(addr.Network(), addr.String()) {
+			t.Errorf("no answer on %s", address)
+		}
+		if isSocketFile(address) {
+			t.Errorf("%s has no socket file", address)
+		}
+	}
+}
+
+// Test the client package speaks the protocol of the daemon
+func TestClientPackage(t *testing.T) {
+	setConfig(&Config{Services: []Service{{Name: "client-web"}}})
+	defer setConfig(nil)
+	path, _ := serveTestSocket(t, time.Hour)
+	c := client.New(path)
+
+	status, err := c.Status(context.Background())
+	if err != nil || !strings.HasPrefix(status, "Total: 1") {
+		t.Errorf("Status() = %q, %v", status, err)
+	}
+	if _, err := c.Restart(context.Background(), "missing"); client.Code(err) != ErrCodeServiceNotFound {
+		t.Errorf("Restart() of an unknown service error = %v, want %s", err, ErrCodeServiceNotFound)
+	}
+}
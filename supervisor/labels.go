@@ -0,0 +1,111 @@
+package supervisor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parseSelector parses "key=value,key2=value2" into required label values
+func parseSelector(selector string) (map[string]string, error) {
+	required := make(map[string]string)
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(term, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isValidLabelKey(key) {
+			return nil, fmt.Errorf("invalid selector term '%s' (expected key=value)", term)
+		}
+		required[key] = strings.TrimSpace(value)
+	}
+	if len(required) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	return required, nil
+}
+
+// matchesSelector reports whether labels carry every required key=value pair
+func matchesSelector(labels, required map[string]string) bool {
+	for key, value := range required {
+		if got, ok := labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidLabelKey accepts letters, digits and . _ / - (e.g. "tier", "app.kubernetes.io/name")
+func isValidLabelKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '_', r == '/', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// formatLabels renders labels as a sorted "key=value,..." list
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// hasTag reports whether tags contains tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func validateTags(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	for _, tag := range service.Tags {
+		if !isValidLabelKey(tag) {
+			errors = append(errors, ValidationError{
+				Field:   "tags",
+				Service: service.Name,
+				Message: fmt.Sprintf("invalid tag '%s' (use letters, digits, '.', '_', '/' or '-')", tag),
+			})
+		}
+	}
+
+	return errors
+}
+
+func validateLabels(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	for key := range service.Labels {
+		if !isValidLabelKey(key) {
+			errors = append(errors, ValidationError{
+				Field:   "labels",
+				Service: service.Name,
+				Message: fmt.Sprintf("invalid label key '%s' (use letters, digits, '.', '_', '/' or '-')", key),
+			})
+		}
+	}
+
+	return errors
+}
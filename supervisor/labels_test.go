@@ -0,0 +1,82 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test selector parsing
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     int
+		wantErr  bool
+	}{
+		{"tier=backend", 1, false},
+		{"tier=backend, env=prod", 2, false},
+		{"app.kubernetes.io/name=api", 1, false},
+		{"tier", 0, true},
+		{"", 0, true},
+		{"bad key=x", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := parseSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("parseSelector() = %v, want %d terms", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test label-based selection of bulk targets
+func TestResolveTargetsSelector(t *testing.T) {
+	useConfig(t, &Config{Services: []Service{
+		{Name: "db", Labels: map[string]string{"tier": "data"}},
+		{Name: "api", Labels: map[string]string{"tier": "backend", "env": "prod"}, DependsOn: DependsOnField{"db"}},
+		{Name: "worker-1", Labels: map[string]string{"tier": "backend"}},
+		{Name: "web"},
+	}})
+
+	tests := []struct {
+		name    string
+		cmd     IPCCommand
+		want    string
+		wantErr bool
+	}{
+		{"Selector", IPCCommand{Selector: "tier=backend"}, "api,worker-1", false},
+		{"Multiple terms", IPCCommand{Selector: "tier=backend,env=prod"}, "api", false},
+		{"Selector and pattern", IPCCommand{Selector: "tier=backend", Pattern: "worker-*"}, "worker-1", false},
+		{"No match", IPCCommand{Selector: "tier=frontend"}, "", true},
+		{"Invalid", IPCCommand{Selector: "tier"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := resolveTargets(tt.cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Join(targets, ","); got != tt.want {
+				t.Errorf("resolveTargets() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test label keys are validated
+func TestValidateLabels(t *testing.T) {
+	valid := Service{Name: "api", Labels: map[string]string{"tier": "backend", "team/owner": "core"}}
+	if errs := validateLabels(&valid); len(errs) != 0 {
+		t.Errorf("validateLabels() = %v, want no errors", errs)
+	}
+
+	invalid := Service{Name: "api", Labels: map[string]string{"tier name": "backend"}}
+	if errs := validateLabels(&invalid); len(errs) != 1 {
+		t.Errorf("validateLabels() = %v, want 1 error", errs)
+	}
+}
@@ -0,0 +1,181 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errServiceAlreadyRunning is returned when starting a service that has an active process
+var errServiceAlreadyRunning = errors.New("service is already running")
+
+// Per-service locks serialize lifecycle operations (start, stop, restart) so
+// concurrent requests can never spawn duplicate instances of a service.
+var (
+	serviceLocks   = make(map[string]*sync.Mutex)
+	serviceLocksMu sync.Mutex
+)
+
+// lockService acquires the lifecycle lock of a service and returns its release function
+func lockService(name string) func() {
+	serviceLocksMu.Lock()
+	lock, ok := serviceLocks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		serviceLocks[name] = lock
+	}
+	serviceLocksMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// getActiveService returns the running process registered for name, if any
+func getActiveService(name string) (*ServiceProcess, bool) {
+	servicesMutex.RLock()
+	defer servicesMutex.RUnlock()
+	serviceProc, exists := activeServices[name]
+	return serviceProc, exists
+}
+
+// currentConfig returns the active configuration. Configs are never modified
+// once published; apply and reload install a new one with setConfig.
+func currentConfig() *Config {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return globalConfig
+}
+
+// setConfig publishes config as the active configuration
+func setConfig(config *Config) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	globalConfig = config
+}
+
+// findServiceConfig returns the configured definition of a service
+func findServiceConfig(name string) (Service, bool) {
+	config := currentConfig()
+	if config == nil {
+		return Service{}, false
+	}
+	for i := range config.Services {
+		if config.Services[i].Name == name {
+			return config.Services[i], true
+		}
+	}
+	return Service{}, false
+}
+
+// stopService gracefully stops a running service and waits until it has exited
+func stopService(name string) error {
+	unlock := lockService(name)
+	defer unlock()
+	return stopServiceLocked(name)
+}
+
+// stopServiceLocked is stopService for callers already holding the service lock
+func stopServiceLocked(name string) error {
+	cancelPendingRestart(name)
+
+	serviceProc, exists := getActiveService(name)
+	if !exists {
+		return errServiceNotRunning(name)
+	}
+
+	logger.Info("Stopping service:", name)
+	if serviceProc.Cancel != nil {
+		serviceProc.Cancel()
+	}
+
+	if serviceProc.Exited == nil {
+		return nil
+	}
+
+	// terminateService force kills once the stop escalation times out; allow a
+	// small grace period on top of it for the process to be reaped.
+	timeout := 15 * time.Second
+	if config := currentConfig(); config != nil {
+		timeout = stopDuration(&serviceProc.Config, config.Timeouts) + 5*time.Second
+	}
+
+	select {
+	case <-serviceProc.Exited:
+		return nil
+	case <-time.After(timeout):
+		return withCode(ErrCodeTimeout, fmt.Errorf("timed out waiting for service '%s' to stop", name))
+	}
+}
+
+// supervisions counts the goroutines supervising services started or
+// restarted outside the startup stages, which Supervisor.Wait waits for
+var supervisions sync.WaitGroup
+
+// startService starts a configured service that is not currently running
+func startService(name string) error {
+	unlock := lockService(name)
+	defer unlock()
+	return startServiceLocked(name)
+}
+
+// startServiceLocked is startService for callers already holding the service lock.
+// It returns once the process is spawned; supervision continues in the background.
+func startServiceLocked(name string) error {
+	service, ok := findServiceConfig(name)
+	if !ok {
+		return errServiceNotFound(name)
+	}
+	resetCrashLoop(name)
+
+	config := currentConfig()
+	serviceProcess, err := launchService(service, getLongestServiceNameLength(config.Services))
+	if errors.Is(err, errServiceAlreadyRunning) {
+		return withCode(ErrCodeAlreadyRunning, fmt.Errorf("service '%s' is already running", name))
+	}
+	if err != nil || serviceProcess == nil {
+		return err
+	}
+
+	timeouts := config.Timeouts
+	supervisions.Add(1)
+	go func() {
+		defer supervisions.Done()
+		if err := superviseService(serviceProcess, timeouts); err != nil {
+			handleServiceError(&service, err)
+		}
+	}()
+	return nil
+}
+
+// signalProcessGroup delivers sig to every process of a service. Services run
+// in their own session (see pty.Start), so the main process leads a process
+// group that also holds the children a wrapper shell started.
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	if err := syscall.Kill(-pid, sig); err != syscall.ESRCH {
+		return err
+	}
+	// No such group: the main process left it with setsid or setpgid
+	return syscall.Kill(pid, sig)
+}
+
+// signalService delivers sig to the main process of a running service, or
+// with group to its whole process group
+func signalService(name string, sig syscall.Signal, group bool) error {
+	serviceProc, exists := getActiveService(name)
+	if !exists {
+		return errServiceNotRunning(name)
+	}
+
+	pid := serviceProc.GetPID()
+	if pid == 0 {
+		return fmt.Errorf("service '%s' has no process", name)
+	}
+
+	logger.Info(fmt.Sprintf("Sending %s to service '%s' (PID: %d)", signalName(sig), colorize(ColorCyan, name), pid))
+	if group {
+		return signalProcessGroup(pid, sig)
+	}
+	return syscall.Kill(pid, sig)
+}
@@ -0,0 +1,155 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// LintWarning is a suspicious but valid piece of configuration
+type LintWarning struct {
+	Field   string `json:"field"`
+	Service string `json:"service,omitempty"`
+	Message string `json:"message"`
+}
+
+func (w LintWarning) String() string {
+	if w.Service != "" {
+		return fmt.Sprintf("service '%s', field '%s': %s", w.Service, w.Field, w.Message)
+	}
+	return fmt.Sprintf("field '%s': %s", w.Field, w.Message)
+}
+
+// lintConfig returns warnings for a validated config
+func lintConfig(config *Config) []LintWarning {
+	var warnings []LintWarning
+
+	enabled := make(map[string]bool, len(config.Services))
+	for _, service := range config.Services {
+		enabled[service.Name] = service.Enabled == nil || *service.Enabled
+	}
+
+	for i := range config.Services {
+		service := &config.Services[i]
+		warnings = append(warnings, lintWaitAfter(service)...)
+		warnings = append(warnings, lintDisabledDependencies(service, enabled)...)
+		warnings = append(warnings, lintScriptPermissions(service)...)
+		warnings = append(warnings, lintRequiredNoRestart(service)...)
+	}
+
+	return warnings
+}
+
+// lintWaitAfter flags wait_after entries that never apply
+func lintWaitAfter(service *Service) []LintWarning {
+	var warnings []LintWarning
+
+	if service.WaitAfter == nil {
+		return warnings
+	}
+
+	if !service.WaitAfter.IsPerDep {
+		if service.WaitAfter.Global > 0 && len(service.DependsOn) == 0 {
+			warnings = append(warnings, LintWarning{
+				Field:   "wait_after",
+				Service: service.Name,
+				Message: "wait_after is set but the service has no depends_on",
+			})
+		}
+		return warnings
+	}
+
+	deps := make(map[string]bool, len(service.DependsOn))
+	for _, dep := range service.DependsOn {
+		deps[dep] = true
+	}
+
+	unused := make([]string, 0)
+	for depName := range service.WaitAfter.PerDep {
+		if !deps[depName] {
+			unused = append(unused, depName)
+		}
+	}
+	sort.Strings(unused)
+	for _, depName := range unused {
+		warnings = append(warnings, LintWarning{
+			Field:   "wait_after",
+			Service: service.Name,
+			Message: fmt.Sprintf("wait_after entry '%s' is not listed in depends_on", depName),
+		})
+	}
+
+	return warnings
+}
+
+// lintDisabledDependencies flags dependencies that will never start
+func lintDisabledDependencies(service *Service, enabled map[string]bool) []LintWarning {
+	var warnings []LintWarning
+
+	if service.Enabled != nil && !*service.Enabled {
+		return warnings
+	}
+
+	for _, dep := range service.DependsOn {
+		if isEnabled, ok := enabled[dep]; ok && !isEnabled {
+			warnings = append(warnings, LintWarning{
+				Field:   "depends_on",
+				Service: service.Name,
+				Message: fmt.Sprintf("depends on disabled service '%s'", dep),
+			})
+		}
+	}
+
+	return warnings
+}
+
+// lintScriptPermissions flags scripts any local user could modify
+func lintScriptPermissions(service *Service) []LintWarning {
+	var warnings []LintWarning
+
+	scripts := []struct{ field, path string }{
+		{"pre_script", service.PreScript},
+		{"pos_script", service.PosScript},
+	}
+	for _, script := range scripts {
+		if script.path == "" {
+			continue
+		}
+		info, err := os.Stat(rootPath(script.path))
+		if err != nil {
+			continue
+		}
+		if info.Mode().Perm()&0o002 != 0 {
+			warnings = append(warnings, LintWarning{
+				Field:   script.field,
+				Service: service.Name,
+				Message: fmt.Sprintf("script '%s' is world-writable", script.path),
+			})
+		}
+	}
+
+	return warnings
+}
+
+// lintRequiredNoRestart flags required services explicitly set to never
+// restart, where any crash shuts down the whole container
+func lintRequiredNoRestart(service *Service) []LintWarning {
+	var warnings []LintWarning
+
+	if service.Required && service.Restart == RestartNever {
+		warnings = append(warnings, LintWarning{
+			Field:   "restart",
+			Service: service.Name,
+			Message: "required service never restarts: a single crash shuts down every service",
+		})
+	}
+
+	return warnings
+}
+
+// printLintWarnings logs every warning
+func printLintWarnings(warnings []LintWarning) {
+	for _, warning := range warnings {
+		logger.Warn(warning.String())
+	}
+}
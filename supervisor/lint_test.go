@@ -0,0 +1,96 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test lint warnings for suspicious configurations
+func TestLintConfig(t *testing.T) {
+	dir := t.TempDir()
+	writable := filepath.Join(dir, "writable.sh")
+	private := filepath.Join(dir, "private.sh")
+	for path, mode := range map[string]os.FileMode{writable: 0o777, private: 0o755} {
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	disabled := false
+
+	tests := []struct {
+		name     string
+		services []Service
+		want     []string
+	}{
+		{
+			name: "Clean",
+			services: []Service{
+				{Name: "db"},
+				{Name: "api", DependsOn: DependsOnField{"db"}, WaitAfter: &WaitAfterField{Global: 2}, PreScript: private},
+			},
+		},
+		{
+			name:     "Global wait_after without dependencies",
+			services: []Service{{Name: "api", WaitAfter: &WaitAfterField{Global: 5}}},
+			want:     []string{"no depends_on"},
+		},
+		{
+			name: "Per-dependency wait_after not in depends_on",
+			services: []Service{
+				{Name: "db"},
+				{Name: "api", DependsOn: DependsOnField{"db"}, WaitAfter: &WaitAfterField{
+					IsPerDep: true, PerDep: map[string]int{"db": 1, "cache": 2},
+				}},
+			},
+			want: []string{"'cache' is not listed"},
+		},
+		{
+			name: "Dependency on disabled service",
+			services: []Service{
+				{Name: "db", Enabled: &disabled},
+				{Name: "api", DependsOn: DependsOnField{"db"}},
+			},
+			want: []string{"disabled service 'db'"},
+		},
+		{
+			name: "Disabled dependent is ignored",
+			services: []Service{
+				{Name: "db", Enabled: &disabled},
+				{Name: "api", DependsOn: DependsOnField{"db"}, Enabled: &disabled},
+			},
+		},
+		{
+			name:     "World-writable script",
+			services: []Service{{Name: "api", PreScript: writable, PosScript: private}},
+			want:     []string{"world-writable"},
+		},
+		{
+			name:     "Required service that never restarts",
+			services: []Service{{Name: "api", Required: true, Restart: RestartNever}},
+			want:     []string{"never restarts"},
+		},
+		{
+			name:     "Required service with the default policy is not flagged",
+			services: []Service{{Name: "api", Required: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := lintConfig(&Config{Services: tt.services})
+			if len(warnings) != len(tt.want) {
+				t.Fatalf("lintConfig() = %v, want %d warnings", warnings, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(warnings[i].Message, want) {
+					t.Errorf("warning %d = %q, want it to mention %q", i, warnings[i].Message, want)
+				}
+			}
+		})
+	}
+}
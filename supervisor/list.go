@@ -0,0 +1,241 @@
+package supervisor
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// List sort keys
+const (
+	SortByName     = "name"
+	SortByState    = "state"
+	SortByUptime   = "uptime"
+	SortByCPU      = "cpu"
+	SortByMemory   = "memory"
+	SortByRestarts = "restarts"
+)
+
+// sortServices orders services in place by key; ties are broken by name
+func sortServices(services []ServiceInfo, key string) error {
+	var less func(a, b *ServiceInfo) bool
+	switch key {
+	case "", SortByName:
+		less = func(a, b *ServiceInfo) bool { return false }
+	case SortByState:
+		less = func(a, b *ServiceInfo) bool { return a.State < b.State }
+	case SortByUptime:
+		less = func(a, b *ServiceInfo) bool { return a.Uptime > b.Uptime }
+	case SortByCPU:
+		less = func(a, b *ServiceInfo) bool { return cpuPercent(a) > cpuPercent(b) }
+	case SortByMemory:
+		less = func(a, b *ServiceInfo) bool { return a.RSS > b.RSS }
+	case SortByRestarts:
+		less = func(a, b *ServiceInfo) bool { return a.TotalRestarts > b.TotalRestarts }
+	default:
+		return fmt.Errorf("invalid sort key '%s' (use %s, %s, %s, %s, %s or %s)", key,
+			SortByName, SortByState, SortByUptime, SortByCPU, SortByMemory, SortByRestarts)
+	}
+
+	sort.SliceStable(services, func(i, j int) bool {
+		a, b := &services[i], &services[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Name < b.Name
+	})
+	return nil
+}
+
+// filterServices keeps the services matching every key=value filter.
+// Supported keys: state (e.g. FAILED), name (glob), tag and required (true/false).
+func filterServices(services []ServiceInfo, filters []string) ([]ServiceInfo, error) {
+	type filter struct{ key, value string }
+	parsed := make([]filter, 0, len(filters))
+	for _, f := range filters {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid filter '%s' (expected key=value)", f)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch key {
+		case "state", "name", "tag", "required", "health":
+		default:
+			return nil, fmt.Errorf("unknown filter key '%s' (use state, name, tag, required or health)", key)
+		}
+		parsed = append(parsed, filter{key, strings.TrimSpace(value)})
+	}
+
+	out := make([]ServiceInfo, 0, len(services))
+	for _, service := range services {
+		keep := true
+		for _, f := range parsed {
+			switch f.key {
+			case "state":
+				keep = strings.EqualFold(service.State.String(), f.value)
+			case "name":
+				matched, err := path.Match(f.value, service.Name)
+				if err != nil {
+					return nil, fmt.Errorf("invalid name pattern '%s': %w", f.value, err)
+				}
+				keep = matched
+			case "tag":
+				keep = hasTag(service.Tags, f.value)
+			case "required":
+				keep = fmt.Sprint(service.Required) == strings.ToLower(f.value)
+			case "health":
+				keep = strings.EqualFold(string(service.Health), f.value)
+			}
+			if !keep {
+				break
+			}
+		}
+		if keep {
+			out = append(out, service)
+		}
+	}
+	return out, nil
+}
+
+// cpuPercent returns the sampled CPU usage of a service, -1 without a sample
+func cpuPercent(service *ServiceInfo) float64 {
+	if service.CPUPercent == nil {
+		return -1
+	}
+	return *service.CPUPercent
+}
+
+// serviceRow renders one service as colored table cells
+func serviceRow(service *ServiceInfo) []string {
+	required := colorize(ColorGray, "No")
+	if service.Required {
+		required = colorize(ColorYellow, "Yes")
+	}
+
+	lastError := service.LastError
+	if len(lastError) > 30 {
+		lastError = lastError[:27] + "..."
+	}
+	if lastError != "" {
+		lastError = colorize(ColorRed, lastError)
+	} else {
+		lastError = colorize(ColorGray, "-")
+	}
+
+	state := colorize(getStateColor(service.State), service.State.String())
+	if service.Health != HealthNone {
+		state += " " + colorize(getHealthColor(service.Health), "("+string(service.Health)+")")
+	}
+
+	// Stopped services have no process
+	pid := colorize(ColorGray, "-")
+	uptime := colorize(ColorGray, "-")
+	if service.PID > 0 {
+		pid = colorize(ColorWhite, fmt.Sprint(service.PID))
+		uptime = colorize(ColorWhite, service.Uptime.Round(time.Second).String())
+	}
+
+	// Usage needs two samples, so a service just started has none yet
+	cpu := colorize(ColorGray, "-")
+	rss := colorize(ColorGray, "-")
+	if service.CPUPercent != nil {
+		cpu = colorize(ColorWhite, fmt.Sprintf("%.1f%%", *service.CPUPercent))
+		rss = colorize(ColorWhite, formatBytes(float64(service.RSS)))
+	}
+
+	exit := colorize(ColorGray, "-")
+	if service.ExitCode != nil {
+		color := ColorGreen
+		if *service.ExitCode != 0 {
+			color = ColorRed
+		}
+		exit = colorize(color, fmt.Sprint(*service.ExitCode))
+	}
+
+	// Automatic restarts since the daemon started
+	restarts := colorize(ColorGray, "0")
+	if service.TotalRestarts > 0 {
+		restarts = colorize(ColorYellow, fmt.Sprint(service.TotalRestarts))
+	}
+
+	return []string{
+		colorize(ColorCyan, service.Name),
+		state,
+		pid,
+		uptime,
+		cpu,
+		rss,
+		exit,
+		restarts,
+		required,
+		lastError,
+	}
+}
+
+// selectServices keeps the services whose labels match selector
+func selectServices(services []ServiceInfo, selector string) ([]ServiceInfo, error) {
+	if selector == "" {
+		return services, nil
+	}
+	required, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if matchesSelector(service.Labels, required) {
+			out = append(out, service)
+		}
+	}
+	return out, nil
+}
+
+// listOptions holds the flags of the list command
+type listOptions struct {
+	Sort     string
+	Selector string
+	Filters  []string
+	Offset   int
+	Limit    int
+}
+
+func listServices(opts listOptions) error {
+	var all []ServiceInfo
+	total := 0
+	err := sendIPCStream(IPCCommand{Type: CmdListServices, Offset: opts.Offset, Limit: opts.Limit}, func(frame *IPCResponse) error {
+		all = append(all, frame.Services...)
+		total = frame.Total
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	services, err := filterServices(all, opts.Filters)
+	if err != nil {
+		return err
+	}
+	if services, err = selectServices(services, opts.Selector); err != nil {
+		return err
+	}
+	if err := sortServices(services, opts.Sort); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(services))
+	for i := range services {
+		rows = append(rows, serviceRow(&services[i]))
+	}
+
+	fmt.Print(renderTable([]string{"NAME", "STATE", "PID", "UPTIME", "CPU", "RSS", "EXIT", "RESTARTS", "REQUIRED", "LAST_ERROR"}, rows))
+	if len(all) < total {
+		fmt.Println(colorize(ColorGray, fmt.Sprintf("Showing %d-%d of %d services", opts.Offset+1, opts.Offset+len(all), total)))
+	}
+	return nil
+}
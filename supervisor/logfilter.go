@@ -0,0 +1,172 @@
+package supervisor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// lineLevelPattern finds the level a line of service output announces: a
+// leading word such as "DEBUG", "[warn]" or "<error>", a logfmt level=info or
+// a JSON "level": "debug"
+var lineLevelPattern = regexp.MustCompile(`(?i)^\W{0,2}(trace|debug|info|warn|warning|error|fatal)\b` +
+	`|\blevel=["']?(trace|debug|info|warn|warning|error|fatal)\b` +
+	`|"(?:level|severity)"\s*:\s*"(trace|debug|info|warn|warning|error|fatal)"`)
+
+// logFilter decides which lines of a service reach the console, with the
+// log_level, log_include, log_exclude and log_sample of the service
+type logFilter struct {
+	level   LogLevel
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	sample  int
+
+	mu       sync.Mutex // Guards excluded, counted by the stdout and stderr readers
+	excluded int
+}
+
+// newLogFilter returns the console filter of a service, nil without one
+func newLogFilter(service *Service) *logFilter {
+	if service.LogLevel == "" && len(service.LogInclude) == 0 && len(service.LogExclude) == 0 {
+		return nil
+	}
+	filter := &logFilter{level: LogLevelDebug, sample: service.LogSample}
+	if service.LogLevel != "" {
+		// Validated with the config
+		filter.level, _ = parseLogLevel(service.LogLevel)
+	}
+	filter.include = compilePatterns(service.LogInclude)
+	filter.exclude = compilePatterns(service.LogExclude)
+	return filter
+}
+
+// compilePatterns compiles validated patterns, skipping any that don't
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			compiled = append(compiled, re)
+		}
+	}
+	return compiled
+}
+
+// pass reports whether a line goes to the console. level is the level of the
+// stream the line came from, used when the line doesn't announce its own.
+func (f *logFilter) pass(line string, level LogLevel) bool {
+	if f == nil {
+		return true
+	}
+	if lineLevel(line, level) < f.level {
+		return false
+	}
+	if len(f.include) > 0 && !matchesAny(f.include, line) {
+		return false
+	}
+	if !matchesAny(f.exclude, line) {
+		return true
+	}
+	if f.sample <= 0 {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.excluded++
+	return (f.excluded-1)%f.sample == 0
+}
+
+// lineLevel returns the level a line announces, else level
+func lineLevel(line string, level LogLevel) LogLevel {
+	match := lineLevelPattern.FindStringSubmatch(line)
+	if match == nil {
+		return level
+	}
+	for _, name := range match[1:] {
+		switch strings.ToLower(name) {
+		case "trace", "debug":
+			return LogLevelDebug
+		case "info":
+			return LogLevelInfo
+		case "warn", "warning":
+			return LogLevelWarn
+		case "error", "fatal":
+			return LogLevelError
+		}
+	}
+	return level
+}
+
+func matchesAny(patterns []*regexp.Regexp, line string) bool {
+	for _, re := range patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeLogFilter summarizes the console filter of a service for describe
+func describeLogFilter(service *Service) string {
+	var parts []string
+	if service.LogLevel != "" {
+		parts = append(parts, "level "+service.LogLevel)
+	}
+	if len(service.LogInclude) > 0 {
+		parts = append(parts, "include "+strings.Join(service.LogInclude, ", "))
+	}
+	if len(service.LogExclude) > 0 {
+		exclude := "exclude " + strings.Join(service.LogExclude, ", ")
+		if service.LogSample > 0 {
+			exclude += fmt.Sprintf(" (1 in %d kept)", service.LogSample)
+		}
+		parts = append(parts, exclude)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func validateLogFilter(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.LogLevel != "" {
+		if _, err := parseLogLevel(service.LogLevel); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "log_level",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		}
+	}
+	errors = append(errors, validatePatterns(service.Name, "log_include", service.LogInclude)...)
+	errors = append(errors, validatePatterns(service.Name, "log_exclude", service.LogExclude)...)
+	if service.LogSample < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "log_sample",
+			Service: service.Name,
+			Message: "log_sample cannot be negative",
+		})
+	} else if service.LogSample > 0 && len(service.LogExclude) == 0 {
+		errors = append(errors, ValidationError{
+			Field:   "log_sample",
+			Service: service.Name,
+			Message: "log_sample requires log_exclude, it keeps one in N of the excluded lines",
+		})
+	}
+
+	return errors
+}
+
+func validatePatterns(service, field string, patterns []string) ValidationErrors {
+	var errors ValidationErrors
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Service: service,
+				Message: fmt.Sprintf("invalid pattern '%s': %v", pattern, err),
+			})
+		}
+	}
+	return errors
+}
@@ -0,0 +1,99 @@
+package supervisor
+
+import "testing"
+
+func TestLineLevel(t *testing.T) {
+	tests := []struct {
+		line string
+		want LogLevel
+	}{
+		{"DEBUG cache hit", LogLevelDebug},
+		{"[warn] disk almost full", LogLevelWarn},
+		{"<error> connection refused", LogLevelError},
+		{"Info: listening on :8080", LogLevelInfo},
+		{`time=2025-01-15T14:02:35Z level=debug msg="health check"`, LogLevelDebug},
+		{`{"time":"2025-01-15T14:02:35Z","level":"warning","msg":"slow"}`, LogLevelWarn},
+		{`{"severity":"FATAL","message":"panic"}`, LogLevelError},
+		{"GET /healthz 200", LogLevelWarn},
+		{"information is not a level", LogLevelWarn},
+		{"an error in the middle is not a level", LogLevelWarn},
+	}
+
+	for _, tt := range tests {
+		if got := lineLevel(tt.line, LogLevelWarn); got != tt.want {
+			t.Errorf("lineLevel(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLogFilterPass(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		line    string
+		level   LogLevel
+		want    bool
+	}{
+		{"no filter", Service{}, "DEBUG anything", LogLevelInfo, true},
+		{"below log_level", Service{LogLevel: "warn"}, "GET /healthz 200", LogLevelInfo, false},
+		{"stream level at log_level", Service{LogLevel: "warn"}, "disk almost full", LogLevelWarn, true},
+		{"announced level above log_level", Service{LogLevel: "warn"}, "ERROR disk full", LogLevelInfo, true},
+		{"announced level below log_level", Service{LogLevel: "info"}, "debug: cache hit", LogLevelWarn, false},
+		{"matches log_include", Service{LogInclude: []string{"^job "}}, "job 42 done", LogLevelInfo, true},
+		{"misses log_include", Service{LogInclude: []string{"^job "}}, "tick", LogLevelInfo, false},
+		{"matches log_exclude", Service{LogExclude: []string{"GET /healthz"}}, "GET /healthz 200", LogLevelInfo, false},
+		{"misses log_exclude", Service{LogExclude: []string{"GET /healthz"}}, "GET /api 200", LogLevelInfo, true},
+		{"log_exclude wins over log_include", Service{LogInclude: []string{"GET"}, LogExclude: []string{"/healthz"}}, "GET /healthz", LogLevelInfo, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newLogFilter(&tt.service).pass(tt.line, tt.level); got != tt.want {
+				t.Errorf("pass(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+// Test log_sample keeps the first of every N excluded lines
+func TestLogFilterSample(t *testing.T) {
+	filter := newLogFilter(&Service{LogExclude: []string{"healthz"}, LogSample: 3})
+
+	var kept []int
+	for i := 1; i <= 7; i++ {
+		if filter.pass("GET /healthz 200", LogLevelInfo) {
+			kept = append(kept, i)
+		}
+	}
+	if len(kept) != 3 || kept[0] != 1 || kept[1] != 4 || kept[2] != 7 {
+		t.Errorf("kept lines %v, want [1 4 7]", kept)
+	}
+	if !filter.pass("GET /api 200", LogLevelInfo) {
+		t.Error("a line log_exclude doesn't match was dropped")
+	}
+}
+
+func TestValidateLogFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		wantErr bool
+	}{
+		{"none", Service{Name: "web"}, false},
+		{"valid", Service{Name: "web", LogLevel: "warn", LogInclude: []string{"^GET"}, LogExclude: []string{"healthz"}, LogSample: 10}, false},
+		{"unknown level", Service{Name: "web", LogLevel: "verbose"}, true},
+		{"bad include pattern", Service{Name: "web", LogInclude: []string{"("}}, true},
+		{"bad exclude pattern", Service{Name: "web", LogExclude: []string{"[a-"}}, true},
+		{"negative sample", Service{Name: "web", LogExclude: []string{"healthz"}, LogSample: -1}, true},
+		{"sample without exclude", Service{Name: "web", LogSample: 10}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateLogFilter(&tt.service)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateLogFilter() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
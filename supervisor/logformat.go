@@ -0,0 +1,171 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Log formats of the console output
+const (
+	LogFormatText = "text" // Colored labels and [service] prefixes (the default)
+	LogFormatJSON = "json" // One JSON object per line, without ANSI colors
+)
+
+// Timestamp settings of [logging] timestamps and the per-service timestamps;
+// any other value is a custom Go time layout such as "2006-01-02 15:04:05"
+const (
+	TimestampNone        = "none"        // No timestamp (the default)
+	TimestampRFC3339     = "rfc3339"     // 2006-01-02T15:04:05Z07:00
+	TimestampRFC3339Nano = "rfc3339nano" // 2006-01-02T15:04:05.999999999Z07:00
+)
+
+// envLogFormat sets the default of --log-format
+const envLogFormat = "GO_OVERLAY_LOG_FORMAT"
+
+// logFormat selects how supervisor messages and service output are written
+var logFormat = LogFormatText
+
+// supervisorTimestamps is the time layout prefixed to supervisor messages ("" = none)
+var supervisorTimestamps string
+
+// ansiEscape matches the color sequences removed from JSON records
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// logRecord is a line of console output in the json format
+type logRecord struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Service string `json:"service"`
+	Stream  string `json:"stream,omitempty"` // "stderr" for stderr lines of services run with pipes
+	Message string `json:"message"`
+}
+
+// logFormatFlag binds --log-format to logFormat, rejecting unknown formats at parse time
+type logFormatFlag struct{}
+
+func (logFormatFlag) String() string { return logFormat }
+
+func (logFormatFlag) Set(value string) error {
+	switch strings.ToLower(value) {
+	case LogFormatText:
+		logFormat = LogFormatText
+	case LogFormatJSON:
+		logFormat = LogFormatJSON
+	default:
+		return fmt.Errorf("invalid log format '%s' (use %s or %s)", value, LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+func (logFormatFlag) Type() string { return "format" }
+
+// formatJSONRecord renders a message as a JSON line, stripping ANSI colors
+func formatJSONRecord(level LogLevel, service, message string) string {
+	return formatJSONStreamRecord(level, service, "", message)
+}
+
+// formatJSONStreamRecord renders a line of a service output stream as a JSON line
+func formatJSONStreamRecord(level LogLevel, service, stream, message string) string {
+	data, err := json.Marshal(logRecord{
+		Time:    time.Now().Format(time.RFC3339Nano),
+		Level:   level.String(),
+		Service: service,
+		Stream:  stream,
+		Message: ansiEscape.ReplaceAllString(message, ""),
+	})
+	if err != nil {
+		return message
+	}
+	return string(data)
+}
+
+// timestampLayout resolves a timestamps setting to a Go time layout ("" = none)
+func timestampLayout(setting string) string {
+	switch strings.ToLower(setting) {
+	case "", TimestampNone:
+		return ""
+	case TimestampRFC3339:
+		return time.RFC3339
+	case TimestampRFC3339Nano:
+		return time.RFC3339Nano
+	}
+	return setting
+}
+
+// serviceTimestampLayout returns the time layout prefixed to the output of a
+// service: its own timestamps setting, else the [logging] one
+func serviceTimestampLayout(service *Service) string {
+	if service.Timestamps != "" {
+		return timestampLayout(service.Timestamps)
+	}
+	if config := currentConfig(); config != nil {
+		return timestampLayout(config.Logging.Timestamps)
+	}
+	return ""
+}
+
+// withTimestamp prefixes text with the current time in layout ("" = unchanged)
+func withTimestamp(layout, text string) string {
+	if layout == "" {
+		return text
+	}
+	return time.Now().Format(layout) + " " + text
+}
+
+// formatSupervisorMessage renders a supervisor message after its colored
+// label (text) or as a JSON record of the given level
+func formatSupervisorMessage(level LogLevel, label, message string) string {
+	if logFormat == LogFormatJSON {
+		return formatJSONRecord(level, supervisorLogSource, message)
+	}
+	if label != "" {
+		message = label + " " + message
+	}
+	return withTimestamp(supervisorTimestamps, message)
+}
+
+// writeServiceOutput writes a line of service output after the padded service
+// name and optional timestamp (text) or as a JSON record, which always has one
+func writeServiceOutput(service, paddedName, layout, line string) {
+	if logFormat == LogFormatJSON {
+		writeServiceLine(service, formatJSONRecord(LogLevelInfo, service, line))
+		return
+	}
+	writeServiceLine(service, withTimestamp(layout, fmt.Sprintf("[%s] %s", paddedName, line)))
+}
+
+// writeServiceStderr writes a line a service wrote to stderr: the service name
+// is colored by level (text) or the JSON record has the level and a stream
+func writeServiceStderr(service, paddedName, layout string, level LogLevel, line string) {
+	if logFormat == LogFormatJSON {
+		writeServiceLine(service, formatJSONStreamRecord(level, service, "stderr", line))
+		return
+	}
+	writeServiceLine(service, withTimestamp(layout, fmt.Sprintf("[%s] %s", colorize(logLevelColor(level), paddedName), line)))
+}
+
+// validateTimestampSetting checks a timestamps setting: a known name or a Go
+// time layout with at least one time element
+func validateTimestampSetting(field, service, setting string) ValidationErrors {
+	layout := timestampLayout(setting)
+	if layout == "" || layout != setting {
+		return nil
+	}
+	reference := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if reference.Format(layout) != layout {
+		return nil
+	}
+	return ValidationErrors{{
+		Field:   field,
+		Service: service,
+		Message: fmt.Sprintf("invalid timestamps '%s' (use %s, %s, %s or a Go time layout such as \"2006-01-02 15:04:05\")",
+			setting, TimestampNone, TimestampRFC3339, TimestampRFC3339Nano),
+	}}
+}
+
+func validateTimestamps(service *Service) ValidationErrors {
+	return validateTimestampSetting("timestamps", service.Name, service.Timestamps)
+}
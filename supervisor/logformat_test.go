@@ -0,0 +1,150 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test --log-format accepts text and json only
+func TestLogFormatFlag(t *testing.T) {
+	saved := logFormat
+	defer func() { logFormat = saved }()
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"text", LogFormatText, false},
+		{"json", LogFormatJSON, false},
+		{"JSON", LogFormatJSON, false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			err := logFormatFlag{}.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && logFormat != tt.want {
+				t.Errorf("logFormat = %q, want %q", logFormat, tt.want)
+			}
+		})
+	}
+}
+
+// Test JSON records carry the level and service and no ANSI colors
+func TestFormatJSONRecord(t *testing.T) {
+	line := formatJSONRecord(LogLevelWarn, "web", "Service '"+colorize(ColorCyan, "web")+"' is \x1b[1;31munhealthy\x1b[0m")
+
+	var record logRecord
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		t.Fatalf("record is not JSON: %v (%s)", err, line)
+	}
+	if record.Level != "warn" || record.Service != "web" {
+		t.Errorf("record = %+v, want level warn and service web", record)
+	}
+	if record.Message != "Service 'web' is unhealthy" {
+		t.Errorf("Message = %q, want colors stripped", record.Message)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, record.Time); err != nil {
+		t.Errorf("Time = %q is not RFC3339: %v", record.Time, err)
+	}
+}
+
+// Test supervisor messages and service output are both JSON in json mode
+func TestJSONLogOutput(t *testing.T) {
+	savedFormat, savedLevel, savedPipe := logFormat, logLevel, logPipe
+	defer func() { logFormat, logLevel, logPipe = savedFormat, savedLevel, savedPipe }()
+
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+	logPipe = newLogPipeline(out, LoggingConfig{})
+	logFormat = LogFormatJSON
+	logLevel = LogLevelInfo
+
+	logger.Error("supervisor message")
+	writeServiceOutput("web", "web   ", time.RFC3339, "service output")
+
+	if !waitFor(t, time.Second, func() bool { return strings.Count(out.String(), "\n") == 2 }) {
+		t.Fatalf("expected 2 lines, got %q", out.String())
+	}
+
+	want := []logRecord{
+		{Level: "error", Service: supervisorLogSource, Message: "supervisor message"},
+		{Level: "info", Service: "web", Message: "service output"},
+	}
+	for i, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record logRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %d is not JSON: %v (%s)", i, err, line)
+		}
+		record.Time = ""
+		if record != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, record, want[i])
+		}
+	}
+}
+
+// Test timestamps settings resolve to time layouts and invalid layouts are rejected
+func TestTimestampSettings(t *testing.T) {
+	tests := []struct {
+		setting string
+		layout  string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"none", "", false},
+		{"rfc3339", time.RFC3339, false},
+		{"RFC3339Nano", time.RFC3339Nano, false},
+		{"2006-01-02 15:04:05", "2006-01-02 15:04:05", false},
+		{"yes", "yes", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.setting, func(t *testing.T) {
+			if got := timestampLayout(tt.setting); got != tt.layout {
+				t.Errorf("timestampLayout(%q) = %q, want %q", tt.setting, got, tt.layout)
+			}
+			errs := validateTimestamps(&Service{Name: "web", Timestamps: tt.setting})
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateTimestamps(%q) = %v, wantErr %v", tt.setting, errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Test a service setting overrides [logging] timestamps
+func TestServiceTimestampLayout(t *testing.T) {
+	setConfig(&Config{Logging: LoggingConfig{Timestamps: TimestampRFC3339}})
+	defer setConfig(nil)
+
+	if got := serviceTimestampLayout(&Service{Name: "web"}); got != time.RFC3339 {
+		t.Errorf("inherited layout = %q, want %q", got, time.RFC3339)
+	}
+	if got := serviceTimestampLayout(&Service{Name: "web", Timestamps: TimestampNone}); got != "" {
+		t.Errorf("layout with timestamps = none is %q, want none", got)
+	}
+	if got := serviceTimestampLayout(&Service{Name: "web", Timestamps: "15:04:05"}); got != "15:04:05" {
+		t.Errorf("custom layout = %q", got)
+	}
+}
+
+// Test text output lines start with the timestamp
+func TestWithTimestamp(t *testing.T) {
+	if got := withTimestamp("", "[web] hello"); got != "[web] hello" {
+		t.Errorf("withTimestamp() without layout = %q", got)
+	}
+
+	got := withTimestamp(time.RFC3339, "[web] hello")
+	stamp, rest, found := strings.Cut(got, " ")
+	if !found || rest != "[web] hello" {
+		t.Fatalf("withTimestamp() = %q", got)
+	}
+	if _, err := time.Parse(time.RFC3339, stamp); err != nil {
+		t.Errorf("prefix %q is not RFC3339: %v", stamp, err)
+	}
+}
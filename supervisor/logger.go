@@ -0,0 +1,168 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Logger receives the supervisor's own messages, whose arguments are joined
+// as fmt.Sprint does. zap's SugaredLogger is a Logger; NewSlogLogger adapts a
+// log/slog logger and NewTextLogger writes the console format to any sink.
+// Messages below --log-level never reach it.
+type Logger interface {
+	Debug(args ...any)
+	Info(args ...any)
+	Warn(args ...any)
+	Error(args ...any)
+}
+
+// consoleLogger is a Logger labelling successes apart from other info
+// messages and printing unlabelled lines, such as the status summary. Other
+// loggers get both as info messages.
+type consoleLogger interface {
+	Logger
+	Success(args ...any)
+	Print(args ...any)
+}
+
+// logger is the supervisor's logger: it drops messages below the log level
+// and passes the others to the Logger set with SetLogger
+var logger leveledLogger
+
+// defaultLogger writes to the console through the log pipeline, ordered with
+// service output
+var defaultLogger = &textLogger{write: writeSupervisorLine}
+
+// SetLogger sends the supervisor's messages to l instead of the console; nil
+// restores the console. Service output is not affected.
+func SetLogger(l Logger) {
+	if l == nil {
+		logger.next.Store(nil)
+		return
+	}
+	logger.next.Store(&l)
+}
+
+// SetLogLevel sets the minimum level of the messages passed to the Logger,
+// as --log-level does
+func SetLogLevel(level LogLevel) {
+	logLevel = level
+}
+
+// leveledLogger filters messages by level before the current Logger
+type leveledLogger struct {
+	next atomic.Pointer[Logger]
+}
+
+func (l *leveledLogger) current() Logger {
+	if next := l.next.Load(); next != nil {
+		return *next
+	}
+	return defaultLogger
+}
+
+func (l *leveledLogger) Debug(args ...any) {
+	if logEnabled(LogLevelDebug) {
+		l.current().Debug(args...)
+	}
+}
+
+func (l *leveledLogger) Info(args ...any) {
+	if logEnabled(LogLevelInfo) {
+		l.current().Info(args...)
+	}
+}
+
+func (l *leveledLogger) Warn(args ...any) {
+	if logEnabled(LogLevelWarn) {
+		l.current().Warn(args...)
+	}
+}
+
+func (l *leveledLogger) Error(args ...any) {
+	if logEnabled(LogLevelError) {
+		l.current().Error(args...)
+	}
+}
+
+// Success logs an info message reporting that something succeeded
+func (l *leveledLogger) Success(args ...any) {
+	if !logEnabled(LogLevelInfo) {
+		return
+	}
+	if console, ok := l.current().(consoleLogger); ok {
+		console.Success(args...)
+		return
+	}
+	l.current().Info(args...)
+}
+
+// Print logs an info message without a label
+func (l *leveledLogger) Print(args ...any) {
+	if !logEnabled(LogLevelInfo) {
+		return
+	}
+	if console, ok := l.current().(consoleLogger); ok {
+		console.Print(args...)
+		return
+	}
+	l.current().Info(args...)
+}
+
+// textLogger writes messages after a colored label, or as JSON records with
+// --log-format json, one line at a time
+type textLogger struct {
+	write func(line string)
+}
+
+// NewTextLogger returns a Logger writing the console format to w
+func NewTextLogger(w io.Writer) Logger {
+	var mu sync.Mutex
+	return &textLogger{write: func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = fmt.Fprintln(w, line)
+	}}
+}
+
+func (l *textLogger) Debug(args ...any) { l.log(LogLevelDebug, "", "", args) }
+func (l *textLogger) Info(args ...any)  { l.log(LogLevelInfo, "INFO", ColorBoldBlue, args) }
+func (l *textLogger) Warn(args ...any)  { l.log(LogLevelWarn, "WARN", ColorBoldYellow, args) }
+func (l *textLogger) Error(args ...any) { l.log(LogLevelError, "ERROR", ColorBoldRed, args) }
+
+func (l *textLogger) Success(args ...any) {
+	l.log(LogLevelInfo, "SUCCESS", ColorBoldGreen, args)
+}
+
+func (l *textLogger) Print(args ...any) { l.log(LogLevelInfo, "", "", args) }
+
+func (l *textLogger) log(level LogLevel, label, color string, args []any) {
+	var prefix string
+	if label != "" {
+		prefix = colorize(color, fmt.Sprintf("[%-7s]", label))
+	}
+	l.write(formatSupervisorMessage(level, prefix, fmt.Sprint(args...)))
+}
+
+// slogLogger passes messages to a log/slog logger, without colors
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger passing messages to a log/slog logger
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{logger: l}
+}
+
+func (l *slogLogger) Debug(args ...any) { l.log(slog.LevelDebug, args) }
+func (l *slogLogger) Info(args ...any)  { l.log(slog.LevelInfo, args) }
+func (l *slogLogger) Warn(args ...any)  { l.log(slog.LevelWarn, args) }
+func (l *slogLogger) Error(args ...any) { l.log(slog.LevelError, args) }
+
+func (l *slogLogger) log(level slog.Level, args []any) {
+	l.logger.Log(context.Background(), level, ansiEscape.ReplaceAllString(fmt.Sprint(args...), ""))
+}
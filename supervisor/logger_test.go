@@ -0,0 +1,151 @@
+package supervisor
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger is a Logger keeping every message with its level
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) record(level string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, level+": "+fmt.Sprint(args...))
+}
+
+// matching returns the messages containing substr, leaving out those of
+// services other tests left running
+func (l *recordingLogger) matching(substr string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var messages []string
+	for _, message := range l.messages {
+		if strings.Contains(message, substr) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func (l *recordingLogger) Debug(args ...any) { l.record("debug", args) }
+func (l *recordingLogger) Info(args ...any)  { l.record("info", args) }
+func (l *recordingLogger) Warn(args ...any)  { l.record("warn", args) }
+func (l *recordingLogger) Error(args ...any) { l.record("error", args) }
+
+// useLogger sends supervisor messages to l at level until the test ends
+func useLogger(t *testing.T, l Logger, level LogLevel) {
+	t.Helper()
+	savedLevel := logLevel
+	SetLogger(l)
+	SetLogLevel(level)
+	t.Cleanup(func() {
+		SetLogger(nil)
+		logLevel = savedLevel
+	})
+}
+
+// Test a custom logger gets the messages at or above the level, and successes
+// and unlabelled lines as info messages
+func TestSetLogger(t *testing.T) {
+	recorder := &recordingLogger{}
+	useLogger(t, recorder, LogLevelInfo)
+
+	logger.Debug("logger-test hidden")
+	logger.Info("logger-test ", "starting")
+	logger.Success("logger-test started")
+	logger.Print("logger-test summary")
+	logger.Warn("logger-test slow")
+	logger.Error("logger-test failed")
+
+	got := recorder.matching("logger-test")
+	want := []string{"info: logger-test starting", "info: logger-test started", "info: logger-test summary", "warn: logger-test slow", "error: logger-test failed"}
+	if !slices.Equal(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
+
+// Test state transitions are only logged at debug level
+func TestStateTransitionsLoggedAtDebug(t *testing.T) {
+	savedColor := colorEnabled
+	colorEnabled = false
+	defer func() { colorEnabled = savedColor }()
+
+	recorder := &recordingLogger{}
+	useLogger(t, recorder, LogLevelInfo)
+
+	serviceProc := &ServiceProcess{Name: "logger-test"}
+	serviceProc.SetState(ServiceStateRunning)
+	if got := recorder.matching("logger-test"); len(got) != 0 {
+		t.Errorf("state transition logged at info: %q", got)
+	}
+
+	SetLogLevel(LogLevelDebug)
+	serviceProc.SetState(ServiceStateStopped)
+	got := recorder.matching("logger-test")
+	if len(got) != 1 || got[0] != "debug: Service 'logger-test' state changed from RUNNING to STOPPED" {
+		t.Errorf("messages = %q, want the transition at debug", got)
+	}
+}
+
+func TestTextLogger(t *testing.T) {
+	savedColor := colorEnabled
+	colorEnabled = false
+	defer func() { colorEnabled = savedColor }()
+
+	var out bytes.Buffer
+	useLogger(t, NewTextLogger(&out), LogLevelDebug)
+
+	logger.Debug("logger-test details")
+	logger.Success("logger-test started")
+	logger.Error("logger-test failed")
+
+	got := linesContaining(out.String(), "logger-test")
+	want := []string{"logger-test details", "[SUCCESS] logger-test started", "[ERROR  ] logger-test failed"}
+	if !slices.Equal(got, want) {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// Test the slog adapter maps levels and strips colors
+func TestSlogLogger(t *testing.T) {
+	var out bytes.Buffer
+	handler := slog.NewTextHandler(&out, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	useLogger(t, NewSlogLogger(slog.New(handler)), LogLevelInfo)
+
+	logger.Success("Service '", ColorCyan+"logger-test"+ColorReset, "' started")
+	logger.Warn("logger-test slow")
+
+	got := linesContaining(out.String(), "logger-test")
+	want := []string{`level=INFO msg="Service 'logger-test' started"`, `level=WARN msg="logger-test slow"`}
+	if !slices.Equal(got, want) {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// linesContaining returns the lines of output containing substr
+func linesContaining(output, substr string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, substr) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,95 @@
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LogLevel is the minimum severity of supervisor messages that are printed
+type LogLevel int
+
+// Supervisor log levels, from most to least verbose
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// supervisorLogSource is the pipeline source of the supervisor's own messages,
+// distinguishing them from service output
+const supervisorLogSource = "go-overlay"
+
+var (
+	// logLevel filters the messages of logger
+	logLevel = LogLevelInfo
+	// quietMode only lets errors through, overriding --log-level
+	quietMode bool
+)
+
+var logLevelNames = map[LogLevel]string{
+	LogLevelDebug: "debug",
+	LogLevelInfo:  "info",
+	LogLevelWarn:  "warn",
+	LogLevelError: "error",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// parseLogLevel accepts debug, info, warn (or warning) and error
+func parseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LogLevelDebug, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "warn", "warning":
+		return LogLevelWarn, nil
+	case "error":
+		return LogLevelError, nil
+	}
+	return LogLevelInfo, fmt.Errorf("invalid log level '%s' (use debug, info, warn or error)", name)
+}
+
+// logLevelFlag binds --log-level to logLevel, rejecting unknown levels at parse time
+type logLevelFlag struct{}
+
+func (logLevelFlag) String() string { return logLevel.String() }
+
+func (logLevelFlag) Set(value string) error {
+	level, err := parseLogLevel(value)
+	if err != nil {
+		return err
+	}
+	logLevel = level
+	return nil
+}
+
+func (logLevelFlag) Type() string { return "level" }
+
+// applyLogFlags resolves the effective level once flags are parsed:
+// --quiet wins, and --debug lowers the level to debug
+func applyLogFlags() {
+	switch {
+	case quietMode:
+		logLevel = LogLevelError
+	case debugMode:
+		logLevel = LogLevelDebug
+	}
+}
+
+// logEnabled reports whether messages of the given level are printed
+func logEnabled(level LogLevel) bool {
+	return level >= logLevel
+}
+
+// writeSupervisorLine sends a supervisor message through the log pipeline,
+// so it is ordered with (and buffered like) service output
+func writeSupervisorLine(text string) {
+	writeServiceLine(supervisorLogSource, text)
+}
@@ -0,0 +1,95 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test log level names are parsed case-insensitively
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    LogLevel
+		wantErr bool
+	}{
+		{"debug", LogLevelDebug, false},
+		{"INFO", LogLevelInfo, false},
+		{"warn", LogLevelWarn, false},
+		{"warning", LogLevelWarn, false},
+		{"error", LogLevelError, false},
+		{"verbose", LogLevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseLogLevel(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLogLevel(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLogLevel(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// Test --quiet and --debug override the configured level
+func TestApplyLogFlags(t *testing.T) {
+	savedLevel, savedQuiet, savedDebug := logLevel, quietMode, debugMode
+	defer func() { logLevel, quietMode, debugMode = savedLevel, savedQuiet, savedDebug }()
+
+	tests := []struct {
+		name  string
+		level LogLevel
+		quiet bool
+		debug bool
+		want  LogLevel
+	}{
+		{"default", LogLevelInfo, false, false, LogLevelInfo},
+		{"explicit level", LogLevelWarn, false, false, LogLevelWarn},
+		{"quiet", LogLevelDebug, true, false, LogLevelError},
+		{"debug", LogLevelInfo, false, true, LogLevelDebug},
+		{"quiet wins over debug", LogLevelInfo, true, true, LogLevelError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logLevel, quietMode, debugMode = tt.level, tt.quiet, tt.debug
+			applyLogFlags()
+			if logLevel != tt.want {
+				t.Errorf("logLevel = %v, want %v", logLevel, tt.want)
+			}
+		})
+	}
+}
+
+// Test supervisor messages below the level are dropped and the rest go through the pipeline
+func TestSupervisorLogFiltering(t *testing.T) {
+	savedLevel, savedPipe := logLevel, logPipe
+	defer func() { logLevel, logPipe = savedLevel, savedPipe }()
+
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+	logPipe = newLogPipeline(out, LoggingConfig{})
+	logLevel = LogLevelWarn
+
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Success("success message")
+	logger.Warn("warn message")
+	logger.Error("error message")
+
+	if !waitFor(t, time.Second, func() bool { return strings.Contains(out.String(), "error message") }) {
+		t.Fatalf("error message not written: %q", out.String())
+	}
+	got := out.String()
+	for _, hidden := range []string{"debug message", "info message", "success message"} {
+		if strings.Contains(got, hidden) {
+			t.Errorf("%q printed at level warn", hidden)
+		}
+	}
+	if !strings.Contains(got, "warn message") {
+		t.Errorf("warn message missing at level warn: %q", got)
+	}
+}
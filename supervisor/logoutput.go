@@ -0,0 +1,331 @@
+package supervisor
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// log_output defaults
+const (
+	defaultLogOutputMaxSize  = 10 // Megabytes
+	defaultLogOutputMaxFiles = 5
+)
+
+// LogOutput writes the output of a service to a file rotated by size and age,
+// like s6-log. Rotated files are named <path>.1 (newest) to <path>.<max_files>.
+type LogOutput struct {
+	Path     string `toml:"path"`
+	MaxSize  int    `toml:"max_size,omitempty"`  // Megabytes before the file is rotated (default 10)
+	MaxAge   int    `toml:"max_age,omitempty"`   // Seconds before the file is rotated (0 = no limit)
+	MaxFiles int    `toml:"max_files,omitempty"` // Rotated files kept (default 5)
+	Compress bool   `toml:"compress,omitempty"`  // gzip rotated files
+}
+
+func (o *LogOutput) maxSize() int64 {
+	size := o.MaxSize
+	if size <= 0 {
+		size = defaultLogOutputMaxSize
+	}
+	return int64(size) * 1024 * 1024
+}
+
+func (o *LogOutput) maxFiles() int {
+	if o.MaxFiles <= 0 {
+		return defaultLogOutputMaxFiles
+	}
+	return o.MaxFiles
+}
+
+// rotatingFile appends lines to a LogOutput file, rotating it when it grows
+// past max_size or gets older than max_age
+type rotatingFile struct {
+	mu     sync.Mutex
+	config LogOutput
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func openRotatingFile(config LogOutput) (*rotatingFile, error) {
+	r := &rotatingFile{config: config}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.config.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	r.file, r.size, r.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+// WriteLine appends line, rotating first when the file is full or too old
+func (r *rotatingFile) WriteLine(line string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return fmt.Errorf("log output %s is closed", r.config.Path)
+	}
+	if r.size > 0 && (r.size+int64(len(line))+1 > r.config.maxSize() || r.expired()) {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := io.WriteString(r.file, line+"\n")
+	r.size += int64(n)
+	return err
+}
+
+func (r *rotatingFile) expired() bool {
+	return r.config.MaxAge > 0 && time.Since(r.opened) >= time.Duration(r.config.MaxAge)*time.Second
+}
+
+// rotate shifts <path>.N to <path>.N+1 (dropping the oldest), moves the
+// current file to <path>.1 and reopens an empty one
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	ext := ""
+	if r.config.Compress {
+		ext = ".gz"
+	}
+	base := r.config.Path
+	keep := r.config.maxFiles()
+
+	_ = os.Remove(base + "." + strconv.Itoa(keep) + ext)
+	for i := keep - 1; i >= 1; i-- {
+		_ = os.Rename(base+"."+strconv.Itoa(i)+ext, base+"."+strconv.Itoa(i+1)+ext)
+	}
+	if err := os.Rename(base, base+".1"); err != nil {
+		return err
+	}
+	if r.config.Compress {
+		if err := compressFile(base + ".1"); err != nil {
+			return err
+		}
+	}
+	return r.open()
+}
+
+// Close closes the current file
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// compressFile replaces path with path.gz
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = gz.Close()
+		_ = out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// serviceOutput dispatches each line of output of a service: to the console,
+// to the in-memory history and, with log_output, to its file. Without a
+// PTY, stderr lines are tagged and may go to stderr_log_output instead.
+type serviceOutput struct {
+	name        string
+	paddedName  string
+	layout      string
+	stderrLevel LogLevel
+	filter      *logFilter      // Lines kept off the console, nil for none
+	limiter     *logRateLimiter // nil without log_rate_limit
+
+	// Join the lines of each stream into records, nil without multiline
+	stdoutRecords *multilineJoiner
+	stderrRecords *multilineJoiner
+
+	mu         sync.Mutex // Guards the files, written by the stdout and stderr readers
+	file       *rotatingFile
+	stderrFile *rotatingFile // Defaults to file
+}
+
+// newServiceOutput prepares the output of a service, padding its name to
+// maxLength. A log_output that can't be opened is reported and skipped.
+func newServiceOutput(service *Service, maxLength int) *serviceOutput {
+	out := &serviceOutput{
+		name:        service.Name,
+		paddedName:  formatServiceName(service.Name, maxLength),
+		layout:      serviceTimestampLayout(service),
+		stderrLevel: serviceStderrLevel(service),
+		filter:      newLogFilter(service),
+		limiter:     newLogRateLimiter(service),
+	}
+	if service.LogOutput != nil {
+		out.file = openServiceLogOutput(service.Name, "log_output", *service.LogOutput)
+	}
+	out.stderrFile = out.file
+	out.stdoutRecords = newMultilineJoiner(service.Multiline, out.writeRecord)
+	out.stderrRecords = newMultilineJoiner(service.Multiline, out.writeStderrRecord)
+	if service.StderrLogOutput != nil {
+		out.stderrFile = openServiceLogOutput(service.Name, "stderr_log_output", *service.StderrLogOutput)
+	}
+	return out
+}
+
+// openServiceLogOutput opens a log file of a service, reporting failures
+func openServiceLogOutput(service, field string, config LogOutput) *rotatingFile {
+	file, err := openRotatingFile(config)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Could not open %s of service '%s': %v",
+			field, colorize(ColorCyan, service), err))
+		return nil
+	}
+	return file
+}
+
+// writeLine writes a line the service wrote to stdout, or to its PTY
+func (o *serviceOutput) writeLine(line string) {
+	if o.stdoutRecords != nil {
+		o.stdoutRecords.add(line)
+		return
+	}
+	o.writeRecord(line)
+}
+
+// writeStderrLine writes a line the service wrote to stderr (without a PTY only)
+func (o *serviceOutput) writeStderrLine(line string) {
+	if o.stderrRecords != nil {
+		o.stderrRecords.add(line)
+		return
+	}
+	o.writeStderrRecord(line)
+}
+
+// writeRecord writes a line of stdout, or the lines multiline joined, to the
+// history, the console and log_output
+func (o *serviceOutput) writeRecord(line string) {
+	serviceLogs.record(o.name, line)
+	if o.filter.pass(line, LogLevelInfo) && o.limiter.allow() {
+		writeServiceOutput(o.name, o.paddedName, o.layout, line)
+	}
+	o.writeFile(&o.file, line)
+}
+
+// writeStderrRecord writes a line of stderr, or the lines multiline joined
+func (o *serviceOutput) writeStderrRecord(line string) {
+	serviceLogs.record(o.name, line)
+	if o.filter.pass(line, o.stderrLevel) && o.limiter.allow() {
+		writeServiceStderr(o.name, o.paddedName, o.layout, o.stderrLevel, line)
+	}
+	o.writeFile(&o.stderrFile, line)
+}
+
+// writeFile appends line to *file, dropping the file after a write error
+func (o *serviceOutput) writeFile(file **rotatingFile, line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f := *file
+	if f == nil {
+		return
+	}
+	if err := f.WriteLine(withTimestamp(o.layout, line)); err != nil {
+		logger.Error(fmt.Sprintf("Error writing log output of service '%s': %v",
+			colorize(ColorCyan, o.name), err))
+		_ = f.Close()
+		if o.file == f {
+			o.file = nil
+		}
+		if o.stderrFile == f {
+			o.stderrFile = nil
+		}
+	}
+}
+
+func (o *serviceOutput) close() {
+	o.stdoutRecords.flush()
+	o.stderrRecords.flush()
+	o.limiter.flush()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file != nil {
+		_ = o.file.Close()
+	}
+	if o.stderrFile != nil && o.stderrFile != o.file {
+		_ = o.stderrFile.Close()
+	}
+}
+
+func validateLogOutput(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	output := service.LogOutput
+	if output == nil {
+		return errors
+	}
+	if output.Path == "" {
+		errors = append(errors, ValidationError{
+			Field:   "log_output.path",
+			Service: service.Name,
+			Message: "log_output requires a path",
+		})
+	}
+	if service.LogFile != "" {
+		errors = append(errors, ValidationError{
+			Field:   "log_output",
+			Service: service.Name,
+			Message: "log_output cannot be combined with log_file",
+		})
+	}
+	if output.MaxSize < 0 || output.MaxAge < 0 || output.MaxFiles < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "log_output",
+			Service: service.Name,
+			Message: "max_size, max_age and max_files cannot be negative",
+		})
+	}
+
+	return errors
+}
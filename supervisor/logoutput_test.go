@@ -0,0 +1,126 @@
+package supervisor
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test the file is rotated when full and only max_files rotated files are kept
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "web.log")
+	r, err := openRotatingFile(LogOutput{Path: path, MaxSize: 1, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("openRotatingFile() error = %v", err)
+	}
+	defer r.Close()
+
+	// Each line fills more than half of the 1MB limit: one line per file
+	line := strings.Repeat("x", 600*1024)
+	for i := 0; i < 4; i++ {
+		if err := r.WriteLine(line); err != nil {
+			t.Fatalf("WriteLine() error = %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+		if info.Size() != int64(len(line)+1) {
+			t.Errorf("%s has %d bytes, want one line", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 rotated files, found %s.3", path)
+	}
+}
+
+// Test max_age rotates on the next write and compress gzips rotated files
+func TestRotatingFileAgeCompress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.log")
+	r, err := openRotatingFile(LogOutput{Path: path, MaxAge: 1, Compress: true})
+	if err != nil {
+		t.Fatalf("openRotatingFile() error = %v", err)
+	}
+	defer r.Close()
+
+	if err := r.WriteLine("old"); err != nil {
+		t.Fatalf("WriteLine() error = %v", err)
+	}
+	r.opened = r.opened.Add(-2 * time.Second)
+	if err := r.WriteLine("new"); err != nil {
+		t.Fatalf("WriteLine() error = %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "new\n" {
+		t.Errorf("current file = %q, want the new line", data)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Error("uncompressed rotated file left behind")
+	}
+
+	file, err := os.Open(path + ".1.gz")
+	if err != nil {
+		t.Fatalf("expected compressed file: %v", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	if data, _ := io.ReadAll(gz); string(data) != "old\n" {
+		t.Errorf("rotated file = %q, want the old line", data)
+	}
+}
+
+// Test service output reaches the log_output file, appending across restarts
+func TestServiceOutputLogOutput(t *testing.T) {
+	savedPipe := logPipe
+	defer func() { logPipe = savedPipe }()
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+	logPipe = newLogPipeline(out, LoggingConfig{})
+
+	path := filepath.Join(t.TempDir(), "web.log")
+	service := &Service{Name: "web", LogOutput: &LogOutput{Path: path}}
+
+	for _, line := range []string{"first", "second"} {
+		output := newServiceOutput(service, 3)
+		output.writeLine(line)
+		output.close()
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "first\nsecond\n" {
+		t.Errorf("log_output file = %q", data)
+	}
+}
+
+// Test log_output validation
+func TestValidateLogOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		wantErr bool
+	}{
+		{"Not set", Service{Name: "web"}, false},
+		{"Valid", Service{Name: "web", LogOutput: &LogOutput{Path: "/var/log/web.log", MaxFiles: 3}}, false},
+		{"Missing path", Service{Name: "web", LogOutput: &LogOutput{MaxSize: 5}}, true},
+		{"With log_file", Service{Name: "web", LogFile: "/var/log/in.log", LogOutput: &LogOutput{Path: "/var/log/web.log"}}, true},
+		{"Negative", Service{Name: "web", LogOutput: &LogOutput{Path: "/var/log/web.log", MaxAge: -1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateLogOutput(&tt.service)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateLogOutput() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
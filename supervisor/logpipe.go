@@ -0,0 +1,180 @@
+package supervisor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Log overflow policies applied when the console can't keep up with services
+const (
+	LogOverflowBlock      = "block"       // Producers wait for room (no line is lost)
+	LogOverflowDropOldest = "drop-oldest" // The oldest buffered line is discarded and counted
+)
+
+const (
+	defaultLogBufferSize = 1024
+	logFlushTimeout      = 2 * time.Second
+)
+
+// LoggingConfig configures the pipeline between service output and the console
+type LoggingConfig struct {
+	Overflow   string `toml:"overflow,omitempty"`
+	BufferSize int    `toml:"buffer_size,omitempty"`
+	Timestamps string `toml:"timestamps,omitempty"` // Prefix every line with the time (rfc3339, rfc3339nano or a Go layout)
+}
+
+// logLine is a formatted output line waiting to be written
+type logLine struct {
+	service string
+	text    string
+}
+
+// logPipeline decouples services from the console through a bounded buffer
+// drained by a single writer goroutine.
+type logPipeline struct {
+	out      io.Writer
+	lines    chan logLine
+	dropped  map[string]uint64
+	overflow string
+	mu       sync.Mutex
+}
+
+// logPipe carries service output to stdout; nil writes synchronously
+var logPipe *logPipeline
+
+func newLogPipeline(out io.Writer, cfg LoggingConfig) *logPipeline {
+	size := cfg.BufferSize
+	if size <= 0 {
+		size = defaultLogBufferSize
+	}
+	overflow := cfg.Overflow
+	if overflow == "" {
+		overflow = LogOverflowBlock
+	}
+
+	p := &logPipeline{
+		out:      out,
+		lines:    make(chan logLine, size),
+		dropped:  make(map[string]uint64),
+		overflow: overflow,
+	}
+	go p.run()
+	return p
+}
+
+func (p *logPipeline) run() {
+	for line := range p.lines {
+		fmt.Fprintln(p.out, line.text)
+	}
+}
+
+// Write queues a line of service output according to the overflow policy
+func (p *logPipeline) Write(service, text string) {
+	line := logLine{service: service, text: text}
+
+	if p.overflow != LogOverflowDropOldest {
+		p.lines <- line
+		return
+	}
+
+	for {
+		select {
+		case p.lines <- line:
+			return
+		default:
+		}
+
+		// Buffer full: discard the oldest line to make room
+		select {
+		case old := <-p.lines:
+			p.mu.Lock()
+			p.dropped[old.service]++
+			p.mu.Unlock()
+		default:
+		}
+	}
+}
+
+// Dropped returns the number of lines of service discarded so far
+func (p *logPipeline) Dropped(service string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dropped[service]
+}
+
+// TotalDropped returns the number of lines discarded across all services
+func (p *logPipeline) TotalDropped() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var total uint64
+	for _, count := range p.dropped {
+		total += count
+	}
+	return total
+}
+
+// Flush waits (up to timeout) for buffered lines to be written
+func (p *logPipeline) Flush(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for len(p.lines) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// startLogPipeline installs the log pipeline configured in cfg
+func startLogPipeline(cfg LoggingConfig) {
+	logPipe = newLogPipeline(os.Stdout, cfg)
+	supervisorTimestamps = timestampLayout(cfg.Timestamps)
+}
+
+// writeServiceLine sends a line of service output through the log pipeline
+func writeServiceLine(service, text string) {
+	if logPipe == nil {
+		fmt.Println(text)
+		return
+	}
+	logPipe.Write(service, text)
+}
+
+// droppedLogLines returns the lines of service discarded by the log pipeline
+func droppedLogLines(service string) uint64 {
+	if logPipe == nil {
+		return 0
+	}
+	return logPipe.Dropped(service)
+}
+
+// flushLogs drains the log pipeline before the supervisor exits
+func flushLogs() {
+	if logPipe != nil {
+		logPipe.Flush(logFlushTimeout)
+	}
+}
+
+func validateLogging(cfg *LoggingConfig) ValidationErrors {
+	var errors ValidationErrors
+
+	switch cfg.Overflow {
+	case "", LogOverflowBlock, LogOverflowDropOldest:
+	default:
+		errors = append(errors, ValidationError{
+			Field:   "logging.overflow",
+			Message: fmt.Sprintf("overflow must be '%s' or '%s'", LogOverflowBlock, LogOverflowDropOldest),
+		})
+	}
+
+	if cfg.BufferSize < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "logging.buffer_size",
+			Message: "buffer_size must be a positive number of lines",
+		})
+	}
+
+	errors = append(errors, validateTimestampSetting("logging.timestamps", "", cfg.Timestamps)...)
+
+	return errors
+}
@@ -0,0 +1,118 @@
+package supervisor
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter holds every write until released
+type blockingWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// Test drop-oldest discards and counts lines when the console is stalled
+func TestLogPipelineDropOldest(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	p := newLogPipeline(out, LoggingConfig{Overflow: LogOverflowDropOldest, BufferSize: 2})
+
+	// The writer picks up the first line and stalls on it
+	p.Write("web", "1")
+	waitFor(t, time.Second, func() bool { return len(p.lines) == 0 })
+
+	done := make(chan struct{})
+	go func() {
+		for _, line := range []string{"2", "3", "4", "5"} {
+			p.Write("web", line)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Write() blocked with drop-oldest policy")
+	}
+
+	// The buffer keeps the newest two lines
+	if got := p.Dropped("web"); got != 2 {
+		t.Errorf("Dropped() = %d, want 2", got)
+	}
+	if got := p.TotalDropped(); got != 2 {
+		t.Errorf("TotalDropped() = %d, want 2", got)
+	}
+
+	close(out.release)
+	p.Flush(time.Second)
+	waitFor(t, time.Second, func() bool { return strings.Count(out.String(), "\n") == 3 })
+	if !strings.HasSuffix(out.String(), "4\n5\n") {
+		t.Errorf("output = %q, want the newest lines kept", out.String())
+	}
+}
+
+// Test block policy never drops lines
+func TestLogPipelineBlock(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	p := newLogPipeline(out, LoggingConfig{BufferSize: 1})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			p.Write("web", "line")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Write() did not block on a full buffer")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(out.release)
+	<-done
+	p.Flush(time.Second)
+	waitFor(t, time.Second, func() bool { return strings.Count(out.String(), "\n") == 5 })
+	if got := p.TotalDropped(); got != 0 {
+		t.Errorf("TotalDropped() = %d, want 0", got)
+	}
+}
+
+// Test logging configuration validation
+func TestValidateLogging(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LoggingConfig
+		wantErr bool
+	}{
+		{"Defaults", LoggingConfig{}, false},
+		{"Drop oldest", LoggingConfig{Overflow: LogOverflowDropOldest, BufferSize: 100}, false},
+		{"Unknown policy", LoggingConfig{Overflow: "drop-newest"}, true},
+		{"Negative buffer", LoggingConfig{BufferSize: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := validateLogging(&tt.cfg); (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateLogging() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
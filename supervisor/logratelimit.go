@@ -0,0 +1,94 @@
+package supervisor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// logRateWindow is the period log_rate_limit counts lines over
+const logRateWindow = time.Second
+
+// logRateLimiter keeps a service flooding its output from reaching the
+// console: past log_rate_limit lines in a second, lines are suppressed and
+// counted, and the count is reported once the second is over
+type logRateLimiter struct {
+	service string
+	limit   int
+
+	mu          sync.Mutex // Lines come from the stdout and stderr readers
+	windowStart time.Time
+	lines       int
+	suppressed  int
+	report      *time.Timer // Reports the suppressed lines at the end of the window
+}
+
+// newLogRateLimiter returns the rate limiter of a service, nil without a
+// log_rate_limit
+func newLogRateLimiter(service *Service) *logRateLimiter {
+	if service.LogRateLimit <= 0 {
+		return nil
+	}
+	return &logRateLimiter{service: service.Name, limit: service.LogRateLimit}
+}
+
+// allow reports whether a line may go to the console
+func (l *logRateLimiter) allow() bool {
+	return l.allowAt(time.Now())
+}
+
+func (l *logRateLimiter) allowAt(now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.windowStart) >= logRateWindow {
+		l.reportLocked()
+		l.windowStart, l.lines = now, 0
+	}
+	l.lines++
+	if l.lines <= l.limit {
+		return true
+	}
+	l.suppressed++
+	if l.report == nil {
+		l.report = time.AfterFunc(l.windowStart.Add(logRateWindow).Sub(now), l.flush)
+	}
+	return false
+}
+
+// flush reports the lines suppressed so far
+func (l *logRateLimiter) flush() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reportLocked()
+}
+
+func (l *logRateLimiter) reportLocked() {
+	if l.report != nil {
+		l.report.Stop()
+		l.report = nil
+	}
+	if l.suppressed == 0 {
+		return
+	}
+	logger.Warn(fmt.Sprintf("Service '%s' exceeded log_rate_limit (%d lines/s): %d lines suppressed",
+		colorize(ColorCyan, l.service), l.limit, l.suppressed))
+	l.suppressed = 0
+}
+
+func validateLogRateLimit(service *Service) ValidationErrors {
+	if service.LogRateLimit >= 0 {
+		return nil
+	}
+	return ValidationErrors{{
+		Field:   "log_rate_limit",
+		Service: service.Name,
+		Message: "log_rate_limit cannot be negative",
+	}}
+}
@@ -0,0 +1,69 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test lines past the limit are suppressed until the next second, which
+// reports how many were
+func TestLogRateLimiter(t *testing.T) {
+	recorder := &recordingLogger{}
+	useLogger(t, recorder, LogLevelInfo)
+
+	limiter := newLogRateLimiter(&Service{Name: "flood-test", LogRateLimit: 3})
+	start := time.Now()
+
+	var allowed int
+	for i := 0; i < 10; i++ {
+		if limiter.allowAt(start.Add(time.Duration(i) * time.Millisecond)) {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("allowed %d lines in a second, want 3", allowed)
+	}
+	if got := recorder.matching("flood-test"); len(got) != 0 {
+		t.Errorf("suppressed lines reported before the end of the second: %q", got)
+	}
+
+	if !limiter.allowAt(start.Add(logRateWindow)) {
+		t.Error("the first line of the next second was suppressed")
+	}
+	got := recorder.matching("flood-test")
+	if len(got) != 1 || !strings.Contains(got[0], "7 lines suppressed") || !strings.HasPrefix(got[0], "warn: ") {
+		t.Errorf("messages = %q, want a warning about 7 suppressed lines", got)
+	}
+}
+
+// Test suppressed lines are reported at the end of the second even when the
+// service goes quiet
+func TestLogRateLimiterReportsWhenQuiet(t *testing.T) {
+	recorder := &recordingLogger{}
+	useLogger(t, recorder, LogLevelInfo)
+
+	limiter := newLogRateLimiter(&Service{Name: "quiet-test", LogRateLimit: 1})
+	limiter.allow()
+	limiter.allow()
+
+	if !waitFor(t, 3*time.Second, func() bool { return len(recorder.matching("quiet-test")) == 1 }) {
+		t.Fatalf("messages = %q, want the suppressed line reported", recorder.matching("quiet-test"))
+	}
+	limiter.flush()
+	if got := recorder.matching("quiet-test"); len(got) != 1 {
+		t.Errorf("suppressed lines reported twice: %q", got)
+	}
+}
+
+func TestLogRateLimiterDisabled(t *testing.T) {
+	limiter := newLogRateLimiter(&Service{Name: "web"})
+	for i := 0; i < 1000; i++ {
+		if !limiter.allow() {
+			t.Fatal("a line was suppressed without log_rate_limit")
+		}
+	}
+	if errs := validateLogRateLimit(&Service{Name: "web", LogRateLimit: -1}); len(errs) == 0 {
+		t.Error("a negative log_rate_limit was accepted")
+	}
+}
@@ -0,0 +1,234 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Log history settings for `go-overlay logs`
+const (
+	defaultLogBufferLines = 500 // Recent output lines kept per service (log_buffer_lines)
+	logFollowBuffer       = 256 // Lines queued per follower before it misses lines
+	logFrameLines         = 100 // Max lines sent in one frame while following
+	crashOutputLines      = 5   // Lines reported when a service fails
+	recentOutputLines     = 3   // Lines included in the service listing
+)
+
+// outputDrainTimeout bounds the wait for the last output of an exited service
+const outputDrainTimeout = 500 * time.Millisecond
+
+// logRecorder keeps the recent output of every service in a ring of
+// log_buffer_lines lines and fans new lines out to followers. History
+// survives restarts and crashes so the last lines before a failure stay
+// visible.
+type logRecorder struct {
+	mu        sync.Mutex
+	size      int            // Default ring size
+	sizes     map[string]int // Per-service ring sizes
+	history   map[string][]string
+	followers map[string]map[chan string]struct{}
+}
+
+func newLogRecorder(size int) *logRecorder {
+	return &logRecorder{
+		size:      size,
+		sizes:     make(map[string]int),
+		history:   make(map[string][]string),
+		followers: make(map[string]map[chan string]struct{}),
+	}
+}
+
+var serviceLogs = newLogRecorder(defaultLogBufferLines)
+
+// resize sets the number of lines kept for a service (the default if lines
+// <= 0), dropping the oldest lines that no longer fit
+func (r *logRecorder) resize(name string, lines int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lines <= 0 {
+		delete(r.sizes, name)
+	} else {
+		r.sizes[name] = lines
+	}
+	r.history[name] = r.trim(name, r.history[name])
+}
+
+// trim drops the oldest lines that exceed the ring size of a service
+func (r *logRecorder) trim(name string, lines []string) []string {
+	size, ok := r.sizes[name]
+	if !ok {
+		size = r.size
+	}
+	if len(lines) > size {
+		lines = lines[len(lines)-size:]
+	}
+	return lines
+}
+
+// record appends a line of output of a service. Followers that can't keep up
+// miss lines rather than slowing the service down.
+func (r *logRecorder) record(name, line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.history[name] = r.trim(name, append(r.history[name], line))
+
+	for follower := range r.followers[name] {
+		select {
+		case follower <- line:
+		default:
+		}
+	}
+}
+
+// tail returns a copy of the last n lines of a service (all of them if n <= 0)
+func (r *logRecorder) tail(name string, n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tailLocked(name, n)
+}
+
+func (r *logRecorder) tailLocked(name string, n int) []string {
+	lines := r.history[name]
+	if n > 0 && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	return append([]string(nil), lines...)
+}
+
+// follow returns the last n lines of a service and subscribes to the next
+// ones, without gap or overlap between the two. stop unsubscribes.
+func (r *logRecorder) follow(name string, n int) (history []string, lines <-chan string, stop func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	follower := make(chan string, logFollowBuffer)
+	if r.followers[name] == nil {
+		r.followers[name] = make(map[chan string]struct{})
+	}
+	r.followers[name][follower] = struct{}{}
+
+	stop = func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.followers[name], follower)
+	}
+	return r.tailLocked(name, n), follower, stop
+}
+
+// waitForOutput waits until the output of an exited service is fully read,
+// at most timeout: processes it started may keep the terminal open
+func (sp *ServiceProcess) waitForOutput(timeout time.Duration) {
+	if sp.outputDone == nil {
+		return
+	}
+	select {
+	case <-sp.outputDone:
+	case <-time.After(timeout):
+	}
+}
+
+// reportCrashOutput logs the last lines a service wrote before it failed
+func reportCrashOutput(name string) {
+	lines := serviceLogs.tail(name, crashOutputLines)
+	if len(lines) == 0 {
+		return
+	}
+	logger.Error(fmt.Sprintf("Last output of service '%s':", colorize(ColorCyan, name)))
+	for _, line := range lines {
+		logger.Error("  " + line)
+	}
+}
+
+func validateLogBufferLines(service *Service) ValidationErrors {
+	var errors ValidationErrors
+	if service.LogBufferLines < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "log_buffer_lines",
+			Service: service.Name,
+			Message: "log_buffer_lines must be a positive number of lines",
+		})
+	}
+	return errors
+}
+
+// streamServiceLogs sends the recent output of a service and, when following,
+// keeps sending new lines until the client disconnects or the daemon stops
+func streamServiceLogs(conn net.Conn, encoder *json.Encoder, cmd IPCCommand) error {
+	if _, ok := findServiceConfig(cmd.ServiceName); !ok {
+		return encoder.Encode(notFoundResponse(cmd.ServiceName))
+	}
+
+	if !cmd.Follow {
+		return encoder.Encode(IPCResponse{
+			Success: true,
+			Lines:   serviceLogs.tail(cmd.ServiceName, cmd.Tail),
+		})
+	}
+
+	history, lines, stop := serviceLogs.follow(cmd.ServiceName, cmd.Tail)
+	defer stop()
+	if err := encoder.Encode(IPCResponse{Success: true, Lines: history, More: true}); err != nil {
+		return err
+	}
+
+	// The client sends nothing after its command: a read returns once it hangs up
+	disconnected := make(chan struct{})
+	go func() {
+		_, _ = conn.Read(make([]byte, 1))
+		close(disconnected)
+	}()
+
+	return relayLogLines(lines, disconnected, func(batch []string, more bool) error {
+		return encoder.Encode(IPCResponse{Success: true, Lines: batch, More: more})
+	})
+}
+
+// relayLogLines sends followed lines in batches of up to logFrameLines until
+// done is closed or the daemon stops, which is announced with a last empty
+// batch that has more unset
+func relayLogLines(lines <-chan string, done <-chan struct{}, send func(batch []string, more bool) error) error {
+	for {
+		select {
+		case line := <-lines:
+			batch := []string{line}
+		drain:
+			for len(batch) < logFrameLines {
+				select {
+				case line := <-lines:
+					batch = append(batch, line)
+				default:
+					break drain
+				}
+			}
+			if err := send(batch, true); err != nil {
+				return err
+			}
+		case <-done:
+			return nil
+		case <-shutdownContext().Done():
+			return send(nil, false)
+		}
+	}
+}
+
+// showLogs prints the recent output of a service, then new lines as they
+// arrive when following
+func showLogs(serviceName string, lines int, follow bool) error {
+	cmd := IPCCommand{
+		Type:        CmdServiceLogs,
+		ServiceName: serviceName,
+		Tail:        lines,
+		Follow:      follow,
+	}
+	return sendIPCStream(cmd, func(frame *IPCResponse) error {
+		for _, line := range frame.Lines {
+			fmt.Println(line)
+		}
+		return nil
+	})
+}
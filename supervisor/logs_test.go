@@ -0,0 +1,204 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test the history is bounded and tails return the most recent lines
+func TestLogRecorderTail(t *testing.T) {
+	r := newLogRecorder(3)
+	for i := 1; i <= 5; i++ {
+		r.record("web", fmt.Sprintf("line %d", i))
+	}
+	r.record("db", "other")
+
+	tests := []struct {
+		name string
+		n    int
+		want []string
+	}{
+		{"All kept", 0, []string{"line 3", "line 4", "line 5"}},
+		{"Last two", 2, []string{"line 4", "line 5"}},
+		{"More than kept", 10, []string{"line 3", "line 4", "line 5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.tail("web", tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tail(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+
+	if got := r.tail("unknown", 0); len(got) != 0 {
+		t.Errorf("tail() of unknown service = %v", got)
+	}
+}
+
+// Test followers get new lines only, and never block the service
+func TestLogRecorderFollow(t *testing.T) {
+	r := newLogRecorder(10)
+	r.record("web", "before")
+
+	history, lines, stop := r.follow("web", 0)
+	if !reflect.DeepEqual(history, []string{"before"}) {
+		t.Errorf("history = %v", history)
+	}
+
+	r.record("web", "after")
+	r.record("db", "other service")
+	select {
+	case line := <-lines:
+		if line != "after" {
+			t.Errorf("followed line = %q, want after", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("followed line not delivered")
+	}
+
+	// A follower that doesn't read must not block record
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < logFollowBuffer*2; i++ {
+			r.record("web", "flood")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("record blocked on a slow follower")
+	}
+
+	stop()
+	if len(r.followers["web"]) != 0 {
+		t.Error("follower still subscribed after stop")
+	}
+}
+
+// Test logs -f streams the history, then new lines, until the client hangs up
+func TestStreamServiceLogs(t *testing.T) {
+	resetShutdown()
+	setConfig(&Config{Services: []Service{{Name: "logs-web"}}})
+	saved := serviceLogs
+	serviceLogs = newLogRecorder(10)
+	defer func() {
+		serviceLogs = saved
+		setConfig(nil)
+		cancelShutdown()
+	}()
+	serviceLogs.record("logs-web", "first")
+	serviceLogs.record("logs-web", "second")
+
+	server, client := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- streamServiceLogs(server, json.NewEncoder(server),
+			IPCCommand{Type: CmdServiceLogs, ServiceName: "logs-web", Tail: 1, Follow: true})
+	}()
+
+	decoder := json.NewDecoder(client)
+	var frame IPCResponse
+	if err := decoder.Decode(&frame); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !frame.Success || !frame.More || !reflect.DeepEqual(frame.Lines, []string{"second"}) {
+		t.Errorf("history frame = %+v", frame)
+	}
+
+	serviceLogs.record("logs-web", "third")
+	frame = IPCResponse{}
+	if err := decoder.Decode(&frame); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(frame.Lines, []string{"third"}) {
+		t.Errorf("followed frame = %+v", frame)
+	}
+
+	_ = client.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("streamServiceLogs() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("streamServiceLogs() did not return after the client hung up")
+	}
+}
+
+// Test logs of an unknown service fail
+func TestStreamServiceLogsUnknownService(t *testing.T) {
+	setConfig(&Config{})
+	defer setConfig(nil)
+
+	server, client := net.Pipe()
+	go func() {
+		_ = streamServiceLogs(server, json.NewEncoder(server),
+			IPCCommand{Type: CmdServiceLogs, ServiceName: "missing"})
+	}()
+
+	var frame IPCResponse
+	if err := json.NewDecoder(client).Decode(&frame); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if frame.Success {
+		t.Errorf("expected failure, got %+v", frame)
+	}
+}
+
+// Test log_buffer_lines resizes the ring of a single service
+func TestLogRecorderResize(t *testing.T) {
+	r := newLogRecorder(5)
+	for i := 1; i <= 5; i++ {
+		r.record("web", fmt.Sprintf("line %d", i))
+		r.record("db", fmt.Sprintf("line %d", i))
+	}
+
+	r.resize("web", 2)
+	if got := r.tail("web", 0); !reflect.DeepEqual(got, []string{"line 4", "line 5"}) {
+		t.Errorf("tail() after shrinking = %v", got)
+	}
+	r.record("web", "line 6")
+	if got := r.tail("web", 0); !reflect.DeepEqual(got, []string{"line 5", "line 6"}) {
+		t.Errorf("tail() after record = %v", got)
+	}
+	if got := r.tail("db", 0); len(got) != 5 {
+		t.Errorf("other service kept %d lines, want 5", len(got))
+	}
+
+	// Back to the default size
+	r.resize("web", 0)
+	for i := 7; i <= 10; i++ {
+		r.record("web", fmt.Sprintf("line %d", i))
+	}
+	if got := r.tail("web", 0); len(got) != 5 {
+		t.Errorf("tail() after reset kept %d lines, want 5", len(got))
+	}
+}
+
+// Test log_buffer_lines validation
+func TestValidateLogBufferLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   int
+		wantErr bool
+	}{
+		{"Default", 0, false},
+		{"Custom", 2000, false},
+		{"Negative", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateLogBufferLines(&Service{Name: "web", LogBufferLines: tt.lines})
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateLogBufferLines() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
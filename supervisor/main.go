@@ -0,0 +1,3181 @@
+package supervisor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+	"github.com/srelabz/go-overlay/client"
+)
+
+var (
+	debugMode bool
+	s6Compat  bool
+	version   = "v0.1.2"
+)
+
+// Socket path for inter-process communication
+const socketPath = client.DefaultAddress
+
+// defaultConfigFile is used unless --config or GO_OVERLAY_CONFIG is set
+const defaultConfigFile = "/services.toml"
+
+// envConfigFile overrides the default config file path
+const envConfigFile = "GO_OVERLAY_CONFIG"
+
+// Config file loaded by the daemon and re-read on SIGHUP, and the default of
+// check, diff and apply
+var daemonConfigFile = defaultConfigFile
+
+// ANSI color codes
+const (
+	ColorReset   = "\033[0m"
+	ColorRed     = "\033[31m"
+	ColorGreen   = "\033[32m"
+	ColorYellow  = "\033[33m"
+	ColorBlue    = "\033[34m"
+	ColorMagenta = "\033[35m"
+	ColorCyan    = "\033[36m"
+	ColorWhite   = "\033[37m"
+	ColorGray    = "\033[90m"
+
+	// Bold colors
+	ColorBoldRed     = "\033[1;31m"
+	ColorBoldGreen   = "\033[1;32m"
+	ColorBoldYellow  = "\033[1;33m"
+	ColorBoldBlue    = "\033[1;34m"
+	ColorBoldMagenta = "\033[1;35m"
+	ColorBoldCyan    = "\033[1;36m"
+	ColorBoldWhite   = "\033[1;37m"
+)
+
+// ServiceState represents the current state of a service
+type ServiceState = client.ServiceState
+
+// Service state constants
+const (
+	ServiceStatePending  = client.ServiceStatePending
+	ServiceStateStarting = client.ServiceStateStarting
+	ServiceStateRunning  = client.ServiceStateRunning
+	ServiceStateStopping = client.ServiceStateStopping
+	ServiceStateStopped  = client.ServiceStateStopped
+	ServiceStateFailed   = client.ServiceStateFailed
+	ServiceStateBackoff  = client.ServiceStateBackoff
+)
+
+// CommandType represents the type of IPC command
+type CommandType = client.CommandType
+
+// IPC command type constants
+const (
+	CmdListServices   = client.CmdListServices
+	CmdRestartService = client.CmdRestartService
+	CmdGetStatus      = client.CmdGetStatus
+	CmdServiceEnv     = client.CmdServiceEnv
+	CmdOperation      = client.CmdOperation
+	CmdStopServices   = client.CmdStopServices
+	CmdStartServices  = client.CmdStartServices
+	CmdUpgrade        = client.CmdUpgrade
+	CmdGetConfig      = client.CmdGetConfig
+	CmdApply          = client.CmdApply
+	CmdReloadService  = client.CmdReloadService
+	CmdServiceStats   = client.CmdServiceStats
+	CmdNotifyReady    = client.CmdNotifyReady
+	CmdServiceLogs    = client.CmdServiceLogs
+	CmdHello          = client.CmdHello
+	CmdSubscribe      = client.CmdSubscribe
+	CmdSignalService  = client.CmdSignalService
+	CmdServiceExec    = client.CmdServiceExec
+	CmdScale          = client.CmdScale
+	CmdAudit          = client.CmdAudit
+	CmdHistory        = client.CmdHistory
+)
+
+// IPCCommand represents a command sent via IPC
+type IPCCommand = client.Command
+
+// ServiceInfo contains information about a service
+type ServiceInfo = client.Service
+
+// IPCResponse represents a response to an IPC command
+type IPCResponse struct {
+	Message   string            `json:"message,omitempty"`
+	Code      ErrorCode         `json:"code,omitempty"` // Why the command failed, when known
+	Services  []ServiceInfo     `json:"services,omitempty"`
+	Env       []string          `json:"env,omitempty"`
+	Operation *OperationInfo    `json:"operation,omitempty"`
+	Results   []OperationResult `json:"results,omitempty"`
+	Config    *Config           `json:"config,omitempty"`
+	Stats     *ServiceStats     `json:"stats,omitempty"`
+	Exec      *ExecContext      `json:"exec,omitempty"`
+	Lines     []string          `json:"lines,omitempty"` // Service output lines
+	Events    []Event           `json:"events,omitempty"`
+	Audit     []AuditEntry      `json:"audit,omitempty"`   // Recent control operations
+	History   []ServiceRecord   `json:"history,omitempty"` // Restart counts, exit codes and runtime stops
+	Total     int               `json:"total,omitempty"`   // Number of items before pagination
+	Success   bool              `json:"success"`
+	More      bool              `json:"more,omitempty"` // More chunks follow on the stream
+
+	// Hello handshake: protocol version, daemon version and supported commands
+	Version       int           `json:"version,omitempty"`
+	DaemonVersion string        `json:"daemon_version,omitempty"`
+	Commands      []CommandType `json:"commands,omitempty"`
+}
+
+// Shutdown of the current run of the services, replaced by resetShutdown
+// and guarded by shutdownMu: read it with shutdownContext
+var (
+	shutdownMu                  sync.RWMutex
+	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
+	shutdownOnce                = &sync.Once{}
+	shutdownDone                = make(chan struct{}) // Closed once gracefulShutdown completed
+)
+
+// Global variables for graceful shutdown
+var (
+	activeServices = make(map[string]*ServiceProcess)
+	servicesMutex  sync.RWMutex
+	shutdownWg     sync.WaitGroup
+
+	// IPC server
+	ipcServer    net.Listener
+	globalConfig *Config
+	configMutex  sync.RWMutex // Guards globalConfig, replaced as a whole by apply/reload
+)
+
+// Timeouts contains configuration for various timeout values
+type Timeouts struct {
+	PostScript      int `toml:"post_script_timeout,omitempty"`
+	ServiceShutdown int `toml:"service_shutdown_timeout,omitempty"`
+	GlobalShutdown  int `toml:"global_shutdown_timeout,omitempty"`
+	DependencyWait  int `toml:"dependency_wait_timeout,omitempty"`
+	PreShutdown     int `toml:"pre_shutdown_timeout,omitempty"`
+	FinishScript    int `toml:"finish_script_timeout,omitempty"`
+}
+
+// DependsOnField supports both single string and array of strings
+type DependsOnField []string
+
+func (d *DependsOnField) UnmarshalTOML(data interface{}) error {
+	switch v := data.(type) {
+	case string:
+		*d = []string{v}
+	case []interface{}:
+		deps := make([]string, len(v))
+		for i, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("depends_on array must contain only strings")
+			}
+			deps[i] = str
+		}
+		*d = deps
+	default:
+		return fmt.Errorf("depends_on must be a string or array of strings")
+	}
+	return nil
+}
+
+// Support decoding a single string via encoding.TextUnmarshaler (used by toml v2 for strings)
+// Note: We avoid implementing encoding.TextUnmarshaler on DependsOnField
+// to prevent go-toml/v2 from decoding arrays element-by-element and
+// overwriting the field. UnmarshalTOML above handles both string and array.
+
+// WaitAfterField supports both int (global wait) and map (per-dependency wait)
+type WaitAfterField struct {
+	PerDep   map[string]int // Per-dependency wait times
+	Global   int            // Global wait time for all dependencies
+	IsPerDep bool           // Flag to indicate which mode is used
+}
+
+// UnmarshalTOML decodes both integer and map forms into WaitAfterField.
+func (w *WaitAfterField) UnmarshalTOML(data interface{}) error {
+	switch v := data.(type) {
+	case int64:
+		w.Global = int(v)
+		w.IsPerDep = false
+	case map[string]interface{}:
+		w.PerDep = make(map[string]int)
+		for key, val := range v {
+			intVal, ok := val.(int64)
+			if !ok {
+				return fmt.Errorf("wait_after map values must be integers")
+			}
+			w.PerDep[key] = int(intVal)
+		}
+		w.IsPerDep = true
+	default:
+		return fmt.Errorf("wait_after must be an integer or a map of dependency names to wait times")
+	}
+	return nil
+}
+
+// Note: We intentionally only keep the value-receiver implementation above,
+// since pointer receivers cannot be duplicated with the same method name.
+
+// GetWaitTime returns the wait time for a specific dependency
+func (w *WaitAfterField) GetWaitTime(depName string) int {
+	if w.IsPerDep {
+		if waitTime, exists := w.PerDep[depName]; exists {
+			return waitTime
+		}
+		return 0 // No wait if not specified in map
+	}
+	return w.Global
+}
+
+type Service struct {
+	Name        string          `toml:"name"`
+	Command     string          `toml:"command"`
+	LogFile     string          `toml:"log_file,omitempty"`
+	PreScript   string          `toml:"pre_script,omitempty"`
+	PosScript   string          `toml:"pos_script,omitempty"`
+	User        string          `toml:"user,omitempty"`
+	Group       string          `toml:"group,omitempty"` // Defaults to the primary group of user
+	Args        []string        `toml:"args"`
+	DependsOn   DependsOnField  `toml:"depends_on,omitempty"`
+	WaitAfter   *WaitAfterField `toml:"wait_after,omitempty"`
+	Enabled     *bool           `toml:"enabled,omitempty"`      // Changed to pointer to detect if set
+	Required    bool            `toml:"required,omitempty"`     // If true, failure stops whole system
+	Publish     *PublishField   `toml:"publish,omitempty"`      // Connection info exposed to dependents
+	Register    *RegisterConfig `toml:"register,omitempty"`     // Consul/etcd registration
+	WaitFor     []WaitCondition `toml:"wait_for,omitempty"`     // Preconditions checked before start
+	ControlFIFO bool            `toml:"control_fifo,omitempty"` // Expose an s6-style control FIFO
+
+	Labels map[string]string `toml:"labels,omitempty"` // Arbitrary key/values for selectors
+	Tags   []string          `toml:"tags,omitempty"`   // Groups for --tag operations
+
+	// Exit code of the supervisor when this service shuts it down (default:
+	// the exit code of the service)
+	ShutdownExitCode *int `toml:"shutdown_exit_code,omitempty"`
+
+	// Services with a higher priority are stopped first during shutdown (default 0)
+	ShutdownPriority int `toml:"shutdown_priority,omitempty"`
+	// Startup stage: a stage starts once the previous one is up or completed (default 0)
+	Stage int `toml:"stage,omitempty"`
+
+	// Recent output lines kept in memory for `logs` and crash reports (default 500)
+	LogBufferLines int `toml:"log_buffer_lines,omitempty"`
+	// Prefix output lines with the time, overriding [logging] timestamps
+	Timestamps string `toml:"timestamps,omitempty"`
+	// Also write the output to a file rotated by size and age
+	LogOutput *LogOutput `toml:"log_output,omitempty"`
+	// With pty = false the service runs with pipes instead of a PTY, so its
+	// stderr stays apart: logged at stderr_level (default warn) and written to
+	// stderr_log_output instead of log_output, if set. Unset, a PTY is used
+	// only when the supervisor's stdout is a terminal.
+	PTY             *bool      `toml:"pty,omitempty"`
+	StderrLevel     string     `toml:"stderr_level,omitempty"`
+	StderrLogOutput *LogOutput `toml:"stderr_log_output,omitempty"`
+	// Keep lines off the console: below log_level, matching none of the
+	// log_include patterns or matching one of log_exclude. log_sample keeps
+	// one in N of the excluded lines. `logs` and log_output get every line.
+	LogLevel   string   `toml:"log_level,omitempty"`
+	LogInclude []string `toml:"log_include,omitempty"`
+	LogExclude []string `toml:"log_exclude,omitempty"`
+	LogSample  int      `toml:"log_sample,omitempty"`
+	// Lines per second shown on the console; the lines past it are counted
+	// and reported as suppressed (0 = no limit)
+	LogRateLimit int `toml:"log_rate_limit,omitempty"`
+	// Join the lines of a record, such as a stack trace, before they are
+	// prefixed or encoded as JSON
+	Multiline *Multiline `toml:"multiline,omitempty"`
+
+	// Environment of the service on top of the supervisor's, or of a minimal
+	// one with clean_env; env overrides env_file
+	Env      map[string]string `toml:"env,omitempty"`
+	EnvFile  string            `toml:"env_file,omitempty"`
+	CleanEnv bool              `toml:"clean_env,omitempty"`
+
+	// Resource limits set with setrlimit before the command is executed
+	Limits *ResourceLimits `toml:"limits,omitempty"`
+	// cgroup v2 controls of the service, applied when running as root
+	Cgroup *CgroupLimits `toml:"cgroup,omitempty"`
+	// CPU and I/O scheduling priority of the service
+	Nice   int         `toml:"nice,omitempty"`
+	IONice *IOPriority `toml:"ionice,omitempty"`
+	// Files rendered from Go templates before every start
+	Templates []Template `toml:"templates,omitempty"`
+	// Files read at start and exposed as variables or files of the service
+	Secrets []Secret `toml:"secrets,omitempty"`
+
+	// How `go-overlay reload` asks the service to reload: a signal or a command
+	ReloadSignal string `toml:"reload_signal,omitempty"`
+	ReloadCmd    string `toml:"reload_cmd,omitempty"`
+
+	// Command run before the service is asked to stop, killed after
+	// pre_stop_timeout seconds
+	PreStop        string `toml:"pre_stop,omitempty"`
+	PreStopTimeout int    `toml:"pre_stop_timeout,omitempty"`
+
+	// Command run after the service process ended, whether it exited on its
+	// own or was stopped, killed after on_exit_timeout seconds
+	OnExit        string `toml:"on_exit,omitempty"`
+	OnExitTimeout int    `toml:"on_exit_timeout,omitempty"`
+
+	// Signal that asks the service to stop, escalated to SIGTERM after
+	// stop_timeout seconds (default: service_shutdown_timeout) and to SIGKILL
+	// kill_timeout seconds later
+	StopSignal  string `toml:"stop_signal,omitempty"`
+	StopTimeout int    `toml:"stop_timeout,omitempty"`
+	KillTimeout int    `toml:"kill_timeout,omitempty"`
+
+	// Restart policy after the service exits on its own (always, on-failure, never)
+	Restart           string `toml:"restart,omitempty"`
+	RestartBackoff    int    `toml:"restart_backoff,omitempty"`     // Initial delay in seconds, doubled per attempt
+	RestartBackoffMax int    `toml:"restart_backoff_max,omitempty"` // Backoff cap in seconds
+	RestartMaxRetries int    `toml:"restart_max_retries,omitempty"` // 0 = retry forever
+
+	// Crash loop breaker: stop restarting a service restarted
+	// crash_loop_restarts times within crash_loop_window seconds
+	CrashLoopRestarts int `toml:"crash_loop_restarts,omitempty"`
+	CrashLoopWindow   int `toml:"crash_loop_window,omitempty"`
+
+	// Exit codes that override the restart policy: always restart after the
+	// first, never after the second
+	RestartOnExitCodes []int `toml:"restart_on_exit_codes,omitempty"`
+	NoRestartExitCodes []int `toml:"no_restart_exit_codes,omitempty"`
+
+	// Timers: seconds to wait before the service starts at startup, and
+	// seconds of uptime after which it is restarted (0 = never)
+	StartDelay   int `toml:"start_delay,omitempty"`
+	RestartEvery int `toml:"restart_every,omitempty"`
+
+	// Instances of a service template: the service runs as name@1 to
+	// name@<instances>, with %i replaced by the number of the instance
+	Instances  int    `toml:"instances,omitempty"`
+	InstanceOf string `toml:"-"` // Name of the template of an instance
+	Instance   string `toml:"-"` // Identifier of an instance, as in GO_OVERLAY_INSTANCE
+
+	HealthCheck        *HealthCheck    `toml:"health_check,omitempty"`
+	Readiness          *ReadinessProbe `toml:"readiness,omitempty"`
+	DependsOnCondition string          `toml:"depends_on_condition,omitempty"` // Wait for dependencies to be started, healthy or ready
+
+	// Sign of life required from the running service, aborted when it misses one
+	Watchdog *Watchdog `toml:"watchdog,omitempty"`
+
+	// Notified when this service fails, besides the notifiers of the config
+	Notifiers []Notifier `toml:"notifiers,omitempty"`
+
+	// Check command and scripts exist at config time (defaults to the global setting)
+	ValidateCommands *bool `toml:"validate_commands,omitempty"`
+}
+
+type Config struct {
+	StatusDir string        `toml:"status_dir,omitempty"` // Root of the filesystem status interface
+	Services  []Service     `toml:"services"`
+	Timeouts  Timeouts      `toml:"timeouts,omitempty"`
+	Logging   LoggingConfig `toml:"logging,omitempty"`
+	API       APIConfig     `toml:"api,omitempty"`     // Optional HTTP API
+	Control   ControlConfig `toml:"control,omitempty"` // Who may run control commands
+
+	// Set to false to skip command/script existence checks for every service
+	ValidateCommands *bool `toml:"validate_commands,omitempty"`
+
+	// Scripts run in order before any service starts (like cont-init.d)
+	InitDir string `toml:"init_dir,omitempty"`
+
+	// Script run before any service is stopped during graceful shutdown
+	PreShutdownScript string `toml:"pre_shutdown_script,omitempty"`
+
+	// Scripts run in order once every service has stopped (like cont-finish.d)
+	FinishDir string `toml:"finish_dir,omitempty"`
+
+	// Append-only file recording control operations
+	AuditLog string `toml:"audit_log,omitempty"`
+
+	// Restart counts, exit codes and runtime stops kept across supervisor restarts
+	StateFile string `toml:"state_file,omitempty"`
+
+	// Notified when any service fails
+	Notifiers []Notifier `toml:"notifiers,omitempty"`
+
+	// Service whose end shuts the supervisor down, which exits with its exit code
+	ExitCodeFrom string `toml:"exit_code_from,omitempty"`
+
+	// Services starting at once at boot (0 = no limit)
+	MaxConcurrentStarts int `toml:"max_concurrent_starts,omitempty"`
+
+	// Services with instances, whose instances are in Services
+	ServiceTemplates []Service `toml:"-"`
+}
+
+// Internal raw representations to support flexible TOML decoding (go-toml/v2)
+type serviceRaw struct {
+	Name        string          `toml:"name"`
+	Command     string          `toml:"command"`
+	LogFile     string          `toml:"log_file,omitempty"`
+	PreScript   string          `toml:"pre_script,omitempty"`
+	PosScript   string          `toml:"pos_script,omitempty"`
+	User        string          `toml:"user,omitempty"`
+	Group       string          `toml:"group,omitempty"`
+	Args        []string        `toml:"args"`
+	DependsOn   interface{}     `toml:"depends_on,omitempty"`
+	WaitAfter   interface{}     `toml:"wait_after,omitempty"`
+	Enabled     *bool           `toml:"enabled,omitempty"`
+	Required    bool            `toml:"required,omitempty"`
+	Publish     *PublishField   `toml:"publish,omitempty"`
+	Register    *RegisterConfig `toml:"register,omitempty"`
+	WaitFor     []WaitCondition `toml:"wait_for,omitempty"`
+	ControlFIFO bool            `toml:"control_fifo,omitempty"`
+
+	Labels           map[string]string `toml:"labels,omitempty"`
+	Tags             []string          `toml:"tags,omitempty"`
+	ShutdownExitCode *int              `toml:"shutdown_exit_code,omitempty"`
+	ShutdownPriority int               `toml:"shutdown_priority,omitempty"`
+	Stage            int               `toml:"stage,omitempty"`
+	LogBufferLines   int               `toml:"log_buffer_lines,omitempty"`
+	Timestamps       string            `toml:"timestamps,omitempty"`
+	LogOutput        *LogOutput        `toml:"log_output,omitempty"`
+	PTY              *bool             `toml:"pty,omitempty"`
+	StderrLevel      string            `toml:"stderr_level,omitempty"`
+	StderrLogOutput  *LogOutput        `toml:"stderr_log_output,omitempty"`
+	LogLevel         string            `toml:"log_level,omitempty"`
+	LogInclude       []string          `toml:"log_include,omitempty"`
+	LogExclude       []string          `toml:"log_exclude,omitempty"`
+	LogSample        int               `toml:"log_sample,omitempty"`
+	LogRateLimit     int               `toml:"log_rate_limit,omitempty"`
+	Multiline        *Multiline        `toml:"multiline,omitempty"`
+	Env              map[string]string `toml:"env,omitempty"`
+	EnvFile          string            `toml:"env_file,omitempty"`
+	CleanEnv         bool              `toml:"clean_env,omitempty"`
+	Limits           *ResourceLimits   `toml:"limits,omitempty"`
+	Cgroup           *CgroupLimits     `toml:"cgroup,omitempty"`
+	Nice             int               `toml:"nice,omitempty"`
+	IONice           *IOPriority       `toml:"ionice,omitempty"`
+	Templates        []Template        `toml:"templates,omitempty"`
+	Secrets          []Secret          `toml:"secrets,omitempty"`
+	ReloadSignal     string            `toml:"reload_signal,omitempty"`
+	ReloadCmd        string            `toml:"reload_cmd,omitempty"`
+	PreStop          string            `toml:"pre_stop,omitempty"`
+	PreStopTimeout   int               `toml:"pre_stop_timeout,omitempty"`
+	OnExit           string            `toml:"on_exit,omitempty"`
+	OnExitTimeout    int               `toml:"on_exit_timeout,omitempty"`
+	StopSignal       string            `toml:"stop_signal,omitempty"`
+	StopTimeout      int               `toml:"stop_timeout,omitempty"`
+	KillTimeout      int               `toml:"kill_timeout,omitempty"`
+	ValidateCommands *bool             `toml:"validate_commands,omitempty"`
+
+	Restart           string `toml:"restart,omitempty"`
+	RestartBackoff    int    `toml:"restart_backoff,omitempty"`
+	RestartBackoffMax int    `toml:"restart_backoff_max,omitempty"`
+	RestartMaxRetries int    `toml:"restart_max_retries,omitempty"`
+
+	CrashLoopRestarts int `toml:"crash_loop_restarts,omitempty"`
+	CrashLoopWindow   int `toml:"crash_loop_window,omitempty"`
+
+	RestartOnExitCodes []int `toml:"restart_on_exit_codes,omitempty"`
+	NoRestartExitCodes []int `toml:"no_restart_exit_codes,omitempty"`
+
+	StartDelay   int `toml:"start_delay,omitempty"`
+	RestartEvery int `toml:"restart_every,omitempty"`
+
+	Instances *int `toml:"instances,omitempty"` // Set, even to 0, for a service template
+
+	HealthCheck        *HealthCheck    `toml:"health_check,omitempty"`
+	Readiness          *ReadinessProbe `toml:"readiness,omitempty"`
+	DependsOnCondition string          `toml:"depends_on_condition,omitempty"`
+	Watchdog           *Watchdog       `toml:"watchdog,omitempty"`
+	Notifiers          []Notifier      `toml:"notifiers,omitempty"`
+}
+
+type configRaw struct {
+	StatusDir string        `toml:"status_dir,omitempty"`
+	Services  []serviceRaw  `toml:"services"`
+	Timeouts  Timeouts      `toml:"timeouts,omitempty"`
+	Logging   LoggingConfig `toml:"logging,omitempty"`
+	API       APIConfig     `toml:"api,omitempty"`
+	Control   ControlConfig `toml:"control,omitempty"`
+
+	ValidateCommands    *bool  `toml:"validate_commands,omitempty"`
+	InitDir             string `toml:"init_dir,omitempty"`
+	PreShutdownScript   string `toml:"pre_shutdown_script,omitempty"`
+	FinishDir           string `toml:"finish_dir,omitempty"`
+	AuditLog            string `toml:"audit_log,omitempty"`
+	StateFile           string `toml:"state_file,omitempty"`
+	MaxConcurrentStarts int    `toml:"max_concurrent_starts,omitempty"`
+	ExitCodeFrom        string `toml:"exit_code_from,omitempty"`
+
+	Notifiers []Notifier `toml:"notifiers,omitempty"`
+}
+
+func parseConfig(r io.Reader) (Config, error) {
+	var raw configRaw
+	if err := toml.NewDecoder(r).Decode(&raw); err != nil {
+		return Config{}, err
+	}
+
+	// Conversion errors are collected so every bad entry is reported at once
+	var errs ConfigErrors
+
+	cfg := Config{
+		Timeouts:         raw.Timeouts,
+		StatusDir:        raw.StatusDir,
+		Logging:          raw.Logging,
+		API:              raw.API,
+		Control:          raw.Control,
+		ValidateCommands: raw.ValidateCommands,
+
+		InitDir:             raw.InitDir,
+		PreShutdownScript:   raw.PreShutdownScript,
+		FinishDir:           raw.FinishDir,
+		AuditLog:            raw.AuditLog,
+		StateFile:           raw.StateFile,
+		MaxConcurrentStarts: raw.MaxConcurrentStarts,
+		ExitCodeFrom:        raw.ExitCodeFrom,
+		Notifiers:           raw.Notifiers,
+	}
+	for i := range raw.Services {
+		sr := &raw.Services[i]
+		if sr.Name == "" {
+			// Skip stray entries (e.g., partial tables without a name)
+			continue
+		}
+		var wa *WaitAfterField
+		switch v := sr.WaitAfter.(type) {
+		case nil:
+			// leave nil
+		case int64:
+			wa = &WaitAfterField{Global: int(v), IsPerDep: false}
+		case map[string]interface{}:
+			mp := make(map[string]int)
+			for k, anyVal := range v {
+				iv, ok := anyVal.(int64)
+				if !ok {
+					errs = append(errs, serviceFieldError(i, "wait_after", "wait_after map values must be integers"))
+					continue
+				}
+				mp[k] = int(iv)
+			}
+			wa = &WaitAfterField{PerDep: mp, IsPerDep: true}
+		default:
+			errs = append(errs, serviceFieldError(i, "wait_after",
+				"wait_after must be an integer or a map of dependency names to wait times"))
+		}
+
+		// convert depends_on
+		var deps DependsOnField
+		switch dv := sr.DependsOn.(type) {
+		case nil:
+		case string:
+			deps = []string{dv}
+		case []interface{}:
+			out := make([]string, len(dv))
+			for j, item := range dv {
+				s, ok := item.(string)
+				if !ok {
+					errs = append(errs, serviceFieldError(i, "depends_on", "depends_on array must contain only strings"))
+					continue
+				}
+				out[j] = s
+			}
+			deps = out
+		default:
+			errs = append(errs, serviceFieldError(i, "depends_on", "depends_on must be a string or array of strings"))
+		}
+
+		svc := Service{
+			Name:        sr.Name,
+			Command:     sr.Command,
+			Args:        sr.Args,
+			LogFile:     sr.LogFile,
+			PreScript:   sr.PreScript,
+			PosScript:   sr.PosScript,
+			DependsOn:   deps,
+			WaitAfter:   wa,
+			Enabled:     sr.Enabled,
+			User:        sr.User,
+			Group:       sr.Group,
+			Required:    sr.Required,
+			Publish:     sr.Publish,
+			Register:    sr.Register,
+			WaitFor:     sr.WaitFor,
+			ControlFIFO: sr.ControlFIFO,
+
+			Labels:           sr.Labels,
+			Tags:             sr.Tags,
+			ShutdownExitCode: sr.ShutdownExitCode,
+			ShutdownPriority: sr.ShutdownPriority,
+			Stage:            sr.Stage,
+			LogBufferLines:   sr.LogBufferLines,
+			Timestamps:       sr.Timestamps,
+			LogOutput:        sr.LogOutput,
+			PTY:              sr.PTY,
+			StderrLevel:      sr.StderrLevel,
+			StderrLogOutput:  sr.StderrLogOutput,
+			LogLevel:         sr.LogLevel,
+			LogInclude:       sr.LogInclude,
+			LogExclude:       sr.LogExclude,
+			LogSample:        sr.LogSample,
+			LogRateLimit:     sr.LogRateLimit,
+			Multiline:        sr.Multiline,
+			Env:              sr.Env,
+			EnvFile:          sr.EnvFile,
+			CleanEnv:         sr.CleanEnv,
+			Limits:           sr.Limits,
+			Cgroup:           sr.Cgroup,
+			Nice:             sr.Nice,
+			IONice:           sr.IONice,
+			Templates:        sr.Templates,
+			Secrets:          sr.Secrets,
+			ReloadSignal:     sr.ReloadSignal,
+			ReloadCmd:        sr.ReloadCmd,
+			PreStop:          sr.PreStop,
+			PreStopTimeout:   sr.PreStopTimeout,
+			OnExit:           sr.OnExit,
+			OnExitTimeout:    sr.OnExitTimeout,
+			StopSignal:       sr.StopSignal,
+			StopTimeout:      sr.StopTimeout,
+			KillTimeout:      sr.KillTimeout,
+			ValidateCommands: sr.ValidateCommands,
+
+			Restart:           sr.Restart,
+			RestartBackoff:    sr.RestartBackoff,
+			RestartBackoffMax: sr.RestartBackoffMax,
+			RestartMaxRetries: sr.RestartMaxRetries,
+
+			CrashLoopRestarts: sr.CrashLoopRestarts,
+			CrashLoopWindow:   sr.CrashLoopWindow,
+
+			RestartOnExitCodes: sr.RestartOnExitCodes,
+			NoRestartExitCodes: sr.NoRestartExitCodes,
+
+			StartDelay:   sr.StartDelay,
+			RestartEvery: sr.RestartEvery,
+
+			HealthCheck:        sr.HealthCheck,
+			Readiness:          sr.Readiness,
+			DependsOnCondition: sr.DependsOnCondition,
+			Watchdog:           sr.Watchdog,
+			Notifiers:          sr.Notifiers,
+		}
+		errs = append(errs, expandServiceVars(i, &svc)...)
+
+		if sr.Instances != nil {
+			if *sr.Instances < 0 {
+				errs = append(errs, serviceFieldError(i, "instances", "instances must not be negative"))
+				continue
+			}
+			svc.Instances = *sr.Instances
+			cfg.ServiceTemplates = append(cfg.ServiceTemplates, svc)
+			cfg.Services = append(cfg.Services, expandInstances(&svc)...)
+			continue
+		}
+		cfg.Services = append(cfg.Services, svc)
+	}
+
+	if len(errs) > 0 {
+		return Config{}, errs
+	}
+	resolveInstanceDependencies(&cfg)
+	return cfg, nil
+}
+
+type ServiceProcess struct {
+	Name      string
+	LastError error
+	StartTime time.Time
+	Config    Service // Store original config for restart
+	Process   *exec.Cmd
+	PTY       *os.File
+	Stdout    *os.File // Output pipes without a PTY; nil on a PTY
+	Stderr    *os.File
+	Cancel    context.CancelFunc
+	StateMu   sync.RWMutex
+	State     ServiceState
+	Health    HealthState   // Guarded by StateMu
+	Ready     bool          // Passed its readiness probe; guarded by StateMu
+	Exited    chan struct{} // Closed once the process has exited and been cleaned up
+	ExitCode  int           // Set once the process has exited on its own
+	exitOnce  sync.Once
+	ctx       context.Context // Canceled to request a graceful stop
+	waitErr   chan error      // Receives the result of cmd.Wait exactly once
+
+	watchdogPing chan struct{} // Receives the WATCHDOG=1 of notify watchdogs
+	readyPipe    *os.File      // Read end of the notification fd, handed over on upgrade
+
+	outputDone chan struct{} // Closed once the PTY or pipe output is fully read; nil without either
+}
+
+// SetState updates the service state with logging
+func (sp *ServiceProcess) SetState(state ServiceState) {
+	sp.StateMu.Lock()
+	defer sp.StateMu.Unlock()
+	oldState := sp.State
+	sp.State = state
+
+	// Color-coded state transition message
+	oldStateStr := colorize(getStateColor(oldState), oldState.String())
+	newStateStr := colorize(getStateColor(state), state.String())
+	// Logged at debug: state events already record every transition
+	logger.Debug(fmt.Sprintf("Service '%s' state changed from %s to %s",
+		colorize(ColorCyan, sp.Name), oldStateStr, newStateStr))
+
+	writeServiceStatus(sp.Name, state, sp.GetPID())
+	if isReadyState(state, sp.Config.Readiness, sp.Ready) {
+		writeReadyFlag(sp.Name)
+	}
+	events.publish(Event{Type: EventState, Service: sp.Name, From: oldState.String(), To: state.String()})
+}
+
+func (sp *ServiceProcess) GetState() ServiceState {
+	sp.StateMu.RLock()
+	defer sp.StateMu.RUnlock()
+	return sp.State
+}
+
+func (sp *ServiceProcess) GetError() error {
+	sp.StateMu.RLock()
+	defer sp.StateMu.RUnlock()
+	return sp.LastError
+}
+
+func (sp *ServiceProcess) SetError(err error) {
+	sp.StateMu.Lock()
+	defer sp.StateMu.Unlock()
+	sp.LastError = err
+	if err != nil {
+		oldState := sp.State
+		sp.State = ServiceStateFailed
+		writeServiceStatus(sp.Name, ServiceStateFailed, 0)
+		events.publish(Event{Type: EventState, Service: sp.Name, From: oldState.String(), To: ServiceStateFailed.String(), Message: err.Error()})
+		// Only log error if not in test mode (when debugMode is explicitly set)
+		// In tests, this message is expected but can be noisy
+		logger.Error(fmt.Sprintf("Service '%s' failed with error: %v",
+			colorize(ColorCyan, sp.Name), err))
+	}
+}
+
+func (sp *ServiceProcess) GetPID() int {
+	if sp.Process != nil && sp.Process.Process != nil {
+		return sp.Process.Process.Pid
+	}
+	return 0
+}
+
+// ValidationError represents a configuration validation error
+type ValidationError struct {
+	Field   string
+	Service string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Service != "" {
+		return fmt.Sprintf("validation error in service '%s', field '%s': %s", e.Service, e.Field, e.Message)
+	}
+	return fmt.Sprintf("validation error in field '%s': %s", e.Field, e.Message)
+}
+
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 0 {
+		return "no validation errors"
+	}
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Auto-install function to create symlink in PATH
+func autoInstallInPath() {
+	// Get the current executable path
+	execPath, err := os.Executable()
+	if err != nil {
+		logger.Info("Warning: Could not determine executable path:", err)
+		return
+	}
+
+	// Check if we're already in a standard PATH location
+	pathDirs := []string{"/usr/local/bin", "/usr/bin", "/bin"}
+	execDir := filepath.Dir(execPath)
+
+	for _, pathDir := range pathDirs {
+		if execDir == pathDir {
+			logger.Info("Already installed in PATH:", execDir)
+			return
+		}
+	}
+
+	// Target installation path
+	targetPath := "/usr/local/bin/go-overlay"
+
+	// Check if symlink already exists and points to our executable
+	if linkTarget, err := os.Readlink(targetPath); err == nil {
+		if linkTarget == execPath {
+			return // Already correctly installed
+		}
+		// Remove existing symlink if it points somewhere else
+		_ = os.Remove(targetPath)
+	}
+
+	// Create symlink
+	if err := os.Symlink(execPath, targetPath); err != nil {
+		logger.Warn(fmt.Sprintf("Could not create symlink in PATH: %v", err))
+		logger.Warn(fmt.Sprintf("You can manually run: sudo ln -sf %s %s", execPath, targetPath))
+		return
+	}
+
+	logger.Success("Auto-installed in PATH as 'go-overlay'")
+	logger.Info("You can now use: go-overlay list, go-overlay restart <service>, etc.")
+}
+
+// Main runs the go-overlay command line with the arguments of the process:
+// the daemon, or a client command. It exits the process.
+func Main() {
+	RunHelper()
+
+	if value := os.Getenv(envLogFormat); value != "" {
+		if err := (logFormatFlag{}).Set(value); err != nil {
+			logger.Error(fmt.Sprintf("Error: %s: %v", envLogFormat, err))
+			os.Exit(1)
+		}
+	}
+
+	rootCmd := &cobra.Command{
+		Use:   "go-overlay",
+		Short: "Go-based service supervisor like s6-overlay",
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {
+			// The reaper already printed the banner for its supervisor child
+			if logEnabled(LogLevelInfo) && os.Getenv(envReaperChild) == "" {
+				if logFormat == LogFormatJSON {
+					logger.Print("Go Overlay - Version: ", version)
+				} else {
+					fmt.Printf("Go Overlay - Version: %s\n", version)
+				}
+			}
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runDaemon()
+		},
+	}
+
+	// Run command: supervise the services and a CMD that ends the container
+	runCmd := &cobra.Command{
+		Use:   "run [--] <command> [args...]",
+		Short: "Start the services, then run a command and shut down when it exits (like a container CMD)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			passthroughCmd = args
+			return runDaemon()
+		},
+	}
+	runCmd.Flags().SetInterspersed(false)
+
+	// List services command
+	var listOpts listOptions
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all services and their status",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return listServices(listOpts)
+		},
+	}
+	listCmd.Flags().StringVar(&listOpts.Sort, "sort", SortByName, "Sort by name, state, uptime, cpu, memory or restarts")
+	listCmd.Flags().StringArrayVar(&listOpts.Filters, "filter", nil, "Filter services, e.g. state=FAILED, name='web-*', tag=batch, required=true, health=unhealthy (repeatable)")
+	listCmd.Flags().StringVarP(&listOpts.Selector, "selector", "l", "", "Only services matching labels (key=value,...)")
+	listCmd.Flags().IntVar(&listOpts.Offset, "offset", 0, "Skip the first N services (by name)")
+	listCmd.Flags().IntVar(&listOpts.Limit, "limit", 0, "Show at most N services (0 = all)")
+
+	// Restart service command
+	var restartWait bool
+	var restartSel bulkSelection
+	restartCmd := &cobra.Command{
+		Use:   "restart [service-name|pattern]",
+		Short: "Restart a service, services matching a glob pattern or selector, or --all",
+		Args:  bulkArgs(&restartSel),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if restartSel.isSet() || isGlobPattern(args[0]) {
+				return runBulkCommand(ActionRestart, firstArg(args), restartSel)
+			}
+			return restartService(args[0], restartWait)
+		},
+	}
+	restartCmd.Flags().BoolVar(&restartWait, "wait", false, "Wait for the restart to complete and show progress")
+	restartSel.addFlags(restartCmd, "Restart")
+
+	// Reload service command
+	reloadCmd := &cobra.Command{
+		Use:   "reload <service-name>",
+		Short: "Ask a service to reload its configuration (reload_signal or reload_cmd)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return requestReload(args[0])
+		},
+	}
+
+	// Signal command
+	var signalGroup bool
+	signalCmd := &cobra.Command{
+		Use:   "signal <service-name> <signal>",
+		Short: "Send a signal (HUP, USR1, 10...) to a service",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return requestSignal(args[0], args[1], signalGroup)
+		},
+	}
+	signalCmd.Flags().BoolVar(&signalGroup, "group", false, "Signal every process of the service, not only its main process")
+
+	// Logs command
+	var logsLines int
+	var logsFollow bool
+	logsCmd := &cobra.Command{
+		Use:              "logs <service-name>",
+		Short:            "Show the recent output of a service (-f to follow)",
+		Args:             cobra.ExactArgs(1),
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return showLogs(args[0], logsLines, logsFollow)
+		},
+	}
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 100, "Number of recent lines to show (0 = all kept)")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new lines as the service writes them")
+
+	// Events command
+	eventsCmd := &cobra.Command{
+		Use:              "events [service-name]",
+		Short:            "Stream service lifecycle events as JSON lines",
+		Args:             cobra.MaximumNArgs(1),
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return showEvents(firstArg(args))
+		},
+	}
+
+	// Stop service command
+	var stopSel bulkSelection
+	stopCmd := &cobra.Command{
+		Use:   "stop [service-name|pattern]",
+		Short: "Stop a service, services matching a glob pattern or selector, or --all",
+		Args:  bulkArgs(&stopSel),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return runBulkCommand(ActionStop, firstArg(args), stopSel)
+		},
+	}
+	stopSel.addFlags(stopCmd, "Stop")
+
+	// Start service command
+	var startSel bulkSelection
+	startCmd := &cobra.Command{
+		Use:   "start [service-name|pattern]",
+		Short: "Start a stopped service, services matching a glob pattern or selector, or --all",
+		Args:  bulkArgs(&startSel),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return runBulkCommand(ActionStart, firstArg(args), startSel)
+		},
+	}
+	startSel.addFlags(startCmd, "Start")
+
+	// Status command
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show overall system status",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return showStatus()
+		},
+	}
+
+	// Stats command - resource usage history of a service
+	var statsWatch bool
+	statsCmd := &cobra.Command{
+		Use:   "stats <service-name>",
+		Short: "Show recent CPU, memory and disk I/O of a service (min/avg/max)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return showStats(args[0], statsWatch)
+		},
+	}
+	statsCmd.Flags().BoolVarP(&statsWatch, "watch", "w", false, "Refresh the summary every sampling interval")
+
+	// Notify-ready command - called by a service once it is ready
+	notifyReadyCmd := &cobra.Command{
+		Use:   "notify-ready [service-name]",
+		Short: "Mark a service with readiness.notify as ready (defaults to $GO_OVERLAY_SERVICE)",
+		Args:  cobra.MaximumNArgs(1),
+		// No banner: called from inside services
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return notifyReady(firstArg(args))
+		},
+	}
+
+	// Check command - validate a config file without starting services
+	var checkRootfs string
+	var checkLint bool
+	checkCmd := &cobra.Command{
+		Use:     "check [config-file]",
+		Aliases: []string{"validate"},
+		Short:   "Validate a services configuration file without starting anything",
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Problems with the file, not the invocation: skip the usage text
+			cmd.SilenceUsage = true
+			configFile := daemonConfigFile
+			if len(args) > 0 {
+				configFile = args[0]
+			}
+			return checkConfig(configFile, checkRootfs, checkLint)
+		},
+	}
+	checkCmd.Flags().BoolVar(&checkLint, "lint", false, "Also report warnings and fail if any are found")
+	checkCmd.Flags().StringVar(&checkRootfs, "rootfs", "", "Validate commands, scripts, users and log directories against this image root")
+
+	// Describe command - show the definition and state of one service
+	describeCmd := &cobra.Command{
+		Use:   "describe <service-name>",
+		Short: "Show the configuration and state of a service",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return describeService(args[0])
+		},
+	}
+
+	// Diff command - preview what a config file would change in the daemon
+	diffCmd := &cobra.Command{
+		Use:   "diff [config-file]",
+		Short: "Show how a config file differs from the running services",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			configFile := daemonConfigFile
+			if len(args) > 0 {
+				configFile = args[0]
+			}
+			return diffConfig(configFile)
+		},
+	}
+
+	// Apply command - reconcile the daemon with a config file
+	applyCmd := &cobra.Command{
+		Use:   "apply [config-file]",
+		Short: "Apply a config file to the running daemon, touching only changed services",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			configFile := daemonConfigFile
+			if len(args) > 0 {
+				configFile = args[0]
+			}
+			return applyConfigFile(configFile)
+		},
+	}
+
+	// Export command - serialize daemon state
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export daemon state",
+		// No banner: the output is meant to be redirected to a file
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+	}
+	var exportOutput string
+	exportConfigCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Print the daemon's effective configuration as services.toml",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return exportConfig(exportOutput)
+		},
+	}
+	exportConfigCmd.Flags().StringVarP(&exportOutput, "output", "o", "-", "Write to this file instead of stdout")
+	exportCmd.AddCommand(exportConfigCmd)
+
+	// Config command - inspect a config file without the daemon
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect a services configuration",
+		// No banner: the output is meant to be read by tools
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+	}
+	var dumpFormat string
+	configDumpCmd := &cobra.Command{
+		Use:   "dump [config-file]",
+		Short: "Print the resolved configuration with defaults filled in and variables expanded",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			configFile := daemonConfigFile
+			if len(args) > 0 {
+				configFile = args[0]
+			}
+			return dumpConfig(configFile, dumpFormat)
+		},
+	}
+	configDumpCmd.Flags().StringVar(&dumpFormat, "format", DumpFormatTOML, "Output format: toml or json")
+	configCmd.AddCommand(configDumpCmd)
+
+	// Graph command - render the dependency graph of a config file
+	var graphFormat string
+	graphCmd := &cobra.Command{
+		Use:   "graph [config-file]",
+		Short: "Print the service dependency graph in DOT or Mermaid format",
+		Args:  cobra.MaximumNArgs(1),
+		// No banner: the output is meant to be piped to a renderer
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			configFile := daemonConfigFile
+			if len(args) > 0 {
+				configFile = args[0]
+			}
+			return showGraph(configFile, graphFormat)
+		},
+	}
+	graphCmd.Flags().StringVar(&graphFormat, "format", GraphFormatDOT, "Output format: dot or mermaid")
+
+	// Import commands - convert the config of another supervisor
+	var importOutput string
+	importS6Cmd := &cobra.Command{
+		Use:   "import-s6 [services-dir]",
+		Short: "Convert s6-overlay service directories (" + defaultS6ServicesDir + " or s6-rc.d) into a services.toml",
+		Args:  cobra.MaximumNArgs(1),
+		// No banner: the output is meant to be redirected to a file
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			dir := defaultS6ServicesDir
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			return importS6(dir, importOutput)
+		},
+	}
+	importS6Cmd.Flags().StringVarP(&importOutput, "output", "o", "-", "Write to this file instead of stdout")
+	importComposeCmd := &cobra.Command{
+		Use:              "import-compose <compose-file>",
+		Short:            "Convert the services of a Docker Compose file into a services.toml",
+		Args:             cobra.ExactArgs(1),
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return importCompose(args[0], importOutput)
+		},
+	}
+	importComposeCmd.Flags().StringVarP(&importOutput, "output", "o", "-", "Write to this file instead of stdout")
+	importSystemdCmd := &cobra.Command{
+		Use:              "import-systemd <unit-file>...",
+		Short:            "Convert systemd .service unit files into a services.toml",
+		Args:             cobra.MinimumNArgs(1),
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return importSystemd(args, importOutput)
+		},
+	}
+	importSystemdCmd.Flags().StringVarP(&importOutput, "output", "o", "-", "Write to this file instead of stdout")
+	importSupervisordCmd := &cobra.Command{
+		Use:              "import-supervisord <supervisord.conf>",
+		Short:            "Convert the [program:x] sections of a supervisord config into a services.toml",
+		Args:             cobra.ExactArgs(1),
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return importSupervisord(args[0], importOutput)
+		},
+	}
+	importSupervisordCmd.Flags().StringVarP(&importOutput, "output", "o", "-", "Write to this file instead of stdout")
+
+	// Schema command - JSON Schema of services.toml for editors and CI
+	var schemaOutput string
+	schemaCmd := &cobra.Command{
+		Use:              "schema",
+		Short:            "Print the JSON Schema of services.toml",
+		Args:             cobra.NoArgs,
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cmd.SilenceUsage = true
+			return writeSchema(schemaOutput)
+		},
+	}
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "-", "Write to this file instead of stdout")
+
+	// Install command - manual installation
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install go-overlay in system PATH",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			autoInstallInPath()
+			return nil
+		},
+	}
+
+	// Upgrade command - re-exec the daemon with a new binary
+	var upgradeBinary string
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Re-exec the supervisor with a new binary without restarting services",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return upgradeSupervisor(upgradeBinary)
+		},
+	}
+	upgradeCmd.Flags().StringVar(&upgradeBinary, "binary", "", "Path of the new go-overlay binary (default: the daemon's executable)")
+
+	// With-env command - exec a command with the container environment
+	var withEnvService, withEnvDir string
+	withEnvCmd := &cobra.Command{
+		Use:   "with-env [--service name] -- command [args...]",
+		Short: "Run a command with the saved container environment",
+		Args:  cobra.MinimumNArgs(1),
+		// No banner: the output belongs to the exec'd command
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return withEnv(withEnvDir, withEnvService, args)
+		},
+	}
+	withEnvCmd.Flags().StringVar(&withEnvService, "service", "", "Also apply the resolved environment of this service")
+	withEnvCmd.Flags().StringVar(&withEnvDir, "env-dir", containerEnvDir(), "Directory holding the saved container environment")
+	withEnvCmd.Flags().SetInterspersed(false)
+
+	// Exec command - run a command the way a service runs
+	execCmd := &cobra.Command{
+		Use:   "exec <service> -- command [args...]",
+		Short: "Run a command with the environment, user, directory and cgroup of a service",
+		Args:  cobra.MinimumNArgs(2),
+		// No banner: the output belongs to the exec'd command
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(_ *cobra.Command, args []string) error {
+			command := args[1:]
+			if command[0] == "--" {
+				command = command[1:]
+			}
+			if len(command) == 0 {
+				return fmt.Errorf("missing command")
+			}
+			return execInService(args[0], command)
+		},
+	}
+	execCmd.Flags().SetInterspersed(false)
+
+	// Scale command - change the number of instances of a service template
+	scaleCmd := &cobra.Command{
+		Use:   "scale <service-name> <instances>",
+		Short: "Start or stop instances of a service template to run the given number",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return requestScale(args[0], args[1])
+		},
+	}
+
+	// Audit command - show recent control operations
+	var auditLines int
+	var auditJSON bool
+	auditCmd := &cobra.Command{
+		Use:              "audit",
+		Short:            "Show recent control operations: who ran them, on what, and the result",
+		Args:             cobra.NoArgs,
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return showAudit(auditLines, auditJSON)
+		},
+	}
+	auditCmd.Flags().IntVarP(&auditLines, "lines", "n", 20, "Number of recent entries to show (0 = all kept)")
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false, "Print entries as JSON lines")
+
+	// History command - restart counts, exit codes and lifecycle events
+	var historyJSON bool
+	historyCmd := &cobra.Command{
+		Use:              "history [service]",
+		Short:            "Show the restart counts and last exit codes of services, or the starts, exits and stops of one",
+		Args:             cobra.MaximumNArgs(1),
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return showHistory(name, historyJSON)
+		},
+	}
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the records as JSON")
+
+	// Add flags
+	rootCmd.PersistentFlags().BoolVar(&waitForDaemon, "wait-for-daemon", false,
+		"Client commands: retry connecting until the daemon socket is up")
+	rootCmd.PersistentFlags().DurationVar(&daemonWaitTimeout, "daemon-timeout", defaultDaemonWaitTimeout,
+		"Client commands: how long --wait-for-daemon keeps retrying")
+	rootCmd.PersistentFlags().Var(logLevelFlag{}, "log-level", "Supervisor log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().Var(logFormatFlag{}, "log-format", "Log format: text or json (default from "+envLogFormat+")")
+	rootCmd.PersistentFlags().BoolVarP(&quietMode, "quiet", "q", false, "Only print supervisor errors (no banner or progress messages)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false,
+		"Disable colors (also with "+envNoColor+" or when stdout isn't a terminal)")
+	rootCmd.PersistentFlags().StringVarP(&daemonConfigFile, "config", "c", configFileDefault(),
+		"Config file of the daemon, and default of check, diff and apply (default from "+envConfigFile+")")
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", os.Getenv("GO_OVERLAY_CONFIG_DIR"),
+		"Directory of *.toml files adding services to the config file (default from GO_OVERLAY_CONFIG_DIR)")
+	rootCmd.Flags().BoolVar(&debugMode, "debug", false, "Enable debug mode (implies --log-level debug)")
+	cobra.OnInitialize(applyLogFlags, applyColorFlags)
+	rootCmd.Flags().BoolVar(&s6Compat, "s6-compat", os.Getenv("GO_OVERLAY_S6_COMPAT") != "",
+		"Enable s6-overlay compatibility (import /run/s6/container_environment)")
+	rootCmd.Flags().BoolVar(&noReap, "no-reap", os.Getenv("GO_OVERLAY_NO_REAP") != "",
+		"Don't reap orphaned processes when running as PID 1")
+	rootCmd.Flags().BoolVar(&noIPC, "no-ipc", os.Getenv("GO_OVERLAY_NO_IPC") != "",
+		"Don't open the control socket: client commands can't reach the daemon")
+	runCmd.Flags().AddFlagSet(rootCmd.Flags())
+
+	// Add subcommands
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(reloadCmd)
+	rootCmd.AddCommand(signalCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(eventsCmd)
+	rootCmd.AddCommand(notifyReadyCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(describeCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(scaleCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(importS6Cmd)
+	rootCmd.AddCommand(importComposeCmd)
+	rootCmd.AddCommand(importSystemdCmd)
+	rootCmd.AddCommand(importSupervisordCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(installCmd)
+	rootCmd.AddCommand(withEnvCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(upgradeCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		logger.Error("Error: ", err)
+		flushLogs()
+		os.Exit(cliExitCode(err))
+	}
+}
+
+// runDaemon starts the supervisor: it loads the config, starts every service
+// and only returns on failure, exiting once the services have shut down
+func runDaemon() error {
+	// As PID 1, reap zombies and supervise from a child process
+	if shouldReap() {
+		return runReaper()
+	}
+
+	if debugMode {
+		_printEnvVariables()
+	}
+
+	// Auto-install in PATH for easier CLI usage
+	autoInstallInPath()
+
+	if s6Compat {
+		importS6Environment(s6ContainerEnvDir)
+	}
+
+	// Initialize shutdown context
+	resetShutdown()
+
+	// Setup signal handler
+	setupSignalHandler()
+
+	// Pick up services handed over by a previous supervisor (upgrade)
+	loadUpgradeState()
+
+	if err := loadServices(daemonConfigFile); err != nil {
+		return err
+	}
+	os.Exit(supervisorExitCode())
+	return nil
+}
+
+func setupSignalHandler() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigChan
+		logger.Info("Received signal:", sig)
+		logger.Info("Initiating graceful shutdown...")
+		gracefulShutdown()
+		os.Exit(supervisorExitCode())
+	}()
+
+	// SIGHUP re-reads the config file and applies the differences
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			logger.Info("Received SIGHUP, reloading configuration...")
+			reloadConfigFile(daemonConfigFile)
+		}
+	}()
+
+	// SIGUSR2 re-execs the supervisor binary in place (self-upgrade)
+	upgradeChan := make(chan os.Signal, 1)
+	signal.Notify(upgradeChan, syscall.SIGUSR2)
+	go func() {
+		for range upgradeChan {
+			logger.Info("Received SIGUSR2, upgrading supervisor...")
+			if err := performUpgrade(""); err != nil {
+				logger.Error(fmt.Sprintf("Supervisor upgrade failed: %v", err))
+			}
+		}
+	}()
+}
+
+// gracefulShutdown stops every service and closes shutdownDone. Only the
+// first call shuts down; the others wait for it to complete.
+func gracefulShutdown() {
+	shutdownMu.RLock()
+	once, done := shutdownOnce, shutdownDone
+	shutdownMu.RUnlock()
+
+	once.Do(func() {
+		shutdownServices()
+		close(done)
+	})
+}
+
+// shutdownContext returns the context canceled once the services shut down
+func shutdownContext() context.Context {
+	shutdownMu.RLock()
+	defer shutdownMu.RUnlock()
+	return shutdownCtx
+}
+
+// cancelShutdown cancels the shutdown context, telling every service and
+// background task to stop
+func cancelShutdown() {
+	shutdownMu.RLock()
+	cancel := shutdownCancel
+	shutdownMu.RUnlock()
+	cancel()
+}
+
+// shutdownCompleted returns the channel closed once gracefulShutdown completed
+func shutdownCompleted() <-chan struct{} {
+	shutdownMu.RLock()
+	defer shutdownMu.RUnlock()
+	return shutdownDone
+}
+
+// resetShutdown arms the shutdown for a new run of the services, after an
+// earlier Supervisor of the process stopped
+func resetShutdown() {
+	shutdownMu.Lock()
+	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
+	shutdownOnce = &sync.Once{}
+	shutdownDone = make(chan struct{})
+	shutdownMu.Unlock()
+
+	shutdownExit.mu.Lock()
+	shutdownExit.code = nil
+	shutdownExit.mu.Unlock()
+}
+
+func shutdownServices() {
+	// Both the early and the regular exit leave no buffered log lines behind
+	defer flushLogs()
+	logger.Info("Starting graceful shutdown process...")
+
+	// Print current service statuses only if we have active services
+	if activeServiceCount() > 0 {
+		printServiceStatuses()
+	}
+
+	// Runs before any service receives its stop signal
+	runPreShutdownScript()
+
+	globalTimeout := globalShutdownTimeout()
+	deadline := time.Now().Add(globalTimeout)
+
+	// Services with a higher shutdown_priority are stopped first, in waves
+	stopShutdownWaves(deadline)
+
+	// Cancel the shutdown context to signal all services to stop
+	cancelShutdown()
+
+	// Close IPC server
+	closeIPCServer()
+
+	// Remove socket file
+	if isSocketFile(controlAddress) {
+		_ = os.Remove(controlAddress)
+	}
+
+	// If no active services, we can exit early
+	if activeServiceCount() == 0 {
+		logger.Info("No active services to shutdown")
+		runFinishScripts()
+		flushNotifications()
+		return
+	}
+
+	shutdownTimer := time.NewTimer(time.Until(deadline))
+	defer shutdownTimer.Stop()
+
+	// Channel to signal when all services have stopped
+	done := make(chan struct{})
+
+	go func() {
+		shutdownWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("All services stopped gracefully")
+	case <-shutdownTimer.C:
+		logger.Info("Shutdown timeout reached after", globalTimeout, ", forcing termination...")
+		forceKillAllServices()
+		// Give a bit more time for force kill to complete
+		select {
+		case <-done:
+			logger.Info("All services stopped after force kill")
+		case <-time.After(5 * time.Second):
+			logger.Info("Some services may still be running after force kill timeout")
+		}
+	}
+
+	// Runs once every service has stopped, before the supervisor exits
+	runFinishScripts()
+	flushNotifications()
+	logger.Info("Graceful shutdown completed")
+}
+
+func forceKillAllServices() {
+	servicesMutex.RLock()
+	defer servicesMutex.RUnlock()
+
+	for name, serviceProc := range activeServices {
+		if serviceProc.Process != nil && serviceProc.Process.Process != nil {
+			logger.Info("Force killing service:", name)
+			if err := signalProcessGroup(serviceProc.Process.Process.Pid, syscall.SIGKILL); err != nil {
+				logger.Info("Error force killing service", name, ":", err)
+			}
+		}
+	}
+}
+
+func addActiveService(name string, serviceProc *ServiceProcess) {
+	servicesMutex.Lock()
+	defer servicesMutex.Unlock()
+	serviceProc.SetState(ServiceStateStarting)
+	serviceProc.StartTime = time.Now()
+	activeServices[name] = serviceProc
+	shutdownWg.Add(1)
+}
+
+// activeServiceCount returns how many services are active
+func activeServiceCount() int {
+	servicesMutex.RLock()
+	defer servicesMutex.RUnlock()
+	return len(activeServices)
+}
+
+// removeActiveService unregisters serviceProc if it is still the active
+// process for name (a restart may already have replaced it).
+func removeActiveService(name string, serviceProc *ServiceProcess) {
+	servicesMutex.Lock()
+	defer servicesMutex.Unlock()
+
+	if current, exists := activeServices[name]; exists && current == serviceProc {
+		serviceProc.SetState(ServiceStateStopped)
+		serviceProc.closeOutput()
+		delete(activeServices, name)
+		shutdownWg.Done()
+	}
+
+	if serviceProc.Exited != nil {
+		serviceProc.exitOnce.Do(func() { close(serviceProc.Exited) })
+	}
+}
+
+// loadServices loads the config file and runs its services until the
+// supervisor shuts down
+func loadServices(configFile string) error {
+	config, err := loadAndValidateConfig(configFile)
+	if err != nil {
+		return err
+	}
+	if err := prepareServices(&config); err != nil {
+		return err
+	}
+	return startAllServices(config)
+}
+
+// prepareServices publishes a validated config and sets up what its services
+// need before they start: the control socket, logs, state, status directory,
+// HTTP API and init scripts
+func prepareServices(config *Config) error {
+	if inheritedState != nil && config.StatusDir == "" {
+		config.StatusDir = inheritedState.StatusDir
+	}
+	setConfig(config)
+	if config.Control.Listen != "" {
+		controlAddress = config.Control.Listen
+	}
+	if noIPC {
+		logger.Info("IPC server disabled (--no-ipc)")
+	} else if err := startIPCServer(controlAddress); err != nil {
+		logger.Info("Warning: Could not start IPC server:", err)
+	}
+	openControlSocket(controlAddress, config.Control)
+	if config.AuditLog != "" {
+		if err := controlAudit.open(rootPath(config.AuditLog)); err != nil {
+			return fmt.Errorf("could not open audit log: %w", err)
+		}
+	}
+	if config.StateFile != "" {
+		openStateFile(rootPath(config.StateFile))
+	}
+	startLogPipeline(config.Logging)
+	startStatsSampler()
+	printLintWarnings(lintConfig(config))
+	initStatusDir(config.StatusDir, config.Services)
+	if statusDir != "" {
+		if err := saveContainerEnvironment(containerEnvDir(), baseEnvironment()); err != nil {
+			logger.Warn(fmt.Sprintf("Could not save container environment: %v", err))
+		}
+	}
+	startControlFIFOs(config.Services)
+	if config.API.Listen != "" {
+		if err := startAPIServer(config.API); err != nil {
+			logger.Warn("Could not start HTTP API: ", err)
+		}
+	}
+	// Init scripts already ran before an upgrade handed the services over
+	if inheritedState == nil {
+		if err := runInitScripts(config); err != nil {
+			return err
+		}
+	}
+	adoptInheritedServices(*config)
+	return nil
+}
+
+func loadAndValidateConfig(configFile string) (Config, error) {
+	logger.Info(fmt.Sprintf("Loading services from %s", colorize(ColorCyan, configSources(configFile, configDir))))
+
+	var config Config
+	var err error
+	if len(passthroughCmd) > 0 {
+		config, err = loadPassthroughConfig(configFile, configDir)
+		if err == nil {
+			err = addPassthroughService(&config, passthroughCmd)
+		}
+	} else {
+		config, err = parseConfigSources(configFile, configDir)
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := validateConfig(&config); err != nil {
+		return Config{}, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	logger.Success("Configuration validated successfully")
+	logger.Info(fmt.Sprintf("Timeouts configured: PostScript=%ds, ServiceShutdown=%ds, GlobalShutdown=%ds",
+		config.Timeouts.PostScript,
+		config.Timeouts.ServiceShutdown,
+		config.Timeouts.GlobalShutdown))
+
+	return config, nil
+}
+
+func startAllServices(config Config) error {
+	startedServices := make(map[string]bool)
+	var mu sync.Mutex
+	maxLength := getLongestServiceNameLength(config.Services)
+	slots := newStartLimiter(config.MaxConcurrentStarts)
+
+	// Each stage starts once every service of the previous one is up or completed
+	stages, groups := serviceStages(config.Services)
+
+	var wg sync.WaitGroup
+	for n, stage := range stages {
+		var runs []*stageRun
+		for _, service := range groups[stage] {
+			if service.Enabled != nil && !*service.Enabled {
+				logger.Info("Service ", service.Name, " is disabled, skipping")
+				continue
+			}
+			if isStoppedByHand(service.Name) {
+				logger.Info(fmt.Sprintf("Service '%s' was stopped by hand before the supervisor restarted, leaving it stopped",
+					colorize(ColorCyan, service.Name)))
+				continue
+			}
+
+			// Already running (adopted from a previous supervisor after an upgrade)
+			if _, running := getActiveService(service.Name); running {
+				mu.Lock()
+				startedServices[service.Name] = true
+				mu.Unlock()
+				continue
+			}
+
+			run := &stageRun{service: service, done: make(chan struct{})}
+			runs = append(runs, run)
+
+			wg.Add(1)
+			go func(run *stageRun, timeouts Timeouts) {
+				defer wg.Done()
+				defer close(run.done)
+				run.ok = processService(run.service, &mu, startedServices, maxLength, timeouts, slots)
+			}(run, config.Timeouts)
+		}
+
+		if n < len(stages)-1 {
+			timeout := time.Duration(config.Timeouts.DependencyWait) * time.Second
+			if !waitForStage(stage, runs, timeout) {
+				logger.Warn(fmt.Sprintf("Not starting stages after stage %d", stage))
+				// The supervisor would otherwise wait for a service that never starts
+				if service := exitCodeFromService(&config); service != nil && service.Stage > stage {
+					shutdownForService(service, fmt.Errorf("stage %d failed", stage))
+				}
+				break
+			}
+		}
+	}
+
+	wg.Wait()
+	printServiceStatuses()
+
+	<-shutdownContext().Done()
+	logger.Info("Shutdown signal received, stopping all services...")
+	<-shutdownCompleted()
+	return nil
+}
+
+// processService starts a service once its conditions and dependencies are
+// met and supervises it until it exits. It reports whether the service
+// started and exited cleanly.
+func processService(s *Service, mu *sync.Mutex, startedServices map[string]bool, maxLength int, timeouts Timeouts, slots startLimiter) bool {
+	if shutdownContext().Err() != nil {
+		logger.Warn(fmt.Sprintf("Shutdown signal received, skipping service: %s", colorize(ColorCyan, s.Name)))
+		return false
+	}
+
+	if err := waitForConditions(s, timeouts); err != nil {
+		handleServiceError(s, err)
+		return false
+	}
+
+	if !slots.acquire() {
+		return false
+	}
+	preScriptOK := runPreScript(s)
+	slots.release()
+	if !preScriptOK {
+		return false
+	}
+
+	if !waitForServiceDependencies(s, mu, startedServices, timeouts) {
+		return false
+	}
+	if !delayStart(s) {
+		return false
+	}
+
+	if !slots.acquire() {
+		return false
+	}
+	serviceDone := make(chan error, 1)
+	go func() {
+		err := startServiceWithPTY(*s, maxLength, timeouts, slots.release)
+		serviceDone <- err
+	}()
+
+	mu.Lock()
+	startedServices[s.Name] = true
+	mu.Unlock()
+
+	postScriptDone := make(chan struct{})
+	go runPostScript(s, timeouts.PostScript, postScriptDone)
+
+	err := <-serviceDone
+	if err != nil {
+		handleServiceError(s, err)
+	}
+
+	<-postScriptDone
+	return err == nil
+}
+
+func runPreScript(s *Service) bool {
+	if s.PreScript == "" {
+		return true
+	}
+
+	logger.Info("| === PRE-SCRIPT START --- [SERVICE: ", s.Name, "] === |")
+
+	if err := os.Chmod(s.PreScript, 0o700); err != nil { // #nosec G302 - execution permission required
+		logger.Info("[PRE-SCRIPT ERROR] Error setting execute permission for script ", s.PreScript, ": ", err)
+		return false
+	}
+
+	if err := runScript(s.PreScript, buildServiceEnv(s)); err != nil {
+		logger.Info("[PRE-SCRIPT ERROR] Error executing pre-script for service ", s.Name, ": ", err)
+		if s.Required {
+			logger.Info("[CRITICAL] Required service ", s.Name, " pre-script failed, initiating shutdown")
+			alert(Event{Type: EventShutdown, Service: s.Name, Message: "pre_script failed: " + err.Error()})
+			shutdownForService(s, err)
+		}
+		return false
+	}
+
+	logger.Info("| === PRE-SCRIPT END --- [SERVICE: ", s.Name, "] === |")
+	return true
+}
+
+func waitForServiceDependencies(s *Service, mu *sync.Mutex, startedServices map[string]bool, timeouts Timeouts) bool {
+	if len(s.DependsOn) == 0 {
+		return true
+	}
+
+	logger.Info(fmt.Sprintf("Service '%s' waiting for dependencies: %s",
+		colorize(ColorCyan, s.Name),
+		colorize(ColorYellow, strings.Join(s.DependsOn, ", "))))
+
+	for _, dep := range s.DependsOn {
+		waitTime := 0
+		if s.WaitAfter != nil {
+			waitTime = s.WaitAfter.GetWaitTime(dep)
+		}
+		if !waitForDependency(dep, waitTime, mu, startedServices, timeouts.DependencyWait, s.DependsOnCondition) {
+			logger.Warn(fmt.Sprintf("Dependency wait canceled for service: %s", colorize(ColorCyan, s.Name)))
+			return false
+		}
+	}
+	return true
+}
+
+func runPostScript(s *Service, postScriptTimeout int, done chan<- struct{}) {
+	defer close(done)
+
+	timeout := time.Duration(postScriptTimeout) * time.Second
+	select {
+	case <-time.After(timeout):
+	case <-shutdownContext().Done():
+		return
+	}
+
+	if s.PosScript == "" {
+		return
+	}
+
+	logger.Info("| === POST-SCRIPT START --- [SERVICE: ", s.Name, "] === |")
+
+	if err := os.Chmod(s.PosScript, 0o700); err != nil { // #nosec G302 - execution permission required
+		logger.Info("[POST-SCRIPT ERROR] Error setting execute permission for script ", s.PosScript, ": ", err)
+		return
+	}
+
+	if err := runScript(s.PosScript, buildServiceEnv(s)); err != nil {
+		logger.Info("[POST-SCRIPT ERROR] Error executing post-script for service ", s.Name, ": ", err)
+		return
+	}
+
+	logger.Info("| === POST-SCRIPT END --- [SERVICE: ", s.Name, "] === |")
+}
+
+func handleServiceError(s *Service, err error) {
+	logger.Error(fmt.Sprintf("Error starting service '%s': %v", colorize(ColorCyan, s.Name), err))
+	alert(Event{Type: EventFailed, Service: s.Name, Message: err.Error()})
+	if s.Required {
+		logger.Error(fmt.Sprintf("[CRITICAL] Required service '%s' failed, initiating shutdown",
+			colorize(ColorCyan, s.Name)))
+		alert(Event{Type: EventShutdown, Service: s.Name, Message: err.Error()})
+		shutdownForService(s, err)
+	}
+}
+
+func isBashAvailable() bool {
+	_, err := exec.LookPath("bash")
+	return err == nil
+}
+
+func runScript(scriptPath string, env []string) error {
+	return runScriptContext(context.Background(), scriptPath, env)
+}
+
+// runScriptContext runs a script, killing it when ctx is done
+func runScriptContext(ctx context.Context, scriptPath string, env []string) error {
+	shell := "sh"
+	if isBashAvailable() {
+		shell = "bash"
+	}
+
+	cmd := exec.CommandContext(ctx, shell, "-c", scriptPath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+	// Kill the whole process group so commands the script started die with it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	return cmd.Run()
+}
+
+// waitForDependency waits until depName was started and meets condition
+// (see depends_on_condition), then waits waitAfter seconds
+func waitForDependency(depName string, waitAfter int, mu *sync.Mutex, startedServices map[string]bool, dependencyWait int, condition string) bool {
+	maxWait := time.Duration(dependencyWait) * time.Second
+	start := time.Now()
+
+	for {
+		// Check for shutdown signal
+		select {
+		case <-shutdownContext().Done():
+			return false
+		default:
+		}
+
+		// Check for timeout
+		if time.Since(start) > maxWait {
+			logger.Error(fmt.Sprintf("Dependency wait timeout exceeded for '%s'",
+				colorize(ColorYellow, depName)))
+			return false
+		}
+
+		mu.Lock()
+		depStarted := startedServices[depName]
+		mu.Unlock()
+
+		if depStarted && dependencyMet(depName, condition) {
+			if waitAfter > 0 {
+				logger.Info(fmt.Sprintf("Dependency '%s' is up. Waiting %ds before starting dependent service",
+					colorize(ColorGreen, depName), waitAfter))
+			} else {
+				logger.Success(fmt.Sprintf("Dependency '%s' is ready", colorize(ColorGreen, depName)))
+			}
+
+			// Wait with cancellation support
+			select {
+			case <-time.After(time.Duration(waitAfter) * time.Second):
+				return true
+			case <-shutdownContext().Done():
+				return false
+			}
+		}
+
+		if depStarted {
+			logger.Info(fmt.Sprintf("Waiting for dependency to become %s: %s", condition, colorize(ColorYellow, depName)))
+		} else {
+			logger.Info(fmt.Sprintf("Waiting for dependency: %s", colorize(ColorYellow, depName)))
+		}
+
+		// Sleep with cancellation support
+		select {
+		case <-time.After(2 * time.Second):
+			continue
+		case <-shutdownContext().Done():
+			return false
+		}
+	}
+}
+
+func joinArgs(args []string) string {
+	return strings.Join(args, " ")
+}
+
+// startServiceWithPTY launches a service and supervises it until it exits.
+// launched is called once the process is spawned or failed to spawn.
+func startServiceWithPTY(service Service, maxLength int, timeouts Timeouts, launched func()) error {
+	unlock := lockService(service.Name)
+	serviceProcess, err := launchService(service, maxLength)
+	unlock()
+	launched()
+
+	if errors.Is(err, errServiceAlreadyRunning) {
+		logger.Info(fmt.Sprintf("Service '%s' is already running, skipping start", colorize(ColorCyan, service.Name)))
+		return nil
+	}
+	if err != nil || serviceProcess == nil {
+		return err
+	}
+	return superviseService(serviceProcess, timeouts)
+}
+
+// launchService spawns the service process and registers it as active.
+// Callers must hold the service lock (see lockService). A nil process with a
+// nil error means the service has nothing to supervise (log_file services).
+func launchService(service Service, maxLength int) (*ServiceProcess, error) {
+	if _, running := getActiveService(service.Name); running {
+		return nil, errServiceAlreadyRunning
+	}
+	serviceLogs.resize(service.Name, service.LogBufferLines)
+
+	if service.LogFile != "" {
+		logger.Info(fmt.Sprintf("Service '%s' is configured to use log file: %s",
+			colorize(ColorCyan, service.Name),
+			colorize(ColorYellow, service.LogFile)))
+		go tailLogFile(service)
+		return nil, nil
+	}
+
+	logger.Info(fmt.Sprintf("Starting service: %s", colorize(ColorCyan, service.Name)))
+
+	var cmd *exec.Cmd
+
+	// Use exec.Command directly instead of shell when possible
+	if len(service.Args) > 0 {
+		// If we have args, pass them directly to the command
+		cmd = exec.Command(service.Command, service.Args...)
+	} else {
+		// No args, just the command
+		cmd = exec.Command(service.Command)
+	}
+
+	// Drop privileges to user/group if specified
+	env, cred, err := serviceProcessEnv(&service)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving user of service %s: %w", service.Name, err)
+	}
+	// Secrets are read on every start, so a rotated secret applies on restart
+	secrets, err := secretEnv(&service)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secrets of service %s: %w", service.Name, err)
+	}
+	env = mergeEnv(env, secrets)
+	if err := writeSecretFiles(&service, cred); err != nil {
+		return nil, fmt.Errorf("error writing secrets of service %s: %w", service.Name, err)
+	}
+	cmd.Env = env
+	if cred != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+	}
+
+	// Render templates on every start, so restarts pick up config changes
+	if err := renderTemplates(&service, env, cred); err != nil {
+		return nil, fmt.Errorf("error rendering templates of service %s: %w", service.Name, err)
+	}
+
+	// Notify services report readiness, and notify watchdogs signs of life,
+	// on an sd_notify socket of their own
+	var notifyConn *notifySocket
+	notifyWatchdog := service.Watchdog != nil && service.Watchdog.Notify
+	if service.Readiness != nil && service.Readiness.Notify || notifyWatchdog {
+		uid, gid := -1, -1
+		if cred != nil {
+			uid, gid = int(cred.Uid), int(cred.Gid)
+		}
+		notifyConn, err = listenNotifySocket(service.Name, uid, gid)
+		if err != nil {
+			return nil, fmt.Errorf("error creating notify socket of service %s: %w", service.Name, err)
+		}
+		cmd.Env = mergeEnv(cmd.Env, map[string]string{EnvNotifySocket: notifySocketPath(service.Name)})
+		if notifyWatchdog {
+			cmd.Env = mergeEnv(cmd.Env, watchdogEnv(service.Watchdog))
+		}
+	}
+
+	if err := wrapExec(cmd, serviceExecSetup(&service)); err != nil {
+		if notifyConn != nil {
+			notifyConn.close()
+		}
+		return nil, fmt.Errorf("error preparing service %s: %w", service.Name, err)
+	}
+
+	// Start the process directly in its cgroup, so its children can't escape
+	if service.Cgroup != nil {
+		cgroup, err := openServiceCgroup(&service)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Service '%s' runs without its cgroup: %v", colorize(ColorCyan, service.Name), err))
+		} else {
+			defer cgroup.Close()
+			if cmd.SysProcAttr == nil {
+				cmd.SysProcAttr = &syscall.SysProcAttr{}
+			}
+			cmd.SysProcAttr.UseCgroupFD = true
+			cmd.SysProcAttr.CgroupFD = int(cgroup.Fd())
+		}
+	}
+
+	var readyPipe, readyPipeWriter *os.File
+	if service.Readiness != nil && service.Readiness.NotificationFD > 0 {
+		readyPipe, readyPipeWriter, err = attachNotificationFD(cmd, service.Readiness.NotificationFD)
+		if err != nil {
+			if notifyConn != nil {
+				notifyConn.close()
+			}
+			return nil, fmt.Errorf("error creating notification fd of service %s: %w", service.Name, err)
+		}
+	}
+
+	if isPassthroughService(&service) {
+		attachPassthroughStdin(cmd)
+	}
+	var ptmx, stdout, stderr *os.File
+	if usePTY(&service) {
+		ptmx, err = pty.Start(cmd)
+	} else {
+		stdout, stderr, err = startWithPipes(cmd)
+	}
+	if readyPipeWriter != nil {
+		// Only the child writes; it holds the last copy of the write end
+		_ = readyPipeWriter.Close()
+	}
+	if err != nil {
+		if notifyConn != nil {
+			notifyConn.close()
+		}
+		if readyPipe != nil {
+			_ = readyPipe.Close()
+		}
+		return nil, fmt.Errorf("error starting service %s: %w", service.Name, err)
+	}
+
+	logger.Success(fmt.Sprintf("Service '%s' started successfully (PID: %d)",
+		colorize(ColorCyan, service.Name), cmd.Process.Pid))
+	recordLifecycle(service.Name, LifecycleEvent{Event: LifecycleStart, PID: cmd.Process.Pid})
+
+	// Create service context for graceful shutdown
+	serviceCtx, serviceCancel := context.WithCancel(shutdownContext())
+
+	// Register the service as active
+	serviceProcess := &ServiceProcess{
+		Name:    service.Name,
+		Process: cmd,
+		PTY:     ptmx,
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Cancel:  serviceCancel,
+		State:   ServiceStatePending,
+		Config:  service,
+		Exited:  make(chan struct{}),
+		ctx:     serviceCtx,
+		waitErr: make(chan error, 1),
+
+		outputDone:   make(chan struct{}),
+		watchdogPing: make(chan struct{}, 1),
+		readyPipe:    readyPipe,
+	}
+	addActiveService(service.Name, serviceProcess)
+
+	// Mark service as running once it's started
+	serviceProcess.SetState(ServiceStateRunning)
+
+	if service.Register != nil {
+		go runServiceRegistration(serviceCtx, serviceProcess)
+	}
+	if service.HealthCheck != nil {
+		go monitorHealth(serviceCtx, serviceProcess)
+	}
+	if service.Readiness != nil {
+		go monitorReadiness(serviceCtx, serviceProcess)
+	}
+	if service.Watchdog != nil {
+		go monitorWatchdog(serviceCtx, serviceProcess)
+	}
+	if notifyConn != nil {
+		go receiveNotify(serviceCtx, serviceProcess, notifyConn)
+	}
+	if readyPipe != nil {
+		go receiveNotificationFD(serviceCtx, serviceProcess, readyPipe)
+	}
+	if service.RestartEvery > 0 {
+		go schedulePeriodicRestart(serviceCtx, serviceProcess)
+	}
+
+	// Start log processing in background
+	go func() {
+		if ptmx != nil {
+			prefixLogs(ptmx, newServiceOutput(&service, maxLength))
+		} else {
+			pipeLogs(stdout, stderr, newServiceOutput(&service, maxLength))
+		}
+		close(serviceProcess.outputDone)
+	}()
+
+	// Reap the process exactly once; both exit paths in superviseService consume this channel
+	go func() {
+		serviceProcess.waitErr <- cmd.Wait()
+	}()
+
+	return serviceProcess, nil
+}
+
+// superviseService blocks until the service exits on its own or is stopped
+// through its context, then unregisters it. Only unexpected exits return an error.
+func superviseService(serviceProcess *ServiceProcess, timeouts Timeouts) error {
+	serviceCtx := serviceProcess.ctx
+	serviceCancel := serviceProcess.Cancel
+	exited := serviceProcess.waitErr
+	service := serviceProcess.Config
+
+	var exitErr, waitErr error
+	exitedOnOwn := false
+	select {
+	case exitErr = <-exited:
+		// Service exited on its own
+		exitedOnOwn = true
+		waitErr = exitErr
+		serviceCancel()
+		serviceProcess.ExitCode = exitStatus(exitErr)
+		recordExitCode(service.Name, serviceProcess.ExitCode)
+		// Read what the service wrote last before its output is closed
+		serviceProcess.waitForOutput(outputDrainTimeout)
+		if exitErr != nil {
+			serviceProcess.SetError(exitErr)
+			reportCrashOutput(service.Name)
+			events.publish(Event{Type: EventFailed, Service: service.Name, Message: exitErr.Error()})
+		} else {
+			events.publish(Event{Type: EventExited, Service: service.Name})
+		}
+	case <-serviceCtx.Done():
+		waitErr = terminateService(serviceProcess, exited, timeouts)
+		serviceProcess.waitForOutput(outputDrainTimeout)
+	}
+
+	// Clean up
+	serviceProcess.closeOutput()
+	uptime := time.Since(serviceProcess.StartTime)
+	recordProcessEnd(serviceProcess, exitedOnOwn, waitErr, uptime)
+	runOnExit(serviceProcess, exitedOnOwn, waitErr, uptime)
+	removeActiveService(service.Name, serviceProcess)
+
+	// A scheduled restart takes over, so the exit is not reported as a failure
+	if exitedOnOwn && scheduleRestart(service, exitErr, uptime) {
+		return nil
+	}
+	// The crash loop breaker fails the service even after a clean exit
+	if err := crashLoopError(service.Name); exitedOnOwn && err != nil {
+		exitErr = err
+	}
+	// Like the CMD of s6-overlay, the end of exit_code_from ends the supervisor
+	if exitedOnOwn && isExitCodeFrom(service.Name) {
+		logger.Info(fmt.Sprintf("Service '%s' ended, shutting down (exit_code_from)", colorize(ColorCyan, service.Name)))
+		alert(Event{Type: EventShutdown, Service: service.Name, Message: "exit_code_from service ended"})
+		go shutdownForService(&service, exitErr)
+	}
+	return exitErr
+}
+
+// terminateService stops a running service with its stop signal escalation
+// (see stopSteps) and force kills it once the last step times out. exited
+// receives the result of cmd.Wait, which is returned.
+func terminateService(serviceProcess *ServiceProcess, exited <-chan error, timeouts Timeouts) error {
+	cmd := serviceProcess.Process
+	name := serviceProcess.Name
+
+	serviceProcess.SetState(ServiceStateStopping)
+	logger.Info(fmt.Sprintf("Gracefully stopping service: %s", colorize(ColorCyan, name)))
+	runPreStop(serviceProcess)
+
+	pid := cmd.Process.Pid
+	for _, step := range stopSteps(&serviceProcess.Config, timeouts) {
+		if err := signalProcessGroup(pid, step.signal); err != nil {
+			logger.Error(fmt.Sprintf("Error sending %s to service '%s': %v",
+				signalName(step.signal), colorize(ColorCyan, name), err))
+			serviceProcess.SetError(err)
+		}
+
+		select {
+		case <-time.After(step.timeout):
+			logger.Warn(fmt.Sprintf("Service '%s' still running %s after %s",
+				colorize(ColorCyan, name), step.timeout, signalName(step.signal)))
+		case err := <-exited:
+			if err != nil {
+				logger.Error(fmt.Sprintf("Service '%s' exited with error: %v",
+					colorize(ColorCyan, name), err))
+				serviceProcess.SetError(err)
+			} else {
+				logger.Success(fmt.Sprintf("Service '%s' stopped gracefully",
+					colorize(ColorCyan, name)))
+			}
+			killLeftovers(pid)
+			return err
+		}
+	}
+
+	// Force kill if not stopped gracefully
+	logger.Warn(fmt.Sprintf("Force killing service '%s'", colorize(ColorCyan, name)))
+	if err := signalProcessGroup(pid, syscall.SIGKILL); err != nil {
+		logger.Error(fmt.Sprintf("Error force killing service '%s': %v",
+			colorize(ColorCyan, name), err))
+		serviceProcess.SetError(err)
+	}
+	return <-exited // Wait for the process to actually exit
+}
+
+// killLeftovers kills what remains of the process group of a stopped service,
+// e.g. children its shell wrapper didn't forward the stop signal to. The group
+// id stays taken while any member is left, so this runs right after the
+// leader was reaped.
+func killLeftovers(pid int) {
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err == nil {
+		logger.Debug(fmt.Sprintf("Killed processes left in the group of PID %d", pid))
+	}
+}
+
+// prefixLogs writes the output of a service to the console, prefixed with its
+// name and optional timestamp, and to its log_output file if any
+func prefixLogs(reader *os.File, output *serviceOutput) {
+	defer output.close()
+	scanOutput(reader, output.name, output.writeLine)
+}
+
+func getLongestServiceNameLength(services []Service) int {
+	maxLength := 0
+	for i := range services {
+		service := &services[i]
+		if len(service.Name) > maxLength {
+			maxLength = len(service.Name)
+		}
+	}
+	return maxLength
+}
+
+func formatServiceName(serviceName string, maxLength int) string {
+	return fmt.Sprintf("%-*s", maxLength, serviceName)
+}
+
+// tailLogFile writes the lines appended to the log_file of a service like its
+// output, with its timestamps, console filters and multiline records
+func tailLogFile(service Service) {
+	serviceName, layout := service.Name, serviceTimestampLayout(&service)
+	filter, limiter := newLogFilter(&service), newLogRateLimiter(&service)
+	write := func(record string) {
+		serviceLogs.record(serviceName, record)
+		if filter.pass(record, LogLevelInfo) && limiter.allow() {
+			writeServiceOutput(serviceName, serviceName, layout, record)
+		}
+	}
+	records := newMultilineJoiner(service.Multiline, write)
+	defer records.flush()
+
+	file, err := os.Open(service.LogFile)
+	if err != nil {
+		logger.Info("Error opening log file for service ", serviceName, ": ", err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		logger.Info("Error seeking log file for service ", serviceName, ": ", err)
+		return
+	}
+
+	scanner := bufio.NewScanner(file)
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownContext().Done():
+			logger.Info("Stopping log tailing for service:", serviceName)
+			return
+		case <-ticker.C:
+			for scanner.Scan() {
+				if records != nil {
+					records.add(scanner.Text())
+				} else {
+					write(scanner.Text())
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				logger.Info("Error reading log file for service ", serviceName, ": ", err)
+				return
+			}
+		}
+	}
+}
+
+// Helper function to get color for service state
+func getStateColor(state ServiceState) string {
+	switch state {
+	case ServiceStatePending:
+		return ColorYellow
+	case ServiceStateStarting:
+		return ColorCyan
+	case ServiceStateRunning:
+		return ColorGreen
+	case ServiceStateStopping:
+		return ColorMagenta
+	case ServiceStateStopped:
+		return ColorGray
+	case ServiceStateFailed:
+		return ColorRed
+	case ServiceStateBackoff:
+		return ColorYellow
+	default:
+		return ColorWhite
+	}
+}
+
+// envNoColor disables colors when set to any non-empty value (https://no-color.org)
+const envNoColor = "NO_COLOR"
+
+var (
+	// colorEnabled turns ANSI colors on in supervisor logs and CLI output
+	colorEnabled = true
+	// noColor is set by --no-color
+	noColor bool
+)
+
+// applyColorFlags disables colors with --no-color or NO_COLOR, or when
+// stdout isn't a terminal, e.g. collected by docker logs
+func applyColorFlags() {
+	colorEnabled = !noColor && os.Getenv(envNoColor) == "" && stdoutIsTerminal()
+}
+
+// Helper function to format colored text
+func colorize(color, text string) string {
+	if !colorEnabled {
+		return text
+	}
+	return color + text + ColorReset
+}
+
+func _printEnvVariables() {
+	logger.Info("Function entry logged.")
+	logger.Debug("| ---------------- START - ENVIRONMENT VARS ---------------- |")
+
+	envVars := os.Environ()
+	for i, env := range envVars {
+		if i == len(envVars)-1 {
+			fmt.Printf("%s", env)
+		} else {
+			fmt.Printf("%s\n", env)
+		}
+	}
+
+	logger.Debug("| ---------------- CLOSE - ENVIRONMENT VARS ---------------- |")
+}
+
+// Validation functions
+func validateConfig(config *Config) error {
+	var errors ValidationErrors
+
+	// Set default timeouts if not specified
+	if config.Timeouts.PostScript == 0 {
+		config.Timeouts.PostScript = 7
+	}
+	if config.Timeouts.ServiceShutdown == 0 {
+		config.Timeouts.ServiceShutdown = 10
+	}
+	if config.Timeouts.GlobalShutdown == 0 {
+		config.Timeouts.GlobalShutdown = 30
+	}
+	if config.Timeouts.DependencyWait == 0 {
+		config.Timeouts.DependencyWait = 300 // 5 minutes
+	}
+	if config.Timeouts.PreShutdown == 0 {
+		config.Timeouts.PreShutdown = 10
+	}
+	if config.Timeouts.FinishScript == 0 {
+		config.Timeouts.FinishScript = 5
+	}
+
+	// Validate services
+	serviceNames := make(map[string]bool)
+	for i := range config.Services {
+		service := &config.Services[i]
+		// Services inherit the global validate_commands setting
+		if service.ValidateCommands == nil && config.ValidateCommands != nil {
+			service.ValidateCommands = config.ValidateCommands
+		}
+
+		// Validate service
+		if errs := validateService(*service); len(errs) > 0 {
+			errors = append(errors, errs...)
+		}
+
+		// Check for duplicate service names
+		if serviceNames[service.Name] {
+			errors = append(errors, ValidationError{
+				Field:   "name",
+				Service: service.Name,
+				Message: "duplicate service name",
+			})
+		}
+		serviceNames[service.Name] = true
+
+		// Set default enabled if not specified
+		if service.Enabled == nil {
+			config.Services[i].Enabled = new(bool)
+			*config.Services[i].Enabled = true
+		}
+	}
+	// Templates get the same defaults as their instances
+	for i := range config.ServiceTemplates {
+		template := &config.ServiceTemplates[i]
+		if template.ValidateCommands == nil && config.ValidateCommands != nil {
+			template.ValidateCommands = config.ValidateCommands
+		}
+		if template.Enabled == nil {
+			template.Enabled = new(bool)
+			*template.Enabled = true
+		}
+	}
+
+	errors = append(errors, validateLogging(&config.Logging)...)
+	errors = append(errors, validateAPI(&config.API)...)
+	errors = append(errors, validateControl(&config.Control)...)
+	errors = append(errors, validateAuditLog(config.AuditLog)...)
+	errors = append(errors, validateStateFile(config.StateFile)...)
+	errors = append(errors, validateNotifiers(config.Notifiers, "")...)
+	errors = append(errors, validateExitCodeFrom(config)...)
+	errors = append(errors, validateInitDir(config)...)
+	errors = append(errors, validatePreShutdownScript(config)...)
+	errors = append(errors, validateFinishDir(config)...)
+	errors = append(errors, validateMaxConcurrentStarts(config)...)
+	errors = append(errors, validateHealthyDependencies(config.Services)...)
+	errors = append(errors, validateReadyDependencies(config.Services)...)
+	errors = append(errors, validateStages(config.Services)...)
+	errors = append(errors, validateServiceTemplates(config)...)
+
+	// Validate dependencies
+	if err := validateDependencies(config.Services); err != nil {
+		errors = append(errors, ValidationError{
+			Field:   "dependencies",
+			Message: err.Error(),
+		})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
+
+func validateService(service Service) ValidationErrors {
+	var errors ValidationErrors
+
+	errors = append(errors, validateRequiredFields(&service)...)
+	errors = append(errors, validateServiceName(&service)...)
+	errors = append(errors, validateCommand(&service)...)
+	errors = append(errors, validateScripts(&service)...)
+	errors = append(errors, validateLogFile(&service)...)
+	errors = append(errors, validateWaitAfter(&service)...)
+	errors = append(errors, validateUser(&service)...)
+	errors = append(errors, validatePublish(&service)...)
+	errors = append(errors, validateRegister(&service)...)
+	errors = append(errors, validateWaitFor(&service)...)
+	errors = append(errors, validateLabels(&service)...)
+	errors = append(errors, validateTags(&service)...)
+	errors = append(errors, validateReload(&service)...)
+	errors = append(errors, validatePreStop(&service)...)
+	errors = append(errors, validateOnExit(&service)...)
+	errors = append(errors, validateShutdownExitCode(&service)...)
+	errors = append(errors, validateStopSignal(&service)...)
+	errors = append(errors, validateRestart(&service)...)
+	errors = append(errors, validateHealthCheck(&service)...)
+	errors = append(errors, validateReadiness(&service)...)
+	errors = append(errors, validateWatchdog(&service)...)
+	errors = append(errors, validateNotifiers(service.Notifiers, service.Name)...)
+	errors = append(errors, validateLogBufferLines(&service)...)
+	errors = append(errors, validateTimestamps(&service)...)
+	errors = append(errors, validateLogOutput(&service)...)
+	errors = append(errors, validatePTY(&service)...)
+	errors = append(errors, validateLogFilter(&service)...)
+	errors = append(errors, validateLogRateLimit(&service)...)
+	errors = append(errors, validateMultiline(&service)...)
+	errors = append(errors, validateEnv(&service)...)
+	errors = append(errors, validateLimits(&service)...)
+	errors = append(errors, validateCgroup(&service)...)
+	errors = append(errors, validatePriority(&service)...)
+	errors = append(errors, validateTemplates(&service)...)
+	errors = append(errors, validateSecrets(&service)...)
+	errors = append(errors, validateTimers(&service)...)
+
+	return errors
+}
+
+func validateRequiredFields(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.Name == "" {
+		errors = append(errors, ValidationError{
+			Field:   "name",
+			Service: service.Name,
+			Message: "service name is required",
+		})
+	}
+
+	if service.Command == "" {
+		errors = append(errors, ValidationError{
+			Field:   "command",
+			Service: service.Name,
+			Message: "command is required",
+		})
+	}
+
+	return errors
+}
+
+func validateServiceName(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.Name != "" {
+		// Instances are named after their template: <template>@<id>
+		name := service.Name
+		if service.InstanceOf != "" {
+			name = service.InstanceOf
+		}
+		validName := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+		if !validName.MatchString(name) {
+			errors = append(errors, ValidationError{
+				Field:   "name",
+				Service: service.Name,
+				Message: "service name must contain only alphanumeric characters, dashes, and underscores",
+			})
+		}
+	}
+
+	return errors
+}
+
+// shouldValidateCommands reports whether command and script paths are checked
+// at config time; disabled for binaries provided later by a pre_script or volume
+func shouldValidateCommands(service *Service) bool {
+	return service.ValidateCommands == nil || *service.ValidateCommands
+}
+
+func validateCommand(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if !shouldValidateCommands(service) {
+		return errors
+	}
+
+	if service.Command != "" && !strings.Contains(service.Command, " ") {
+		if validationRoot != "" {
+			if !lookPathInRoot(service.Command) {
+				errors = append(errors, ValidationError{
+					Field:   "command",
+					Service: service.Name,
+					Message: fmt.Sprintf("command '%s' not found in rootfs %s", service.Command, validationRoot),
+				})
+			}
+			return errors
+		}
+
+		if _, err := exec.LookPath(service.Command); err != nil {
+			if !filepath.IsAbs(service.Command) {
+				errors = append(errors, ValidationError{
+					Field:   "command",
+					Service: service.Name,
+					Message: fmt.Sprintf("command '%s' not found in PATH", service.Command),
+				})
+			} else {
+				if _, err := os.Stat(service.Command); os.IsNotExist(err) {
+					errors = append(errors, ValidationError{
+						Field:   "command",
+						Service: service.Name,
+						Message: fmt.Sprintf("command file '%s' does not exist", service.Command),
+					})
+				}
+			}
+		}
+	}
+
+	return errors
+}
+
+func validateScripts(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if !shouldValidateCommands(service) {
+		return errors
+	}
+
+	if service.PreScript != "" {
+		if _, err := os.Stat(rootPath(service.PreScript)); os.IsNotExist(err) {
+			errors = append(errors, ValidationError{
+				Field:   "pre_script",
+				Service: service.Name,
+				Message: fmt.Sprintf("pre-script file '%s' does not exist", service.PreScript),
+			})
+		}
+	}
+
+	if service.PosScript != "" {
+		if _, err := os.Stat(rootPath(service.PosScript)); os.IsNotExist(err) {
+			errors = append(errors, ValidationError{
+				Field:   "pos_script",
+				Service: service.Name,
+				Message: fmt.Sprintf("post-script file '%s' does not exist", service.PosScript),
+			})
+		}
+	}
+
+	return errors
+}
+
+func validateLogFile(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.LogFile != "" {
+		logDir := filepath.Dir(service.LogFile)
+		if _, err := os.Stat(rootPath(logDir)); os.IsNotExist(err) {
+			errors = append(errors, ValidationError{
+				Field:   "log_file",
+				Service: service.Name,
+				Message: fmt.Sprintf("log file directory '%s' does not exist", logDir),
+			})
+		}
+	}
+
+	return errors
+}
+
+func validateWaitAfter(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.WaitAfter != nil && service.WaitAfter.IsPerDep {
+		for depName, waitTime := range service.WaitAfter.PerDep {
+			if waitTime < 0 || waitTime > 300 {
+				errors = append(errors, ValidationError{
+					Field:   "wait_after",
+					Service: service.Name,
+					Message: fmt.Sprintf("wait_after for dependency '%s' must be between 0 and 300 seconds", depName),
+				})
+			}
+		}
+	} else if service.WaitAfter != nil {
+		if service.WaitAfter.Global < 0 || service.WaitAfter.Global > 300 {
+			errors = append(errors, ValidationError{
+				Field:   "wait_after",
+				Service: service.Name,
+				Message: "wait_after must be between 0 and 300 seconds",
+			})
+		}
+	}
+
+	return errors
+}
+
+func validateDependencies(services []Service) error {
+	serviceMap := make(map[string]Service)
+	for i := range services {
+		service := &services[i]
+		serviceMap[service.Name] = *service
+	}
+
+	// Check if all dependencies exist
+	for i := range services {
+		service := &services[i]
+		for _, dep := range service.DependsOn {
+			if _, exists := serviceMap[dep]; !exists {
+				return fmt.Errorf("service '%s' depends on non-existent service '%s'", service.Name, dep)
+			}
+		}
+
+		// Validate wait_after map references
+		if service.WaitAfter != nil && service.WaitAfter.IsPerDep {
+			for depName := range service.WaitAfter.PerDep {
+				found := false
+				for _, dep := range service.DependsOn {
+					if dep == depName {
+						found = true
+						break
+					}
+				}
+				if !found {
+					return fmt.Errorf("service '%s' has wait_after for '%s' but doesn't depend on it", service.Name, depName)
+				}
+			}
+		}
+	}
+
+	// Check for circular dependencies
+	for i := range services {
+		service := &services[i]
+		if hasCycles(service.Name, serviceMap, make(map[string]bool), make(map[string]bool)) {
+			return fmt.Errorf("circular dependency detected involving service '%s'", service.Name)
+		}
+	}
+
+	return nil
+}
+
+func hasCycles(serviceName string, serviceMap map[string]Service, visited, recursionStack map[string]bool) bool {
+	visited[serviceName] = true
+	recursionStack[serviceName] = true
+
+	service, exists := serviceMap[serviceName]
+	if !exists {
+		return false
+	}
+
+	for _, dep := range service.DependsOn {
+		if !visited[dep] {
+			if hasCycles(dep, serviceMap, visited, recursionStack) {
+				return true
+			}
+		} else if recursionStack[dep] {
+			return true
+		}
+	}
+
+	recursionStack[serviceName] = false
+	return false
+}
+
+// Enhanced service status reporting
+func printServiceStatuses() {
+	if !logEnabled(LogLevelInfo) {
+		return
+	}
+
+	servicesMutex.RLock()
+	defer servicesMutex.RUnlock()
+
+	logger.Print(colorize(ColorBoldCyan, "\n=== Service Status Summary ==="))
+	for name, serviceProc := range activeServices {
+		uptime := time.Since(serviceProc.StartTime).Round(time.Second)
+		state := serviceProc.GetState()
+		stateColored := colorize(getStateColor(state), state.String())
+
+		status := fmt.Sprintf("  %s │ State: %s │ Uptime: %s",
+			colorize(ColorCyan, fmt.Sprintf("%-15s", name)),
+			stateColored,
+			colorize(ColorWhite, uptime.String()))
+
+		if lastError := serviceProc.GetError(); lastError != nil {
+			status += fmt.Sprintf(" │ %s: %s",
+				colorize(ColorRed, "Error"),
+				lastError)
+		}
+
+		logger.Print(status)
+	}
+	logger.Print(colorize(ColorBoldCyan, "=== End Status Summary ===\n"))
+}
+
+func handleIPCConnection(conn net.Conn) {
+	defer conn.Close()
+
+	peer, err := socketPeerCredentials(conn)
+	if err != nil {
+		logger.Debug("Could not read IPC client credentials: ", err)
+	}
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+
+	var cmd IPCCommand
+	if err := decoder.Decode(&cmd); err != nil {
+		logger.Info("Error decoding IPC command:", err)
+		return
+	}
+
+	// Current clients open with a hello handshake, then send their command
+	if cmd.Type == CmdHello {
+		if err := encoder.Encode(handleHello()); err != nil {
+			logger.Info("Error encoding IPC response:", err)
+			return
+		}
+		cmd = IPCCommand{}
+		if err := decoder.Decode(&cmd); err != nil {
+			if !errors.Is(err, io.EOF) {
+				logger.Info("Error decoding IPC command:", err)
+			}
+			return
+		}
+	}
+
+	if err := authorizeIPCCommand(currentControlConfig(), peer, cmd); err != nil {
+		logger.Warn(fmt.Sprintf("Refused IPC command %s: %v", cmd.Type, err))
+		auditDenied(socketAuditClient(peer), cmd, err)
+		if err := encoder.Encode(errorResponse(err)); err != nil {
+			logger.Info("Error encoding IPC response:", err)
+		}
+		return
+	}
+
+	switch cmd.Type {
+	case CmdListServices:
+		if cmd.Stream {
+			if err := streamListServices(encoder, cmd); err != nil {
+				logger.Info("Error streaming IPC response:", err)
+			}
+			return
+		}
+	case CmdServiceLogs:
+		if err := streamServiceLogs(conn, encoder, cmd); err != nil {
+			logger.Info("Error streaming IPC response:", err)
+		}
+		return
+	case CmdSubscribe:
+		if err := streamEvents(conn, encoder, cmd); err != nil {
+			logger.Info("Error streaming IPC response:", err)
+		}
+		return
+	}
+
+	response := dispatchIPCCommand(cmd)
+	auditCommand(socketAuditClient(peer), cmd, response)
+	if err := encoder.Encode(response); err != nil {
+		logger.Info("Error encoding IPC response:", err)
+	}
+}
+
+// dispatchIPCCommand runs a command answered with a single response; the
+// control socket and the HTTP API share it
+func dispatchIPCCommand(cmd IPCCommand) IPCResponse {
+	switch cmd.Type {
+	case CmdListServices:
+		return handleListServices(cmd)
+	case CmdRestartService:
+		if cmd.All || cmd.Pattern != "" {
+			return handleBulkAction(ActionRestart, cmd)
+		}
+		return handleRestartService(cmd.ServiceName)
+	case CmdReloadService:
+		return handleReloadService(cmd.ServiceName)
+	case CmdSignalService:
+		return handleSignalService(cmd)
+	case CmdStopServices:
+		return handleBulkAction(ActionStop, cmd)
+	case CmdStartServices:
+		return handleBulkAction(ActionStart, cmd)
+	case CmdUpgrade:
+		return handleUpgrade(cmd.Binary)
+	case CmdGetStatus:
+		return handleGetStatus()
+	case CmdServiceEnv:
+		return handleServiceEnv(cmd.ServiceName)
+	case CmdServiceExec:
+		return handleServiceExec(cmd.ServiceName)
+	case CmdServiceStats:
+		return handleServiceStats(cmd.ServiceName)
+	case CmdNotifyReady:
+		return handleNotifyReady(cmd.ServiceName)
+	case CmdOperation:
+		return handleOperationStatus(cmd.OperationID)
+	case CmdGetConfig:
+		return handleGetConfig()
+	case CmdApply:
+		return handleApply(cmd.ConfigData)
+	case CmdScale:
+		return handleScale(cmd.ServiceName, cmd.Instances)
+	case CmdAudit:
+		return handleAudit(cmd.Limit)
+	case CmdHistory:
+		return handleHistory(cmd.ServiceName)
+	}
+	return IPCResponse{
+		Success: false,
+		Message: "Unknown command type",
+	}
+}
+
+func handleListServices(cmd IPCCommand) IPCResponse {
+	services := snapshotServices()
+	total := len(services)
+
+	return IPCResponse{
+		Success:  true,
+		Services: paginate(services, cmd.Offset, cmd.Limit),
+		Total:    total,
+	}
+}
+
+func handleRestartService(serviceName string) IPCResponse {
+	op, err := requestRestart(serviceName)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	return IPCResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("Service '%s' restart initiated", serviceName),
+		Operation: &op,
+	}
+}
+
+func handleOperationStatus(operationID string) IPCResponse {
+	op, ok := getOperation(operationID)
+	if !ok {
+		return errorResponse(withCode(ErrCodeOperationNotFound, fmt.Errorf("operation '%s' not found", operationID)))
+	}
+
+	return IPCResponse{
+		Success:   true,
+		Operation: &op,
+	}
+}
+
+func handleGetStatus() IPCResponse {
+	servicesMutex.RLock()
+	defer servicesMutex.RUnlock()
+
+	totalServices := len(activeServices)
+	runningServices := 0
+	failedServices := 0
+	stoppedServices := 0
+
+	for _, serviceProc := range activeServices {
+		state := serviceProc.GetState()
+		if state == ServiceStateRunning {
+			runningServices++
+		} else if state == ServiceStateFailed {
+			failedServices++
+		}
+	}
+
+	// Count defined services without a process as stopped, unless the crash
+	// loop breaker failed them
+	if config := currentConfig(); config != nil {
+		for _, service := range config.Services {
+			if _, running := activeServices[service.Name]; running {
+				continue
+			}
+			if state, _ := restartStatus(service.Name); state == ServiceStateFailed {
+				failedServices++
+				totalServices++
+			} else {
+				stoppedServices++
+			}
+		}
+	}
+	totalServices += stoppedServices
+
+	message := fmt.Sprintf("Total: %d, Running: %d, Failed: %d",
+		totalServices, runningServices, failedServices)
+	if stoppedServices > 0 {
+		message += fmt.Sprintf(", Stopped: %d", stoppedServices)
+	}
+	if logPipe != nil {
+		if dropped := logPipe.TotalDropped(); dropped > 0 {
+			message += fmt.Sprintf(", Dropped log lines: %d", dropped)
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Message: message,
+	}
+}
+
+// Client functions for CLI commands
+func sendIPCCommand(cmd IPCCommand) (*IPCResponse, error) {
+	conn, err := openIPC(cmd.Type)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.Send(cmd); err != nil {
+		return nil, err
+	}
+
+	var response IPCResponse
+	if err := conn.Receive(&response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func restartService(serviceName string, wait bool) error {
+	response, err := sendIPCCommand(IPCCommand{
+		Type:        CmdRestartService,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !response.Success {
+		return responseError(response)
+	}
+
+	fmt.Println(colorize(ColorGreen, "✓ "+response.Message))
+	if !wait || response.Operation == nil {
+		return nil
+	}
+
+	return waitForOperation(response.Operation.ID)
+}
+
+// waitForOperation polls the daemon until an operation finishes, printing its progress
+func waitForOperation(operationID string) error {
+	var lastState OperationState
+	for {
+		response, err := sendIPCCommand(IPCCommand{Type: CmdOperation, OperationID: operationID})
+		if err != nil {
+			return err
+		}
+		if !response.Success || response.Operation == nil {
+			return responseError(response)
+		}
+
+		op := response.Operation
+		if op.State != lastState {
+			fmt.Printf("  %s %s\n", colorize(ColorGray, op.Service+":"), op.State)
+			lastState = op.State
+		}
+
+		if op.Done() {
+			if op.State == OperationFailed {
+				return &IPCError{Code: op.Code, Message: fmt.Sprintf("%s %s failed: %s", op.Type, op.Service, op.Error)}
+			}
+			fmt.Println(colorize(ColorGreen, fmt.Sprintf("✓ Service '%s' %s completed", op.Service, op.Type)))
+			return nil
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func upgradeSupervisor(binary string) error {
+	if binary != "" {
+		absPath, err := filepath.Abs(binary)
+		if err != nil {
+			return err
+		}
+		binary = absPath
+	}
+
+	response, err := sendIPCCommand(IPCCommand{Type: CmdUpgrade, Binary: binary})
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return responseError(response)
+	}
+
+	fmt.Println(colorize(ColorGreen, "✓ "+response.Message))
+	return nil
+}
+
+func showStatus() error {
+	response, err := sendIPCCommand(IPCCommand{Type: CmdGetStatus})
+	if err != nil {
+		return err
+	}
+
+	if response.Success {
+		fmt.Printf("%s: %s\n",
+			colorize(ColorBoldCyan, "System Status"),
+			colorize(ColorGreen, response.Message))
+	} else {
+		return responseError(response)
+	}
+
+	return nil
+}
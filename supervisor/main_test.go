@@ -1,4 +1,4 @@
-package main
+package supervisor
 
 import (
 	"errors"
@@ -78,6 +78,33 @@ func TestColorize(t *testing.T) {
 	}
 }
 
+// Test colors are disabled by --no-color, NO_COLOR and a stdout that isn't a terminal
+func TestApplyColorFlags(t *testing.T) {
+	defer func() { colorEnabled, noColor = true, false }()
+
+	// Test binaries write to a pipe or a file
+	applyColorFlags()
+	if colorEnabled != stdoutIsTerminal() {
+		t.Errorf("colorEnabled = %v, want %v for this stdout", colorEnabled, stdoutIsTerminal())
+	}
+
+	t.Setenv(envNoColor, "1")
+	applyColorFlags()
+	if colorEnabled {
+		t.Error("colors should be disabled with NO_COLOR")
+	}
+	t.Setenv(envNoColor, "")
+	noColor = true
+	applyColorFlags()
+	if colorEnabled {
+		t.Error("colors should be disabled with --no-color")
+	}
+
+	if got := colorize(ColorRed, "error"); got != "error" {
+		t.Errorf("colorize() = %q without colors, want plain text", got)
+	}
+}
+
 // Test DependsOnField UnmarshalTOML
 func TestDependsOnFieldUnmarshalTOML(t *testing.T) {
 	tests := []struct {
@@ -665,6 +692,42 @@ func TestValidateConfigDefaults(t *testing.T) {
 	}
 }
 
+// Test validate_commands = false skips command and script checks
+func TestValidateCommandsDisabled(t *testing.T) {
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name    string
+		global  *bool
+		service *bool
+		wantErr bool
+	}{
+		{"Default validates", nil, nil, true},
+		{"Global off", &disabled, nil, false},
+		{"Service off", nil, &disabled, false},
+		{"Service overrides global", &disabled, &enabled, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				ValidateCommands: tt.global,
+				Services: []Service{{
+					Name:             "late",
+					Command:          "/opt/installed-later/bin/app",
+					PreScript:        "/opt/installed-later/setup.sh",
+					ValidateCommands: tt.service,
+				}},
+			}
+
+			err := validateConfig(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 // Benchmark tests
 func BenchmarkGetStateColor(b *testing.B) {
 	for i := 0; i < b.N; i++ {
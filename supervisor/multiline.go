@@ -0,0 +1,163 @@
+package supervisor
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// multiline defaults
+const (
+	defaultMultilineTimeout  = 200 * time.Millisecond
+	defaultMultilineMaxLines = 500
+)
+
+// Multiline joins the lines of a record, such as a stack trace, into one
+// before it is prefixed or encoded as JSON. With neither continuation nor
+// start, lines following each other within timeout_ms form a record.
+type Multiline struct {
+	Continuation string `toml:"continuation,omitempty"` // Lines matching continue the record of the previous line
+	Start        string `toml:"start,omitempty"`        // Lines matching start a record; the others continue it
+	TimeoutMS    int    `toml:"timeout_ms,omitempty"`   // Milliseconds without a line before a record is complete (default 200)
+	MaxLines     int    `toml:"max_lines,omitempty"`    // Lines a record holds at most (default 500)
+}
+
+func (m *Multiline) timeout() time.Duration {
+	if m.TimeoutMS <= 0 {
+		return defaultMultilineTimeout
+	}
+	return time.Duration(m.TimeoutMS) * time.Millisecond
+}
+
+func (m *Multiline) maxLines() int {
+	if m.MaxLines <= 0 {
+		return defaultMultilineMaxLines
+	}
+	return m.MaxLines
+}
+
+// multilineJoiner joins the lines of an output stream into records, passed
+// to emit once the next record starts, max_lines is reached or no line came
+// for timeout_ms
+type multilineJoiner struct {
+	continuation *regexp.Regexp
+	start        *regexp.Regexp
+	timeout      time.Duration
+	maxLines     int
+	emit         func(record string)
+
+	mu      sync.Mutex // Lines come from the reader, the timer flushes
+	pending []string
+	timer   *time.Timer
+}
+
+// newMultilineJoiner returns a joiner passing records to emit, nil without
+// a multiline config
+func newMultilineJoiner(config *Multiline, emit func(record string)) *multilineJoiner {
+	if config == nil {
+		return nil
+	}
+	j := &multilineJoiner{timeout: config.timeout(), maxLines: config.maxLines(), emit: emit}
+	// Validated with the config
+	if config.Continuation != "" {
+		j.continuation, _ = regexp.Compile(config.Continuation)
+	}
+	if config.Start != "" {
+		j.start, _ = regexp.Compile(config.Start)
+	}
+	return j
+}
+
+// add adds a line to the pending record, or emits the record and starts the
+// next one with it
+func (j *multilineJoiner) add(line string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.pending) > 0 && !j.continues(line) {
+		j.flushLocked()
+	}
+	j.pending = append(j.pending, line)
+	if len(j.pending) >= j.maxLines {
+		j.flushLocked()
+		return
+	}
+	if j.timer == nil {
+		j.timer = time.AfterFunc(j.timeout, j.flush)
+	} else {
+		j.timer.Reset(j.timeout)
+	}
+}
+
+// continues reports whether line belongs to the pending record. Without a
+// pattern it always does: the timer completes records after timeout_ms.
+func (j *multilineJoiner) continues(line string) bool {
+	switch {
+	case j.continuation != nil:
+		return j.continuation.MatchString(line)
+	case j.start != nil:
+		return !j.start.MatchString(line)
+	}
+	return true
+}
+
+// flush emits the pending record, if any
+func (j *multilineJoiner) flush() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.flushLocked()
+}
+
+func (j *multilineJoiner) flushLocked() {
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+	if len(j.pending) == 0 {
+		return
+	}
+	record := strings.Join(j.pending, "\n")
+	j.pending = j.pending[:0]
+	j.emit(record)
+}
+
+func validateMultiline(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	multiline := service.Multiline
+	if multiline == nil {
+		return errors
+	}
+	if multiline.Continuation != "" && multiline.Start != "" {
+		errors = append(errors, ValidationError{
+			Field:   "multiline",
+			Service: service.Name,
+			Message: "multiline takes continuation or start, not both",
+		})
+	}
+	errors = append(errors, validatePatterns(service.Name, "multiline.continuation", []string{multiline.Continuation})...)
+	errors = append(errors, validatePatterns(service.Name, "multiline.start", []string{multiline.Start})...)
+	if multiline.TimeoutMS < 0 || multiline.MaxLines < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "multiline",
+			Service: service.Name,
+			Message: "timeout_ms and max_lines cannot be negative",
+		})
+	}
+
+	return errors
+}
+
+// describeMultiline summarizes how the lines of a service are joined
+func describeMultiline(multiline *Multiline) string {
+	switch {
+	case multiline.Continuation != "":
+		return "lines matching " + multiline.Continuation + " continue a record"
+	case multiline.Start != "":
+		return "lines matching " + multiline.Start + " start a record"
+	}
+	return "lines within " + multiline.timeout().String() + " form a record"
+}
@@ -0,0 +1,155 @@
+package supervisor
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordCollector collects the records a multilineJoiner emits
+type recordCollector struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (c *recordCollector) emit(record string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, record)
+}
+
+func (c *recordCollector) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.records)
+}
+
+func TestMultilineJoiner(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Multiline
+		lines  []string
+		want   []string
+	}{
+		{
+			name:   "Java stack trace with continuation",
+			config: Multiline{Continuation: `^\s+at |^Caused by:`},
+			lines: []string{
+				"Exception in thread \"main\" java.lang.IllegalStateException: boom",
+				"\tat com.example.App.run(App.java:12)",
+				"Caused by: java.io.IOException: closed",
+				"\tat com.example.Io.read(Io.java:40)",
+				"started worker",
+			},
+			want: []string{
+				"Exception in thread \"main\" java.lang.IllegalStateException: boom\n\tat com.example.App.run(App.java:12)\n" +
+					"Caused by: java.io.IOException: closed\n\tat com.example.Io.read(Io.java:40)",
+				"started worker",
+			},
+		},
+		{
+			name:   "Python traceback with start",
+			config: Multiline{Start: `^\d{4}-\d{2}-\d{2} `},
+			lines: []string{
+				"2025-01-15 14:02:35 ERROR request failed",
+				"Traceback (most recent call last):",
+				"  File \"app.py\", line 3, in <module>",
+				"ValueError: bad",
+				"2025-01-15 14:02:36 INFO next request",
+			},
+			want: []string{
+				"2025-01-15 14:02:35 ERROR request failed\nTraceback (most recent call last):\n" +
+					"  File \"app.py\", line 3, in <module>\nValueError: bad",
+				"2025-01-15 14:02:36 INFO next request",
+			},
+		},
+		{
+			name:   "max_lines",
+			config: Multiline{Continuation: `^\s`, MaxLines: 2},
+			lines:  []string{"a", " b", " c", " d"},
+			want:   []string{"a\n b", " c\n d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &recordCollector{}
+			joiner := newMultilineJoiner(&tt.config, collector.emit)
+			for _, line := range tt.lines {
+				joiner.add(line)
+			}
+			joiner.flush()
+			if got := collector.get(); !slices.Equal(got, tt.want) {
+				t.Errorf("records = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test lines written in a burst form a record without a pattern, completed
+// once no line came for timeout_ms
+func TestMultilineJoinerTimeout(t *testing.T) {
+	collector := &recordCollector{}
+	joiner := newMultilineJoiner(&Multiline{TimeoutMS: 50}, collector.emit)
+
+	joiner.add("Traceback (most recent call last):")
+	joiner.add("ValueError: bad")
+	if !waitFor(t, 2*time.Second, func() bool { return len(collector.get()) == 1 }) {
+		t.Fatal("the record was not completed after timeout_ms")
+	}
+	joiner.add("next line")
+	if !waitFor(t, 2*time.Second, func() bool { return len(collector.get()) == 2 }) {
+		t.Fatal("the second record was not completed after timeout_ms")
+	}
+
+	want := []string{"Traceback (most recent call last):\nValueError: bad", "next line"}
+	if got := collector.get(); !slices.Equal(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+}
+
+// Test a joined record is a single JSON record
+func TestMultilineJSONRecord(t *testing.T) {
+	savedFormat, savedPipe := logFormat, logPipe
+	defer func() { logFormat, logPipe = savedFormat, savedPipe }()
+
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+	logPipe = newLogPipeline(out, LoggingConfig{})
+	logFormat = LogFormatJSON
+
+	output := newServiceOutput(&Service{Name: "multiline-test", Multiline: &Multiline{Continuation: `^\s+at `}}, 14)
+	output.writeLine("java.lang.IllegalStateException: boom")
+	output.writeLine("\tat com.example.App.run(App.java:12)")
+	output.close()
+
+	want := `"message":"java.lang.IllegalStateException: boom\n\tat com.example.App.run(App.java:12)"`
+	if !waitFor(t, time.Second, func() bool { return len(linesContaining(out.String(), want)) == 1 }) {
+		t.Errorf("output = %q, want one record with %s", out.String(), want)
+	}
+}
+
+func TestValidateMultiline(t *testing.T) {
+	tests := []struct {
+		name      string
+		multiline *Multiline
+		wantErr   bool
+	}{
+		{"none", nil, false},
+		{"timeout only", &Multiline{TimeoutMS: 100}, false},
+		{"continuation", &Multiline{Continuation: `^\s`}, false},
+		{"both patterns", &Multiline{Continuation: `^\s`, Start: `^\d`}, true},
+		{"bad pattern", &Multiline{Start: "("}, true},
+		{"negative max_lines", &Multiline{MaxLines: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateMultiline(&Service{Name: "web", Multiline: tt.multiline})
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateMultiline() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
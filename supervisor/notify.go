@@ -0,0 +1,362 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Notifier types
+const (
+	NotifierWebhook = "webhook" // POST of the notification as JSON, or of the rendered template
+	NotifierSlack   = "slack"   // Slack-compatible incoming webhook ({"text": ...})
+	NotifierSMTP    = "smtp"    // Email through an SMTP server
+)
+
+// notifyEvents are the events notifiers fire on: a service failed for good,
+// the crash loop breaker stopped restarting one, or a required service failed
+// and the supervisor shuts down
+var notifyEvents = []string{EventFailed, EventCrashLoop, EventShutdown}
+
+// notifyTimeout bounds a single notification, and how long shutdown waits for
+// the pending ones
+const notifyTimeout = 10 * time.Second
+
+// Default templates of the notification text and of the email subject
+const (
+	defaultNotifyTemplate = `[{{.Host}}] service {{.Service}} {{.Event}}{{with .Message}}: {{.}}{{end}}`
+	defaultNotifySubject  = `[go-overlay] {{.Service}} {{.Event}} on {{.Host}}`
+)
+
+// Notifier sends a notification when a service fails. Notifiers of the config
+// fire for every service, those of a service only for it.
+type Notifier struct {
+	Name     string            `toml:"name,omitempty"`     // Shown in logs (default: the type)
+	Type     string            `toml:"type"`               // webhook, slack or smtp
+	Events   []string          `toml:"events,omitempty"`   // failed, crash_loop, shutdown (default: all)
+	Template string            `toml:"template,omitempty"` // text/template of the payload (webhook) or text (slack, smtp)
+	URL      string            `toml:"url,omitempty"`      // webhook and slack
+	Headers  map[string]string `toml:"headers,omitempty"`  // webhook and slack
+
+	// smtp
+	Server   string   `toml:"server,omitempty"` // host:port
+	Username string   `toml:"username,omitempty"`
+	Password string   `toml:"password,omitempty"`
+	From     string   `toml:"from,omitempty"`
+	To       []string `toml:"to,omitempty"`
+	Subject  string   `toml:"subject,omitempty"` // text/template of the subject
+}
+
+// Notification is what notifier templates see as "."; webhooks without a
+// template receive it as JSON
+type Notification struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"` // failed, crash_loop or shutdown
+	Service string    `json:"service"`
+	Message string    `json:"message,omitempty"`
+	Host    string    `json:"host"`
+}
+
+func (n *Notifier) String() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.Type
+}
+
+// firesOn reports whether the notifier is interested in events of type event
+func (n *Notifier) firesOn(event string) bool {
+	return len(n.Events) == 0 || slices.Contains(n.Events, event)
+}
+
+// serviceNotifiers returns the notifiers of the config and of a service
+func serviceNotifiers(config *Config, service *Service) []Notifier {
+	return append(slices.Clone(config.Notifiers), service.Notifiers...)
+}
+
+// pendingNotifications tracks the notifications being sent, so shutdown can
+// wait for the one about the required service that caused it
+var pendingNotifications sync.WaitGroup
+
+// alert publishes event and sends its notifications
+func alert(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	events.publish(event)
+	notifyEvent(event)
+}
+
+// notifyEvent sends the notifications of event in the background
+func notifyEvent(event Event) {
+	if !slices.Contains(notifyEvents, event.Type) {
+		return
+	}
+	config := currentConfig()
+	if config == nil {
+		return
+	}
+
+	service, _ := findServiceConfig(event.Service)
+	notifiers := serviceNotifiers(config, &service)
+
+	host, _ := os.Hostname()
+	note := Notification{Time: event.Time, Event: event.Type, Service: event.Service, Message: event.Message, Host: host}
+	for i := range notifiers {
+		notifier := &notifiers[i]
+		if !notifier.firesOn(event.Type) {
+			continue
+		}
+		pendingNotifications.Add(1)
+		go func() {
+			defer pendingNotifications.Done()
+			if err := notifier.send(note); err != nil {
+				logger.Warn(fmt.Sprintf("Notifier '%s' failed to send the %s notification of service '%s': %v",
+					notifier, note.Event, colorize(ColorCyan, note.Service), err))
+			}
+		}()
+	}
+}
+
+// flushNotifications waits for the notifications being sent, at most
+// notifyTimeout
+func flushNotifications() {
+	done := make(chan struct{})
+	go func() {
+		pendingNotifications.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(notifyTimeout):
+		logger.Warn("Gave up waiting for pending notifications")
+	}
+}
+
+// send delivers one notification
+func (n *Notifier) send(note Notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	switch n.Type {
+	case NotifierWebhook:
+		body, err := n.webhookBody(note)
+		if err != nil {
+			return err
+		}
+		return n.post(ctx, body)
+	case NotifierSlack:
+		text, err := renderNotifyTemplate(n.Template, defaultNotifyTemplate, note)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(map[string]string{"text": text})
+		if err != nil {
+			return err
+		}
+		return n.post(ctx, body)
+	case NotifierSMTP:
+		message, err := n.smtpMessage(note)
+		if err != nil {
+			return err
+		}
+		return n.sendMail(ctx, message)
+	}
+	return fmt.Errorf("unknown notifier type '%s'", n.Type)
+}
+
+// webhookBody is the rendered template, or the notification as JSON
+func (n *Notifier) webhookBody(note Notification) ([]byte, error) {
+	if n.Template == "" {
+		return json.Marshal(note)
+	}
+	body, err := renderNotifyTemplate(n.Template, "", note)
+	return []byte(body), err
+}
+
+func (n *Notifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range n.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// smtpMessage builds the email of a notification
+func (n *Notifier) smtpMessage(note Notification) ([]byte, error) {
+	subject, err := renderNotifyTemplate(n.Subject, defaultNotifySubject, note)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderNotifyTemplate(n.Template, defaultNotifyTemplate, note)
+	if err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", n.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", strings.ReplaceAll(subject, "\n", " "))
+	fmt.Fprintf(&message, "Date: %s\r\n", note.Time.Format(time.RFC1123Z))
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	message.WriteString("\r\n")
+	return message.Bytes(), nil
+}
+
+// sendMail is smtp.SendMail with a deadline, using STARTTLS when the server
+// offers it
+func (n *Notifier) sendMail(ctx context.Context, message []byte) error {
+	host, _, err := net.SplitHostPort(n.Server)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.Server)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.Username, n.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.From); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// renderNotifyTemplate renders text, or fallback when text is empty
+func renderNotifyTemplate(text, fallback string, note Notification) (string, error) {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := parseNotifyTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, note); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// parseNotifyTemplate parses a notifier template; json quotes a value for
+// webhook payloads, e.g. {"text": {{json .Message}}}
+func parseNotifyTemplate(text string) (*template.Template, error) {
+	return template.New("notification").Funcs(template.FuncMap{
+		"json": func(value any) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}).Parse(text)
+}
+
+// validateNotifiers checks the notifiers of the config (service == "") or of
+// a service
+func validateNotifiers(notifiers []Notifier, service string) ValidationErrors {
+	var errors ValidationErrors
+
+	invalid := func(i int, field, message string) {
+		errors = append(errors, ValidationError{
+			Field:   fmt.Sprintf("notifiers[%d].%s", i, field),
+			Service: service,
+			Message: message,
+		})
+	}
+	for i := range notifiers {
+		notifier := &notifiers[i]
+
+		switch notifier.Type {
+		case NotifierWebhook, NotifierSlack:
+			if u, err := url.Parse(notifier.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				invalid(i, "url", fmt.Sprintf("%s notifier needs an http(s) url (got '%s')", notifier.Type, notifier.URL))
+			}
+		case NotifierSMTP:
+			if _, _, err := net.SplitHostPort(notifier.Server); err != nil {
+				invalid(i, "server", fmt.Sprintf("smtp notifier needs a server as host:port (got '%s')", notifier.Server))
+			}
+			if notifier.From == "" || len(notifier.To) == 0 {
+				invalid(i, "to", "smtp notifier needs from and at least one to address")
+			}
+		default:
+			invalid(i, "type", fmt.Sprintf("unknown notifier type '%s' (use webhook, slack or smtp)", notifier.Type))
+		}
+
+		for _, event := range notifier.Events {
+			if !slices.Contains(notifyEvents, event) {
+				invalid(i, "events", fmt.Sprintf("unknown event '%s' (use %s)", event, strings.Join(notifyEvents, ", ")))
+			}
+		}
+		templates := []struct{ field, text string }{
+			{"template", notifier.Template},
+			{"subject", notifier.Subject},
+		}
+		for _, tmpl := range templates {
+			if _, err := parseNotifyTemplate(tmpl.text); err != nil {
+				invalid(i, tmpl.field, fmt.Sprintf("invalid template: %v", err))
+			}
+		}
+	}
+
+	return errors
+}
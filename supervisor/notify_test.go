@@ -0,0 +1,171 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test notifier validation
+func TestValidateNotifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifier Notifier
+		errors   int
+	}{
+		{"webhook", Notifier{Type: NotifierWebhook, URL: "https://hooks.example.com/ops"}, 0},
+		{"slack", Notifier{Type: NotifierSlack, URL: "https://hooks.slack.com/services/T0/B0/X", Events: []string{EventCrashLoop}}, 0},
+		{"smtp", Notifier{Type: NotifierSMTP, Server: "mail:587", From: "overlay@example.com", To: []string{"ops@example.com"}}, 0},
+		{"unknown type", Notifier{Type: "pager"}, 1},
+		{"webhook without url", Notifier{Type: NotifierWebhook}, 1},
+		{"webhook url without scheme", Notifier{Type: NotifierWebhook, URL: "hooks.example.com/ops"}, 1},
+		{"smtp without port", Notifier{Type: NotifierSMTP, Server: "mail", From: "overlay@example.com", To: []string{"ops@example.com"}}, 1},
+		{"smtp without recipients", Notifier{Type: NotifierSMTP, Server: "mail:25", From: "overlay@example.com"}, 1},
+		{"unknown event", Notifier{Type: NotifierSlack, URL: "https://hooks.slack.com/x", Events: []string{"restart"}}, 1},
+		{"bad template", Notifier{Type: NotifierSlack, URL: "https://hooks.slack.com/x", Template: "{{.Service"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateNotifiers([]Notifier{tt.notifier}, "web"); len(got) != tt.errors {
+				t.Errorf("validateNotifiers() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test the payload each notifier type sends
+func TestNotifierSend(t *testing.T) {
+	var mu sync.Mutex
+	var body string
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		body, header = string(data), r.Header
+		mu.Unlock()
+		if strings.Contains(string(data), "reject") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	note := Notification{Time: time.Unix(0, 0).UTC(), Event: EventFailed, Service: "web", Message: "exit status 2", Host: "box"}
+	tests := []struct {
+		name     string
+		notifier Notifier
+		note     Notification
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "webhook",
+			notifier: Notifier{Type: NotifierWebhook, URL: server.URL},
+			note:     note,
+			want:     `{"time":"1970-01-01T00:00:00Z","event":"failed","service":"web","message":"exit status 2","host":"box"}`,
+		},
+		{
+			name:     "webhook template",
+			notifier: Notifier{Type: NotifierWebhook, URL: server.URL, Headers: map[string]string{"X-Token": "secret"}, Template: `{"summary": {{json .Message}}, "service": "{{upper .Service}}"}`},
+			note:     note,
+			want:     `{"summary": "exit status 2", "service": "WEB"}`,
+		},
+		{
+			name:     "slack",
+			notifier: Notifier{Type: NotifierSlack, URL: server.URL},
+			note:     note,
+			want:     `{"text":"[box] service web failed: exit status 2"}`,
+		},
+		{
+			name:     "error status",
+			notifier: Notifier{Type: NotifierSlack, URL: server.URL},
+			note:     Notification{Event: EventFailed, Service: "reject"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.notifier.send(tt.note)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if body != tt.want {
+				t.Errorf("body = %s, want %s", body, tt.want)
+			}
+			if token := tt.notifier.Headers["X-Token"]; token != "" && header.Get("X-Token") != token {
+				t.Errorf("X-Token header = %q, want %q", header.Get("X-Token"), token)
+			}
+		})
+	}
+}
+
+// Test the email of an smtp notifier
+func TestSMTPMessage(t *testing.T) {
+	notifier := Notifier{Type: NotifierSMTP, Server: "mail:25", From: "overlay@example.com", To: []string{"ops@example.com", "dev@example.com"}}
+	note := Notification{Time: time.Unix(0, 0).UTC(), Event: EventCrashLoop, Service: "worker", Message: "5 restarts in 1m0s", Host: "box"}
+
+	message, err := notifier.smtpMessage(note)
+	if err != nil {
+		t.Fatalf("smtpMessage() error = %v", err)
+	}
+	for _, want := range []string{
+		"From: overlay@example.com\r\n",
+		"To: ops@example.com, dev@example.com\r\n",
+		"Subject: [go-overlay] worker crash_loop on box\r\n",
+		"\r\n\r\n[box] service worker crash_loop: 5 restarts in 1m0s\r\n",
+	} {
+		if !strings.Contains(string(message), want) {
+			t.Errorf("message misses %q:\n%s", want, message)
+		}
+	}
+}
+
+// Test notifications fire on failures only, from the notifiers of the config
+// and of the failed service, filtered by their events
+func TestNotifyEvent(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var note Notification
+		_ = json.NewDecoder(r.Body).Decode(&note)
+		mu.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], note.Service+" "+note.Event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	useConfig(t, &Config{
+		Notifiers: []Notifier{{Type: NotifierWebhook, URL: server.URL + "/global"}},
+		Services: []Service{
+			{Name: "web", Notifiers: []Notifier{{Type: NotifierWebhook, URL: server.URL + "/web", Events: []string{EventCrashLoop}}}},
+			{Name: "db"},
+		},
+	})
+
+	alert(Event{Type: EventFailed, Service: "web", Message: "exit status 1"})
+	alert(Event{Type: EventRestart, Service: "web"})
+	alert(Event{Type: EventCrashLoop, Service: "web"})
+	alert(Event{Type: EventShutdown, Service: "db"})
+	flushNotifications()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(received["/global"], ", "); len(received["/global"]) != 3 ||
+		!strings.Contains(got, "web failed") || !strings.Contains(got, "web crash_loop") || !strings.Contains(got, "db shutdown") {
+		t.Errorf("global notifier received %v, want the failure, crash loop and shutdown", received["/global"])
+	}
+	if got := received["/web"]; len(got) != 1 || got[0] != "web crash_loop" {
+		t.Errorf("service notifier received %v, want only the crash loop", got)
+	}
+}
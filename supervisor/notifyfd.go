@@ -0,0 +1,47 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+)
+
+// maxNotificationFD bounds the descriptor a service may ask to be notified on
+const maxNotificationFD = 255
+
+// attachNotificationFD passes the write end of a pipe to cmd as descriptor fd,
+// following s6's notification-fd convention. The caller closes the write end
+// once the process is started and reads readiness from the read end.
+func attachNotificationFD(cmd *exec.Cmd, fd int) (r, w *os.File, err error) {
+	r, w, err = os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	// ExtraFiles[i] becomes descriptor 3+i; nil entries are closed in the child
+	files := make([]*os.File, fd-2)
+	files[fd-3] = w
+	cmd.ExtraFiles = files
+	return r, w, nil
+}
+
+// receiveNotificationFD marks the service ready once it writes a newline to
+// its notification fd. Closing the fd without a newline leaves it not ready.
+func receiveNotificationFD(ctx context.Context, sp *ServiceProcess, r *os.File) {
+	go func() {
+		<-ctx.Done()
+		_ = r.Close()
+	}()
+
+	buf := make([]byte, 512)
+	for {
+		n, err := r.Read(buf)
+		if bytes.IndexByte(buf[:n], '\n') >= 0 {
+			sp.SetReady()
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
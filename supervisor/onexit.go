@@ -0,0 +1,72 @@
+package supervisor
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Variables telling on_exit how the service ended
+const (
+	EnvExitCode   = "EXIT_CODE"   // Exit code, 128+N when killed by signal N
+	EnvExitSignal = "EXIT_SIGNAL" // Signal that killed the service ("SIGKILL"), empty otherwise
+	EnvExitReason = "EXIT_REASON" // "exited" on its own or "stopped" by go-overlay
+	EnvUptime     = "UPTIME"      // Seconds the service ran
+)
+
+// defaultOnExitTimeout bounds an on_exit without on_exit_timeout, in seconds
+const defaultOnExitTimeout = 30
+
+// onExitTimeout returns how long the on_exit of a service may run, 0 without
+// an on_exit
+func onExitTimeout(service *Service) time.Duration {
+	if service.OnExit == "" {
+		return 0
+	}
+	return secondsOr(service.OnExitTimeout, defaultOnExitTimeout)
+}
+
+// runOnExit runs the on_exit of a service whose process has ended, e.g. to
+// send an alert or remove PID and lock files. It runs before the service is
+// restarted or reported stopped.
+func runOnExit(serviceProcess *ServiceProcess, exitedOnOwn bool, waitErr error, uptime time.Duration) {
+	service := &serviceProcess.Config
+	if service.OnExit == "" {
+		return
+	}
+	serviceProcess.SetState(ServiceStateStopping)
+	runServiceHook(service, "on_exit", service.OnExit, onExitTimeout(service), onExitEnv(exitedOnOwn, waitErr, uptime))
+}
+
+// onExitEnv returns the exit context passed to on_exit
+func onExitEnv(exitedOnOwn bool, waitErr error, uptime time.Duration) map[string]string {
+	reason := "stopped"
+	if exitedOnOwn {
+		reason = "exited"
+	}
+	return map[string]string{
+		EnvExitCode:   strconv.Itoa(exitStatus(waitErr)),
+		EnvExitSignal: exitSignal(waitErr),
+		EnvExitReason: reason,
+		EnvUptime:     strconv.Itoa(int(uptime.Seconds())),
+	}
+}
+
+// exitSignal returns the name of the signal that killed a process, or "" if
+// it exited
+func exitSignal(waitErr error) string {
+	var ee *exec.ExitError
+	if !errors.As(waitErr, &ee) {
+		return ""
+	}
+	if status, ok := ee.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return signalName(status.Signal())
+	}
+	return ""
+}
+
+func validateOnExit(service *Service) ValidationErrors {
+	return validateHookTimeout(service, "on_exit", service.OnExit, service.OnExitTimeout)
+}
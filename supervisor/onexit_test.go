@@ -0,0 +1,98 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test on_exit validation
+func TestValidateOnExit(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"none", Service{Name: "web"}, 0},
+		{"hook", Service{Name: "web", OnExit: "rm -f /run/web.pid"}, 0},
+		{"hook with timeout", Service{Name: "web", OnExit: "rm -f /run/web.pid", OnExitTimeout: 5}, 0},
+		{"negative timeout", Service{Name: "web", OnExit: "rm -f /run/web.pid", OnExitTimeout: -1}, 1},
+		{"timeout without hook", Service{Name: "web", OnExitTimeout: 5}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateOnExit(&tt.service); len(got) != tt.errors {
+				t.Errorf("validateOnExit() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test on_exit gets the exit code, signal and uptime of a service that
+// exited on its own and of one that was stopped
+func TestOnExit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		args []string
+		stop bool
+		want string
+	}{
+		{"exited", []string{"-c", "sleep 1; exit 3"}, false, "3  exited 1"},
+		{"stopped", []string{"-c", "exec sleep 30"}, true, "143 SIGTERM stopped 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(dir, tt.name)
+			resetShutdown()
+			setConfig(&Config{
+				Services: []Service{{
+					Name: "web", Command: "/bin/sh", Args: tt.args,
+					OnExit: `echo "$EXIT_CODE $EXIT_SIGNAL $EXIT_REASON $UPTIME" > ` + output,
+				}},
+				Timeouts: Timeouts{ServiceShutdown: 2},
+			})
+			defer func() {
+				cancelShutdown()
+				// The supervise goroutine reads the config until it ends, such as
+				// to alert of the exit
+				supervisions.Wait()
+				setConfig(nil)
+			}()
+
+			if err := startService("web"); err != nil {
+				t.Fatalf("startService() error = %v", err)
+			}
+			if tt.stop {
+				if err := stopService("web"); err != nil {
+					t.Fatalf("stopService() error = %v", err)
+				}
+			}
+
+			var got string
+			if !waitFor(t, 5*time.Second, func() bool {
+				data, _ := os.ReadFile(output)
+				got = strings.TrimSpace(string(data))
+				return got != ""
+			}) {
+				t.Fatal("on_exit did not run")
+			}
+			if got != tt.want {
+				t.Errorf("on_exit got %q, want %q", got, tt.want)
+			}
+			// on_exit runs before the service is removed from the active ones
+			waitFor(t, 5*time.Second, func() bool {
+				_, running := getActiveService("web")
+				return !running
+			})
+		})
+	}
+}
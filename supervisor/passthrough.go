@@ -0,0 +1,72 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// passthroughServiceName is the name of the service running the command
+// given to `go-overlay run`
+const passthroughServiceName = "cmd"
+
+// passthroughCmd is the command given to `go-overlay run`. Like the CMD of an
+// s6-overlay container, it starts once every configured service is up and
+// the supervisor shuts down with its exit code when it exits.
+var passthroughCmd []string
+
+// loadPassthroughConfig parses the config of `go-overlay run`. Without a
+// config file or directory, the command is the only service.
+func loadPassthroughConfig(configFile, dir string) (Config, error) {
+	if dir == "" {
+		if _, err := os.Stat(configFile); os.IsNotExist(err) {
+			logger.Info(fmt.Sprintf("No config file at %s, only running the command", colorize(ColorCyan, configFile)))
+			return parseConfig(strings.NewReader(""))
+		}
+	}
+	return parseConfigSources(configFile, dir)
+}
+
+// addPassthroughService adds command as the cmd service of config, in a
+// stage after every other service, and ends the supervisor with it
+func addPassthroughService(config *Config, command []string) error {
+	if config.ExitCodeFrom != "" {
+		return fmt.Errorf("exit_code_from can't be used with go-overlay run, which exits with the code of the command")
+	}
+
+	stage := 0
+	for i := range config.Services {
+		if config.Services[i].Name == passthroughServiceName {
+			return fmt.Errorf("service name '%s' is reserved for the command of go-overlay run", passthroughServiceName)
+		}
+		stage = max(stage, config.Services[i].Stage+1)
+	}
+
+	config.Services = append(config.Services, Service{
+		Name:    passthroughServiceName,
+		Command: command[0],
+		Args:    command[1:],
+		Stage:   stage,
+	})
+	config.ExitCodeFrom = passthroughServiceName
+	return nil
+}
+
+// isPassthroughService reports whether service runs the command of
+// `go-overlay run`
+func isPassthroughService(service *Service) bool {
+	return len(passthroughCmd) > 0 && service.Name == passthroughServiceName
+}
+
+// attachPassthroughStdin gives the command of `go-overlay run` the stdin of
+// the supervisor, like the CMD of a container. On a PTY, its stdout is the
+// controlling terminal instead.
+func attachPassthroughStdin(cmd *exec.Cmd) {
+	cmd.Stdin = os.Stdin
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Ctty = 1
+}
@@ -0,0 +1,137 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Test the command of go-overlay run is added after every other service and
+// ends the supervisor
+func TestAddPassthroughService(t *testing.T) {
+	config := Config{Services: []Service{
+		{Name: "db", Command: "/bin/db", Stage: 2},
+		{Name: "api", Command: "/bin/api"},
+	}}
+
+	if err := addPassthroughService(&config, []string{"nginx", "-g", "daemon off;"}); err != nil {
+		t.Fatalf("addPassthroughService() error = %v", err)
+	}
+	if len(config.Services) != 3 {
+		t.Fatalf("got %d services, want 3", len(config.Services))
+	}
+	cmd := config.Services[2]
+	if cmd.Name != passthroughServiceName || cmd.Command != "nginx" || len(cmd.Args) != 2 || cmd.Args[1] != "daemon off;" {
+		t.Errorf("cmd service = %+v", cmd)
+	}
+	if cmd.Stage != 3 {
+		t.Errorf("cmd stage = %d, want 3", cmd.Stage)
+	}
+	if config.ExitCodeFrom != passthroughServiceName {
+		t.Errorf("exit_code_from = %q, want %q", config.ExitCodeFrom, passthroughServiceName)
+	}
+}
+
+// Test go-overlay run rejects configs it can't add its command to
+func TestAddPassthroughServiceConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"Reserved name", Config{Services: []Service{{Name: passthroughServiceName, Command: "/bin/app"}}}},
+		{"exit_code_from", Config{ExitCodeFrom: "app", Services: []Service{{Name: "app", Command: "/bin/app"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := addPassthroughService(&tt.config, []string{"true"}); err == nil {
+				t.Error("addPassthroughService() expected an error")
+			}
+		})
+	}
+}
+
+// Test go-overlay run works without a config file
+func TestLoadPassthroughConfigWithoutFile(t *testing.T) {
+	config, err := loadPassthroughConfig(filepath.Join(t.TempDir(), "services.toml"), "")
+	if err != nil {
+		t.Fatalf("loadPassthroughConfig() error = %v", err)
+	}
+	if len(config.Services) != 0 {
+		t.Errorf("got %d services, want none", len(config.Services))
+	}
+}
+
+// Test the command of go-overlay run reads the stdin of the supervisor
+func TestPassthroughStdin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	_, _ = w.WriteString("hello\n")
+	_ = w.Close()
+
+	savedStdin, savedCmd := os.Stdin, passthroughCmd
+	os.Stdin, passthroughCmd = r, []string{"/bin/sh", "-c", "read x; echo \"got $x\""}
+	resetShutdown()
+	config := &Config{Timeouts: Timeouts{ServiceShutdown: 2}}
+	if err := addPassthroughService(config, passthroughCmd); err != nil {
+		t.Fatal(err)
+	}
+	off := false
+	config.Services[0].PTY = &off
+	config.ExitCodeFrom = ""
+	setConfig(config)
+	defer func() {
+		cancelShutdown()
+		supervisions.Wait()
+		setConfig(nil)
+		os.Stdin, passthroughCmd = savedStdin, savedCmd
+	}()
+
+	if err := startService(passthroughServiceName); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	if !waitFor(t, 5*time.Second, func() bool {
+		return slices.Contains(serviceLogs.tail(passthroughServiceName, 10), "got hello")
+	}) {
+		t.Errorf("output = %q, want got hello", serviceLogs.tail(passthroughServiceName, 10))
+	}
+}
+
+// Test a config applied to go-overlay run keeps its command
+func TestApplyKeepsPassthroughService(t *testing.T) {
+	savedCmd := passthroughCmd
+	passthroughCmd = []string{"/bin/true"}
+	defer func() {
+		passthroughCmd = savedCmd
+		setConfig(nil)
+	}()
+	current := &Config{}
+	if err := addPassthroughService(current, passthroughCmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateConfig(current); err != nil {
+		t.Fatal(err)
+	}
+	setConfig(current)
+
+	response := handleApply("")
+	if !response.Success {
+		t.Fatalf("handleApply() = %+v", response)
+	}
+	for _, result := range response.Results {
+		if result.Service == passthroughServiceName {
+			t.Errorf("handleApply() acted on the command: %+v", result)
+		}
+	}
+	if _, ok := findServiceConfig(passthroughServiceName); !ok {
+		t.Error("the command was removed from the config")
+	}
+}
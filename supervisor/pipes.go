@@ -0,0 +1,183 @@
+package supervisor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// defaultStderrLevel is the level stderr lines are logged at without a PTY
+const defaultStderrLevel = LogLevelWarn
+
+// usePTY reports whether a service runs on a PTY or with pipes. Unless pty
+// is set, a PTY is only used when the supervisor's own stdout is a terminal:
+// in a container without -t, programs get the pipes they would get anyway.
+func usePTY(service *Service) bool {
+	if service.PTY != nil {
+		return *service.PTY
+	}
+	return stdoutIsTerminal()
+}
+
+// stdoutIsTerminal reports whether the supervisor's stdout is a terminal
+var stdoutIsTerminal = sync.OnceValue(func() bool {
+	return isTerminal(os.Stdout)
+})
+
+// isTerminal reports whether file is a terminal, the way isatty(3) does
+func isTerminal(file *os.File) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
+
+// serviceStderrLevel returns the level stderr lines of a service are logged at
+func serviceStderrLevel(service *Service) LogLevel {
+	if service.StderrLevel == "" {
+		return defaultStderrLevel
+	}
+	level, err := parseLogLevel(service.StderrLevel)
+	if err != nil {
+		return defaultStderrLevel
+	}
+	return level
+}
+
+// logLevelColor is the color of service names on stderr lines of a level
+func logLevelColor(level LogLevel) string {
+	switch level {
+	case LogLevelError:
+		return ColorRed
+	case LogLevelWarn:
+		return ColorYellow
+	case LogLevelDebug:
+		return ColorGray
+	}
+	return ColorWhite
+}
+
+// startWithPipes starts cmd with stdout and stderr on pipes, returning their
+// read ends, and stdin on /dev/null unless cmd.Stdin is set. Like pty.Start,
+// it makes the process a session leader, so it has its own process group.
+func startWithPipes(cmd *exec.Cmd) (stdout, stderr *os.File, err error) {
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stderr, stderrWriter, err := os.Pipe()
+	if err != nil {
+		_ = stdout.Close()
+		_ = stdoutWriter.Close()
+		return nil, nil, err
+	}
+
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+
+	err = cmd.Start()
+	// The child holds the only write ends, so the readers see EOF once it exits
+	_ = stdoutWriter.Close()
+	_ = stderrWriter.Close()
+	if err != nil {
+		_ = stdout.Close()
+		_ = stderr.Close()
+		return nil, nil, err
+	}
+	return stdout, stderr, nil
+}
+
+// closeOutput closes the PTY or the output pipes of a service, which ends
+// the readers even if a leftover child still holds the other end
+func (sp *ServiceProcess) closeOutput() {
+	for _, file := range []*os.File{sp.PTY, sp.Stdout, sp.Stderr} {
+		if file != nil {
+			_ = file.Close()
+		}
+	}
+}
+
+// scanOutput passes every non-empty line read from reader to write
+func scanOutput(reader *os.File, name string, write func(string)) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			write(line)
+		}
+	}
+	// The supervisor closes the output once the service is gone
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		logger.Info("Error reading logs for service ", name, ": ", err)
+	}
+}
+
+// pipeLogs reads the stdout and stderr pipes of a service until both are
+// closed, keeping stderr lines apart
+func pipeLogs(stdout, stderr *os.File, output *serviceOutput) {
+	defer output.close()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		scanOutput(stdout, output.name, output.writeLine)
+	}()
+	go func() {
+		defer wg.Done()
+		scanOutput(stderr, output.name, output.writeStderrLine)
+	}()
+	wg.Wait()
+}
+
+func validatePTY(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.StderrLevel != "" {
+		if _, err := parseLogLevel(service.StderrLevel); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "stderr_level",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		}
+	}
+	explicitPTY := service.PTY != nil && *service.PTY
+	if explicitPTY && (service.StderrLevel != "" || service.StderrLogOutput != nil) {
+		errors = append(errors, ValidationError{
+			Field:   "pty",
+			Service: service.Name,
+			Message: "stderr_level and stderr_log_output don't apply with pty = true, a PTY merges stderr into stdout",
+		})
+	}
+	if output := service.StderrLogOutput; output != nil {
+		if output.Path == "" {
+			errors = append(errors, ValidationError{
+				Field:   "stderr_log_output.path",
+				Service: service.Name,
+				Message: "stderr_log_output requires a path",
+			})
+		} else if service.LogOutput != nil && service.LogOutput.Path == output.Path {
+			errors = append(errors, ValidationError{
+				Field:   "stderr_log_output.path",
+				Service: service.Name,
+				Message: fmt.Sprintf("'%s' is already the log_output path; omit stderr_log_output to share it", output.Path),
+			})
+		}
+		if output.MaxSize < 0 || output.MaxAge < 0 || output.MaxFiles < 0 {
+			errors = append(errors, ValidationError{
+				Field:   "stderr_log_output",
+				Service: service.Name,
+				Message: "max_size, max_age and max_files cannot be negative",
+			})
+		}
+	}
+
+	return errors
+}
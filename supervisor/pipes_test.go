@@ -0,0 +1,130 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// Test stderr settings are rejected with pty = true
+func TestValidatePTY(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name    string
+		service Service
+		wantErr bool
+	}{
+		{"Not set", Service{Name: "web"}, false},
+		{"Pipes", Service{Name: "web", PTY: &off}, false},
+		{"Stderr level", Service{Name: "web", PTY: &off, StderrLevel: "error"}, false},
+		{"Invalid level", Service{Name: "web", PTY: &off, StderrLevel: "loud"}, true},
+		{"Level with automatic PTY", Service{Name: "web", StderrLevel: "error"}, false},
+		{"Level with PTY", Service{Name: "web", PTY: &on, StderrLevel: "error"}, true},
+		{"Stderr file", Service{Name: "web", PTY: &off, StderrLogOutput: &LogOutput{Path: "/var/log/web.err"}}, false},
+		{"Stderr file with PTY", Service{Name: "web", PTY: &on, StderrLogOutput: &LogOutput{Path: "/var/log/web.err"}}, true},
+		{"Missing path", Service{Name: "web", PTY: &off, StderrLogOutput: &LogOutput{MaxSize: 5}}, true},
+		{"Same path", Service{Name: "web", PTY: &off,
+			LogOutput:       &LogOutput{Path: "/var/log/web.log"},
+			StderrLogOutput: &LogOutput{Path: "/var/log/web.log"}}, true},
+		{"Negative", Service{Name: "web", PTY: &off, StderrLogOutput: &LogOutput{Path: "/var/log/web.err", MaxFiles: -1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validatePTY(&tt.service)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validatePTY() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Test a PTY is recognized as a terminal and a pipe isn't
+func TestIsTerminal(t *testing.T) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("pty.Open() error = %v", err)
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+	if !isTerminal(tty) {
+		t.Error("isTerminal() = false for a PTY")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if isTerminal(w) {
+		t.Error("isTerminal() = true for a pipe")
+	}
+}
+
+// Test a service with pty = false keeps stdout and stderr apart: stderr lines
+// are tagged in JSON logs and written to stderr_log_output
+func TestPipeOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	savedFormat, savedPipe, savedLogs := logFormat, logPipe, serviceLogs
+	defer func() { logFormat, logPipe, serviceLogs = savedFormat, savedPipe, savedLogs }()
+	serviceLogs = newLogRecorder(10)
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+	logPipe = newLogPipeline(out, LoggingConfig{})
+	logFormat = LogFormatJSON
+
+	dir := t.TempDir()
+	off := false
+	resetShutdown()
+	setConfig(&Config{
+		Services: []Service{{
+			Name:            "piped",
+			Command:         "/bin/sh",
+			Args:            []string{"-c", "echo to-stdout; echo to-stderr >&2; exec sleep 30"},
+			PTY:             &off,
+			StderrLevel:     "error",
+			LogOutput:       &LogOutput{Path: filepath.Join(dir, "piped.log")},
+			StderrLogOutput: &LogOutput{Path: filepath.Join(dir, "piped.err")},
+		}},
+		Timeouts: Timeouts{ServiceShutdown: 2},
+	})
+	if err := startService("piped"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	serviceProc, _ := getActiveService("piped")
+	defer func() {
+		_ = stopService("piped")
+		cancelShutdown()
+		// The output readers use the log globals until they are done
+		supervisions.Wait()
+		if serviceProc != nil {
+			serviceProc.waitForOutput(time.Second)
+		}
+		setConfig(nil)
+	}()
+	if !waitFor(t, 3*time.Second, func() bool { return len(serviceLogs.tail("piped", 10)) == 2 }) {
+		t.Fatalf("expected 2 lines of history, got %q", serviceLogs.tail("piped", 10))
+	}
+
+	logs := out.String()
+	if !strings.Contains(logs, `"level":"info","service":"piped","message":"to-stdout"`) {
+		t.Errorf("stdout line not logged at info:\n%s", logs)
+	}
+	if !strings.Contains(logs, `"level":"error","service":"piped","stream":"stderr","message":"to-stderr"`) {
+		t.Errorf("stderr line not tagged:\n%s", logs)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "piped.log")); string(data) != "to-stdout\n" {
+		t.Errorf("log_output file = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "piped.err")); string(data) != "to-stderr\n" {
+		t.Errorf("stderr_log_output file = %q", data)
+	}
+}
@@ -0,0 +1,78 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// EnvMainPID gives pre_stop the PID of the main process of the service, as
+// systemd does for ExecStop=
+const EnvMainPID = "MAINPID"
+
+// defaultPreStopTimeout bounds a pre_stop without pre_stop_timeout, in seconds
+const defaultPreStopTimeout = 30
+
+// preStopTimeout returns how long the pre_stop of a service may run, 0
+// without a pre_stop
+func preStopTimeout(service *Service) time.Duration {
+	if service.PreStop == "" {
+		return 0
+	}
+	return secondsOr(service.PreStopTimeout, defaultPreStopTimeout)
+}
+
+// runPreStop runs the pre_stop of a service about to be stopped, e.g. to
+// deregister it from a load balancer or let it finish in-flight work
+func runPreStop(serviceProcess *ServiceProcess) {
+	service := &serviceProcess.Config
+	if service.PreStop == "" {
+		return
+	}
+	runServiceHook(service, "pre_stop", service.PreStop, preStopTimeout(service),
+		map[string]string{EnvMainPID: strconv.Itoa(serviceProcess.GetPID())})
+}
+
+// runServiceHook runs a hook command of a service with its environment plus
+// env, killing it after timeout. Failures are logged and never keep the
+// service from stopping or restarting.
+func runServiceHook(service *Service, hook, command string, timeout time.Duration, env map[string]string) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Info(fmt.Sprintf("Running %s of service '%s'", hook, colorize(ColorCyan, service.Name)))
+	if err := runScriptContext(ctx, command, mergeEnv(buildServiceEnv(service), env)); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			logger.Warn(fmt.Sprintf("%s of service '%s' timed out after %s", hook, colorize(ColorCyan, service.Name), timeout))
+		} else {
+			logger.Warn(fmt.Sprintf("%s of service '%s' failed: %v", hook, colorize(ColorCyan, service.Name), err))
+		}
+	}
+}
+
+func validatePreStop(service *Service) ValidationErrors {
+	return validateHookTimeout(service, "pre_stop", service.PreStop, service.PreStopTimeout)
+}
+
+// validateHookTimeout checks the <hook>_timeout of a hook command
+func validateHookTimeout(service *Service, hook, command string, timeout int) ValidationErrors {
+	var errors ValidationErrors
+
+	if timeout < 0 {
+		errors = append(errors, ValidationError{
+			Field:   hook + "_timeout",
+			Service: service.Name,
+			Message: fmt.Sprintf("cannot be negative (got %d)", timeout),
+		})
+	}
+	if timeout > 0 && command == "" {
+		errors = append(errors, ValidationError{
+			Field:   hook + "_timeout",
+			Service: service.Name,
+			Message: fmt.Sprintf("%s_timeout needs a %s", hook, hook),
+		})
+	}
+
+	return errors
+}
@@ -0,0 +1,90 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test pre_stop validation
+func TestValidatePreStop(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"none", Service{Name: "web"}, 0},
+		{"hook", Service{Name: "web", PreStop: "deregister web"}, 0},
+		{"hook with timeout", Service{Name: "web", PreStop: "deregister web", PreStopTimeout: 5}, 0},
+		{"negative timeout", Service{Name: "web", PreStop: "deregister web", PreStopTimeout: -1}, 1},
+		{"timeout without hook", Service{Name: "web", PreStopTimeout: 5}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validatePreStop(&tt.service); len(got) != tt.errors {
+				t.Errorf("validatePreStop() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test pre_stop runs while the service is still running, and a hung hook is
+// killed after its timeout without keeping the service from stopping
+func TestPreStop(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "pre_stop")
+
+	tests := []struct {
+		name    string
+		preStop string
+		timeout int
+		want    string
+	}{
+		{"runs before the stop", "kill -0 $MAINPID && echo $MAINPID > " + marker, 0, "running"},
+		{"times out", "sleep 30", 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetShutdown()
+			useConfig(t, &Config{
+				Services: []Service{{
+					Name: "web", Command: "/bin/sleep", Args: []string{"30"},
+					PreStop: tt.preStop, PreStopTimeout: tt.timeout,
+				}},
+				Timeouts: Timeouts{ServiceShutdown: 2},
+			})
+			defer stopTestServices("web")
+
+			if err := startService("web"); err != nil {
+				t.Fatalf("startService() error = %v", err)
+			}
+			sp, _ := getActiveService("web")
+			pid := sp.GetPID()
+
+			start := time.Now()
+			if err := stopService("web"); err != nil {
+				t.Fatalf("stopService() error = %v", err)
+			}
+			if _, running := getActiveService("web"); running {
+				t.Error("service is still running")
+			}
+
+			if tt.want == "running" {
+				data, err := os.ReadFile(marker)
+				if err != nil || strings.TrimSpace(string(data)) != strconv.Itoa(pid) {
+					t.Errorf("pre_stop wrote %q (%v), want MAINPID %d of the running service", data, err, pid)
+				}
+			} else if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("stop took %s, want the hook killed after 1s", elapsed)
+			}
+		})
+	}
+}
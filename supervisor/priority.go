@@ -0,0 +1,119 @@
+package supervisor
+
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+)
+
+// I/O scheduling classes of ionice
+const (
+	IOClassRealtime   = "realtime"
+	IOClassBestEffort = "best-effort"
+	IOClassIdle       = "idle"
+)
+
+// ioprioClasses maps ionice classes to IOPRIO_CLASS_* of linux/ioprio.h
+var ioprioClasses = map[string]int{
+	IOClassRealtime:   1,
+	IOClassBestEffort: 2,
+	IOClassIdle:       3,
+}
+
+// Bounds of nice values and I/O priority levels
+const (
+	minNice        = -20
+	maxNice        = 19
+	maxIOLevel     = 7
+	defaultIOLevel = 4
+	ioprioWho      = 1 // IOPRIO_WHO_PROCESS
+	ioprioShift    = 13
+)
+
+// IOPriority is the I/O scheduling class and level of a service, as set by
+// ionice(1). The level (0 is the highest priority) doesn't apply to idle.
+type IOPriority struct {
+	Class string `toml:"class"`
+	Level *int   `toml:"level,omitempty"` // 0-7, default 4
+}
+
+// value encodes the priority for ioprio_set(2)
+func (p *IOPriority) value() int {
+	level := defaultIOLevel
+	if p.Level != nil {
+		level = *p.Level
+	}
+	if p.Class == IOClassIdle {
+		level = 0
+	}
+	return ioprioClasses[p.Class]<<ioprioShift | level
+}
+
+func (p *IOPriority) String() string {
+	if p.Class == IOClassIdle {
+		return p.Class
+	}
+	level := defaultIOLevel
+	if p.Level != nil {
+		level = *p.Level
+	}
+	return fmt.Sprintf("%s (level %d)", p.Class, level)
+}
+
+// setNice sets the nice value of the calling thread, which the command
+// inherits when executed from it
+func setNice(raw string) error {
+	nice, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("%s: invalid nice '%s'", envExecSetup, raw)
+	}
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice); err != nil {
+		return fmt.Errorf("could not set nice to %d: %w", nice, err)
+	}
+	return nil
+}
+
+// setIOPriority sets the I/O priority encoded by IOPriority.value on the
+// calling thread
+func setIOPriority(raw string) error {
+	ioprio, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("%s: invalid ionice '%s'", envExecSetup, raw)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWho, 0, uintptr(ioprio)); errno != 0 {
+		return fmt.Errorf("could not set I/O priority: %w", errno)
+	}
+	return nil
+}
+
+func validatePriority(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.Nice < minNice || service.Nice > maxNice {
+		errors = append(errors, ValidationError{
+			Field:   "nice",
+			Service: service.Name,
+			Message: fmt.Sprintf("nice %d is out of range (%d to %d)", service.Nice, minNice, maxNice),
+		})
+	}
+
+	if ionice := service.IONice; ionice != nil {
+		if _, ok := ioprioClasses[ionice.Class]; !ok {
+			errors = append(errors, ValidationError{
+				Field:   "ionice.class",
+				Service: service.Name,
+				Message: fmt.Sprintf("unknown class '%s' (use %s, %s or %s)",
+					ionice.Class, IOClassRealtime, IOClassBestEffort, IOClassIdle),
+			})
+		}
+		if ionice.Level != nil && (*ionice.Level < 0 || *ionice.Level > maxIOLevel) {
+			errors = append(errors, ValidationError{
+				Field:   "ionice.level",
+				Service: service.Name,
+				Message: fmt.Sprintf("level %d is out of range (0 to %d)", *ionice.Level, maxIOLevel),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,29 @@
+package supervisor
+
+import "testing"
+
+// Test nice and ionice validation
+func TestValidatePriority(t *testing.T) {
+	level, badLevel := 0, 8
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"none", Service{Name: "web"}, 0},
+		{"nice", Service{Name: "web", Nice: 19}, 0},
+		{"nice too low", Service{Name: "web", Nice: -21}, 1},
+		{"idle", Service{Name: "web", IONice: &IOPriority{Class: IOClassIdle}}, 0},
+		{"best-effort level", Service{Name: "web", IONice: &IOPriority{Class: IOClassBestEffort, Level: &level}}, 0},
+		{"unknown class", Service{Name: "web", IONice: &IOPriority{Class: "low"}}, 1},
+		{"level out of range", Service{Name: "web", IONice: &IOPriority{Class: IOClassRealtime, Level: &badLevel}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validatePriority(&tt.service); len(got) != tt.errors {
+				t.Errorf("validatePriority() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
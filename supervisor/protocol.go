@@ -0,0 +1,62 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// ipcProtocolVersion is the version of the control socket protocol
+const ipcProtocolVersion = client.ProtocolVersion
+
+// ipcCommands are the commands this daemon understands, announced in the
+// hello handshake
+var ipcCommands = []CommandType{
+	CmdHello,
+	CmdListServices,
+	CmdRestartService,
+	CmdGetStatus,
+	CmdServiceEnv,
+	CmdOperation,
+	CmdStopServices,
+	CmdStartServices,
+	CmdUpgrade,
+	CmdGetConfig,
+	CmdApply,
+	CmdReloadService,
+	CmdServiceStats,
+	CmdNotifyReady,
+	CmdServiceLogs,
+	CmdSubscribe,
+	CmdSignalService,
+	CmdServiceExec,
+	CmdScale,
+	CmdAudit,
+	CmdHistory,
+}
+
+// handleHello answers the handshake a client opens a connection with
+func handleHello() IPCResponse {
+	return IPCResponse{
+		Success:       true,
+		Version:       ipcProtocolVersion,
+		DaemonVersion: version,
+		Commands:      ipcCommands,
+	}
+}
+
+// openIPC connects to the daemon and negotiates the protocol for a command,
+// waiting for the daemon with --wait-for-daemon
+func openIPC(cmdType CommandType) (*client.Conn, error) {
+	address := clientControlAddress()
+	daemon := &client.Client{Address: address, Dial: daemonDialer(address)}
+
+	conn, err := daemon.Open(context.Background(), cmdType)
+	var unsupported *client.UnsupportedError
+	if errors.As(err, &unsupported) {
+		return nil, fmt.Errorf("%w; upgrade it with `go-overlay upgrade`", err)
+	}
+	return conn, err
+}
@@ -0,0 +1,60 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"net"
+	"slices"
+	"testing"
+)
+
+// Test a client can open with the hello handshake or send its command directly
+func TestIPCHandshake(t *testing.T) {
+	setConfig(&Config{})
+	defer setConfig(nil)
+
+	tests := []struct {
+		name  string
+		hello bool
+	}{
+		{"Handshake", true},
+		{"Client without handshake", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+			go handleIPCConnection(server)
+
+			encoder := json.NewEncoder(client)
+			decoder := json.NewDecoder(client)
+
+			if tt.hello {
+				if err := encoder.Encode(IPCCommand{Type: CmdHello, Version: ipcProtocolVersion}); err != nil {
+					t.Fatalf("Encode() error = %v", err)
+				}
+				var hello IPCResponse
+				if err := decoder.Decode(&hello); err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if !hello.Success || hello.Version != ipcProtocolVersion || hello.DaemonVersion != version {
+					t.Errorf("hello = %+v", hello)
+				}
+				if !slices.Contains(hello.Commands, CmdServiceLogs) {
+					t.Errorf("hello commands %v miss %s", hello.Commands, CmdServiceLogs)
+				}
+			}
+
+			if err := encoder.Encode(IPCCommand{Type: CmdGetStatus}); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			var response IPCResponse
+			if err := decoder.Decode(&response); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !response.Success {
+				t.Errorf("get_status after handshake = %+v", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// readinessPollInterval is the delay between two attempts of a readiness probe
+const readinessPollInterval = 500 * time.Millisecond
+
+// ReadinessProbe tells when a started service is ready to serve: exactly one
+// of Port, TCP, File, Notify or NotificationFD is set
+type ReadinessProbe struct {
+	Port           int    `toml:"port,omitempty"`            // Local port, ready once it accepts connections
+	TCP            string `toml:"tcp,omitempty"`             // host:port, ready once it accepts connections
+	File           string `toml:"file,omitempty"`            // Ready once the file exists
+	Notify         bool   `toml:"notify,omitempty"`          // Ready once the service sends READY=1 to $NOTIFY_SOCKET or runs `go-overlay notify-ready`
+	NotificationFD int    `toml:"notification_fd,omitempty"` // Ready once the service writes a newline to this fd (s6 notification-fd)
+}
+
+func (r *ReadinessProbe) String() string {
+	switch {
+	case r.Port > 0:
+		return "port " + strconv.Itoa(r.Port)
+	case r.TCP != "":
+		return "tcp " + r.TCP
+	case r.File != "":
+		return "file " + r.File
+	case r.NotificationFD > 0:
+		return "notification-fd " + strconv.Itoa(r.NotificationFD)
+	default:
+		return "notify"
+	}
+}
+
+// address returns the TCP address probed, if any
+func (r *ReadinessProbe) address() string {
+	if r.Port > 0 {
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(r.Port))
+	}
+	return r.TCP
+}
+
+// SetReady marks the service as ready, logging the first transition
+func (sp *ServiceProcess) SetReady() {
+	sp.StateMu.Lock()
+	wasReady := sp.Ready
+	sp.Ready = true
+	// Written under the lock so a concurrent SetState cannot leave a stale flag
+	if isReadyState(sp.State, sp.Config.Readiness, true) {
+		writeReadyFlag(sp.Name)
+	}
+	sp.StateMu.Unlock()
+
+	if !wasReady {
+		logger.Success(fmt.Sprintf("Service '%s' is ready", colorize(ColorCyan, sp.Name)))
+		events.publish(Event{Type: EventReady, Service: sp.Name})
+	}
+}
+
+// IsReady reports whether the service passed its readiness probe
+func (sp *ServiceProcess) IsReady() bool {
+	sp.StateMu.RLock()
+	defer sp.StateMu.RUnlock()
+	return sp.Ready
+}
+
+// monitorReadiness polls the readiness probe of a service until it passes or
+// ctx is done. Notify probes are resolved by receiveNotify and
+// handleNotifyReady, notification fds by receiveNotificationFD instead.
+func monitorReadiness(ctx context.Context, sp *ServiceProcess) {
+	probe := sp.Config.Readiness
+	if probe.Notify || probe.NotificationFD != 0 {
+		return
+	}
+
+	for {
+		if checkReadiness(ctx, probe) {
+			sp.SetReady()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(readinessPollInterval):
+		}
+	}
+}
+
+// checkReadiness runs a single port/tcp/file readiness check
+func checkReadiness(ctx context.Context, probe *ReadinessProbe) bool {
+	if probe.File != "" {
+		_, err := os.Stat(probe.File)
+		return err == nil
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(dialCtx, "tcp", probe.address())
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// serviceReady reports whether a running service passed its readiness probe
+func serviceReady(name string) bool {
+	serviceProc, exists := getActiveService(name)
+	return exists && serviceProc.IsReady()
+}
+
+func handleNotifyReady(serviceName string) IPCResponse {
+	serviceProc, exists := getActiveService(serviceName)
+	if !exists {
+		if _, ok := findServiceConfig(serviceName); !ok {
+			return notFoundResponse(serviceName)
+		}
+		return errorResponse(errServiceNotRunning(serviceName))
+	}
+
+	serviceProc.SetReady()
+	return IPCResponse{
+		Success: true,
+		Message: fmt.Sprintf("Service '%s' marked ready", serviceName),
+	}
+}
+
+// notifyReady tells the daemon that a service is ready; the service defaults
+// to $GO_OVERLAY_SERVICE so it can be called from the service itself
+func notifyReady(serviceName string) error {
+	if serviceName == "" {
+		serviceName = os.Getenv(EnvServiceName)
+	}
+	if serviceName == "" {
+		return fmt.Errorf("no service given and %s is not set", EnvServiceName)
+	}
+
+	response, err := sendIPCCommand(IPCCommand{
+		Type:        CmdNotifyReady,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return responseError(response)
+	}
+	return nil
+}
+
+func validateReadiness(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	probe := service.Readiness
+	if probe == nil {
+		return errors
+	}
+
+	probes := 0
+	if probe.Port != 0 {
+		probes++
+	}
+	if probe.TCP != "" {
+		probes++
+	}
+	if probe.File != "" {
+		probes++
+	}
+	if probe.Notify {
+		probes++
+	}
+	if probe.NotificationFD != 0 {
+		probes++
+	}
+	if probes != 1 {
+		errors = append(errors, ValidationError{
+			Field:   "readiness",
+			Service: service.Name,
+			Message: "readiness must set exactly one of port, tcp, file, notify or notification_fd",
+		})
+	}
+
+	if probe.NotificationFD != 0 && (probe.NotificationFD < 3 || probe.NotificationFD > maxNotificationFD) {
+		errors = append(errors, ValidationError{
+			Field:   "readiness.notification_fd",
+			Service: service.Name,
+			Message: fmt.Sprintf("notification_fd %d must be between 3 and %d", probe.NotificationFD, maxNotificationFD),
+		})
+	}
+
+	if probe.Port < 0 || probe.Port > 65535 {
+		errors = append(errors, ValidationError{
+			Field:   "readiness.port",
+			Service: service.Name,
+			Message: fmt.Sprintf("port %d is out of range", probe.Port),
+		})
+	}
+	if probe.TCP != "" {
+		if _, _, err := net.SplitHostPort(probe.TCP); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "readiness.tcp",
+				Service: service.Name,
+				Message: fmt.Sprintf("invalid address '%s': %v", probe.TCP, err),
+			})
+		}
+	}
+
+	return errors
+}
+
+// validateReadyDependencies checks that services waiting for ready
+// dependencies only depend on services that define a readiness probe
+func validateReadyDependencies(services []Service) ValidationErrors {
+	var errors ValidationErrors
+
+	hasProbe := make(map[string]bool, len(services))
+	for _, service := range services {
+		hasProbe[service.Name] = service.Readiness != nil
+	}
+
+	for _, service := range services {
+		if service.DependsOnCondition != DependsOnReady {
+			continue
+		}
+		for _, dep := range service.DependsOn {
+			if probed, ok := hasProbe[dep]; ok && !probed {
+				errors = append(errors, ValidationError{
+					Field:   "depends_on_condition",
+					Service: service.Name,
+					Message: fmt.Sprintf("dependency '%s' has no readiness probe", dep),
+				})
+			}
+		}
+	}
+
+	return errors
+}
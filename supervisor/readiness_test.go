@@ -0,0 +1,204 @@
+package supervisor
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test readiness probe validation
+func TestValidateReadiness(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"none", Service{Name: "web"}, 0},
+		{"port", Service{Name: "web", Readiness: &ReadinessProbe{Port: 8080}}, 0},
+		{"tcp", Service{Name: "web", Readiness: &ReadinessProbe{TCP: "db:5432"}}, 0},
+		{"file", Service{Name: "web", Readiness: &ReadinessProbe{File: "/run/web.ready"}}, 0},
+		{"notify", Service{Name: "web", Readiness: &ReadinessProbe{Notify: true}}, 0},
+		{"notification fd", Service{Name: "web", Readiness: &ReadinessProbe{NotificationFD: 3}}, 0},
+		{"notification fd is stdio", Service{Name: "web", Readiness: &ReadinessProbe{NotificationFD: 1}}, 1},
+		{"empty", Service{Name: "web", Readiness: &ReadinessProbe{}}, 1},
+		{"two probes", Service{Name: "web", Readiness: &ReadinessProbe{Port: 80, File: "/run/web.ready"}}, 1},
+		{"port out of range", Service{Name: "web", Readiness: &ReadinessProbe{Port: 70000}}, 1},
+		{"bad tcp address", Service{Name: "web", Readiness: &ReadinessProbe{TCP: "db"}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateReadiness(&tt.service); len(got) != tt.errors {
+				t.Errorf("validateReadiness() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test waiting for ready dependencies requires them to define a readiness probe
+func TestValidateReadyDependencies(t *testing.T) {
+	services := []Service{
+		{Name: "db", Readiness: &ReadinessProbe{Port: 5432}},
+		{Name: "cache"},
+		{Name: "api", DependsOn: DependsOnField{"db"}, DependsOnCondition: DependsOnReady},
+		{Name: "web", DependsOn: DependsOnField{"cache"}, DependsOnCondition: DependsOnReady},
+	}
+
+	errs := validateReadyDependencies(services)
+	if len(errs) != 1 || errs[0].Service != "web" {
+		t.Errorf("validateReadyDependencies() = %v, want one error for service web", errs)
+	}
+}
+
+// Test port and file probes mark the service ready once they pass
+func TestMonitorReadiness(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "ready")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name    string
+		probe   ReadinessProbe
+		prepare func()
+	}{
+		{"port", ReadinessProbe{Port: port}, func() {}},
+		{"tcp", ReadinessProbe{TCP: listener.Addr().String()}, func() {}},
+		{"file", ReadinessProbe{File: readyFile}, func() {
+			if err := os.WriteFile(readyFile, nil, 0o600); err != nil {
+				t.Error(err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := &ServiceProcess{Name: "probed", Config: Service{Name: "probed", Readiness: &tt.probe}}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go monitorReadiness(ctx, sp)
+
+			time.Sleep(100 * time.Millisecond)
+			if tt.name == "file" && sp.IsReady() {
+				t.Fatal("service ready before its file exists")
+			}
+			tt.prepare()
+
+			if !waitFor(t, 3*time.Second, sp.IsReady) {
+				t.Error("service not marked ready")
+			}
+		})
+	}
+}
+
+// Test notify-ready marks a running service ready and satisfies dependents
+func TestHandleNotifyReady(t *testing.T) {
+	sp := &ServiceProcess{Name: "notifier", Config: Service{Name: "notifier", Readiness: &ReadinessProbe{Notify: true}}}
+	servicesMutex.Lock()
+	saved := activeServices
+	activeServices = map[string]*ServiceProcess{"notifier": sp}
+	servicesMutex.Unlock()
+	defer func() {
+		servicesMutex.Lock()
+		activeServices = saved
+		servicesMutex.Unlock()
+	}()
+
+	if dependencyMet("notifier", DependsOnReady) {
+		t.Fatal("dependency met before notification")
+	}
+	if !dependencyMet("notifier", DependsOnStarted) {
+		t.Error("started condition not met for a running service")
+	}
+
+	if response := handleNotifyReady("notifier"); !response.Success {
+		t.Fatalf("handleNotifyReady() = %s", response.Message)
+	}
+	if !dependencyMet("notifier", DependsOnReady) {
+		t.Error("dependency not met after notification")
+	}
+
+	if response := handleNotifyReady("missing"); response.Success || response.Code != ErrCodeServiceNotFound {
+		t.Errorf("handleNotifyReady(missing) = %+v, want %s", response, ErrCodeServiceNotFound)
+	}
+}
+
+// Test READY=1 on the sd_notify socket marks the service ready, and the
+// socket is removed once the service is gone
+func TestReceiveNotify(t *testing.T) {
+	socket, err := listenNotifySocket("notify-test", -1, -1)
+	if err != nil {
+		t.Fatalf("listenNotifySocket() error = %v", err)
+	}
+	sp := &ServiceProcess{Name: "notify-test", Config: Service{Name: "notify-test", Readiness: &ReadinessProbe{Notify: true}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	go receiveNotify(ctx, sp, socket)
+
+	conn, err := net.Dial("unixgram", notifySocketPath("notify-test"))
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("STATUS=migrating")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if sp.IsReady() {
+		t.Fatal("service ready before READY=1")
+	}
+	if _, err := conn.Write([]byte("STATUS=serving\nREADY=1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, 3*time.Second, sp.IsReady) {
+		t.Error("service not marked ready by READY=1")
+	}
+
+	cancel()
+	if !waitFor(t, 3*time.Second, func() bool {
+		_, err := os.Stat(notifySocketPath("notify-test"))
+		return os.IsNotExist(err)
+	}) {
+		t.Error("notify socket not removed")
+	}
+}
+
+// Test a newline written to the notification fd marks the service ready
+func TestNotificationFD(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	resetShutdown()
+	setConfig(&Config{
+		Services: []Service{{
+			Name:      "s6-ready",
+			Command:   "/bin/sh",
+			Args:      []string{"-c", "sleep 0.3; echo >&5; exec sleep 30"},
+			Readiness: &ReadinessProbe{NotificationFD: 5},
+		}},
+		Timeouts: Timeouts{ServiceShutdown: 2},
+	})
+	defer func() {
+		_ = stopService("s6-ready")
+		cancelShutdown()
+		setConfig(nil)
+	}()
+
+	if err := startService("s6-ready"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	sp, _ := getActiveService("s6-ready")
+	if sp.IsReady() {
+		t.Fatal("service ready before writing to its notification fd")
+	}
+	if !waitFor(t, 3*time.Second, sp.IsReady) {
+		t.Error("service not marked ready by its notification fd")
+	}
+}
@@ -0,0 +1,123 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// envReaperChild marks the supervisor process started by the PID 1 reaper
+const envReaperChild = "GO_OVERLAY_REAPER_CHILD"
+
+// prSetChildSubreaper is PR_SET_CHILD_SUBREAPER from linux/prctl.h
+const prSetChildSubreaper = 36
+
+// noReap disables the PID 1 reaper (--no-reap or GO_OVERLAY_NO_REAP)
+var noReap bool
+
+// reaperSignals are forwarded from the reaper to the supervisor
+var reaperSignals = []os.Signal{
+	syscall.SIGTERM,
+	syscall.SIGINT,
+	syscall.SIGHUP,
+	syscall.SIGQUIT,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+	syscall.SIGWINCH,
+}
+
+// shouldReap reports whether this process must act as the zombie reaper:
+// only as PID 1 and not already running under the reaper.
+//
+// Orphaned grandchildren are reparented to PID 1 and must be reaped with
+// wait4(-1), which would also steal the exit status of the services that
+// os/exec waits for. Like tini, PID 1 therefore only reaps, and runs the
+// actual supervisor as its child.
+func shouldReap() bool {
+	if os.Getenv(envReaperChild) != "" {
+		// Don't leak the marker into services
+		_ = os.Unsetenv(envReaperChild)
+		return false
+	}
+	return !noReap && os.Getpid() == 1
+}
+
+// runReaper starts the supervisor as a child process, reaps zombies until it
+// exits, then exits with its status. It only returns if the child can't start.
+func runReaper() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not locate the go-overlay binary: %w", err)
+	}
+
+	logger.Debug("Running as PID 1: reaping zombies and supervising from a child process")
+	code, err := reapWhileRunning(exe, os.Args[1:], append(os.Environ(), envReaperChild+"=1"))
+	if err != nil {
+		return err
+	}
+	os.Exit(code)
+	return nil
+}
+
+// reapWhileRunning starts path, forwards reaperSignals to it and reaps every
+// child that exits (including reparented orphans) until path itself exits.
+// It returns the exit code of path, 128+signal if it was killed.
+func reapWhileRunning(path string, args, env []string) (int, error) {
+	// Already implied as PID 1; elsewhere makes orphans of descendants ours
+	_, _, _ = syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0)
+
+	sigs := make(chan os.Signal, 32)
+	signal.Notify(sigs, append([]os.Signal{syscall.SIGCHLD}, reaperSignals...)...)
+	defer signal.Stop(sigs)
+
+	cmd := exec.Command(path, args...) // #nosec G204 - re-executing our own binary
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("could not start the supervisor: %w", err)
+	}
+	childPID := cmd.Process.Pid
+
+	// The child may exit before the first SIGCHLD is delivered, so reap once
+	// up front and again on every signal
+	for {
+		if status, exited := reapChildren(childPID); exited {
+			return exitCode(status), nil
+		}
+		sig := <-sigs
+		if sig != syscall.SIGCHLD {
+			_ = cmd.Process.Signal(sig)
+		}
+	}
+}
+
+// reapChildren collects every exited child without blocking, reporting the
+// status of childPID once it is among them
+func reapChildren(childPID int) (syscall.WaitStatus, bool) {
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || pid <= 0 {
+			return 0, false
+		}
+		if pid == childPID {
+			return status, true
+		}
+		logger.Debug(fmt.Sprintf("Reaped orphaned process %d", pid))
+	}
+}
+
+// exitCode converts a wait status into a shell-style exit code
+func exitCode(status syscall.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
@@ -0,0 +1,95 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// envReaperTestHelper makes TestReaperHelper act as the reaper process
+const envReaperTestHelper = "GO_OVERLAY_TEST_REAPER"
+
+// TestReaperHelper is run in a subprocess by TestReapWhileRunning, since the
+// reaper collects every child of the process it runs in
+func TestReaperHelper(_ *testing.T) {
+	if os.Getenv(envReaperTestHelper) == "" {
+		return
+	}
+
+	// The inner shell exits at once, orphaning a sleep that exits before the
+	// supervised command does
+	script := "sh -c 'sleep 0.2 &'; sleep 0.6; exit 7"
+	code, err := reapWhileRunning("/bin/sh", []string{"-c", script}, os.Environ())
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+
+	// Zombies are still listed in /proc until reaped
+	fmt.Printf("children=%d\n", len(processChildren()[os.Getpid()]))
+	os.Exit(code)
+}
+
+// TestReapWhileRunning tests that orphans are reaped and the exit code of the
+// supervised command is propagated
+func TestReapWhileRunning(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestReaperHelper$") // #nosec G204 - the test binary
+	cmd.Env = append(os.Environ(), envReaperTestHelper+"=1")
+	output, err := cmd.CombinedOutput()
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		t.Fatalf("Expected the helper to exit with the command's code, got %v: %s", err, output)
+	}
+	if exitErr.ExitCode() != 7 {
+		t.Errorf("Expected exit code 7, got %d: %s", exitErr.ExitCode(), output)
+	}
+	if !strings.Contains(string(output), "children=0") {
+		t.Errorf("Expected the orphan to be reaped, got: %s", output)
+	}
+}
+
+// TestExitCode tests the conversion of wait statuses to exit codes
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   syscall.WaitStatus
+		expected int
+	}{
+		{"success", 0, 0},
+		{"exit code", syscall.WaitStatus(3 << 8), 3},
+		{"killed by SIGTERM", syscall.WaitStatus(syscall.SIGTERM), 128 + 15},
+		{"killed by SIGKILL", syscall.WaitStatus(syscall.SIGKILL), 128 + 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.status); got != tt.expected {
+				t.Errorf("exitCode(%#x) = %d, expected %d", uint32(tt.status), got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestShouldReap tests that the supervisor child never reaps and drops the marker
+func TestShouldReap(t *testing.T) {
+	t.Setenv(envReaperChild, "1")
+	if shouldReap() {
+		t.Error("Expected the reaper child not to reap")
+	}
+	if _, set := os.LookupEnv(envReaperChild); set {
+		t.Error("Expected the reaper marker to be removed from the environment")
+	}
+
+	// Tests don't run as PID 1
+	if shouldReap() {
+		t.Error("Expected no reaping outside of PID 1")
+	}
+}
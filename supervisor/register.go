@@ -0,0 +1,411 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Registration providers
+const (
+	RegisterProviderConsul = "consul"
+	RegisterProviderEtcd   = "etcd"
+)
+
+const (
+	defaultConsulAddress   = "http://127.0.0.1:8500"
+	defaultEtcdAddress     = "http://127.0.0.1:2379"
+	defaultEtcdPrefix      = "/services"
+	defaultRegisterTTL     = 30
+	registerRequestTimeout = 5 * time.Second
+)
+
+// RegisterConfig configures service registration with Consul or etcd
+type RegisterConfig struct {
+	Provider string   `toml:"provider"`
+	Address  string   `toml:"address,omitempty"`
+	Name     string   `toml:"name,omitempty"`
+	Host     string   `toml:"host,omitempty"`
+	Token    string   `toml:"token,omitempty"`
+	Prefix   string   `toml:"prefix,omitempty"`
+	Tags     []string `toml:"tags,omitempty"`
+	Port     int      `toml:"port,omitempty"`
+	TTL      int      `toml:"ttl,omitempty"`
+}
+
+// serviceRegistrar registers a service instance with an external catalog
+type serviceRegistrar interface {
+	Register(ctx context.Context) error
+	Heartbeat(ctx context.Context) error
+	Fail(ctx context.Context) error // Takes the instance out of service until it registers again
+	Deregister(ctx context.Context) error
+}
+
+// registration holds the resolved identity of a registered service
+type registration struct {
+	ID      string
+	Name    string
+	Address string
+	Tags    []string
+	Port    int
+	TTL     int
+}
+
+func newRegistration(service *Service) registration {
+	cfg := service.Register
+	reg := registration{
+		Name:    cfg.Name,
+		Address: cfg.Host,
+		Port:    cfg.Port,
+		Tags:    cfg.Tags,
+		TTL:     cfg.TTL,
+	}
+	if reg.Name == "" {
+		// Instances of a template register as one service
+		reg.Name = service.Name
+		if service.InstanceOf != "" {
+			reg.Name = service.InstanceOf
+		}
+	}
+	if reg.Port == 0 && service.Publish != nil {
+		reg.Port = service.Publish.Port
+	}
+	if reg.Address == "" && service.Publish != nil {
+		reg.Address = service.Publish.Host
+	}
+	if reg.TTL == 0 {
+		reg.TTL = defaultRegisterTTL
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "go-overlay"
+	}
+	reg.ID = fmt.Sprintf("%s-%s", hostname, service.Name)
+	return reg
+}
+
+func newServiceRegistrar(service *Service) (serviceRegistrar, error) {
+	cfg := service.Register
+	reg := newRegistration(service)
+	client := &http.Client{Timeout: registerRequestTimeout}
+
+	switch cfg.Provider {
+	case RegisterProviderConsul:
+		address := cfg.Address
+		if address == "" {
+			address = defaultConsulAddress
+		}
+		return &consulRegistrar{client: client, address: strings.TrimRight(address, "/"), token: cfg.Token, reg: reg}, nil
+	case RegisterProviderEtcd:
+		address := cfg.Address
+		if address == "" {
+			address = defaultEtcdAddress
+		}
+		prefix := cfg.Prefix
+		if prefix == "" {
+			prefix = defaultEtcdPrefix
+		}
+		return &etcdRegistrar{client: client, address: strings.TrimRight(address, "/"), prefix: strings.TrimRight(prefix, "/"), reg: reg}, nil
+	default:
+		return nil, fmt.Errorf("unknown registration provider '%s'", cfg.Provider)
+	}
+}
+
+// registerPollInterval is how often a registration follows the health of
+// its service
+var registerPollInterval = time.Second
+
+// Delays before registering again after a failed register, doubled per
+// attempt
+var (
+	registerRetryBackoff    = time.Second
+	registerRetryBackoffMax = time.Minute
+)
+
+// registrationServing reports whether a service takes traffic: healthy with
+// a health check, ready with a readiness probe, started otherwise
+func registrationServing(sp *ServiceProcess) bool {
+	if sp.Config.HealthCheck != nil && sp.GetHealth() != HealthHealthy {
+		return false
+	}
+	if sp.Config.Readiness != nil && !sp.IsReady() {
+		return false
+	}
+	return true
+}
+
+// runServiceRegistration registers the service once it is healthy or ready,
+// keeps its TTL alive while it stays so and fails it while it is unhealthy.
+// A failed register is retried with backoff. The service is deregistered
+// once ctx is canceled (service stopping or exiting).
+func runServiceRegistration(ctx context.Context, sp *ServiceProcess) {
+	service := &sp.Config
+	registrar, err := newServiceRegistrar(service)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Service '%s' registration disabled: %v", colorize(ColorCyan, service.Name), err))
+		return
+	}
+	name, provider := colorize(ColorCyan, service.Name), service.Register.Provider
+
+	heartbeat := time.Duration(newRegistration(service).TTL) * time.Second / 2
+	ticker := time.NewTicker(min(registerPollInterval, heartbeat))
+	defer ticker.Stop()
+
+	var (
+		passing bool      // Registered and passing
+		listed  bool      // Registered, passing or failed
+		next    time.Time // Next register or heartbeat
+		retry   = registerRetryBackoff
+	)
+	for {
+		now := time.Now()
+		serving := registrationServing(sp)
+		switch {
+		case serving && !passing && !now.Before(next):
+			if err := registrar.Register(ctx); err != nil {
+				if ctx.Err() == nil {
+					logger.Error(fmt.Sprintf("Error registering service '%s' with %s, retrying in %s: %v",
+						name, provider, retry, err))
+				}
+				next = now.Add(retry)
+				retry = min(retry*2, registerRetryBackoffMax)
+				break
+			}
+			logger.Success(fmt.Sprintf("Service '%s' registered with %s", name, provider))
+			passing, listed = true, true
+			next = now.Add(heartbeat)
+			retry = registerRetryBackoff
+		case serving && passing && !now.Before(next):
+			next = now.Add(heartbeat)
+			if err := registrar.Heartbeat(ctx); err != nil && ctx.Err() == nil {
+				// Register again, in case the catalog lost the service
+				logger.Warn(fmt.Sprintf("Registration heartbeat failed for service '%s': %v", name, err))
+				passing, next = false, now
+			}
+		case !serving && passing:
+			passing, next = false, time.Time{}
+			if err := registrar.Fail(ctx); err != nil && ctx.Err() == nil {
+				logger.Warn(fmt.Sprintf("Error failing the registration of service '%s' with %s: %v", name, provider, err))
+			} else if err == nil {
+				logger.Warn(fmt.Sprintf("Service '%s' marked failing in %s while it is not healthy", name, provider))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if listed {
+				deregister(registrar, service)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deregister removes a service from its catalog
+func deregister(registrar serviceRegistrar, service *Service) {
+	ctx, cancel := context.WithTimeout(context.Background(), registerRequestTimeout)
+	defer cancel()
+	if err := registrar.Deregister(ctx); err != nil {
+		logger.Error(fmt.Sprintf("Error deregistering service '%s' from %s: %v",
+			colorize(ColorCyan, service.Name), service.Register.Provider, err))
+		return
+	}
+	logger.Info(fmt.Sprintf("Service '%s' deregistered from %s",
+		colorize(ColorCyan, service.Name), service.Register.Provider))
+}
+
+// doRegistryRequest sends a JSON request and decodes the JSON response into out (if non-nil)
+func doRegistryRequest(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, val := range headers {
+		req.Header.Set(key, val)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s returned %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// consulRegistrar uses the Consul agent HTTP API with a TTL check
+type consulRegistrar struct {
+	client  *http.Client
+	address string
+	token   string
+	reg     registration
+}
+
+func (c *consulRegistrar) headers() map[string]string {
+	if c.token == "" {
+		return nil
+	}
+	return map[string]string{"X-Consul-Token": c.token}
+}
+
+func (c *consulRegistrar) checkID() string {
+	return "service:" + c.reg.ID
+}
+
+func (c *consulRegistrar) Register(ctx context.Context) error {
+	body := map[string]interface{}{
+		"ID":   c.reg.ID,
+		"Name": c.reg.Name,
+		"Tags": c.reg.Tags,
+		"Port": c.reg.Port,
+		"Check": map[string]interface{}{
+			"CheckID": c.checkID(),
+			"TTL":     fmt.Sprintf("%ds", c.reg.TTL),
+			"Status":  "passing",
+		},
+	}
+	if c.reg.Address != "" {
+		body["Address"] = c.reg.Address
+	}
+	return doRegistryRequest(ctx, c.client, http.MethodPut, c.address+"/v1/agent/service/register", c.headers(), body, nil)
+}
+
+func (c *consulRegistrar) Heartbeat(ctx context.Context) error {
+	return doRegistryRequest(ctx, c.client, http.MethodPut, c.address+"/v1/agent/check/pass/"+c.checkID(), c.headers(), nil, nil)
+}
+
+func (c *consulRegistrar) Fail(ctx context.Context) error {
+	return doRegistryRequest(ctx, c.client, http.MethodPut, c.address+"/v1/agent/check/fail/"+c.checkID(), c.headers(), nil, nil)
+}
+
+func (c *consulRegistrar) Deregister(ctx context.Context) error {
+	return doRegistryRequest(ctx, c.client, http.MethodPut, c.address+"/v1/agent/service/deregister/"+c.reg.ID, c.headers(), nil, nil)
+}
+
+// etcdRegistrar stores the service under a leased key using the etcd v3 JSON gateway
+type etcdRegistrar struct {
+	client  *http.Client
+	address string
+	prefix  string
+	leaseID string
+	reg     registration
+}
+
+func (e *etcdRegistrar) key() string {
+	return fmt.Sprintf("%s/%s/%s", e.prefix, e.reg.Name, e.reg.ID)
+}
+
+func (e *etcdRegistrar) Register(ctx context.Context) error {
+	var lease struct {
+		ID string `json:"ID"`
+	}
+	if err := doRegistryRequest(ctx, e.client, http.MethodPost, e.address+"/v3/lease/grant",
+		nil, map[string]interface{}{"TTL": e.reg.TTL}, &lease); err != nil {
+		return fmt.Errorf("error granting lease: %w", err)
+	}
+	e.leaseID = lease.ID
+
+	value, err := json.Marshal(map[string]interface{}{
+		"id":      e.reg.ID,
+		"name":    e.reg.Name,
+		"address": e.reg.Address,
+		"port":    e.reg.Port,
+		"tags":    e.reg.Tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	return doRegistryRequest(ctx, e.client, http.MethodPost, e.address+"/v3/kv/put", nil, map[string]interface{}{
+		"key":   base64.StdEncoding.EncodeToString([]byte(e.key())),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": e.leaseID,
+	}, nil)
+}
+
+func (e *etcdRegistrar) Heartbeat(ctx context.Context) error {
+	return doRegistryRequest(ctx, e.client, http.MethodPost, e.address+"/v3/lease/keepalive",
+		nil, map[string]interface{}{"ID": e.leaseID}, nil)
+}
+
+// Fail revokes the lease, as etcd has no failing state: registering again
+// grants a new one
+func (e *etcdRegistrar) Fail(ctx context.Context) error {
+	return e.Deregister(ctx)
+}
+
+func (e *etcdRegistrar) Deregister(ctx context.Context) error {
+	if e.leaseID == "" {
+		return nil
+	}
+	// Revoking the lease deletes every key attached to it
+	if err := doRegistryRequest(ctx, e.client, http.MethodPost, e.address+"/v3/lease/revoke",
+		nil, map[string]interface{}{"ID": e.leaseID}, nil); err != nil {
+		return err
+	}
+	e.leaseID = ""
+	return nil
+}
+
+func validateRegister(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.Register == nil {
+		return errors
+	}
+
+	switch service.Register.Provider {
+	case RegisterProviderConsul, RegisterProviderEtcd:
+	default:
+		errors = append(errors, ValidationError{
+			Field:   "register.provider",
+			Service: service.Name,
+			Message: fmt.Sprintf("provider must be '%s' or '%s'", RegisterProviderConsul, RegisterProviderEtcd),
+		})
+	}
+
+	if service.Register.TTL < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "register.ttl",
+			Service: service.Name,
+			Message: "ttl must be a positive number of seconds",
+		})
+	}
+
+	if service.Register.Port < 0 || service.Register.Port > 65535 {
+		errors = append(errors, ValidationError{
+			Field:   "register.port",
+			Service: service.Name,
+			Message: fmt.Sprintf("port %d must be between 1 and 65535", service.Register.Port),
+		})
+	}
+
+	return errors
+}
@@ -0,0 +1,244 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingServer records the method and path of every request it receives
+func recordingServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if handler != nil {
+			handler(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// Test Consul registration lifecycle
+func TestConsulRegistrar(t *testing.T) {
+	var registered map[string]interface{}
+	srv, calls := recordingServer(t, func(_ http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/agent/service/register" {
+			_ = json.NewDecoder(r.Body).Decode(&registered)
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			t.Errorf("missing consul token header on %s", r.URL.Path)
+		}
+	})
+
+	service := &Service{
+		Name:     "api",
+		Publish:  &PublishField{Port: 8080},
+		Register: &RegisterConfig{Provider: RegisterProviderConsul, Address: srv.URL, Token: "secret", Tags: []string{"v1"}},
+	}
+	registrar, err := newServiceRegistrar(service)
+	if err != nil {
+		t.Fatalf("newServiceRegistrar() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if err := registrar.Register(ctx); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registrar.Heartbeat(ctx); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	if err := registrar.Deregister(ctx); err != nil {
+		t.Fatalf("Deregister() error = %v", err)
+	}
+
+	if registered["Name"] != "api" || registered["Port"] != float64(8080) {
+		t.Errorf("registered payload = %v, want name api and port 8080", registered)
+	}
+
+	id := newRegistration(service).ID
+	want := []string{
+		"PUT /v1/agent/service/register",
+		"PUT /v1/agent/check/pass/service:" + id,
+		"PUT /v1/agent/service/deregister/" + id,
+	}
+	if strings.Join(*calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %v, want %v", *calls, want)
+	}
+}
+
+// Test etcd registration lifecycle
+func TestEtcdRegistrar(t *testing.T) {
+	srv, calls := recordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/lease/grant" {
+			_, _ = w.Write([]byte(`{"ID":"42","TTL":"10"}`))
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/v3/kv/put" && body["ID"] != "42" {
+			t.Errorf("%s called with lease %v, want 42", r.URL.Path, body["ID"])
+		}
+	})
+
+	service := &Service{
+		Name:     "worker",
+		Register: &RegisterConfig{Provider: RegisterProviderEtcd, Address: srv.URL, TTL: 10},
+	}
+	registrar, err := newServiceRegistrar(service)
+	if err != nil {
+		t.Fatalf("newServiceRegistrar() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if err := registrar.Register(ctx); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registrar.Heartbeat(ctx); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	if err := registrar.Deregister(ctx); err != nil {
+		t.Fatalf("Deregister() error = %v", err)
+	}
+
+	want := []string{"POST /v3/lease/grant", "POST /v3/kv/put", "POST /v3/lease/keepalive", "POST /v3/lease/revoke"}
+	if strings.Join(*calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %v, want %v", *calls, want)
+	}
+}
+
+// Test registration errors surface non-2xx responses
+func TestRegistrarErrorStatus(t *testing.T) {
+	srv, _ := recordingServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	})
+
+	registrar, err := newServiceRegistrar(&Service{
+		Name:     "api",
+		Register: &RegisterConfig{Provider: RegisterProviderConsul, Address: srv.URL},
+	})
+	if err != nil {
+		t.Fatalf("newServiceRegistrar() error = %v", err)
+	}
+	if err := registrar.Register(context.Background()); err == nil {
+		t.Error("Register() expected error for 403 response")
+	}
+}
+
+// Test a service registers once healthy, retrying a failed register, is
+// failed while unhealthy and deregistered once stopped
+func TestServiceRegistrationFollowsHealth(t *testing.T) {
+	savedPoll, savedRetry := registerPollInterval, registerRetryBackoff
+	registerPollInterval, registerRetryBackoff = 10*time.Millisecond, 20*time.Millisecond
+	defer func() { registerPollInterval, registerRetryBackoff = savedPoll, savedRetry }()
+
+	var mu sync.Mutex
+	var calls []string
+	registers := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/v1/agent/service/register" {
+			registers++
+			if registers == 1 {
+				http.Error(w, "agent starting", http.StatusInternalServerError)
+				return
+			}
+		}
+		calls = append(calls, r.URL.Path)
+	}))
+	defer srv.Close()
+	called := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(calls)
+	}
+
+	sp := &ServiceProcess{Name: "api", Config: Service{
+		Name:        "api",
+		HealthCheck: &HealthCheck{Exec: "true"},
+		Register:    &RegisterConfig{Provider: RegisterProviderConsul, Address: srv.URL},
+	}}
+	sp.SetHealth(HealthStarting)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runServiceRegistration(ctx, sp)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if got := called(); len(got) != 0 {
+		t.Fatalf("calls before the service is healthy = %v", got)
+	}
+
+	id := newRegistration(&sp.Config).ID
+	sp.SetHealth(HealthHealthy)
+	if !waitFor(t, 2*time.Second, func() bool { return len(called()) == 1 }) {
+		t.Fatalf("calls = %v, want the register retried", called())
+	}
+	sp.SetHealth(HealthUnhealthy)
+	if !waitFor(t, 2*time.Second, func() bool { return len(called()) == 2 }) {
+		t.Fatalf("calls = %v, want the check failed", called())
+	}
+	sp.SetHealth(HealthHealthy)
+	if !waitFor(t, 2*time.Second, func() bool { return len(called()) == 3 }) {
+		t.Fatalf("calls = %v, want the service registered again", called())
+	}
+	cancel()
+	<-done
+
+	want := []string{
+		"/v1/agent/service/register",
+		"/v1/agent/check/fail/service:" + id,
+		"/v1/agent/service/register",
+		"/v1/agent/service/deregister/" + id,
+	}
+	if got := called(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+// Test register validation
+func TestValidateRegister(t *testing.T) {
+	if errs := validateRegister(&Service{Name: "a", Register: &RegisterConfig{Provider: "zookeeper"}}); len(errs) == 0 {
+		t.Error("expected error for unknown provider")
+	}
+	if errs := validateRegister(&Service{Name: "a", Register: &RegisterConfig{Provider: RegisterProviderEtcd, TTL: -1}}); len(errs) == 0 {
+		t.Error("expected error for negative ttl")
+	}
+	if errs := validateRegister(&Service{Name: "a", Register: &RegisterConfig{Provider: RegisterProviderConsul}}); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+// Test [services.register] sub-tables are decoded
+func TestParseConfigRegister(t *testing.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+[[services]]
+name = "api"
+command = "/bin/true"
+
+[services.register]
+provider = "consul"
+tags = ["web"]
+ttl = 15
+`))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	reg := cfg.Services[0].Register
+	if reg == nil || reg.Provider != RegisterProviderConsul || reg.TTL != 15 || len(reg.Tags) != 1 {
+		t.Errorf("Register = %+v, want consul provider with ttl 15 and one tag", reg)
+	}
+}
@@ -0,0 +1,153 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// reloadTimeout bounds how long a reload_cmd may run before it is killed
+const reloadTimeout = 30 * time.Second
+
+// signalNames maps signal names (without the SIG prefix) to signals
+var signalNames = map[string]syscall.Signal{
+	"ABRT":  syscall.SIGABRT,
+	"ALRM":  syscall.SIGALRM,
+	"CONT":  syscall.SIGCONT,
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"KILL":  syscall.SIGKILL,
+	"QUIT":  syscall.SIGQUIT,
+	"STOP":  syscall.SIGSTOP,
+	"TERM":  syscall.SIGTERM,
+	"TSTP":  syscall.SIGTSTP,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"WINCH": syscall.SIGWINCH,
+}
+
+// parseSignal accepts a signal name ("SIGHUP", "hup") or number ("1")
+func parseSignal(name string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, fmt.Errorf("invalid signal number %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+
+	upper := strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	if sig, ok := signalNames[upper]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal '%s'", name)
+}
+
+// signalName returns the name of a signal as written in the config ("SIGHUP")
+func signalName(sig syscall.Signal) string {
+	for name, known := range signalNames {
+		if known == sig {
+			return "SIG" + name
+		}
+	}
+	return fmt.Sprintf("signal %d", int(sig))
+}
+
+func validateReload(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.ReloadSignal != "" && service.ReloadCmd != "" {
+		errors = append(errors, ValidationError{
+			Field:   "reload_signal",
+			Service: service.Name,
+			Message: "reload_signal and reload_cmd are mutually exclusive",
+		})
+	}
+
+	if service.ReloadSignal != "" {
+		if _, err := parseSignal(service.ReloadSignal); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "reload_signal",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	return errors
+}
+
+// reloadService asks a running service to reload its configuration, either by
+// running its reload_cmd or by sending its reload_signal
+func reloadService(name string) (string, error) {
+	serviceProc, exists := getActiveService(name)
+	if !exists {
+		if _, ok := findServiceConfig(name); !ok {
+			return "", errServiceNotFound(name)
+		}
+		return "", errServiceNotRunning(name)
+	}
+	if state := serviceProc.GetState(); state != ServiceStateRunning {
+		return "", withCode(ErrCodeNotRunning, fmt.Errorf("service '%s' is not running (state: %s)", name, state))
+	}
+
+	service := serviceProc.Config
+	switch {
+	case service.ReloadCmd != "":
+		logger.Info(fmt.Sprintf("Reloading service '%s' with reload_cmd", colorize(ColorCyan, name)))
+
+		ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+		defer cancel()
+		if err := runScriptContext(ctx, service.ReloadCmd, buildServiceEnv(&service)); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return "", withCode(ErrCodeTimeout, fmt.Errorf("reload command for service '%s' timed out after %s", name, reloadTimeout))
+			}
+			return "", fmt.Errorf("reload command for service '%s' failed: %w", name, err)
+		}
+		return fmt.Sprintf("Service '%s' reloaded (reload_cmd)", name), nil
+
+	case service.ReloadSignal != "":
+		sig, err := parseSignal(service.ReloadSignal)
+		if err != nil {
+			return "", err
+		}
+		if err := signalService(name, sig, false); err != nil {
+			return "", fmt.Errorf("could not signal service '%s': %w", name, err)
+		}
+		return fmt.Sprintf("Service '%s' reloaded (%s)", name, sig), nil
+	}
+
+	return "", fmt.Errorf("service '%s' has no reload_signal or reload_cmd configured", name)
+}
+
+func handleReloadService(serviceName string) IPCResponse {
+	message, err := reloadService(serviceName)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	return IPCResponse{
+		Success: true,
+		Message: message,
+	}
+}
+
+// requestReload asks the daemon to reload a service
+func requestReload(serviceName string) error {
+	response, err := sendIPCCommand(IPCCommand{
+		Type:        CmdReloadService,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !response.Success {
+		return responseError(response)
+	}
+
+	fmt.Println(colorize(ColorGreen, "✓ "+response.Message))
+	return nil
+}
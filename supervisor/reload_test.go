@@ -0,0 +1,152 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Test signal names and numbers are parsed
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{"SIGHUP", syscall.SIGHUP, false},
+		{"hup", syscall.SIGHUP, false},
+		{"USR2", syscall.SIGUSR2, false},
+		{"10", syscall.Signal(10), false},
+		{"SIGBOGUS", 0, true},
+		{"0", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSignal(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSignal(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSignal(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// Test reload settings validation
+func TestValidateReload(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"none", Service{Name: "web"}, 0},
+		{"signal", Service{Name: "web", ReloadSignal: "SIGHUP"}, 0},
+		{"command", Service{Name: "web", ReloadCmd: "nginx -s reload"}, 0},
+		{"unknown signal", Service{Name: "web", ReloadSignal: "SIGNOPE"}, 1},
+		{"both", Service{Name: "web", ReloadSignal: "SIGHUP", ReloadCmd: "nginx -s reload"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateReload(&tt.service); len(got) != tt.errors {
+				t.Errorf("validateReload() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test reloading a running service through its reload_cmd and reload_signal
+func TestReloadService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "reloaded")
+
+	tests := []struct {
+		name    string
+		service Service
+		wantErr string
+	}{
+		{
+			name: "reload_cmd",
+			service: Service{Command: "/bin/sleep", Args: []string{"30"},
+				ReloadCmd: "echo $GO_OVERLAY_SERVICE > " + marker},
+		},
+		{
+			name: "reload_signal",
+			service: Service{Command: "/bin/sh",
+				Args:         []string{"-c", "trap 'echo reloader > " + marker + "' HUP; while :; do sleep 0.1; done"},
+				ReloadSignal: "SIGHUP"},
+		},
+		{
+			name:    "failing reload_cmd",
+			service: Service{Command: "/bin/sleep", Args: []string{"30"}, ReloadCmd: "exit 3"},
+			wantErr: "failed",
+		},
+		{
+			name:    "not configured",
+			service: Service{Command: "/bin/sleep", Args: []string{"30"}},
+			wantErr: "no reload_signal or reload_cmd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(marker)
+			tt.service.Name = "reloader"
+			setupServiceConfig(t, tt.service)
+
+			if err := startService("reloader"); err != nil {
+				t.Fatalf("startService() error = %v", err)
+			}
+			if !waitFor(t, 5*time.Second, func() bool {
+				proc, ok := getActiveService("reloader")
+				return ok && proc.GetState() == ServiceStateRunning
+			}) {
+				t.Fatal("service did not reach running state")
+			}
+			// Give the shell time to install its trap
+			time.Sleep(200 * time.Millisecond)
+
+			_, err := reloadService("reloader")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("reloadService() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reloadService() error = %v", err)
+			}
+
+			if !waitFor(t, 5*time.Second, func() bool {
+				content, err := os.ReadFile(marker)
+				return err == nil && strings.TrimSpace(string(content)) == "reloader"
+			}) {
+				t.Error("service was not reloaded")
+			}
+			if proc, ok := getActiveService("reloader"); !ok || proc.GetState() != ServiceStateRunning {
+				t.Error("service stopped running after reload")
+			}
+		})
+	}
+}
+
+// Test reloading a service that does not exist or is not running
+func TestReloadServiceNotRunning(t *testing.T) {
+	useConfig(t, &Config{Services: []Service{{Name: "idle", Command: "/bin/true", ReloadSignal: "SIGHUP"}}})
+
+	if _, err := reloadService("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("reloadService(missing) error = %v, want not found", err)
+	}
+	if _, err := reloadService("idle"); errorCode(err) != ErrCodeNotRunning || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("reloadService(idle) error = %v, want not running", err)
+	}
+}
@@ -0,0 +1,174 @@
+package supervisor
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/srelabz/go-overlay/client"
+)
+
+// OperationState is the progress of an asynchronous lifecycle operation
+type OperationState = client.OperationState
+
+// Operation state constants
+const (
+	OperationPending   = client.OperationPending
+	OperationStopping  = client.OperationStopping
+	OperationStarting  = client.OperationStarting
+	OperationCompleted = client.OperationCompleted
+	OperationFailed    = client.OperationFailed
+)
+
+// maxTrackedOperations bounds the number of finished operations kept for status queries
+const maxTrackedOperations = 100
+
+// OperationInfo describes an asynchronous operation and its progress
+type OperationInfo = client.Operation
+
+// operation is the mutable, tracked form of an OperationInfo
+type operation struct {
+	mu   sync.Mutex
+	info OperationInfo
+}
+
+func (op *operation) setState(state OperationState) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.info.State = state
+}
+
+func (op *operation) finish(err error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.info.FinishedAt = time.Now()
+	if err != nil {
+		op.info.State = OperationFailed
+		op.info.Error = err.Error()
+		op.info.Code = errorCode(err)
+		return
+	}
+	op.info.State = OperationCompleted
+}
+
+func (op *operation) snapshot() OperationInfo {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.info
+}
+
+// Operation registry
+var (
+	operations      = make(map[string]*operation)
+	operationOrder  []string
+	operationsMu    sync.Mutex
+	operationNextID int
+)
+
+// newOperation registers a pending operation of the given type for a service
+func newOperation(opType, service string) *operation {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	return newOperationLocked(opType, service)
+}
+
+func newOperationLocked(opType, service string) *operation {
+	operationNextID++
+	op := &operation{info: OperationInfo{
+		ID:        opType + "-" + strconv.Itoa(operationNextID),
+		Type:      opType,
+		Service:   service,
+		State:     OperationPending,
+		StartedAt: time.Now(),
+	}}
+	operations[op.info.ID] = op
+	operationOrder = append(operationOrder, op.info.ID)
+
+	// Forget the oldest finished operations beyond the tracking limit
+	for len(operationOrder) > maxTrackedOperations {
+		oldest := operations[operationOrder[0]]
+		if oldest != nil && !oldest.snapshot().Done() {
+			break
+		}
+		delete(operations, operationOrder[0])
+		operationOrder = operationOrder[1:]
+	}
+	return op
+}
+
+// pendingOperation returns the queued (not yet running) operation of the
+// given type for a service, or registers a new one, and reports whether it
+// is new. Both happen under one lock, so concurrent requests share one
+// operation.
+func pendingOperation(opType, service string) (*operation, bool) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+
+	for _, id := range operationOrder {
+		op := operations[id]
+		info := op.snapshot()
+		if info.Type == opType && info.Service == service && info.State == OperationPending {
+			return op, false
+		}
+	}
+	return newOperationLocked(opType, service), true
+}
+
+// getOperation looks up an operation by ID
+func getOperation(id string) (OperationInfo, bool) {
+	operationsMu.Lock()
+	op, ok := operations[id]
+	operationsMu.Unlock()
+	if !ok {
+		return OperationInfo{}, false
+	}
+	return op.snapshot(), true
+}
+
+// requestRestart queues an asynchronous restart of a service. Restarts of the
+// same service are serialized by the service lock; a restart that is still
+// queued absorbs further requests instead of piling up duplicates.
+func requestRestart(name string) (OperationInfo, error) {
+	if _, ok := findServiceConfig(name); !ok {
+		if _, running := getActiveService(name); !running {
+			return OperationInfo{}, errServiceNotFound(name)
+		}
+	}
+
+	op, created := pendingOperation("restart", name)
+	if created {
+		go runRestart(op, name)
+	}
+	return op.snapshot(), nil
+}
+
+// runRestart performs a restart operation, reporting progress on op, and
+// returns its error
+func runRestart(op *operation, name string) error {
+	unlock := lockService(name)
+	defer unlock()
+
+	logger.Info("Restarting service:", name)
+	events.publish(Event{Type: EventRestart, Service: name, Message: "restart requested"})
+
+	op.setState(OperationStopping)
+	if _, running := getActiveService(name); running {
+		if err := stopServiceLocked(name); err != nil {
+			op.finish(err)
+			return err
+		}
+	}
+
+	op.setState(OperationStarting)
+	err := startServiceLocked(name)
+	op.finish(err)
+
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error restarting service '%s': %v", colorize(ColorCyan, name), err))
+	} else {
+		markStoppedByHand(name, false)
+		logger.Success(fmt.Sprintf("Service '%s' restarted", colorize(ColorCyan, name)))
+	}
+	return err
+}
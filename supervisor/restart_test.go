@@ -0,0 +1,143 @@
+package supervisor
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// useConfig installs config as the global config until the test is done
+func useConfig(t *testing.T, config *Config) {
+	t.Helper()
+	setConfig(config)
+	t.Cleanup(func() { setConfig(nil) })
+}
+
+// stopTestServices stops services started by a test and waits for their
+// supervising and restart goroutines, leaving no restart state behind
+func stopTestServices(names ...string) {
+	for _, name := range names {
+		cancelPendingRestart(name)
+		_ = stopService(name)
+	}
+	cancelShutdown()
+	supervisions.Wait()
+	for _, name := range names {
+		cancelPendingRestart(name)
+	}
+}
+
+// setupServiceConfig installs a global config with a single service, stopped
+// once the test is done
+func setupServiceConfig(t *testing.T, service Service) {
+	t.Helper()
+	resetShutdown()
+	useConfig(t, &Config{Services: []Service{service}, Timeouts: Timeouts{ServiceShutdown: 2}})
+	t.Cleanup(func() { stopTestServices(service.Name) })
+}
+
+// setupSleeperConfig installs a global config with a single long-running service
+func setupSleeperConfig(t *testing.T, name string) {
+	t.Helper()
+	setupServiceConfig(t, Service{Name: name, Command: "/bin/sleep", Args: []string{"30"}})
+}
+
+// Test concurrent restarts never leave duplicate instances behind
+func TestRequestRestartConcurrent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	setupSleeperConfig(t, "restartee")
+
+	if err := startService("restartee"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	first, _ := getActiveService("restartee")
+	firstPID := first.GetPID()
+
+	var wg sync.WaitGroup
+	ids := make(chan string, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			op, err := requestRestart("restartee")
+			if err != nil {
+				t.Errorf("requestRestart() error = %v", err)
+				return
+			}
+			ids <- op.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	for id := range ids {
+		if !waitFor(t, 20*time.Second, func() bool {
+			op, ok := getOperation(id)
+			return ok && op.Done()
+		}) {
+			t.Fatalf("operation %s did not finish", id)
+		}
+		if op, _ := getOperation(id); op.State != OperationCompleted {
+			t.Errorf("operation %s state = %s (%s), want completed", id, op.State, op.Error)
+		}
+	}
+
+	current, ok := getActiveService("restartee")
+	if !ok {
+		t.Fatal("service not running after restarts")
+	}
+	if current.GetPID() == firstPID {
+		t.Error("service PID unchanged after restart")
+	}
+
+	servicesMutex.RLock()
+	count := len(activeServices)
+	servicesMutex.RUnlock()
+	if count != 1 {
+		t.Errorf("active services = %d, want 1", count)
+	}
+}
+
+// Test concurrent requests for a service share one queued operation
+func TestPendingOperationShared(t *testing.T) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ops := make(map[string]*operation)
+	created := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			op, isNew := pendingOperation("restart", "shared-test")
+			mu.Lock()
+			defer mu.Unlock()
+			ops[op.snapshot().ID] = op
+			if isNew {
+				created++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(ops) != 1 || created != 1 {
+		t.Errorf("got %d operations, %d created, want one", len(ops), created)
+	}
+	for _, op := range ops {
+		op.finish(nil)
+	}
+}
+
+// Test restart requests for unknown services fail immediately
+func TestHandleRestartServiceUnknown(t *testing.T) {
+	useConfig(t, &Config{})
+
+	response := handleRestartService("ghost")
+	if response.Success || response.Operation != nil {
+		t.Errorf("handleRestartService() = %+v, want failure", response)
+	}
+	if response := handleOperationStatus("restart-0"); response.Success || response.Code != ErrCodeOperationNotFound {
+		t.Errorf("handleOperationStatus() = %+v, want %s", response, ErrCodeOperationNotFound)
+	}
+}
@@ -0,0 +1,407 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Restart policies applied when a service exits on its own
+const (
+	RestartAlways    = "always"     // Restart after any exit
+	RestartOnFailure = "on-failure" // Restart after a non-zero exit or a signal
+	RestartNever     = "never"      // Leave the service stopped (the default)
+)
+
+// Restart backoff defaults, in seconds
+const (
+	defaultRestartBackoff    = 1
+	defaultRestartBackoffMax = 60
+)
+
+// restartResetAfter is how long a service must stay up for its retry count
+// and backoff to start over
+const restartResetAfter = time.Minute
+
+// defaultCrashLoopWindow is the crash_loop_window, in seconds, of a service
+// setting only crash_loop_restarts
+const defaultCrashLoopWindow = 60
+
+// restartState tracks the automatic restarts of one service
+type restartState struct {
+	attempts  int
+	recent    []time.Time   // Restarts within the crash loop window
+	pending   bool          // Waiting for its backoff to elapse
+	crashLoop string        // Why the crash loop breaker stopped restarting it
+	cancel    chan struct{} // Closed to abandon a pending restart
+}
+
+var (
+	restartStates   = make(map[string]*restartState)
+	restartTotals   = make(map[string]int) // Automatic restarts since the daemon started
+	restartStatesMu sync.Mutex
+)
+
+// lastExitCodes keeps the exit code of every service that exited on its own,
+// so it is still shown once the service is down or has been restarted
+var (
+	lastExitCodes   = make(map[string]int)
+	lastExitCodesMu sync.Mutex
+)
+
+// restartPolicy returns the effective restart policy of a service
+func restartPolicy(service *Service) string {
+	if service.Restart == "" {
+		return RestartNever
+	}
+	return service.Restart
+}
+
+// restartDelay returns the backoff before restart attempt n (0-based): the
+// initial delay doubled on every attempt and capped at restart_backoff_max
+func restartDelay(service *Service, attempt int) time.Duration {
+	backoff := service.RestartBackoff
+	if backoff <= 0 {
+		backoff = defaultRestartBackoff
+	}
+	maxBackoff := service.RestartBackoffMax
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRestartBackoffMax
+	}
+
+	delay := time.Duration(backoff) * time.Second
+	limit := time.Duration(maxBackoff) * time.Second
+	for i := 0; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// exitStatus returns the exit code behind the result of cmd.Wait: 0 after a
+// clean exit, 128+signal if the process was killed and -1 if it is unknown
+func exitStatus(exitErr error) int {
+	if exitErr == nil {
+		return 0
+	}
+	var ee *exec.ExitError
+	if !errors.As(exitErr, &ee) {
+		return -1
+	}
+	if status, ok := ee.Sys().(syscall.WaitStatus); ok {
+		return exitCode(status)
+	}
+	return ee.ExitCode()
+}
+
+// recordExitCode remembers the exit code of a service that exited on its own
+func recordExitCode(name string, code int) {
+	lastExitCodesMu.Lock()
+	lastExitCodes[name] = code
+	lastExitCodesMu.Unlock()
+	saveState()
+}
+
+// lastExitCode returns the exit code a service last exited with on its own
+func lastExitCode(name string) (int, bool) {
+	lastExitCodesMu.Lock()
+	defer lastExitCodesMu.Unlock()
+	code, ok := lastExitCodes[name]
+	return code, ok
+}
+
+// exitCodeInfo returns the last exit code of a service for ServiceInfo, nil
+// if it never exited on its own
+func exitCodeInfo(name string) *int {
+	if code, ok := lastExitCode(name); ok {
+		return &code
+	}
+	return nil
+}
+
+// shouldRestart reports whether a service that exited on its own with
+// exitErr is restarted: no_restart_exit_codes and restart_on_exit_codes are
+// checked first, then the restart policy
+func shouldRestart(service *Service, exitErr error) bool {
+	if code := exitStatus(exitErr); code >= 0 {
+		if slices.Contains(service.NoRestartExitCodes, code) {
+			return false
+		}
+		if slices.Contains(service.RestartOnExitCodes, code) {
+			return true
+		}
+	}
+
+	switch restartPolicy(service) {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitErr != nil
+	}
+	return false
+}
+
+// scheduleRestart applies the restart policy to a service that exited on its
+// own after running for uptime. It returns false when the service stays down:
+// the policy does not restart it, the supervisor is shutting down, or
+// restart_max_retries is exhausted.
+func scheduleRestart(service Service, exitErr error, uptime time.Duration) bool {
+	if !shouldRestart(&service, exitErr) || shutdownContext().Err() != nil {
+		return false
+	}
+
+	restartStatesMu.Lock()
+	state, ok := restartStates[service.Name]
+	if !ok {
+		state = &restartState{}
+		restartStates[service.Name] = state
+	}
+	if uptime >= restartResetAfter {
+		state.attempts = 0
+	}
+	now := time.Now()
+	if reason := crashLoop(&service, state, now); reason != "" {
+		state.crashLoop = reason
+		restartStatesMu.Unlock()
+		logger.Error(fmt.Sprintf("Service '%s' is crash looping (%s), not restarting it",
+			colorize(ColorCyan, service.Name), reason))
+		writeServiceStatus(service.Name, ServiceStateFailed, 0)
+		alert(Event{Type: EventCrashLoop, Service: service.Name, Message: reason})
+		return false
+	}
+	if service.RestartMaxRetries > 0 && state.attempts >= service.RestartMaxRetries {
+		restartStatesMu.Unlock()
+		logger.Error(fmt.Sprintf("Service '%s' exited, giving up after %d restarts",
+			colorize(ColorCyan, service.Name), service.RestartMaxRetries))
+		return false
+	}
+
+	delay := restartDelay(&service, state.attempts)
+	state.attempts++
+	state.recent = append(state.recent, now)
+	state.pending = true
+	restartTotals[service.Name]++
+	attempt := state.attempts
+	cancel := make(chan struct{})
+	state.cancel = cancel
+	restartStatesMu.Unlock()
+	saveState()
+	writeServiceStatus(service.Name, ServiceStateBackoff, 0)
+
+	reason := "exited"
+	if exitErr != nil {
+		reason = fmt.Sprintf("failed (%v)", exitErr)
+	}
+	logger.Warn(fmt.Sprintf("Service '%s' %s, restarting in %s (attempt %d)",
+		colorize(ColorCyan, service.Name), reason, delay, attempt))
+	events.publish(Event{Type: EventRestart, Service: service.Name,
+		Message: fmt.Sprintf("%s, restarting in %s (attempt %d)", reason, delay, attempt)})
+
+	supervisions.Add(1)
+	go func() {
+		defer supervisions.Done()
+		select {
+		case <-time.After(delay):
+		case <-cancel:
+			return
+		case <-shutdownContext().Done():
+			return
+		}
+
+		restartStatesMu.Lock()
+		current := restartStates[service.Name]
+		abandoned := current == nil || current.cancel != cancel
+		if !abandoned {
+			current.pending = false
+		}
+		restartStatesMu.Unlock()
+		if abandoned {
+			return
+		}
+
+		if _, ok := findServiceConfig(service.Name); !ok {
+			logger.Info(fmt.Sprintf("Service '%s' was removed from the config, not restarting",
+				colorize(ColorCyan, service.Name)))
+			return
+		}
+		if err := startService(service.Name); err != nil {
+			if _, running := getActiveService(service.Name); running {
+				return // Started manually in the meantime
+			}
+			logger.Error(fmt.Sprintf("Error restarting service '%s': %v", colorize(ColorCyan, service.Name), err))
+		}
+	}()
+	return true
+}
+
+// cancelPendingRestart abandons a scheduled automatic restart and forgets the
+// retry count, e.g. when the service is stopped by hand
+func cancelPendingRestart(name string) {
+	restartStatesMu.Lock()
+	defer restartStatesMu.Unlock()
+
+	if state, ok := restartStates[name]; ok {
+		if state.cancel != nil {
+			close(state.cancel)
+		}
+		delete(restartStates, name)
+	}
+}
+
+// crashLoop returns why a service restarting at now is crash looping: it
+// already restarted crash_loop_restarts times within crash_loop_window. It
+// forgets the restarts that left the window.
+func crashLoop(service *Service, state *restartState, now time.Time) string {
+	if service.CrashLoopRestarts <= 0 {
+		return ""
+	}
+	window := time.Duration(service.CrashLoopWindow) * time.Second
+	if window <= 0 {
+		window = defaultCrashLoopWindow * time.Second
+	}
+
+	recent := state.recent[:0]
+	for _, at := range state.recent {
+		if now.Sub(at) < window {
+			recent = append(recent, at)
+		}
+	}
+	state.recent = recent
+	if len(recent) < service.CrashLoopRestarts {
+		return ""
+	}
+	return fmt.Sprintf("restarted %d times in %s", len(recent), window)
+}
+
+// crashLoopError returns the error of a service stopped by the crash loop
+// breaker, nil otherwise
+func crashLoopError(name string) error {
+	restartStatesMu.Lock()
+	defer restartStatesMu.Unlock()
+
+	if state, ok := restartStates[name]; ok && state.crashLoop != "" {
+		return fmt.Errorf("crash loop: %s", state.crashLoop)
+	}
+	return nil
+}
+
+// resetCrashLoop lets a service stopped by the crash loop breaker be
+// restarted automatically again, once it is started by hand
+func resetCrashLoop(name string) {
+	restartStatesMu.Lock()
+	defer restartStatesMu.Unlock()
+
+	if state, ok := restartStates[name]; ok && state.crashLoop != "" {
+		delete(restartStates, name)
+	}
+}
+
+// restartCount returns the number of automatic restarts since the service
+// last stayed up for restartResetAfter
+func restartCount(name string) int {
+	restartStatesMu.Lock()
+	defer restartStatesMu.Unlock()
+
+	if state, ok := restartStates[name]; ok {
+		return state.attempts
+	}
+	return 0
+}
+
+// totalRestartCount returns the number of automatic restarts of a service
+// since the daemon started
+func totalRestartCount(name string) int {
+	restartStatesMu.Lock()
+	defer restartStatesMu.Unlock()
+	return restartTotals[name]
+}
+
+// restartStatus returns the state of a service without a process: BACKOFF
+// while it waits to restart, FAILED with the reason once the crash loop
+// breaker stopped restarting it, STOPPED otherwise
+func restartStatus(name string) (ServiceState, string) {
+	restartStatesMu.Lock()
+	defer restartStatesMu.Unlock()
+
+	state, ok := restartStates[name]
+	switch {
+	case !ok:
+		return ServiceStateStopped, ""
+	case state.crashLoop != "":
+		return ServiceStateFailed, "crash loop: " + state.crashLoop
+	case state.pending:
+		return ServiceStateBackoff, ""
+	}
+	return ServiceStateStopped, ""
+}
+
+func validateRestart(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	switch service.Restart {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		errors = append(errors, ValidationError{
+			Field:   "restart",
+			Service: service.Name,
+			Message: fmt.Sprintf("invalid restart policy '%s' (use %s, %s or %s)",
+				service.Restart, RestartAlways, RestartOnFailure, RestartNever),
+		})
+	}
+
+	if service.RestartBackoff < 0 || service.RestartBackoffMax < 0 || service.RestartMaxRetries < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "restart_backoff",
+			Service: service.Name,
+			Message: "restart_backoff, restart_backoff_max and restart_max_retries cannot be negative",
+		})
+	} else if service.RestartBackoff > 0 && service.RestartBackoffMax > 0 && service.RestartBackoff > service.RestartBackoffMax {
+		errors = append(errors, ValidationError{
+			Field:   "restart_backoff_max",
+			Service: service.Name,
+			Message: fmt.Sprintf("restart_backoff_max (%d) is lower than restart_backoff (%d)",
+				service.RestartBackoffMax, service.RestartBackoff),
+		})
+	}
+
+	if service.CrashLoopRestarts < 0 || service.CrashLoopWindow < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "crash_loop_restarts",
+			Service: service.Name,
+			Message: "crash_loop_restarts and crash_loop_window cannot be negative",
+		})
+	} else if service.CrashLoopWindow > 0 && service.CrashLoopRestarts == 0 {
+		errors = append(errors, ValidationError{
+			Field:   "crash_loop_window",
+			Service: service.Name,
+			Message: "crash_loop_window needs crash_loop_restarts",
+		})
+	}
+
+	for _, code := range append(slices.Clone(service.RestartOnExitCodes), service.NoRestartExitCodes...) {
+		if code < 0 || code > 255 {
+			errors = append(errors, ValidationError{
+				Field:   "restart_on_exit_codes",
+				Service: service.Name,
+				Message: fmt.Sprintf("invalid exit code %d (must be between 0 and 255)", code),
+			})
+		}
+	}
+	for _, code := range service.RestartOnExitCodes {
+		if slices.Contains(service.NoRestartExitCodes, code) {
+			errors = append(errors, ValidationError{
+				Field:   "no_restart_exit_codes",
+				Service: service.Name,
+				Message: fmt.Sprintf("exit code %d is in both restart_on_exit_codes and no_restart_exit_codes", code),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,298 @@
+package supervisor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test the backoff doubles per attempt and is capped
+func TestRestartDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		attempt int
+		want    time.Duration
+	}{
+		{"default first attempt", Service{}, 0, time.Second},
+		{"default doubles", Service{}, 3, 8 * time.Second},
+		{"default cap", Service{}, 10, 60 * time.Second},
+		{"custom", Service{RestartBackoff: 2, RestartBackoffMax: 10}, 1, 4 * time.Second},
+		{"custom cap", Service{RestartBackoff: 2, RestartBackoffMax: 10}, 5, 10 * time.Second},
+		{"huge attempt does not overflow", Service{}, 1000, 60 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := restartDelay(&tt.service, tt.attempt); got != tt.want {
+				t.Errorf("restartDelay(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+// Test which exits each policy restarts
+func TestShouldRestart(t *testing.T) {
+	failure := errors.New("exit status 1")
+	tests := []struct {
+		policy  string
+		exitErr error
+		want    bool
+	}{
+		{"", failure, false},
+		{RestartNever, failure, false},
+		{RestartAlways, nil, true},
+		{RestartAlways, failure, true},
+		{RestartOnFailure, nil, false},
+		{RestartOnFailure, failure, true},
+	}
+
+	for _, tt := range tests {
+		service := Service{Restart: tt.policy}
+		if got := shouldRestart(&service, tt.exitErr); got != tt.want {
+			t.Errorf("shouldRestart(%q, %v) = %v, want %v", tt.policy, tt.exitErr, got, tt.want)
+		}
+	}
+}
+
+// Test exit codes override the restart policy
+func TestShouldRestartExitCodes(t *testing.T) {
+	exit := func(script string) error {
+		return exec.Command("/bin/sh", "-c", script).Run()
+	}
+	completed := exit("exit 0")
+	failed := exit("exit 3")
+	killed := exit("kill -9 $$")
+
+	tests := []struct {
+		name     string
+		service  Service
+		exitErr  error
+		wantCode int
+		want     bool
+	}{
+		{"clean exit", Service{Restart: RestartAlways, NoRestartExitCodes: []int{0}}, completed, 0, false},
+		{"other code keeps the policy", Service{Restart: RestartAlways, NoRestartExitCodes: []int{0}}, failed, 3, true},
+		{"killed", Service{Restart: RestartNever, RestartOnExitCodes: []int{137}}, killed, 137, true},
+		{"failure not restarted", Service{Restart: RestartOnFailure, NoRestartExitCodes: []int{3}}, failed, 3, false},
+		{"unknown exit keeps the policy", Service{Restart: RestartOnFailure, NoRestartExitCodes: []int{3}}, errors.New("no such file"), -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitStatus(tt.exitErr); got != tt.wantCode {
+				t.Errorf("exitStatus(%v) = %d, want %d", tt.exitErr, got, tt.wantCode)
+			}
+			if got := shouldRestart(&tt.service, tt.exitErr); got != tt.want {
+				t.Errorf("shouldRestart(%v) = %v, want %v", tt.exitErr, got, tt.want)
+			}
+		})
+	}
+}
+
+// Test restart settings validation
+func TestValidateRestart(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"defaults", Service{Name: "web"}, 0},
+		{"valid", Service{Name: "web", Restart: RestartOnFailure, RestartBackoff: 2, RestartBackoffMax: 30, RestartMaxRetries: 5}, 0},
+		{"unknown policy", Service{Name: "web", Restart: "sometimes"}, 1},
+		{"negative", Service{Name: "web", RestartMaxRetries: -1}, 1},
+		{"max below initial", Service{Name: "web", RestartBackoff: 10, RestartBackoffMax: 5}, 1},
+		{"exit codes", Service{Name: "web", RestartOnExitCodes: []int{137, 143}, NoRestartExitCodes: []int{0}}, 0},
+		{"exit code out of range", Service{Name: "web", RestartOnExitCodes: []int{256}}, 1},
+		{"exit code in both lists", Service{Name: "web", RestartOnExitCodes: []int{1}, NoRestartExitCodes: []int{1}}, 1},
+		{"crash loop", Service{Name: "web", CrashLoopRestarts: 5, CrashLoopWindow: 30}, 0},
+		{"negative crash loop", Service{Name: "web", CrashLoopRestarts: -1}, 1},
+		{"crash loop window alone", Service{Name: "web", CrashLoopWindow: 30}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateRestart(&tt.service); len(got) != tt.errors {
+				t.Errorf("validateRestart() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test a crashing service is restarted with backoff until restart_max_retries is exhausted
+func TestAutomaticRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	runs := filepath.Join(t.TempDir(), "runs")
+	resetShutdown()
+	setConfig(&Config{
+		Services: []Service{{
+			Name: "crasher", Command: "/bin/sh", Args: []string{"-c", "echo run >> " + runs + "; exit 3"},
+			Restart: RestartOnFailure, RestartBackoff: 1, RestartMaxRetries: 2,
+		}},
+		Timeouts: Timeouts{ServiceShutdown: 2},
+	})
+	defer func() {
+		cancelPendingRestart("crasher")
+		cancelShutdown()
+		// The supervise and restart goroutines read the config until they end
+		supervisions.Wait()
+		setConfig(nil)
+	}()
+
+	countRuns := func() int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+
+	if err := startService("crasher"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+
+	// First start plus two retries after 1s and 2s of backoff
+	if !waitFor(t, 10*time.Second, func() bool { return countRuns() == 3 }) {
+		t.Fatalf("service ran %d times, want 3", countRuns())
+	}
+	time.Sleep(2500 * time.Millisecond)
+	if got := countRuns(); got != 3 {
+		t.Errorf("service ran %d times after retries were exhausted, want 3", got)
+	}
+	if got := restartCount("crasher"); got != 2 {
+		t.Errorf("restartCount() = %d, want 2", got)
+	}
+	if code, ok := lastExitCode("crasher"); !ok || code != 3 {
+		t.Errorf("lastExitCode() = %d, %v, want 3", code, ok)
+	}
+}
+
+// Test stopping a service by hand abandons its pending restart
+func TestCancelPendingRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	resetShutdown()
+	setConfig(&Config{Services: []Service{{Name: "flaky", Command: "/bin/true", Restart: RestartAlways}}})
+	defer func() {
+		cancelShutdown()
+		supervisions.Wait()
+		setConfig(nil)
+	}()
+
+	if !scheduleRestart(currentConfig().Services[0], nil, time.Second) {
+		t.Fatal("scheduleRestart() = false, want a restart for restart=always")
+	}
+	if got := restartCount("flaky"); got != 1 {
+		t.Errorf("restartCount() = %d, want 1", got)
+	}
+	if state, _ := restartStatus("flaky"); state != ServiceStateBackoff {
+		t.Errorf("restartStatus() = %v, want BACKOFF", state)
+	}
+
+	if err := stopService("flaky"); errorCode(err) != ErrCodeNotRunning {
+		t.Errorf("stopService() of a service waiting to restart = %v, want %s", err, ErrCodeNotRunning)
+	}
+	if got := restartCount("flaky"); got != 0 {
+		t.Errorf("restartCount() after stop = %d, want 0", got)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, running := getActiveService("flaky"); running {
+		t.Error("service restarted after its pending restart was canceled")
+	}
+}
+
+// Test the crash loop breaker trips once a service restarted
+// crash_loop_restarts times within crash_loop_window
+func TestCrashLoop(t *testing.T) {
+	service := &Service{Name: "web", CrashLoopRestarts: 3, CrashLoopWindow: 10}
+	now := time.Now()
+	state := &restartState{recent: []time.Time{now.Add(-20 * time.Second), now.Add(-5 * time.Second), now.Add(-time.Second)}}
+
+	if reason := crashLoop(service, state, now); reason != "" {
+		t.Errorf("crashLoop() = %q, want none with 2 restarts in the window", reason)
+	}
+	if len(state.recent) != 2 {
+		t.Errorf("recent = %v, want the restart out of the window forgotten", state.recent)
+	}
+
+	state.recent = append(state.recent, now)
+	if reason := crashLoop(service, state, now); reason != "restarted 3 times in 10s" {
+		t.Errorf("crashLoop() = %q, want 3 restarts in 10s", reason)
+	}
+	if reason := crashLoop(&Service{Name: "web"}, state, now); reason != "" {
+		t.Errorf("crashLoop() = %q, want none without crash_loop_restarts", reason)
+	}
+	if reason := crashLoop(&Service{Name: "web", CrashLoopRestarts: 3}, state, now); reason != "restarted 3 times in 1m0s" {
+		t.Errorf("crashLoop() = %q, want the default window", reason)
+	}
+}
+
+// Test a crash looping service stays down as FAILED until started by hand
+func TestCrashLoopBreaker(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	runs := filepath.Join(t.TempDir(), "runs")
+	forgetState(t, "looper")
+	cancelPendingRestart("looper")
+	setupServiceConfig(t, Service{
+		Name: "looper", Command: "/bin/sh", Args: []string{"-c", "echo run >> " + runs + "; exit 0"},
+		Restart: RestartAlways, RestartBackoff: 1, CrashLoopRestarts: 2, CrashLoopWindow: 30,
+	})
+	subscription, unsubscribe := events.subscribe()
+	defer unsubscribe()
+
+	countRuns := func() int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+
+	if err := startService("looper"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	// First start plus two restarts after 1s and 2s of backoff
+	if !waitFor(t, 10*time.Second, func() bool {
+		state, _ := restartStatus("looper")
+		return state == ServiceStateFailed
+	}) {
+		t.Fatalf("service not failed by the crash loop breaker after %d runs", countRuns())
+	}
+	if got := countRuns(); got != 3 {
+		t.Errorf("service ran %d times, want 3", got)
+	}
+	if _, reason := restartStatus("looper"); reason != "crash loop: restarted 2 times in 30s" {
+		t.Errorf("restartStatus() reason = %q", reason)
+	}
+	if err := crashLoopError("looper"); err == nil {
+		t.Error("crashLoopError() = nil, want the crash loop")
+	}
+	if got := totalRestartCount("looper"); got != 2 {
+		t.Errorf("totalRestartCount() = %d, want 2", got)
+	}
+
+	found := false
+	for len(subscription) > 0 {
+		if event := <-subscription; event.Type == EventCrashLoop && event.Service == "looper" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("no crash_loop event published")
+	}
+
+	// Starting it by hand resets the breaker, but not the total
+	if err := startService("looper"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	if err := crashLoopError("looper"); err != nil {
+		t.Errorf("crashLoopError() after a manual start = %v, want nil", err)
+	}
+	if !waitFor(t, 5*time.Second, func() bool { return totalRestartCount("looper") == 3 }) {
+		t.Errorf("totalRestartCount() = %d, want 3", totalRestartCount("looper"))
+	}
+}
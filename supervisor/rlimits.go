@@ -0,0 +1,115 @@
+package supervisor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Resources from linux/resource.h missing from the syscall package
+const (
+	rlimitNproc   = 6
+	rlimitMemlock = 8
+)
+
+// rlimitUnlimited is the configured value for RLIM_INFINITY
+const rlimitUnlimited = -1
+
+// ResourceLimits are setrlimit(2) limits of a service; each sets both the
+// soft and the hard limit, and -1 means unlimited
+type ResourceLimits struct {
+	NoFile  *int64 `toml:"nofile,omitempty"`  // Open files
+	NProc   *int64 `toml:"nproc,omitempty"`   // Processes of the service user
+	Core    *int64 `toml:"core,omitempty"`    // Core dump size in bytes
+	MemLock *int64 `toml:"memlock,omitempty"` // Locked memory in bytes
+	CPU     *int64 `toml:"cpu,omitempty"`     // CPU time in seconds
+}
+
+// rlimitSetting is one configured limit
+type rlimitSetting struct {
+	name     string
+	resource int
+	value    *int64
+}
+
+func (l *ResourceLimits) settings() []rlimitSetting {
+	return []rlimitSetting{
+		{"nofile", syscall.RLIMIT_NOFILE, l.NoFile},
+		{"nproc", rlimitNproc, l.NProc},
+		{"core", syscall.RLIMIT_CORE, l.Core},
+		{"memlock", rlimitMemlock, l.MemLock},
+		{"cpu", syscall.RLIMIT_CPU, l.CPU},
+	}
+}
+
+// String renders the limits as name=value pairs, "unlimited" for -1
+func (l *ResourceLimits) String() string {
+	var parts []string
+	for _, setting := range l.settings() {
+		if setting.value == nil {
+			continue
+		}
+		value := strconv.FormatInt(*setting.value, 10)
+		if *setting.value == rlimitUnlimited {
+			value = "unlimited"
+		}
+		parts = append(parts, setting.name+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// encode renders the limits for the exec helper, e.g. "nofile=1024,core=0"
+func (l *ResourceLimits) encode() string {
+	var parts []string
+	for _, setting := range l.settings() {
+		if setting.value != nil {
+			parts = append(parts, fmt.Sprintf("%s=%d", setting.name, *setting.value))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// setLimits sets the limits encoded by encode on the current process
+func setLimits(spec string) error {
+	names := map[string]int{}
+	for _, setting := range (&ResourceLimits{}).settings() {
+		names[setting.name] = setting.resource
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		name, raw, _ := strings.Cut(pair, "=")
+		resource, known := names[name]
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if !known || err != nil {
+			return fmt.Errorf("%s: invalid limit '%s'", envExecSetup, pair)
+		}
+
+		limit := uint64(value)
+		if value == rlimitUnlimited {
+			limit = ^uint64(0) // RLIM_INFINITY
+		}
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: limit, Max: limit}); err != nil {
+			return fmt.Errorf("could not set %s limit to %s: %w", name, raw, err)
+		}
+	}
+	return nil
+}
+
+func validateLimits(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.Limits == nil {
+		return errors
+	}
+	for _, setting := range service.Limits.settings() {
+		if setting.value != nil && *setting.value < rlimitUnlimited {
+			errors = append(errors, ValidationError{
+				Field:   "limits." + setting.name,
+				Service: service.Name,
+				Message: fmt.Sprintf("%s limit %d is negative (use -1 for unlimited)", setting.name, *setting.value),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,23 @@
+package supervisor
+
+import "testing"
+
+// Test limits validation and their encoding for the exec helper
+func TestResourceLimits(t *testing.T) {
+	nofile, core, bad := int64(1024), int64(rlimitUnlimited), int64(-2)
+
+	limits := &ResourceLimits{NoFile: &nofile, Core: &core}
+	if got := limits.encode(); got != "nofile=1024,core=-1" {
+		t.Errorf("encode() = %q", got)
+	}
+	if got := limits.String(); got != "nofile=1024 core=unlimited" {
+		t.Errorf("String() = %q", got)
+	}
+
+	if errs := validateLimits(&Service{Name: "web", Limits: limits}); len(errs) != 0 {
+		t.Errorf("validateLimits() = %v", errs)
+	}
+	if errs := validateLimits(&Service{Name: "web", Limits: &ResourceLimits{CPU: &bad}}); len(errs) != 1 {
+		t.Errorf("validateLimits() of a negative limit = %v, want 1 error", errs)
+	}
+}
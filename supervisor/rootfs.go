@@ -0,0 +1,69 @@
+package supervisor
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultSearchPath is used to resolve commands inside a rootfs when PATH is unset
+const defaultSearchPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// validationRoot is the image root that commands, scripts, users and log
+// directories are checked against ("" means the running system)
+var validationRoot string
+
+// rootPath maps an absolute path of the target image onto the validation root
+func rootPath(path string) string {
+	if validationRoot == "" {
+		return path
+	}
+	return filepath.Join(validationRoot, path)
+}
+
+// lookPathInRoot reports whether command resolves to an executable inside the
+// validation root, searching PATH for bare command names
+func lookPathInRoot(command string) bool {
+	if strings.Contains(command, "/") {
+		return isExecutableFile(rootPath(command))
+	}
+
+	searchPath := os.Getenv("PATH")
+	if searchPath == "" {
+		searchPath = defaultSearchPath
+	}
+	for _, dir := range filepath.SplitList(searchPath) {
+		if dir != "" && isExecutableFile(rootPath(filepath.Join(dir, command))) {
+			return true
+		}
+	}
+	return false
+}
+
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// existsInRootDB looks name (or a numeric id) up in a passwd-style file of the
+// validation root: /etc/passwd for users, /etc/group for groups
+func existsInRootDB(db, name string) bool {
+	file, err := os.Open(rootPath(db))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 {
+			continue
+		}
+		if fields[0] == name || fields[2] == name {
+			return true
+		}
+	}
+	return false
+}
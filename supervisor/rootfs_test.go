@@ -0,0 +1,87 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestRootfs builds a minimal image root with one executable, one script and one user and group
+func newTestRootfs(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	files := map[string]os.FileMode{
+		"usr/bin/nginx":          0o755,
+		"scripts/setup.sh":       0o755,
+		"usr/share/doc/readme":   0o644,
+		"var/log/app/.keep":      0o644,
+		"etc/passwd":             0o644,
+		"etc/group":              0o644,
+		"opt/app/bin/server-bin": 0o755,
+	}
+	for name, mode := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	passwd := "root:x:0:0:root:/root:/bin/sh\nwww-data:x:33:33::/var/www:/usr/sbin/nologin\n"
+	if err := os.WriteFile(filepath.Join(root, "etc/passwd"), []byte(passwd), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	group := "root:x:0:\nwww-data:x:33:\n"
+	if err := os.WriteFile(filepath.Join(root, "etc/group"), []byte(group), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// Test service validation resolves paths and users inside the rootfs
+func TestValidateServiceInRootfs(t *testing.T) {
+	validationRoot = newTestRootfs(t)
+	defer func() { validationRoot = "" }()
+	t.Setenv("PATH", "/usr/local/bin:/usr/bin")
+
+	tests := []struct {
+		name    string
+		service Service
+		wantErr bool
+	}{
+		{"Command in PATH", Service{Name: "web", Command: "nginx"}, false},
+		{"Absolute command", Service{Name: "app", Command: "/opt/app/bin/server-bin"}, false},
+		{"Missing command", Service{Name: "web", Command: "caddy"}, true},
+		{"Not executable", Service{Name: "web", Command: "/usr/share/doc/readme"}, true},
+		{"Script", Service{Name: "web", Command: "nginx", PreScript: "/scripts/setup.sh"}, false},
+		{"Missing script", Service{Name: "web", Command: "nginx", PosScript: "/scripts/notify.sh"}, true},
+		{"Log directory", Service{Name: "web", Command: "nginx", LogFile: "/var/log/app/out.log"}, false},
+		{"Missing log directory", Service{Name: "web", Command: "nginx", LogFile: "/var/log/nginx/out.log"}, true},
+		{"User", Service{Name: "web", Command: "nginx", User: "www-data"}, false},
+		{"Numeric user", Service{Name: "web", Command: "nginx", User: "33"}, false},
+		{"Missing user", Service{Name: "web", Command: "nginx", User: "nginx"}, true},
+		{"Group", Service{Name: "web", Command: "nginx", User: "www-data", Group: "www-data"}, false},
+		{"Missing group", Service{Name: "web", Command: "nginx", Group: "nginx"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateService(tt.service)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateService() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Test check rejects a rootfs that is not a directory
+func TestCheckConfigInvalidRootfs(t *testing.T) {
+	if err := checkConfig("/nonexistent/services.toml", filepath.Join(t.TempDir(), "missing"), false); err == nil {
+		t.Error("checkConfig() succeeded with a missing rootfs")
+	}
+	if validationRoot != "" {
+		t.Errorf("validationRoot = %q, want it reset", validationRoot)
+	}
+}
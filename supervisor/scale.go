@@ -0,0 +1,137 @@
+package supervisor
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// scaledConfig returns a copy of config where the service template named
+// name has count instances. Dependencies on the template follow, without
+// changing the services that depend on it otherwise.
+func scaledConfig(config *Config, name string, count int) *Config {
+	scaled := *config
+	scaled.ServiceTemplates = slices.Clone(config.ServiceTemplates)
+	template, _ := findServiceTemplate(&scaled, name)
+	template.Instances = count
+	instances := expandInstances(template)
+
+	scaled.Services = nil
+	for _, service := range config.Services {
+		if service.InstanceOf == name {
+			if instances != nil {
+				scaled.Services = append(scaled.Services, instances...)
+				instances = nil
+			}
+			continue
+		}
+		// Written back as dependencies on templates, and resolved again below
+		service.DependsOn = foldInstanceDependencies(service.DependsOn, config)
+		if wa := service.WaitAfter; wa != nil && wa.IsPerDep {
+			service.WaitAfter = &WaitAfterField{PerDep: foldInstanceWaits(wa.PerDep, config), IsPerDep: true}
+		}
+		scaled.Services = append(scaled.Services, service)
+	}
+	scaled.Services = append(scaled.Services, instances...)
+
+	resolveInstanceDependencies(&scaled)
+	return &scaled
+}
+
+// scaleService starts or gracefully stops instances of a service template
+// until count of them are configured. Like apply, other services keep
+// running; dependents see the new instances on their next start.
+func scaleService(name string, count int) ([]OperationResult, error) {
+	applyMutex.Lock()
+	defer applyMutex.Unlock()
+
+	current := currentConfig()
+	if current == nil {
+		return nil, fmt.Errorf("no configuration loaded")
+	}
+	template, ok := findServiceTemplate(current, name)
+	if !ok {
+		if _, exists := findServiceConfig(name); exists {
+			return nil, fmt.Errorf("service '%s' has no instances to scale", name)
+		}
+		return nil, withCode(ErrCodeServiceNotFound, fmt.Errorf("service template '%s' not found", name))
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("instances must not be negative")
+	}
+
+	before := templateInstances(current.Services, name)
+	logger.Info(fmt.Sprintf("Scaling service '%s' from %d to %d instance(s)",
+		colorize(ColorCyan, name), len(before), count))
+	desired := scaledConfig(current, name, count)
+	after := templateInstances(desired.Services, name)
+
+	var results []OperationResult
+	// Stop the removed instances, last first
+	for _, instance := range reversed(before) {
+		if slices.Contains(after, instance) {
+			continue
+		}
+		result := bulkStop(instance)
+		result.Message = joinMessages("removed", result.Message)
+		results = append(results, result)
+	}
+
+	setConfig(desired)
+
+	var added []Service
+	for _, instance := range after {
+		if slices.Contains(before, instance) {
+			continue
+		}
+		service, _ := findServiceConfig(instance)
+		added = append(added, service)
+		writeServiceStatus(instance, ServiceStatePending, 0)
+		if template.Enabled != nil && !*template.Enabled {
+			results = append(results, OperationResult{
+				Service: instance, Action: ActionStart, Status: ResultSkipped, Message: "added (disabled)",
+			})
+			continue
+		}
+		result := bulkStart(instance)
+		result.Message = joinMessages("added", result.Message)
+		results = append(results, result)
+	}
+
+	startControlFIFOs(added)
+	return results, nil
+}
+
+func handleScale(name string, count int) IPCResponse {
+	results, err := scaleService(name, count)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.Status == ResultFailed {
+			failed++
+		}
+	}
+	return IPCResponse{
+		Success: failed == 0,
+		Message: applySummary("scale", results, failed),
+		Code:    commonCode(results),
+		Results: results,
+	}
+}
+
+// requestScale asks the daemon to run count instances of a service template
+func requestScale(name, count string) error {
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid number of instances '%s'", count)
+	}
+
+	response, err := sendIPCCommand(IPCCommand{Type: CmdScale, ServiceName: name, Instances: n})
+	if err != nil {
+		return err
+	}
+	return printOperationResults(response)
+}
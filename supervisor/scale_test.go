@@ -0,0 +1,138 @@
+package supervisor
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Test a scaled config has the new instances in place of the old ones, and
+// dependencies on the template follow
+func TestScaledConfig(t *testing.T) {
+	config := mustParseConfig(t, `
+validate_commands = false
+
+[[services]]
+name = "worker"
+command = "/usr/bin/worker"
+args = ["%i"]
+instances = 2
+
+[[services]]
+name = "api"
+command = "/usr/bin/api"
+depends_on = ["worker", "worker@1"]
+wait_after = { worker = 3 }
+`)
+
+	scaled := scaledConfig(config, "worker", 3)
+
+	var names []string
+	for _, service := range scaled.Services {
+		names = append(names, service.Name)
+	}
+	if want := []string{"worker@1", "worker@2", "worker@3", "api"}; !slices.Equal(names, want) {
+		t.Fatalf("services = %v, want %v", names, want)
+	}
+	if scaled.Services[2].Args[0] != "3" {
+		t.Errorf("worker@3 args = %q, want [3]", scaled.Services[2].Args)
+	}
+	api := scaled.Services[3]
+	if want := []string{"worker@1", "worker@2", "worker@3"}; !slices.Equal(api.DependsOn, want) {
+		t.Errorf("depends_on = %v, want %v", api.DependsOn, want)
+	}
+	if want := map[string]int{"worker@1": 3, "worker@2": 3, "worker@3": 3}; !reflect.DeepEqual(api.WaitAfter.PerDep, want) {
+		t.Errorf("wait_after = %v, want %v", api.WaitAfter.PerDep, want)
+	}
+	if scaled.ServiceTemplates[0].Instances != 3 {
+		t.Errorf("template instances = %d, want 3", scaled.ServiceTemplates[0].Instances)
+	}
+
+	// The running config is left untouched
+	if len(config.Services) != 3 || config.ServiceTemplates[0].Instances != 2 || len(config.Services[2].WaitAfter.PerDep) != 2 {
+		t.Errorf("scaledConfig() changed the config it copies: %+v", config)
+	}
+
+	scaled = scaledConfig(config, "worker", 0)
+	if len(scaled.Services) != 1 || len(scaled.Services[0].DependsOn) != 0 {
+		t.Errorf("scaled to 0 = %+v, want api without dependencies", scaled.Services)
+	}
+}
+
+// Test scale starts and stops instances, leaving the others running
+func TestScaleService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	resetShutdown()
+	setConfig(mustParseConfig(t, `
+[timeouts]
+service_shutdown_timeout = 2
+
+[[services]]
+name = "worker"
+command = "/bin/sleep"
+args = ["30"]
+instances = 2
+
+[[services]]
+name = "single"
+command = "/bin/sleep"
+args = ["30"]
+`))
+	t.Cleanup(func() {
+		for _, name := range []string{"worker@1", "worker@2", "worker@3"} {
+			_ = stopService(name)
+		}
+		cancelShutdown()
+		// Restarts and supervise goroutines read shutdownContext() until they end
+		supervisions.Wait()
+		setConfig(nil)
+	})
+
+	for _, name := range []string{"worker@1", "worker@2"} {
+		if err := startService(name); err != nil {
+			t.Fatalf("startService(%s) error = %v", name, err)
+		}
+	}
+	first, _ := getActiveService("worker@1")
+	firstPID := first.GetPID()
+
+	results, err := scaleService("worker", 3)
+	if err != nil {
+		t.Fatalf("scaleService(3) error = %v", err)
+	}
+	if len(results) != 1 || results[0].Service != "worker@3" || results[0].Status != ResultOK {
+		t.Errorf("scaleService(3) = %+v, want worker@3 started", results)
+	}
+	if _, running := getActiveService("worker@3"); !running {
+		t.Error("worker@3 is not running")
+	}
+
+	results, err = scaleService("worker", 1)
+	if err != nil {
+		t.Fatalf("scaleService(1) error = %v", err)
+	}
+	if len(results) != 2 || results[0].Service != "worker@3" || results[1].Service != "worker@2" {
+		t.Errorf("scaleService(1) = %+v, want worker@3 and worker@2 stopped", results)
+	}
+	for _, name := range []string{"worker@2", "worker@3"} {
+		if _, running := getActiveService(name); running {
+			t.Errorf("%s is still running", name)
+		}
+		if _, ok := findServiceConfig(name); ok {
+			t.Errorf("%s is still configured", name)
+		}
+	}
+	if sp, _ := getActiveService("worker@1"); sp == nil || sp.GetPID() != firstPID {
+		t.Error("worker@1 was restarted")
+	}
+
+	if _, err := scaleService("single", 2); err == nil {
+		t.Error("scaleService() should fail for a service without instances")
+	}
+	if _, err := scaleService("missing", 2); err == nil {
+		t.Error("scaleService() should fail for an unknown service")
+	}
+}
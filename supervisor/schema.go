@@ -0,0 +1,153 @@
+package supervisor
+
+//go:generate go run .. schema -o ../docs/services.schema.json
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// jsonSchemaDraft is the JSON Schema version `go-overlay schema` emits
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is the subset of JSON Schema needed to describe services.toml
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+}
+
+// schemaEnums lists the values accepted by string fields, by struct type
+// and TOML key
+var schemaEnums = map[string][]string{
+	"serviceRaw.restart":              {RestartAlways, RestartOnFailure, RestartNever},
+	"serviceRaw.depends_on_condition": {DependsOnStarted, DependsOnHealthy, DependsOnReady},
+	"serviceRaw.stderr_level":         {"debug", "info", "warn", "warning", "error"},
+	"serviceRaw.log_level":            {"debug", "info", "warn", "warning", "error"},
+	"LoggingConfig.overflow":          {LogOverflowBlock, LogOverflowDropOldest},
+	"IOPriority.class":                {IOClassRealtime, IOClassBestEffort, IOClassIdle},
+	"RegisterConfig.provider":         {RegisterProviderConsul, RegisterProviderEtcd},
+	"Notifier.type":                   {NotifierWebhook, NotifierSlack, NotifierSMTP},
+	"Notifier.events":                 {EventFailed, EventCrashLoop, EventShutdown},
+}
+
+// schemaUnions describes the fields decoded from several TOML forms, by
+// struct type and TOML key
+var schemaUnions = map[string][]*jsonSchema{
+	"serviceRaw.depends_on": {
+		{Type: "string"},
+		{Type: "array", Items: &jsonSchema{Type: "string"}},
+	},
+	"serviceRaw.wait_after": {
+		{Type: "integer"},
+		{Type: "object", AdditionalProperties: &jsonSchema{Type: "integer"}},
+	},
+}
+
+// schemaOptional are the fields without omitempty that may still be left out
+var schemaOptional = map[string]bool{
+	"configRaw.services": true,
+	"serviceRaw.args":    true,
+}
+
+// configSchema returns the JSON Schema of services.toml, derived from the
+// structs it is decoded into so it can't drift from them
+func configSchema() *jsonSchema {
+	schema := typeSchema(reflect.TypeOf(configRaw{}))
+	schema.Schema = jsonSchemaDraft
+	schema.Title = "go-overlay services.toml"
+	return schema
+}
+
+// typeSchema returns the schema of a Go type as decoded from TOML
+func typeSchema(t reflect.Type) *jsonSchema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: typeSchema(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	// interface{} fields are described in schemaUnions
+	return &jsonSchema{}
+}
+
+// structSchema returns the schema of a struct from its toml tags. Unknown
+// keys are rejected, so editors catch typos.
+func structSchema(t reflect.Type) *jsonSchema {
+	schema := &jsonSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: false,
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("toml")
+		if !field.IsExported() || tag == "" || tag == "-" {
+			continue
+		}
+		key, options, _ := strings.Cut(tag, ",")
+		id := t.Name() + "." + key
+
+		var property *jsonSchema
+		if union, ok := schemaUnions[id]; ok {
+			property = &jsonSchema{OneOf: union}
+		} else {
+			property = typeSchema(field.Type)
+		}
+		if values, ok := schemaEnums[id]; ok {
+			if property.Items != nil {
+				property.Items = &jsonSchema{Type: property.Items.Type, Enum: values}
+			} else {
+				property.Enum = values
+			}
+		}
+		schema.Properties[key] = property
+
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer && !schemaOptional[id] {
+			schema.Required = append(schema.Required, key)
+		}
+	}
+	return schema
+}
+
+// writeSchema writes the JSON Schema of services.toml to output ("-" for stdout)
+func writeSchema(output string) error {
+	data, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding schema: %w", err)
+	}
+	data = append(data, '\n')
+
+	if output == "" || output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil { // #nosec G306 - the schema is not secret
+		return fmt.Errorf("error writing %s: %w", output, err)
+	}
+	logger.Success(fmt.Sprintf("Schema written to %s", colorize(ColorCyan, output)))
+	return nil
+}
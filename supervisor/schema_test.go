@@ -0,0 +1,76 @@
+package supervisor
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestSchemaIsUpToDate(t *testing.T) {
+	want, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../docs/services.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(got), want) {
+		t.Error("docs/services.schema.json is stale, run go generate")
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	schema := configSchema()
+
+	if schema.AdditionalProperties != false {
+		t.Error("unknown top-level keys should be rejected")
+	}
+	if slices.Contains(schema.Required, "services") {
+		t.Error("services should not be required")
+	}
+
+	services := schema.Properties["services"]
+	if services == nil || services.Type != "array" || services.Items == nil {
+		t.Fatalf("services = %+v, want an array of tables", services)
+	}
+	service := services.Items
+	if !slices.Equal(service.Required, []string{"name", "command"}) {
+		t.Errorf("service required = %v, want [name command]", service.Required)
+	}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"command", "string"},
+		{"args", "array"},
+		{"enabled", "boolean"},
+		{"stage", "integer"},
+		{"env", "object"},
+		{"health_check", "object"},
+	}
+	for _, tt := range tests {
+		property := service.Properties[tt.key]
+		if property == nil {
+			t.Errorf("%s is missing", tt.key)
+			continue
+		}
+		if property.Type != tt.want {
+			t.Errorf("%s type = %q, want %q", tt.key, property.Type, tt.want)
+		}
+	}
+
+	if restart := service.Properties["restart"]; !slices.Equal(restart.Enum, []string{RestartAlways, RestartOnFailure, RestartNever}) {
+		t.Errorf("restart enum = %v", restart.Enum)
+	}
+	if dependsOn := service.Properties["depends_on"]; len(dependsOn.OneOf) != 2 {
+		t.Errorf("depends_on should accept a string or an array, got %+v", dependsOn)
+	}
+	events := schema.Properties["notifiers"].Items.Properties["events"]
+	if events.Type != "array" || !slices.Contains(events.Items.Enum, EventCrashLoop) {
+		t.Errorf("notifier events = %+v, want an array of events", events)
+	}
+}
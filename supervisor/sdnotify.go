@@ -0,0 +1,92 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvNotifySocket is the sd_notify(3) socket given to notify services
+const EnvNotifySocket = "NOTIFY_SOCKET"
+
+// notifySocketPath returns the datagram socket a service reports readiness on
+func notifySocketPath(name string) string {
+	return filepath.Join(filepath.Dir(socketPath), "go-overlay-notify-"+name+".sock")
+}
+
+// notifySocket is the sd_notify socket of one run of a service
+type notifySocket struct {
+	*net.UnixConn
+	path string
+	info os.FileInfo
+}
+
+// listenNotifySocket creates the sd_notify socket of a service, replacing a
+// stale one left by a previous run. uid/gid own the socket so a service that
+// drops privileges can still write to it; pass -1 to keep the current owner.
+func listenNotifySocket(name string, uid, gid int) (*notifySocket, error) {
+	path := notifySocketPath(name)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	socket := &notifySocket{UnixConn: conn, path: path}
+	socket.info, _ = os.Stat(path)
+	if uid >= 0 {
+		if err := os.Chown(path, uid, gid); err != nil {
+			socket.close()
+			return nil, err
+		}
+	}
+	return socket, nil
+}
+
+// close closes the socket and removes its file, unless a later run of the
+// service already replaced it
+func (s *notifySocket) close() {
+	_ = s.Close()
+	if info, err := os.Stat(s.path); err == nil && s.info != nil && os.SameFile(info, s.info) {
+		_ = os.Remove(s.path)
+	}
+}
+
+// receiveNotify reads sd_notify datagrams until ctx is done and closes conn.
+// READY=1 marks the service ready, WATCHDOG=1 feeds its watchdog and STATUS=
+// is logged; other fields (RELOADING, STOPPING, ...) are accepted and ignored.
+func receiveNotify(ctx context.Context, sp *ServiceProcess, conn *notifySocket) {
+	go func() {
+		<-ctx.Done()
+		conn.close()
+	}()
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			key, value, _ := strings.Cut(line, "=")
+			switch key {
+			case "READY":
+				if value == "1" {
+					sp.SetReady()
+				}
+			case "WATCHDOG":
+				if value == "1" {
+					sp.pingWatchdog()
+				}
+			case "STATUS":
+				logger.Info(fmt.Sprintf("Service '%s' status: %s", colorize(ColorCyan, sp.Name), value))
+			}
+		}
+	}
+}
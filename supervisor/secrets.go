@@ -0,0 +1,130 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// defaultSecretMode are the permissions of secret files without a mode
+const defaultSecretMode = 0o400
+
+// Secret is a file read when a service starts, such as a Docker or
+// Kubernetes secret under /run/secrets, exposed to the service as an
+// environment variable, a file it owns, or both. Only the path of the secret
+// is in the config.
+type Secret struct {
+	Source string `toml:"source"`
+	Env    string `toml:"env,omitempty"`    // Variable set to the content, without its trailing newline
+	Target string `toml:"target,omitempty"` // File the secret is copied to, owned by the service user
+	Mode   string `toml:"mode,omitempty"`   // Octal permissions of the target, default "0400"
+}
+
+// secretEnv reads the secrets of a service exposed as variables
+func secretEnv(service *Service) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, secret := range service.Secrets {
+		if secret.Env == "" {
+			continue
+		}
+		data, err := os.ReadFile(secret.Source)
+		if err != nil {
+			return nil, fmt.Errorf("could not read secret: %w", err)
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		values[secret.Env] = strings.TrimSuffix(value, "\r")
+	}
+	return values, nil
+}
+
+// writeSecretFiles copies the secrets of a service that have a target
+func writeSecretFiles(service *Service, cred *syscall.Credential) error {
+	for _, secret := range service.Secrets {
+		if secret.Target == "" {
+			continue
+		}
+		mode, err := parseFileMode(secret.Mode, defaultSecretMode)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(secret.Source)
+		if err != nil {
+			return fmt.Errorf("could not read secret: %w", err)
+		}
+		if err := writeServiceFile(secret.Target, data, mode, cred); err != nil {
+			return fmt.Errorf("could not write secret %s: %w", secret.Target, err)
+		}
+	}
+	return nil
+}
+
+func validateSecrets(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	envs := make(map[string]bool, len(service.Secrets))
+	targets := make(map[string]bool, len(service.Secrets))
+	for i, secret := range service.Secrets {
+		field := fmt.Sprintf("secrets[%d]", i)
+		if secret.Source == "" || (secret.Env == "" && secret.Target == "") {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Service: service.Name,
+				Message: "a secret requires a source and an env, a target or both",
+			})
+			continue
+		}
+		if secret.Env != "" {
+			switch {
+			case !isValidEnvName(secret.Env):
+				errors = append(errors, ValidationError{
+					Field:   field + ".env",
+					Service: service.Name,
+					Message: fmt.Sprintf("invalid variable name '%s'", secret.Env),
+				})
+			case envs[secret.Env]:
+				errors = append(errors, ValidationError{
+					Field:   field + ".env",
+					Service: service.Name,
+					Message: fmt.Sprintf("variable %s is set by more than one secret", secret.Env),
+				})
+			default:
+				if _, set := service.Env[secret.Env]; set {
+					errors = append(errors, ValidationError{
+						Field:   field + ".env",
+						Service: service.Name,
+						Message: fmt.Sprintf("variable %s is also set in env", secret.Env),
+					})
+				}
+			}
+			envs[secret.Env] = true
+		}
+		if secret.Target != "" {
+			if !filepath.IsAbs(secret.Target) {
+				errors = append(errors, ValidationError{
+					Field:   field + ".target",
+					Service: service.Name,
+					Message: fmt.Sprintf("target '%s' must be an absolute path", secret.Target),
+				})
+			}
+			if targets[secret.Target] {
+				errors = append(errors, ValidationError{
+					Field:   field + ".target",
+					Service: service.Name,
+					Message: fmt.Sprintf("target '%s' is written by more than one secret", secret.Target),
+				})
+			}
+			targets[secret.Target] = true
+		}
+		if _, err := parseFileMode(secret.Mode, defaultSecretMode); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   field + ".mode",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,83 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test secrets are read into variables without their trailing newline and
+// copied to their targets with their mode
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+	password := filepath.Join(dir, "db_password")
+	key := filepath.Join(dir, "tls.key")
+	_ = os.WriteFile(password, []byte("s3cret\r\n"), 0o600)
+	_ = os.WriteFile(key, []byte("KEY\n"), 0o600)
+
+	service := &Service{Name: "api", Secrets: []Secret{
+		{Source: password, Env: "DB_PASSWORD"},
+		{Source: key, Target: filepath.Join(dir, "app", "tls.key")},
+		{Source: key, Env: "TLS_KEY", Target: filepath.Join(dir, "app", "key.pem"), Mode: "0440"},
+	}}
+
+	env, err := secretEnv(service)
+	if err != nil {
+		t.Fatalf("secretEnv() error = %v", err)
+	}
+	if env["DB_PASSWORD"] != "s3cret" || env["TLS_KEY"] != "KEY" || len(env) != 2 {
+		t.Errorf("secretEnv() = %q", env)
+	}
+
+	if err := writeSecretFiles(service, nil); err != nil {
+		t.Fatalf("writeSecretFiles() error = %v", err)
+	}
+	for path, mode := range map[string]os.FileMode{"tls.key": 0o400, "key.pem": 0o440} {
+		path = filepath.Join(dir, "app", path)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("secret file missing: %v", err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("%s mode = %v, want %v", path, info.Mode().Perm(), mode)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "KEY\n" {
+			t.Errorf("%s = %q, want the secret as is", path, data)
+		}
+	}
+
+	service.Secrets[0].Source = filepath.Join(dir, "missing")
+	if _, err := secretEnv(service); err == nil {
+		t.Error("secretEnv() should fail for a missing secret")
+	}
+}
+
+// Test secret validation
+func TestValidateSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		wantErr bool
+	}{
+		{"Env", Service{Name: "web", Secrets: []Secret{{Source: "/run/secrets/pw", Env: "PW"}}}, false},
+		{"Target", Service{Name: "web", Secrets: []Secret{{Source: "/run/secrets/pw", Target: "/etc/pw", Mode: "0600"}}}, false},
+		{"Neither", Service{Name: "web", Secrets: []Secret{{Source: "/run/secrets/pw"}}}, true},
+		{"Missing source", Service{Name: "web", Secrets: []Secret{{Env: "PW"}}}, true},
+		{"Invalid env", Service{Name: "web", Secrets: []Secret{{Source: "/run/secrets/pw", Env: "1PW"}}}, true},
+		{"Env twice", Service{Name: "web", Secrets: []Secret{
+			{Source: "/run/secrets/a", Env: "PW"}, {Source: "/run/secrets/b", Env: "PW"}}}, true},
+		{"Also in env", Service{Name: "web", Env: map[string]string{"PW": "plain"},
+			Secrets: []Secret{{Source: "/run/secrets/pw", Env: "PW"}}}, true},
+		{"Relative target", Service{Name: "web", Secrets: []Secret{{Source: "/run/secrets/pw", Target: "pw"}}}, true},
+		{"Invalid mode", Service{Name: "web", Secrets: []Secret{{Source: "/run/secrets/pw", Target: "/etc/pw", Mode: "999"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateSecrets(&tt.service)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateSecrets() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultGlobalShutdownTimeout applies when no configuration is loaded
+const defaultGlobalShutdownTimeout = 30 * time.Second
+
+// globalShutdownTimeout returns the configured budget for the whole shutdown
+func globalShutdownTimeout() time.Duration {
+	if config := currentConfig(); config != nil && config.Timeouts.GlobalShutdown > 0 {
+		return time.Duration(config.Timeouts.GlobalShutdown) * time.Second
+	}
+	return defaultGlobalShutdownTimeout
+}
+
+// runPreShutdownScript runs the global pre_shutdown_script, killing it after
+// pre_shutdown_timeout. Failures are logged and never block the shutdown.
+func runPreShutdownScript() {
+	config := currentConfig()
+	if config == nil || config.PreShutdownScript == "" {
+		return
+	}
+
+	timeout := time.Duration(config.Timeouts.PreShutdown) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Info("| === PRE-SHUTDOWN SCRIPT START === |")
+	env := mergeEnv(baseEnvironment(), map[string]string{
+		EnvSocket:  controlAddress,
+		EnvVersion: version,
+	})
+	if err := runScriptContext(ctx, config.PreShutdownScript, env); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			logger.Warn(fmt.Sprintf("Pre-shutdown script timed out after %s", timeout))
+		} else {
+			logger.Warn(fmt.Sprintf("Pre-shutdown script failed: %v", err))
+		}
+	}
+	logger.Info("| === PRE-SHUTDOWN SCRIPT END === |")
+}
+
+func validatePreShutdownScript(config *Config) ValidationErrors {
+	var errors ValidationErrors
+
+	if config.PreShutdownScript == "" || (config.ValidateCommands != nil && !*config.ValidateCommands) {
+		return errors
+	}
+
+	if _, err := os.Stat(rootPath(config.PreShutdownScript)); os.IsNotExist(err) {
+		errors = append(errors, ValidationError{
+			Field:   "pre_shutdown_script",
+			Message: fmt.Sprintf("pre-shutdown script '%s' does not exist", config.PreShutdownScript),
+		})
+	}
+
+	return errors
+}
+
+// shutdownWaves groups the active services by shutdown_priority, highest first
+func shutdownWaves() [][]*ServiceProcess {
+	servicesMutex.RLock()
+	byPriority := make(map[int][]*ServiceProcess)
+	for _, serviceProc := range activeServices {
+		priority := serviceProc.Config.ShutdownPriority
+		byPriority[priority] = append(byPriority[priority], serviceProc)
+	}
+	servicesMutex.RUnlock()
+
+	priorities := make([]int, 0, len(byPriority))
+	for priority := range byPriority {
+		priorities = append(priorities, priority)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+
+	waves := make([][]*ServiceProcess, 0, len(priorities))
+	for _, priority := range priorities {
+		wave := byPriority[priority]
+		sort.Slice(wave, func(i, j int) bool { return wave[i].Name < wave[j].Name })
+		waves = append(waves, wave)
+	}
+	return waves
+}
+
+// stopShutdownWaves stops services wave by wave when they declare different
+// shutdown priorities, waiting for each wave to exit before the next one. The
+// last wave is left to the regular shutdown so it stops with everything else.
+func stopShutdownWaves(deadline time.Time) {
+	waves := shutdownWaves()
+	if len(waves) < 2 {
+		return
+	}
+
+	for _, wave := range waves[:len(waves)-1] {
+		names := make([]string, 0, len(wave))
+		for _, serviceProc := range wave {
+			names = append(names, serviceProc.Name)
+		}
+		logger.Info(fmt.Sprintf("Stopping shutdown wave (priority %d): %s",
+			wave[0].Config.ShutdownPriority, colorize(ColorCyan, strings.Join(names, ", "))))
+
+		for _, serviceProc := range wave {
+			if serviceProc.Cancel != nil {
+				serviceProc.Cancel()
+			}
+		}
+
+		for _, serviceProc := range wave {
+			if serviceProc.Exited == nil {
+				continue
+			}
+			select {
+			case <-serviceProc.Exited:
+			case <-time.After(time.Until(deadline)):
+				logger.Warn("Shutdown timeout reached while stopping wave, stopping remaining services")
+				return
+			}
+		}
+	}
+}
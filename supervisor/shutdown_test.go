@@ -0,0 +1,142 @@
+package supervisor
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test active services are grouped into waves by descending priority
+func TestShutdownWaves(t *testing.T) {
+	servicesMutex.Lock()
+	saved := activeServices
+	activeServices = map[string]*ServiceProcess{
+		"writer":  {Name: "writer", Config: Service{ShutdownPriority: 10}},
+		"api":     {Name: "api"},
+		"web":     {Name: "web"},
+		"metrics": {Name: "metrics", Config: Service{ShutdownPriority: -5}},
+	}
+	servicesMutex.Unlock()
+	defer func() {
+		servicesMutex.Lock()
+		activeServices = saved
+		servicesMutex.Unlock()
+	}()
+
+	waves := shutdownWaves()
+	want := [][]string{{"writer"}, {"api", "web"}, {"metrics"}}
+	if len(waves) != len(want) {
+		t.Fatalf("shutdownWaves() returned %d waves, want %d", len(waves), len(want))
+	}
+	for i, wave := range waves {
+		if len(wave) != len(want[i]) {
+			t.Fatalf("wave %d has %d services, want %v", i, len(wave), want[i])
+		}
+		for j, serviceProc := range wave {
+			if serviceProc.Name != want[i][j] {
+				t.Errorf("wave %d service %d = %s, want %s", i, j, serviceProc.Name, want[i][j])
+			}
+		}
+	}
+}
+
+// Test prioritized waves stop before the last wave is touched
+func TestStopShutdownWaves(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	resetShutdown()
+	setConfig(&Config{
+		Services: []Service{
+			{Name: "writer", Command: "/bin/sleep", Args: []string{"30"}, ShutdownPriority: 10},
+			{Name: "api", Command: "/bin/sleep", Args: []string{"30"}},
+			{Name: "metrics", Command: "/bin/sleep", Args: []string{"30"}, ShutdownPriority: -5},
+		},
+		Timeouts: Timeouts{ServiceShutdown: 2},
+	})
+	t.Cleanup(func() {
+		for _, name := range []string{"writer", "api", "metrics"} {
+			_ = stopService(name)
+		}
+		cancelShutdown()
+		setConfig(nil)
+	})
+
+	for _, name := range []string{"writer", "api", "metrics"} {
+		if err := startService(name); err != nil {
+			t.Fatalf("startService(%s) error = %v", name, err)
+		}
+	}
+
+	stopShutdownWaves(time.Now().Add(10 * time.Second))
+
+	for name, wantRunning := range map[string]bool{"writer": false, "api": false, "metrics": true} {
+		if _, running := getActiveService(name); running != wantRunning {
+			t.Errorf("service %s running = %v, want %v", name, running, wantRunning)
+		}
+	}
+}
+
+// Test the pre-shutdown script runs with the supervisor environment and is killed on timeout
+func TestRunPreShutdownScript(t *testing.T) {
+	dir := t.TempDir()
+	marker := dir + "/ran"
+
+	tests := []struct {
+		name    string
+		script  string
+		timeout int
+		ran     bool
+	}{
+		{"runs", "echo $GO_OVERLAY_SOCKET > " + marker, 5, true},
+		{"killed on timeout", "sleep 5; touch " + marker, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(marker)
+			useConfig(t, &Config{
+				PreShutdownScript: tt.script,
+				Timeouts:          Timeouts{PreShutdown: tt.timeout},
+			})
+
+			start := time.Now()
+			runPreShutdownScript()
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("runPreShutdownScript() took %s, want it bounded by the timeout", elapsed)
+			}
+
+			content, err := os.ReadFile(marker)
+			if ran := err == nil; ran != tt.ran {
+				t.Fatalf("script ran = %v, want %v", ran, tt.ran)
+			}
+			if tt.ran && strings.TrimSpace(string(content)) != socketPath {
+				t.Errorf("GO_OVERLAY_SOCKET = %q, want %q", strings.TrimSpace(string(content)), socketPath)
+			}
+		})
+	}
+}
+
+// Test a missing pre-shutdown script is reported unless command validation is disabled
+func TestValidatePreShutdownScript(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name   string
+		config Config
+		errors int
+	}{
+		{"unset", Config{}, 0},
+		{"missing", Config{PreShutdownScript: "/nonexistent/pre-shutdown.sh"}, 1},
+		{"validation disabled", Config{PreShutdownScript: "/nonexistent/pre-shutdown.sh", ValidateCommands: &disabled}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validatePreShutdownScript(&tt.config); len(got) != tt.errors {
+				t.Errorf("validatePreShutdownScript() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
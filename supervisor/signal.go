@@ -0,0 +1,51 @@
+package supervisor
+
+import (
+	"fmt"
+)
+
+// handleSignalService delivers the signal of cmd to a running service
+func handleSignalService(cmd IPCCommand) IPCResponse {
+	if _, ok := findServiceConfig(cmd.ServiceName); !ok {
+		return notFoundResponse(cmd.ServiceName)
+	}
+
+	sig, err := parseSignal(cmd.Signal)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	if err := signalService(cmd.ServiceName, sig, cmd.Group); err != nil {
+		return errorResponse(fmt.Errorf("could not signal service '%s': %w", cmd.ServiceName, err))
+	}
+
+	return IPCResponse{
+		Success: true,
+		Message: fmt.Sprintf("Sent %s to service '%s'", signalName(sig), cmd.ServiceName),
+	}
+}
+
+// requestSignal asks the daemon to send a signal to a service
+func requestSignal(serviceName, signal string, group bool) error {
+	// Catch typos before bothering the daemon
+	if _, err := parseSignal(signal); err != nil {
+		return err
+	}
+
+	response, err := sendIPCCommand(IPCCommand{
+		Type:        CmdSignalService,
+		ServiceName: serviceName,
+		Signal:      signal,
+		Group:       group,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !response.Success {
+		return responseError(response)
+	}
+
+	fmt.Println(colorize(ColorGreen, "✓ "+response.Message))
+	return nil
+}
@@ -0,0 +1,61 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test sending signals to a service through the control socket command
+func TestHandleSignalService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	marker := filepath.Join(t.TempDir(), "signaled")
+	setupServiceConfig(t, Service{Name: "signaled", Command: "/bin/sh",
+		Args: []string{"-c", "trap 'echo usr1 > " + marker + "' USR1; while :; do sleep 0.1; done"}})
+
+	if err := startService("signaled"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	// Give the shell time to install its trap
+	time.Sleep(200 * time.Millisecond)
+
+	tests := []struct {
+		name    string
+		cmd     IPCCommand
+		wantErr string
+	}{
+		{"unknown service", IPCCommand{ServiceName: "missing", Signal: "USR1"}, "not found"},
+		{"unknown signal", IPCCommand{ServiceName: "signaled", Signal: "SIGNOPE"}, "unknown signal"},
+		{"by name", IPCCommand{ServiceName: "signaled", Signal: "usr1"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cmd.Type = CmdSignalService
+			response := handleSignalService(tt.cmd)
+			if tt.wantErr != "" {
+				if response.Success || !strings.Contains(response.Message, tt.wantErr) {
+					t.Errorf("handleSignalService() = %+v, want error %q", response, tt.wantErr)
+				}
+				return
+			}
+			if !response.Success {
+				t.Fatalf("handleSignalService() = %+v", response)
+			}
+		})
+	}
+
+	if !waitFor(t, 5*time.Second, func() bool {
+		content, err := os.ReadFile(marker)
+		return err == nil && strings.TrimSpace(string(content)) == "usr1"
+	}) {
+		t.Error("service did not receive SIGUSR1")
+	}
+	if proc, ok := getActiveService("signaled"); !ok || proc.GetState() != ServiceStateRunning {
+		t.Error("service stopped running after the signal")
+	}
+}
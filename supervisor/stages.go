@@ -0,0 +1,118 @@
+package supervisor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stagePollInterval is how often a stage is checked while waiting for it to settle
+const stagePollInterval = 500 * time.Millisecond
+
+// stageRun tracks the start of one service of a stage
+type stageRun struct {
+	service *Service
+	done    chan struct{} // Closed when processService returns
+	ok      bool          // Whether it started and exited cleanly; valid once done
+}
+
+// serviceStages groups services by stage, lowest first, keeping the config
+// order within a stage
+func serviceStages(services []Service) ([]int, map[int][]*Service) {
+	groups := make(map[int][]*Service)
+	for i := range services {
+		service := &services[i]
+		groups[service.Stage] = append(groups[service.Stage], service)
+	}
+
+	stages := make([]int, 0, len(groups))
+	for stage := range groups {
+		stages = append(stages, stage)
+	}
+	sort.Ints(stages)
+	return stages, groups
+}
+
+// stageServiceUp reports whether a service of a stage is running and, when it
+// has a readiness probe, ready
+func stageServiceUp(name string) bool {
+	serviceProc, exists := getActiveService(name)
+	if !exists || serviceProc.GetState() != ServiceStateRunning {
+		return false
+	}
+	if serviceProc.Config.Readiness != nil {
+		return serviceReady(name)
+	}
+	return true
+}
+
+// waitForStage waits until every service of a stage is up or completed. It
+// returns false if one of them failed, the wait timed out or the supervisor
+// is shutting down; later stages are then not started.
+func waitForStage(stage int, runs []*stageRun, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		settled := true
+		for _, run := range runs {
+			select {
+			case <-run.done:
+				if !run.ok {
+					logger.Error(fmt.Sprintf("Stage %d failed: service '%s' did not start or complete",
+						stage, colorize(ColorCyan, run.service.Name)))
+					return false
+				}
+			default:
+				if !stageServiceUp(run.service.Name) {
+					settled = false
+				}
+			}
+		}
+		if settled {
+			logger.Success(fmt.Sprintf("Stage %d is up", stage))
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			logger.Error(fmt.Sprintf("Stage %d did not come up within %s", stage, timeout))
+			return false
+		}
+
+		select {
+		case <-time.After(stagePollInterval):
+		case <-shutdownContext().Done():
+			return false
+		}
+	}
+}
+
+// validateStages rejects services depending on a service of a later stage,
+// which would wait for a service that can't start before them
+func validateStages(services []Service) ValidationErrors {
+	var errors ValidationErrors
+
+	stages := make(map[string]int, len(services))
+	for i := range services {
+		stages[services[i].Name] = services[i].Stage
+	}
+
+	for i := range services {
+		service := &services[i]
+		var later []string
+		for _, dep := range service.DependsOn {
+			if stage, ok := stages[dep]; ok && stage > service.Stage {
+				later = append(later, dep)
+			}
+		}
+		if len(later) > 0 {
+			errors = append(errors, ValidationError{
+				Field:   "stage",
+				Service: service.Name,
+				Message: fmt.Sprintf("depends on %s in a later stage", strings.Join(later, ", ")),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,98 @@
+package supervisor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test services are grouped by stage, lowest first, in config order
+func TestServiceStages(t *testing.T) {
+	services := []Service{
+		{Name: "app", Stage: 2},
+		{Name: "init"},
+		{Name: "db", Stage: 1},
+		{Name: "cache", Stage: 1},
+	}
+
+	stages, groups := serviceStages(services)
+	if !reflect.DeepEqual(stages, []int{0, 1, 2}) {
+		t.Fatalf("stages = %v", stages)
+	}
+
+	var names []string
+	for _, service := range groups[1] {
+		names = append(names, service.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"db", "cache"}) {
+		t.Errorf("stage 1 = %v, want db, cache", names)
+	}
+}
+
+// Test a stage settles once its services are running or completed
+func TestWaitForStage(t *testing.T) {
+	resetShutdown()
+	defer cancelShutdown()
+
+	servicesMutex.Lock()
+	saved := activeServices
+	activeServices = map[string]*ServiceProcess{
+		"running":  {Name: "running", State: ServiceStateRunning},
+		"starting": {Name: "starting", State: ServiceStateStarting},
+	}
+	servicesMutex.Unlock()
+	defer func() {
+		servicesMutex.Lock()
+		activeServices = saved
+		servicesMutex.Unlock()
+	}()
+
+	finished := func(name string, ok bool) *stageRun {
+		run := &stageRun{service: &Service{Name: name}, done: make(chan struct{}), ok: ok}
+		close(run.done)
+		return run
+	}
+	pending := func(name string) *stageRun {
+		return &stageRun{service: &Service{Name: name}, done: make(chan struct{})}
+	}
+
+	tests := []struct {
+		name string
+		runs []*stageRun
+		want bool
+	}{
+		{"Running and completed", []*stageRun{pending("running"), finished("oneshot", true)}, true},
+		{"Failed", []*stageRun{pending("running"), finished("oneshot", false)}, false},
+		{"Still starting", []*stageRun{pending("starting")}, false},
+		{"Empty", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := waitForStage(0, tt.runs, 100*time.Millisecond); got != tt.want {
+				t.Errorf("waitForStage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test depending on a service of a later stage is rejected
+func TestValidateStages(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []Service
+		wantErr  bool
+	}{
+		{"Earlier stage", []Service{{Name: "db"}, {Name: "app", Stage: 1, DependsOn: DependsOnField{"db"}}}, false},
+		{"Same stage", []Service{{Name: "db", Stage: 1}, {Name: "app", Stage: 1, DependsOn: DependsOnField{"db"}}}, false},
+		{"Later stage", []Service{{Name: "db", Stage: 2}, {Name: "app", Stage: 1, DependsOn: DependsOnField{"db"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := validateStages(tt.services); (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateStages() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package supervisor
+
+import (
+	"fmt"
+)
+
+// startLimiter bounds how many services start at once at boot
+// (max_concurrent_starts). A service holds a slot while it runs its
+// pre_script and while its process is spawned, never while it waits for its
+// conditions or dependencies, so waiting services can't starve the ones they
+// wait for. A nil limiter doesn't limit anything.
+type startLimiter chan struct{}
+
+// newStartLimiter returns a limiter of max slots, nil for no limit
+func newStartLimiter(max int) startLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return make(startLimiter, max)
+}
+
+// acquire waits for a free slot. It returns false if the supervisor started
+// shutting down in the meantime.
+func (l startLimiter) acquire() bool {
+	if l == nil {
+		return shutdownContext().Err() == nil
+	}
+	select {
+	case l <- struct{}{}:
+		return true
+	case <-shutdownContext().Done():
+		return false
+	}
+}
+
+// release frees a slot taken by acquire
+func (l startLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+func validateMaxConcurrentStarts(config *Config) ValidationErrors {
+	var errors ValidationErrors
+
+	if config.MaxConcurrentStarts < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "max_concurrent_starts",
+			Message: fmt.Sprintf("cannot be negative (got %d)", config.MaxConcurrentStarts),
+		})
+	}
+
+	return errors
+}
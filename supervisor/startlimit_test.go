@@ -0,0 +1,70 @@
+package supervisor
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test no more than max_concurrent_starts services hold a start slot at once
+func TestStartLimiter(t *testing.T) {
+	resetShutdown()
+	defer cancelShutdown()
+
+	slots := newStartLimiter(2)
+	var starting, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !slots.acquire() {
+				t.Error("acquire() = false before shutdown")
+				return
+			}
+			n := starting.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			starting.Add(-1)
+			slots.release()
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrent starts = %d, want 2", got)
+	}
+}
+
+// Test a service waiting for a slot gives up on shutdown, and no limit never waits
+func TestStartLimiterShutdown(t *testing.T) {
+	resetShutdown()
+	defer cancelShutdown()
+
+	if unlimited := newStartLimiter(0); unlimited != nil || !unlimited.acquire() {
+		t.Fatal("a limit of 0 should never wait")
+	}
+
+	slots := newStartLimiter(1)
+	if !slots.acquire() {
+		t.Fatal("acquire() = false with a free slot")
+	}
+	acquired := make(chan bool, 1)
+	go func() { acquired <- slots.acquire() }()
+
+	cancelShutdown()
+	select {
+	case ok := <-acquired:
+		if ok {
+			t.Error("acquire() = true after shutdown with no free slot")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquire() kept waiting after shutdown")
+	}
+}
@@ -0,0 +1,217 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServiceRecord is the operational history of a service the supervisor keeps
+// in its state_file, so it survives a restart or a crash of the supervisor
+type ServiceRecord struct {
+	Name          string `json:"name"`
+	TotalRestarts int    `json:"total_restarts,omitempty"`  // Automatic restarts
+	ExitCode      *int   `json:"exit_code,omitempty"`       // Last exit on its own, if any
+	StoppedByHand bool   `json:"stopped_by_hand,omitempty"` // Stopped by a control command, left stopped on boot
+
+	Events []LifecycleEvent `json:"events,omitempty"` // Recent starts, exits and stops, oldest first
+}
+
+// persistentState is the content of the state_file
+type persistentState struct {
+	Version  string          `json:"version"`
+	SavedAt  time.Time       `json:"saved_at"`
+	Services []ServiceRecord `json:"services"`
+}
+
+var (
+	// stateFile is where the state is saved, empty when it isn't persisted
+	stateFile   string
+	stateFileMu sync.Mutex
+
+	// stoppedByHand holds the services stopped by a control command since
+	// they last started, which a restarted supervisor leaves stopped
+	stoppedByHand   = make(map[string]bool)
+	stoppedByHandMu sync.Mutex
+)
+
+// markStoppedByHand records that a control command stopped (or started) a
+// service
+func markStoppedByHand(name string, stopped bool) {
+	stoppedByHandMu.Lock()
+	changed := stoppedByHand[name] != stopped
+	if stopped {
+		stoppedByHand[name] = true
+	} else {
+		delete(stoppedByHand, name)
+	}
+	stoppedByHandMu.Unlock()
+
+	if changed {
+		saveState()
+	}
+}
+
+// isStoppedByHand reports whether a control command stopped a service
+func isStoppedByHand(name string) bool {
+	stoppedByHandMu.Lock()
+	defer stoppedByHandMu.Unlock()
+	return stoppedByHand[name]
+}
+
+// serviceRecords returns the records of the configured services and of every
+// service with a history, in name order
+func serviceRecords() []ServiceRecord {
+	names := make(map[string]bool)
+	if config := currentConfig(); config != nil {
+		for _, service := range config.Services {
+			names[service.Name] = true
+		}
+	}
+	restartStatesMu.Lock()
+	for name := range restartTotals {
+		names[name] = true
+	}
+	restartStatesMu.Unlock()
+	lastExitCodesMu.Lock()
+	for name := range lastExitCodes {
+		names[name] = true
+	}
+	lastExitCodesMu.Unlock()
+	stoppedByHandMu.Lock()
+	for name := range stoppedByHand {
+		names[name] = true
+	}
+	stoppedByHandMu.Unlock()
+	lifecycleMu.Lock()
+	for name := range lifecycleHistory {
+		names[name] = true
+	}
+	lifecycleMu.Unlock()
+
+	records := make([]ServiceRecord, 0, len(names))
+	for name := range names {
+		records = append(records, ServiceRecord{
+			Name:          name,
+			TotalRestarts: totalRestartCount(name),
+			ExitCode:      exitCodeInfo(name),
+			StoppedByHand: isStoppedByHand(name),
+			Events:        lifecycleEvents(name),
+		})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	return records
+}
+
+// saveState writes the records to the state_file, if one is configured. The
+// file is replaced atomically so a crash never leaves half of it. A failed
+// write is logged: the state in memory is still right.
+func saveState() {
+	stateFileMu.Lock()
+	defer stateFileMu.Unlock()
+	if stateFile == "" {
+		return
+	}
+
+	var records []ServiceRecord
+	for _, record := range serviceRecords() {
+		if record.TotalRestarts > 0 || record.ExitCode != nil || record.StoppedByHand || len(record.Events) > 0 {
+			records = append(records, record)
+		}
+	}
+	if err := writeStateFile(stateFile, persistentState{Version: version, SavedAt: time.Now().UTC(), Services: records}); err != nil {
+		logger.Error(fmt.Sprintf("Could not save state: %v", err))
+	}
+}
+
+func writeStateFile(path string, state persistentState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readStateFile reads the state saved by a previous supervisor. A missing
+// file is an empty state.
+func readStateFile(path string) (persistentState, error) {
+	var state persistentState
+	data, err := os.ReadFile(path) // #nosec G304 - path comes from the config
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("%s is corrupt: %w", path, err)
+	}
+	return state, nil
+}
+
+// openStateFile restores the records saved at path and saves them there from
+// now on. An unreadable file is reported and replaced, rather than keeping
+// the supervisor from starting its services.
+func openStateFile(path string) {
+	state, err := readStateFile(path)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Could not restore state: %v", err))
+	}
+	restoreState(state)
+
+	stateFileMu.Lock()
+	stateFile = path
+	stateFileMu.Unlock()
+
+	if len(state.Services) > 0 {
+		logger.Info(fmt.Sprintf("Restored the state of %d service(s) from %s", len(state.Services), colorize(ColorCyan, path)))
+	}
+}
+
+// restoreState merges saved records into the state in memory
+func restoreState(state persistentState) {
+	for _, record := range state.Services {
+		restartStatesMu.Lock()
+		restartTotals[record.Name] += record.TotalRestarts
+		restartStatesMu.Unlock()
+
+		if record.ExitCode != nil {
+			lastExitCodesMu.Lock()
+			if _, ok := lastExitCodes[record.Name]; !ok {
+				lastExitCodes[record.Name] = *record.ExitCode
+			}
+			lastExitCodesMu.Unlock()
+		}
+		if record.StoppedByHand {
+			stoppedByHandMu.Lock()
+			stoppedByHand[record.Name] = true
+			stoppedByHandMu.Unlock()
+		}
+		restoreLifecycleEvents(record.Name, record.Events)
+	}
+}
+
+func validateStateFile(path string) ValidationErrors {
+	var errors ValidationErrors
+
+	if path != "" && !filepath.IsAbs(path) {
+		errors = append(errors, ValidationError{
+			Field:   "state_file",
+			Message: fmt.Sprintf("state_file '%s' must be an absolute path", path),
+		})
+	}
+
+	return errors
+}
@@ -0,0 +1,128 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// forgetState clears the state of a service and stops saving the state file
+func forgetState(t *testing.T, name string) {
+	t.Helper()
+	cleanup := func() {
+		stateFileMu.Lock()
+		stateFile = ""
+		stateFileMu.Unlock()
+		restartStatesMu.Lock()
+		delete(restartTotals, name)
+		restartStatesMu.Unlock()
+		lastExitCodesMu.Lock()
+		delete(lastExitCodes, name)
+		lastExitCodesMu.Unlock()
+		stoppedByHandMu.Lock()
+		delete(stoppedByHand, name)
+		stoppedByHandMu.Unlock()
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+}
+
+func findRecord(records []ServiceRecord, name string) (ServiceRecord, bool) {
+	for _, record := range records {
+		if record.Name == name {
+			return record, true
+		}
+	}
+	return ServiceRecord{}, false
+}
+
+// Test the state is saved on every change and restored by a new supervisor
+func TestStateFile(t *testing.T) {
+	const name = "state-web"
+	forgetState(t, name)
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+
+	openStateFile(path)
+	restartStatesMu.Lock()
+	restartTotals[name] = 4
+	restartStatesMu.Unlock()
+	recordExitCode(name, 3)
+	markStoppedByHand(name, true)
+
+	state, err := readStateFile(path)
+	if err != nil {
+		t.Fatalf("readStateFile() error = %v", err)
+	}
+	record, ok := findRecord(state.Services, name)
+	if !ok || record.TotalRestarts != 4 || record.ExitCode == nil || *record.ExitCode != 3 || !record.StoppedByHand {
+		t.Fatalf("saved record = %+v, want 4 restarts, exit code 3 and stopped by hand", record)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("state file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A new supervisor starts with nothing in memory
+	forgetState(t, name)
+	openStateFile(path)
+	if got := totalRestartCount(name); got != 4 {
+		t.Errorf("restored restarts = %d, want 4", got)
+	}
+	if code, ok := lastExitCode(name); !ok || code != 3 {
+		t.Errorf("restored exit code = %d, %v, want 3", code, ok)
+	}
+	if !isStoppedByHand(name) {
+		t.Error("service should still be stopped by hand")
+	}
+
+	markStoppedByHand(name, false)
+	state, _ = readStateFile(path)
+	if record, _ := findRecord(state.Services, name); record.StoppedByHand {
+		t.Error("starting the service should be saved")
+	}
+	if record, ok := findRecord(serviceRecords(), name); !ok || record.TotalRestarts != 4 {
+		t.Errorf("serviceRecords() = %+v, want the restored record", record)
+	}
+}
+
+// Test a missing state file is an empty state and a corrupt one is replaced
+func TestReadStateFile(t *testing.T) {
+	const name = "state-corrupt"
+	forgetState(t, name)
+	dir := t.TempDir()
+
+	state, err := readStateFile(filepath.Join(dir, "missing.json"))
+	if err != nil || len(state.Services) != 0 {
+		t.Errorf("readStateFile(missing) = %+v, %v, want an empty state", state, err)
+	}
+
+	path := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte(`{"services": [`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readStateFile(path); err == nil {
+		t.Error("readStateFile(corrupt) should fail")
+	}
+
+	openStateFile(path)
+	recordExitCode(name, 1)
+	state, err = readStateFile(path)
+	if err != nil {
+		t.Fatalf("state file should have been replaced: %v", err)
+	}
+	if _, ok := findRecord(state.Services, name); !ok {
+		t.Errorf("saved state = %+v, want %s", state, name)
+	}
+}
+
+func TestValidateStateFile(t *testing.T) {
+	if errs := validateStateFile("/var/lib/go-overlay/state.json"); len(errs) != 0 {
+		t.Errorf("absolute path rejected: %v", errs)
+	}
+	if errs := validateStateFile("state.json"); len(errs) != 1 {
+		t.Errorf("relative path accepted: %v", errs)
+	}
+}
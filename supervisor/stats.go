@@ -0,0 +1,439 @@
+package supervisor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resource sampling settings: 120 samples every 5s keep the last 10 minutes
+const (
+	statsSampleInterval = 5 * time.Second
+	statsHistorySize    = 120
+)
+
+// clockTicks is USER_HZ, the unit of the CPU times in /proc/<pid>/stat. It is
+// 100 on every Linux architecture go-overlay ships for.
+const clockTicks = 100
+
+// StatsSample is the resource usage of a service (process tree) at one point in time
+type StatsSample struct {
+	Time       time.Time `json:"time"`
+	CPUPercent float64   `json:"cpu_percent"`
+	RSS        uint64    `json:"rss"`        // Resident memory in bytes
+	ReadRate   float64   `json:"read_rate"`  // Bytes read from storage per second
+	WriteRate  float64   `json:"write_rate"` // Bytes written to storage per second
+}
+
+// ServiceStats is the recorded resource history of a service
+type ServiceStats struct {
+	Service  string        `json:"service"`
+	Interval time.Duration `json:"interval"`
+	Samples  []StatsSample `json:"samples"`
+}
+
+// procCounters are the cumulative counters of a process tree used to derive rates
+type procCounters struct {
+	at         time.Time
+	pid        int
+	cpuTicks   uint64
+	readBytes  uint64
+	writeBytes uint64
+	rss        uint64
+}
+
+// statsRecorder keeps a bounded time series per service. History survives
+// restarts and crashes so the samples leading up to a failure stay visible.
+type statsRecorder struct {
+	mu      sync.Mutex
+	size    int
+	history map[string][]StatsSample
+	last    map[string]procCounters
+}
+
+func newStatsRecorder(size int) *statsRecorder {
+	return &statsRecorder{
+		size:    size,
+		history: make(map[string][]StatsSample),
+		last:    make(map[string]procCounters),
+	}
+}
+
+var serviceStats = newStatsRecorder(statsHistorySize)
+
+// record derives a sample from the counters of a service and appends it.
+// The first reading of a process only primes the rates.
+func (r *statsRecorder) record(name string, counters procCounters) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev, ok := r.last[name]
+	r.last[name] = counters
+	if !ok || prev.pid != counters.pid {
+		return
+	}
+
+	elapsed := counters.at.Sub(prev.at).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	sample := StatsSample{
+		Time:       counters.at,
+		CPUPercent: float64(deltaCounter(counters.cpuTicks, prev.cpuTicks)) / clockTicks / elapsed * 100,
+		RSS:        counters.rss,
+		ReadRate:   float64(deltaCounter(counters.readBytes, prev.readBytes)) / elapsed,
+		WriteRate:  float64(deltaCounter(counters.writeBytes, prev.writeBytes)) / elapsed,
+	}
+
+	samples := append(r.history[name], sample)
+	if len(samples) > r.size {
+		samples = samples[len(samples)-r.size:]
+	}
+	r.history[name] = samples
+}
+
+// forget drops the rate baseline of a service that is no longer running
+func (r *statsRecorder) forget(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.last, name)
+}
+
+// samples returns a copy of the recorded history of a service
+func (r *statsRecorder) samples(name string) ([]StatsSample, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	samples, ok := r.history[name]
+	return append([]StatsSample(nil), samples...), ok
+}
+
+// current returns the latest sample of a service if it was taken from the
+// running process pid during the last sampling interval
+func (r *statsRecorder) current(name string, pid int) (StatsSample, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	samples := r.history[name]
+	last, ok := r.last[name]
+	if len(samples) == 0 || !ok || last.pid != pid {
+		return StatsSample{}, false
+	}
+	// After a restart the latest sample is of the previous process
+	latest := samples[len(samples)-1]
+	if !latest.Time.Equal(last.at) || time.Since(latest.Time) > 2*statsSampleInterval {
+		return StatsSample{}, false
+	}
+	return latest, true
+}
+
+// deltaCounter returns cur-prev, or 0 when a counter went backwards (a child
+// process exited and its counters left the tree)
+func deltaCounter(cur, prev uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// startStatsSampler samples every running service until shutdown
+func startStatsSampler() {
+	go func() {
+		ticker := time.NewTicker(statsSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-shutdownContext().Done():
+				return
+			case <-ticker.C:
+				sampleServices()
+			}
+		}
+	}()
+}
+
+// sampleServices records one sample for every running service
+func sampleServices() {
+	pids := make(map[string]int)
+	servicesMutex.RLock()
+	for name, serviceProc := range activeServices {
+		if pid := serviceProc.GetPID(); pid > 0 && serviceProc.GetState() == ServiceStateRunning {
+			pids[name] = pid
+		}
+	}
+	servicesMutex.RUnlock()
+
+	children := processChildren()
+	for name, pid := range pids {
+		counters, err := readTreeCounters(pid, children)
+		if err != nil {
+			serviceStats.forget(name)
+			continue
+		}
+		serviceStats.record(name, counters)
+	}
+}
+
+// processChildren maps every PID to its direct children
+func processChildren() map[int][]int {
+	children := make(map[int][]int)
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return children
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := readProcStat(pid)
+		if err != nil {
+			continue
+		}
+		children[stat.ppid] = append(children[stat.ppid], pid)
+	}
+	return children
+}
+
+// readTreeCounters sums the counters of pid and all of its descendants, so
+// services wrapped by `su` or a shell are measured as a whole
+func readTreeCounters(pid int, children map[int][]int) (procCounters, error) {
+	root, err := readProcStat(pid)
+	if err != nil {
+		return procCounters{}, err
+	}
+
+	counters := procCounters{at: time.Now(), pid: pid}
+	queue := []int{pid}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		queue = append(queue, children[current]...)
+
+		stat := root
+		if current != pid {
+			if stat, err = readProcStat(current); err != nil {
+				continue // Exited since the process table was scanned
+			}
+		}
+		counters.cpuTicks += stat.utime + stat.stime
+		counters.rss += stat.rssPages * uint64(os.Getpagesize())
+
+		read, write := readProcIO(current)
+		counters.readBytes += read
+		counters.writeBytes += write
+	}
+	return counters, nil
+}
+
+// procStat holds the fields of /proc/<pid>/stat used for sampling
+type procStat struct {
+	ppid     int
+	utime    uint64
+	stime    uint64
+	rssPages uint64
+}
+
+func readProcStat(pid int) (procStat, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	return parseProcStat(string(data))
+}
+
+// parseProcStat parses a /proc/<pid>/stat line. The command name is wrapped
+// in parentheses and may itself contain spaces or parentheses.
+func parseProcStat(line string) (procStat, error) {
+	end := strings.LastIndexByte(line, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("malformed stat line")
+	}
+	// Fields after the command name start at "state" (field 3 in proc(5))
+	fields := strings.Fields(line[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("malformed stat line: %d fields", len(fields))
+	}
+
+	var stat procStat
+	var err error
+	if stat.ppid, err = strconv.Atoi(fields[1]); err != nil {
+		return procStat{}, err
+	}
+	if stat.utime, err = strconv.ParseUint(fields[11], 10, 64); err != nil {
+		return procStat{}, err
+	}
+	if stat.stime, err = strconv.ParseUint(fields[12], 10, 64); err != nil {
+		return procStat{}, err
+	}
+	if stat.rssPages, err = strconv.ParseUint(fields[21], 10, 64); err != nil {
+		return procStat{}, err
+	}
+	return stat, nil
+}
+
+// readProcIO returns the storage bytes read and written by pid. The file is
+// only readable for processes we may ptrace, so failures count as zero.
+func readProcIO(pid int) (read, write uint64) {
+	file, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "io"))
+	if err != nil {
+		return 0, 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "read_bytes":
+			read = n
+		case "write_bytes":
+			write = n
+		}
+	}
+	return read, write
+}
+
+func handleServiceStats(serviceName string) IPCResponse {
+	samples, recorded := serviceStats.samples(serviceName)
+	if !recorded {
+		if _, ok := findServiceConfig(serviceName); !ok {
+			return notFoundResponse(serviceName)
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Stats: &ServiceStats{
+			Service:  serviceName,
+			Interval: statsSampleInterval,
+			Samples:  samples,
+		},
+	}
+}
+
+// statsSummary is the min/avg/max of one metric over the recorded window
+type statsSummary struct {
+	Current, Min, Avg, Max float64
+	MaxAt                  time.Time
+}
+
+// summarize computes the summary of the metric selected by value
+func summarize(samples []StatsSample, value func(StatsSample) float64) statsSummary {
+	var summary statsSummary
+	if len(samples) == 0 {
+		return summary
+	}
+
+	var total float64
+	for i, sample := range samples {
+		v := value(sample)
+		total += v
+		if i == 0 || v < summary.Min {
+			summary.Min = v
+		}
+		if i == 0 || v > summary.Max {
+			summary.Max = v
+			summary.MaxAt = sample.Time
+		}
+	}
+	summary.Current = value(samples[len(samples)-1])
+	summary.Avg = total / float64(len(samples))
+	return summary
+}
+
+// formatBytes renders a byte count with a binary unit (e.g. 12.5 MiB)
+func formatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+// renderStats formats the recorded history of a service as a summary table
+func renderStats(stats *ServiceStats) string {
+	if len(stats.Samples) == 0 {
+		return fmt.Sprintf("No samples recorded for service '%s' yet (sampled every %s)\n",
+			stats.Service, stats.Interval)
+	}
+
+	first := stats.Samples[0].Time
+	last := stats.Samples[len(stats.Samples)-1].Time
+	noun := "samples"
+	if len(stats.Samples) == 1 {
+		noun = "sample"
+	}
+	header := fmt.Sprintf("Resource usage of '%s': %d %s over the last %s (every %s)\n\n",
+		colorize(ColorCyan, stats.Service), len(stats.Samples), noun,
+		last.Sub(first).Round(time.Second), stats.Interval)
+
+	percent := func(v float64) string { return fmt.Sprintf("%.1f%%", v) }
+	rate := func(v float64) string { return formatBytes(v) + "/s" }
+
+	metrics := []struct {
+		name   string
+		value  func(StatsSample) float64
+		format func(float64) string
+	}{
+		{"CPU", func(s StatsSample) float64 { return s.CPUPercent }, percent},
+		{"Memory (RSS)", func(s StatsSample) float64 { return float64(s.RSS) }, formatBytes},
+		{"Disk read", func(s StatsSample) float64 { return s.ReadRate }, rate},
+		{"Disk write", func(s StatsSample) float64 { return s.WriteRate }, rate},
+	}
+
+	rows := make([][]string, 0, len(metrics))
+	for _, metric := range metrics {
+		summary := summarize(stats.Samples, metric.value)
+		rows = append(rows, []string{
+			metric.name,
+			metric.format(summary.Current),
+			metric.format(summary.Min),
+			metric.format(summary.Avg),
+			metric.format(summary.Max),
+			summary.MaxAt.Format("15:04:05"),
+		})
+	}
+
+	return header + renderTable([]string{"METRIC", "CURRENT", "MIN", "AVG", "MAX", "PEAK AT"}, rows)
+}
+
+// showStats prints the resource history of a service, refreshing every
+// sampling interval when watch is set
+func showStats(serviceName string, watch bool) error {
+	for {
+		response, err := sendIPCCommand(IPCCommand{Type: CmdServiceStats, ServiceName: serviceName})
+		if err != nil {
+			return err
+		}
+		if !response.Success || response.Stats == nil {
+			return responseError(response)
+		}
+
+		if watch {
+			fmt.Print("\033[H\033[2J") // Clear the screen before redrawing
+		}
+		fmt.Print(renderStats(response.Stats))
+		if !watch {
+			return nil
+		}
+		time.Sleep(response.Stats.Interval)
+	}
+}
@@ -0,0 +1,185 @@
+package supervisor
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test /proc/<pid>/stat parsing, including command names with spaces and parentheses
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    procStat
+		wantErr bool
+	}{
+		{
+			name: "simple",
+			line: "42 (nginx) S 1 42 42 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 1 0 100 1000000 300 18446744073709551615",
+			want: procStat{ppid: 1, utime: 250, stime: 50, rssPages: 300},
+		},
+		{
+			name: "spaces in name",
+			line: "7 (my (odd) app) R 3 7 7 0 -1 0 0 0 0 0 10 20 0 0 20 0 1 0 100 1000 5 0",
+			want: procStat{ppid: 3, utime: 10, stime: 20, rssPages: 5},
+		},
+		{name: "truncated", line: "7 (app) R 3 7", wantErr: true},
+		{name: "no name", line: "garbage", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProcStat(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProcStat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseProcStat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test the recorder derives rates from counter deltas and keeps a bounded history
+func TestStatsRecorder(t *testing.T) {
+	r := newStatsRecorder(3)
+	start := time.Now()
+
+	counters := func(sec int, ticks, written uint64) procCounters {
+		return procCounters{
+			at:         start.Add(time.Duration(sec) * time.Second),
+			pid:        10,
+			cpuTicks:   ticks,
+			writeBytes: written,
+			rss:        4096,
+		}
+	}
+
+	r.record("web", counters(0, 0, 0))
+	if samples, _ := r.samples("web"); len(samples) != 0 {
+		t.Fatalf("first reading recorded %d samples, want 0", len(samples))
+	}
+
+	// 50 ticks over 1s = half a CPU
+	r.record("web", counters(1, 50, 2048))
+	samples, _ := r.samples("web")
+	if len(samples) != 1 {
+		t.Fatalf("recorded %d samples, want 1", len(samples))
+	}
+	if samples[0].CPUPercent != 50 || samples[0].WriteRate != 2048 || samples[0].RSS != 4096 {
+		t.Errorf("sample = %+v, want 50%% CPU, 2048 B/s written, 4096 RSS", samples[0])
+	}
+
+	for sec := 2; sec <= 5; sec++ {
+		r.record("web", counters(sec, uint64(sec*50), 0))
+	}
+	if samples, _ := r.samples("web"); len(samples) != 3 {
+		t.Errorf("history holds %d samples, want it bounded to 3", len(samples))
+	}
+	if sample, ok := r.current("web", 10); !ok || sample.CPUPercent != 50 {
+		t.Errorf("current() = %+v, %v, want the latest sample", sample, ok)
+	}
+
+	// A new PID (restart) only primes the rates, history is kept
+	restarted := counters(6, 0, 0)
+	restarted.pid = 11
+	r.record("web", restarted)
+	if samples, _ := r.samples("web"); len(samples) != 3 {
+		t.Errorf("history after restart holds %d samples, want 3", len(samples))
+	}
+	if _, ok := r.current("web", 11); ok {
+		t.Error("current() returned a sample of the previous process")
+	}
+
+	// The latest sample of the running process is current
+	live := newStatsRecorder(3)
+	now := time.Now()
+	live.record("web", procCounters{at: now.Add(-time.Second), pid: 12})
+	live.record("web", procCounters{at: now, pid: 12, cpuTicks: 25, rss: 8192})
+	if sample, ok := live.current("web", 12); !ok || sample.CPUPercent != 25 || sample.RSS != 8192 {
+		t.Errorf("current() = %+v, %v, want 25%% CPU and 8192 RSS", sample, ok)
+	}
+	if _, ok := live.current("web", 13); ok {
+		t.Error("current() returned a sample of another PID")
+	}
+
+	stale := newStatsRecorder(3)
+	stale.record("web", procCounters{at: now.Add(-time.Minute), pid: 12})
+	stale.record("web", procCounters{at: now.Add(-50 * time.Second), pid: 12})
+	if _, ok := stale.current("web", 12); ok {
+		t.Error("current() returned a sample older than two intervals")
+	}
+}
+
+// Test min/avg/max and the time of the peak
+func TestSummarize(t *testing.T) {
+	now := time.Now()
+	samples := []StatsSample{
+		{Time: now, CPUPercent: 10},
+		{Time: now.Add(time.Second), CPUPercent: 90},
+		{Time: now.Add(2 * time.Second), CPUPercent: 20},
+	}
+
+	got := summarize(samples, func(s StatsSample) float64 { return s.CPUPercent })
+	if got.Current != 20 || got.Min != 10 || got.Avg != 40 || got.Max != 90 {
+		t.Errorf("summarize() = %+v, want current 20, min 10, avg 40, max 90", got)
+	}
+	if !got.MaxAt.Equal(now.Add(time.Second)) {
+		t.Errorf("MaxAt = %v, want the time of the 90%% sample", got.MaxAt)
+	}
+}
+
+// Test byte formatting
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1536, "1.5 KiB"},
+		{10 * 1024 * 1024, "10.0 MiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.in); got != tt.want {
+			t.Errorf("formatBytes(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Test sampling a live process tree reports its memory
+func TestReadTreeCounters(t *testing.T) {
+	counters, err := readTreeCounters(os.Getpid(), processChildren())
+	if err != nil {
+		t.Fatalf("readTreeCounters() error = %v", err)
+	}
+	if counters.rss == 0 {
+		t.Error("readTreeCounters() reported no resident memory for the test process")
+	}
+}
+
+// Test the rendered summary lists every metric
+func TestRenderStats(t *testing.T) {
+	now := time.Now()
+	out := stripANSI(renderStats(&ServiceStats{
+		Service:  "web",
+		Interval: statsSampleInterval,
+		Samples: []StatsSample{
+			{Time: now, CPUPercent: 5, RSS: 1 << 20},
+			{Time: now.Add(statsSampleInterval), CPUPercent: 15, RSS: 2 << 20},
+		},
+	}))
+	for _, want := range []string{"2 samples", "CPU", "10.0%", "15.0%", "Memory (RSS)", "2.0 MiB", "Disk write"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("renderStats() missing %q:\n%s", want, out)
+		}
+	}
+
+	empty := renderStats(&ServiceStats{Service: "web", Interval: statsSampleInterval})
+	if !strings.Contains(empty, "No samples") {
+		t.Errorf("renderStats() without samples = %q", empty)
+	}
+}
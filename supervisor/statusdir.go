@@ -0,0 +1,123 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// defaultStatusDir is the root of the filesystem status interface
+const defaultStatusDir = "/run/go-overlay"
+
+// statusDir is the active status root; empty disables the filesystem interface
+var statusDir string
+
+// Status files maintained per service under <statusDir>/<service>/
+const (
+	statusFileState = "state" // Current ServiceState name
+	statusFilePID   = "pid"   // PID of the main process, 0 when not running
+	statusFileSince = "since" // RFC3339 timestamp of the last state change
+	statusFileReady = "ready" // Present only while the service is ready
+)
+
+// initStatusDir creates the status tree for every configured service so that
+// shell tooling can poll it without speaking JSON over the IPC socket.
+func initStatusDir(dir string, services []Service) {
+	if dir == "" {
+		dir = defaultStatusDir
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil { // #nosec G301 - status files are meant to be world-readable
+		logger.Warn(fmt.Sprintf("Status directory disabled, could not create %s: %v", dir, err))
+		return
+	}
+	statusDir = dir
+
+	if err := writeStatusFile(filepath.Join(dir, "supervisor.pid"), strconv.Itoa(os.Getpid())); err != nil {
+		logger.Warn(fmt.Sprintf("Could not write supervisor pid file: %v", err))
+	}
+
+	for i := range services {
+		state := ServiceStatePending
+		if services[i].Enabled != nil && !*services[i].Enabled {
+			state = ServiceStateStopped
+		}
+		writeServiceStatus(services[i].Name, state, 0)
+	}
+}
+
+// serviceStatusDir returns the status directory for a service
+func serviceStatusDir(name string) string {
+	return filepath.Join(statusDir, name)
+}
+
+// writeServiceStatus refreshes the status files of a service
+func writeServiceStatus(name string, state ServiceState, pid int) {
+	if statusDir == "" {
+		return
+	}
+
+	dir := serviceStatusDir(name)
+	if err := os.MkdirAll(dir, 0o755); err != nil { // #nosec G301 - status files are meant to be world-readable
+		logger.Debug("Error creating status directory for ", name, ": ", err)
+		return
+	}
+
+	files := map[string]string{
+		statusFileState: state.String(),
+		statusFilePID:   strconv.Itoa(pid),
+		statusFileSince: time.Now().Format(time.RFC3339),
+	}
+	for file, content := range files {
+		if err := writeStatusFile(filepath.Join(dir, file), content); err != nil {
+			logger.Debug("Error writing status file ", file, " for ", name, ": ", err)
+		}
+	}
+
+	// The ready flag is written by writeReadyFlag, only removed here
+	if state != ServiceStateRunning {
+		_ = os.Remove(filepath.Join(dir, statusFileReady))
+	}
+}
+
+// writeReadyFlag creates the ready file of a service
+func writeReadyFlag(name string) {
+	if statusDir == "" {
+		return
+	}
+	if err := writeStatusFile(filepath.Join(serviceStatusDir(name), statusFileReady), ""); err != nil {
+		logger.Debug("Error writing ready flag for ", name, ": ", err)
+	}
+}
+
+// isReadyState reports whether dependents may consider a service ready: one
+// with a readiness probe, notify or notification fd only once it passed it
+func isReadyState(state ServiceState, probe *ReadinessProbe, ready bool) bool {
+	return state == ServiceStateRunning && (probe == nil || ready)
+}
+
+// writeStatusFile atomically replaces path so readers never see partial content
+func writeStatusFile(path, content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if content != "" {
+		if _, err := tmp.WriteString(content + "\n"); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Chmod(0o644); err != nil { // #nosec G302 - status files are meant to be world-readable
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,83 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readStatusFile(t *testing.T, service, file string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(statusDir, service, file))
+	if err != nil {
+		t.Fatalf("reading %s/%s: %v", service, file, err)
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// Test the filesystem status interface follows state transitions
+func TestStatusDirFollowsState(t *testing.T) {
+	defer func() { statusDir = "" }()
+
+	disabled := false
+	initStatusDir(t.TempDir(), []Service{{Name: "web"}, {Name: "cron", Enabled: &disabled}})
+
+	if got := readStatusFile(t, "web", statusFileState); got != "PENDING" {
+		t.Errorf("initial state = %q, want PENDING", got)
+	}
+	if got := readStatusFile(t, "cron", statusFileState); got != "STOPPED" {
+		t.Errorf("disabled service state = %q, want STOPPED", got)
+	}
+
+	sp := &ServiceProcess{Name: "web", State: ServiceStatePending}
+	sp.SetState(ServiceStateRunning)
+
+	if got := readStatusFile(t, "web", statusFileState); got != "RUNNING" {
+		t.Errorf("state = %q, want RUNNING", got)
+	}
+	if _, err := os.Stat(filepath.Join(statusDir, "web", statusFileReady)); err != nil {
+		t.Errorf("ready flag missing for running service: %v", err)
+	}
+
+	sp.SetState(ServiceStateStopped)
+	if _, err := os.Stat(filepath.Join(statusDir, "web", statusFileReady)); !os.IsNotExist(err) {
+		t.Errorf("ready flag should be removed when stopped, stat err = %v", err)
+	}
+	if got := readStatusFile(t, "web", statusFilePID); got != "0" {
+		t.Errorf("pid = %q, want 0", got)
+	}
+}
+
+// Test a service with a readiness probe is only flagged ready once it passed it
+func TestStatusDirReadyFollowsProbe(t *testing.T) {
+	defer func() { statusDir = "" }()
+	initStatusDir(t.TempDir(), []Service{{Name: "api"}})
+	readyPath := filepath.Join(statusDir, "api", statusFileReady)
+
+	sp := &ServiceProcess{Name: "api", State: ServiceStatePending,
+		Config: Service{Name: "api", Readiness: &ReadinessProbe{Notify: true}}}
+	sp.SetState(ServiceStateRunning)
+	if _, err := os.Stat(readyPath); !os.IsNotExist(err) {
+		t.Errorf("ready flag written before the probe passed, stat err = %v", err)
+	}
+
+	sp.SetReady()
+	if _, err := os.Stat(readyPath); err != nil {
+		t.Errorf("ready flag missing after SetReady: %v", err)
+	}
+
+	sp.SetState(ServiceStateStopped)
+	if _, err := os.Stat(readyPath); !os.IsNotExist(err) {
+		t.Errorf("ready flag should be removed when stopped, stat err = %v", err)
+	}
+}
+
+// Test writes are no-ops while the status interface is disabled
+func TestStatusDirDisabled(t *testing.T) {
+	statusDir = ""
+	writeServiceStatus("web", ServiceStateRunning, 1)
+	if _, err := os.Stat("web"); !os.IsNotExist(err) {
+		t.Error("status files written while status directory is disabled")
+	}
+}
@@ -0,0 +1,84 @@
+package supervisor
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// defaultKillTimeout is how long a service gets after the SIGTERM that
+// follows a custom stop_signal, before it is killed
+const defaultKillTimeout = 5
+
+// stopStep is one signal of the escalation that stops a service, and how
+// long to wait for the service to exit before the next one
+type stopStep struct {
+	signal  syscall.Signal
+	timeout time.Duration
+}
+
+// stopSteps returns the escalation that stops a service: stop_signal, then
+// SIGTERM if stop_signal is another signal. SIGKILL follows the last step.
+func stopSteps(service *Service, timeouts Timeouts) []stopStep {
+	stopTimeout := service.StopTimeout
+	if stopTimeout <= 0 {
+		stopTimeout = timeouts.ServiceShutdown
+	}
+	killTimeout := service.KillTimeout
+	if killTimeout <= 0 {
+		killTimeout = defaultKillTimeout
+	}
+
+	sig := syscall.SIGTERM
+	if service.StopSignal != "" {
+		if parsed, err := parseSignal(service.StopSignal); err == nil {
+			sig = parsed
+		}
+	}
+
+	steps := []stopStep{{signal: sig, timeout: time.Duration(stopTimeout) * time.Second}}
+	if sig != syscall.SIGTERM {
+		steps = append(steps, stopStep{signal: syscall.SIGTERM, timeout: time.Duration(killTimeout) * time.Second})
+	}
+	return steps
+}
+
+// stopDuration is the longest a service can take to stop before it is
+// killed, pre_stop and on_exit included
+func stopDuration(service *Service, timeouts Timeouts) time.Duration {
+	total := preStopTimeout(service) + onExitTimeout(service)
+	for _, step := range stopSteps(service, timeouts) {
+		total += step.timeout
+	}
+	return total
+}
+
+func validateStopSignal(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.StopSignal != "" {
+		if sig, err := parseSignal(service.StopSignal); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "stop_signal",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		} else if sig == syscall.SIGKILL || sig == syscall.SIGSTOP {
+			errors = append(errors, ValidationError{
+				Field:   "stop_signal",
+				Service: service.Name,
+				Message: fmt.Sprintf("%s cannot be used as stop_signal: the service could not shut down gracefully", service.StopSignal),
+			})
+		}
+	}
+
+	if service.StopTimeout < 0 || service.KillTimeout < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "stop_timeout",
+			Service: service.Name,
+			Message: "stop_timeout and kill_timeout cannot be negative",
+		})
+	}
+
+	return errors
+}
@@ -0,0 +1,168 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Test the stop escalation of a service
+func TestStopSteps(t *testing.T) {
+	timeouts := Timeouts{ServiceShutdown: 10}
+	tests := []struct {
+		name    string
+		service Service
+		want    []stopStep
+	}{
+		{"default", Service{}, []stopStep{{syscall.SIGTERM, 10 * time.Second}}},
+		{"stop timeout", Service{StopTimeout: 3}, []stopStep{{syscall.SIGTERM, 3 * time.Second}}},
+		{"SIGTERM by name", Service{StopSignal: "TERM"}, []stopStep{{syscall.SIGTERM, 10 * time.Second}}},
+		{"custom signal", Service{StopSignal: "SIGQUIT"}, []stopStep{
+			{syscall.SIGQUIT, 10 * time.Second},
+			{syscall.SIGTERM, defaultKillTimeout * time.Second},
+		}},
+		{"custom timeouts", Service{StopSignal: "int", StopTimeout: 2, KillTimeout: 1}, []stopStep{
+			{syscall.SIGINT, 2 * time.Second},
+			{syscall.SIGTERM, time.Second},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stopSteps(&tt.service, timeouts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("stopSteps() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("stopSteps()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// Test stop_signal validation
+func TestValidateStopSignal(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"default", Service{Name: "web"}, 0},
+		{"valid", Service{Name: "web", StopSignal: "SIGQUIT", StopTimeout: 30, KillTimeout: 5}, 0},
+		{"unknown", Service{Name: "web", StopSignal: "SIGFOO"}, 1},
+		{"kill", Service{Name: "web", StopSignal: "KILL"}, 1},
+		{"negative timeout", Service{Name: "web", KillTimeout: -1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateStopSignal(&tt.service); len(got) != tt.errors {
+				t.Errorf("validateStopSignal() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test a service is stopped with its stop_signal, and with SIGTERM if it ignores it
+func TestStopSignalEscalation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		max    time.Duration
+	}{
+		{"stops on stop_signal", "trap 'exit 0' INT; trap '' TERM; while :; do sleep 0.1; done", time.Second},
+		{"escalates to SIGTERM", "trap '' INT; trap 'exit 0' TERM; while :; do sleep 0.1; done", 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetShutdown()
+			setConfig(&Config{
+				Services: []Service{{
+					Name: "stopper", Command: "/bin/sh", Args: []string{"-c", tt.script},
+					StopSignal: "SIGINT", StopTimeout: 1, KillTimeout: 10,
+				}},
+				Timeouts: Timeouts{ServiceShutdown: 10},
+			})
+			defer func() {
+				cancelShutdown()
+				setConfig(nil)
+			}()
+
+			if err := startService("stopper"); err != nil {
+				t.Fatalf("startService() error = %v", err)
+			}
+			time.Sleep(300 * time.Millisecond) // Let the shell install its traps
+
+			started := time.Now()
+			if err := stopService("stopper"); err != nil {
+				t.Fatalf("stopService() error = %v", err)
+			}
+			if elapsed := time.Since(started); elapsed > tt.max {
+				t.Errorf("stopping took %s, want under %s", elapsed, tt.max)
+			}
+		})
+	}
+}
+
+// Test stopping a shell-wrapped service also stops the children it started
+func TestStopKillsProcessGroup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	resetShutdown()
+	setConfig(&Config{
+		Services: []Service{{
+			Name: "wrapper", Command: "/bin/sh",
+			// The child ignores the SIGHUP of the terminal closing with its parent
+			Args: []string{"-c", "trap '' HUP; sleep 300 & echo $! > " + pidFile + "; wait"},
+		}},
+		Timeouts: Timeouts{ServiceShutdown: 5},
+	})
+	defer func() {
+		cancelShutdown()
+		setConfig(nil)
+	}()
+
+	if err := startService("wrapper"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	var child int
+	if !waitFor(t, 5*time.Second, func() bool {
+		data, _ := os.ReadFile(pidFile)
+		child, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		return child > 0
+	}) {
+		t.Fatal("child process did not start")
+	}
+
+	if err := stopService("wrapper"); err != nil {
+		t.Fatalf("stopService() error = %v", err)
+	}
+	if !waitFor(t, 2*time.Second, func() bool { return !processAlive(child) }) {
+		_ = syscall.Kill(child, syscall.SIGKILL)
+		t.Errorf("child %d of the service survived the stop", child)
+	}
+}
+
+// processAlive reports whether pid runs and isn't a zombie waiting to be reaped
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
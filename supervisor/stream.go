@@ -0,0 +1,158 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// listChunkSize is the number of services sent per frame on a streamed listing
+const listChunkSize = 100
+
+// snapshotServices returns the state of every active service ordered by name,
+// so pages stay stable across paginated requests.
+func snapshotServices() []ServiceInfo {
+	servicesMutex.RLock()
+	defer servicesMutex.RUnlock()
+
+	services := make([]ServiceInfo, 0, len(activeServices))
+	for name, serviceProc := range activeServices {
+		var lastError string
+		if err := serviceProc.GetError(); err != nil {
+			lastError = err.Error()
+		}
+
+		var cpuPercent *float64
+		var rss uint64
+		if sample, ok := serviceStats.current(name, serviceProc.GetPID()); ok {
+			cpuPercent, rss = &sample.CPUPercent, sample.RSS
+		}
+
+		services = append(services, ServiceInfo{
+			Name:          name,
+			State:         serviceProc.GetState(),
+			PID:           serviceProc.GetPID(),
+			Uptime:        time.Since(serviceProc.StartTime),
+			LastError:     lastError,
+			Required:      serviceProc.Config.Required,
+			DroppedLogs:   droppedLogLines(name),
+			Labels:        serviceProc.Config.Labels,
+			Tags:          serviceProc.Config.Tags,
+			Restarts:      restartCount(name),
+			TotalRestarts: totalRestartCount(name),
+			ExitCode:      exitCodeInfo(name),
+			Health:        serviceProc.GetHealth(),
+			Ready:         serviceProc.IsReady(),
+			Cgroup:        readCgroupUsage(&serviceProc.Config),
+			CPUPercent:    cpuPercent,
+			RSS:           rss,
+
+			RecentOutput: serviceLogs.tail(name, recentOutputLines),
+		})
+	}
+
+	// Defined services without a process (stopped by hand, disabled or not
+	// started yet) are listed as stopped so they can be found and started;
+	// those waiting for an automatic restart or failed by the crash loop
+	// breaker are listed as such
+	if config := currentConfig(); config != nil {
+		for _, service := range config.Services {
+			if _, running := activeServices[service.Name]; running {
+				continue
+			}
+			state, lastError := restartStatus(service.Name)
+			services = append(services, ServiceInfo{
+				Name:          service.Name,
+				State:         state,
+				LastError:     lastError,
+				Required:      service.Required,
+				Labels:        service.Labels,
+				Tags:          service.Tags,
+				Restarts:      restartCount(service.Name),
+				TotalRestarts: totalRestartCount(service.Name),
+				ExitCode:      exitCodeInfo(service.Name),
+
+				RecentOutput: serviceLogs.tail(service.Name, recentOutputLines),
+			})
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
+
+// paginate returns the window of services starting at offset holding at most
+// limit services (limit <= 0 means no limit)
+func paginate(items []ServiceInfo, offset, limit int) []ServiceInfo {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// streamListServices writes the (paginated) listing as a sequence of frames of
+// at most listChunkSize services; every frame but the last has More set.
+func streamListServices(encoder *json.Encoder, cmd IPCCommand) error {
+	services := snapshotServices()
+	total := len(services)
+	services = paginate(services, cmd.Offset, cmd.Limit)
+
+	for {
+		chunk := services
+		if len(chunk) > listChunkSize {
+			chunk = chunk[:listChunkSize]
+		}
+		services = services[len(chunk):]
+
+		frame := IPCResponse{
+			Success:  true,
+			Services: chunk,
+			Total:    total,
+			More:     len(services) > 0,
+		}
+		if err := encoder.Encode(frame); err != nil {
+			return err
+		}
+		if !frame.More {
+			return nil
+		}
+	}
+}
+
+// sendIPCStream sends a streaming command and calls handle for every frame
+// until the daemon signals the last one
+func sendIPCStream(cmd IPCCommand, handle func(*IPCResponse) error) error {
+	conn, err := openIPC(cmd.Type)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cmd.Stream = true
+	if err := conn.Send(cmd); err != nil {
+		return err
+	}
+
+	for {
+		var frame IPCResponse
+		if err := conn.Receive(&frame); err != nil {
+			return err
+		}
+		if !frame.Success {
+			return responseError(&frame)
+		}
+		if err := handle(&frame); err != nil {
+			return err
+		}
+		if !frame.More {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package supervisor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// Test pagination windows
+func TestPaginate(t *testing.T) {
+	items := make([]ServiceInfo, 5)
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		want          int
+	}{
+		{"All", 0, 0, 5},
+		{"Limit", 0, 2, 2},
+		{"Offset", 3, 0, 2},
+		{"Offset and limit", 1, 3, 3},
+		{"Offset past end", 10, 0, 0},
+		{"Negative offset", -1, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(paginate(items, tt.offset, tt.limit)); got != tt.want {
+				t.Errorf("paginate() returned %d items, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test streamed listings are split into chunks flagged with More
+func TestStreamListServices(t *testing.T) {
+	servicesMutex.Lock()
+	saved := activeServices
+	activeServices = make(map[string]*ServiceProcess)
+	for i := 0; i < listChunkSize+20; i++ {
+		name := fmt.Sprintf("svc-%03d", i)
+		activeServices[name] = &ServiceProcess{Name: name, State: ServiceStateRunning}
+	}
+	servicesMutex.Unlock()
+	defer func() {
+		servicesMutex.Lock()
+		activeServices = saved
+		servicesMutex.Unlock()
+	}()
+
+	tests := []struct {
+		name   string
+		cmd    IPCCommand
+		frames []int
+	}{
+		{"Everything", IPCCommand{}, []int{listChunkSize, 20}},
+		{"Page", IPCCommand{Offset: 10, Limit: 5}, []int{5}},
+		{"Past end", IPCCommand{Offset: 500}, []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := streamListServices(json.NewEncoder(&buf), tt.cmd); err != nil {
+				t.Fatalf("streamListServices() error = %v", err)
+			}
+
+			decoder := json.NewDecoder(&buf)
+			for i, want := range tt.frames {
+				var frame IPCResponse
+				if err := decoder.Decode(&frame); err != nil {
+					t.Fatalf("frame %d: %v", i, err)
+				}
+				if len(frame.Services) != want {
+					t.Errorf("frame %d has %d services, want %d", i, len(frame.Services), want)
+				}
+				if frame.Total != listChunkSize+20 {
+					t.Errorf("frame %d Total = %d, want %d", i, frame.Total, listChunkSize+20)
+				}
+				if frame.More != (i < len(tt.frames)-1) {
+					t.Errorf("frame %d More = %v", i, frame.More)
+				}
+			}
+			if decoder.More() {
+				t.Error("unexpected extra frame")
+			}
+		})
+	}
+
+	// The first service of a page follows name order
+	var buf bytes.Buffer
+	_ = streamListServices(json.NewEncoder(&buf), IPCCommand{Offset: 10, Limit: 1})
+	var frame IPCResponse
+	_ = json.NewDecoder(&buf).Decode(&frame)
+	if len(frame.Services) != 1 || frame.Services[0].Name != "svc-010" {
+		t.Errorf("page = %+v, want svc-010", frame.Services)
+	}
+}
+
+// Test defined services without a process are listed as stopped
+func TestSnapshotServicesIncludesStopped(t *testing.T) {
+	servicesMutex.Lock()
+	saved := activeServices
+	activeServices = map[string]*ServiceProcess{
+		"web": {Name: "web", State: ServiceStateRunning},
+	}
+	servicesMutex.Unlock()
+	setConfig(&Config{Services: []Service{
+		{Name: "web"},
+		{Name: "worker", Required: true, Tags: []string{"batch"}},
+	}})
+	savedLogs := serviceLogs
+	serviceLogs = newLogRecorder(10)
+	defer func() {
+		servicesMutex.Lock()
+		activeServices = saved
+		servicesMutex.Unlock()
+		setConfig(nil)
+		serviceLogs = savedLogs
+	}()
+	for _, line := range []string{"one", "two", "three", "four"} {
+		serviceLogs.record("worker", line)
+	}
+
+	services := snapshotServices()
+	if len(services) != 2 {
+		t.Fatalf("snapshotServices() returned %d services, want 2", len(services))
+	}
+	if services[0].Name != "web" || services[0].State != ServiceStateRunning {
+		t.Errorf("services[0] = %+v, want running web", services[0])
+	}
+	worker := services[1]
+	if worker.Name != "worker" || worker.State != ServiceStateStopped || worker.PID != 0 {
+		t.Errorf("services[1] = %+v, want stopped worker", worker)
+	}
+	if !worker.Required || len(worker.Tags) != 1 {
+		t.Errorf("stopped service lost its definition: %+v", worker)
+	}
+	if len(worker.RecentOutput) != recentOutputLines || worker.RecentOutput[recentOutputLines-1] != "four" {
+		t.Errorf("RecentOutput = %v, want the last %d lines", worker.RecentOutput, recentOutputLines)
+	}
+
+	if msg := handleGetStatus().Message; msg != "Total: 2, Running: 1, Failed: 0, Stopped: 1" {
+		t.Errorf("status = %q", msg)
+	}
+}
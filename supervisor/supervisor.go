@@ -0,0 +1,164 @@
+// Package supervisor runs services like s6-overlay. It is the go-overlay
+// daemon and command line (see Main), and runs inside other Go programs with
+// a Supervisor:
+//
+//	func main() {
+//		supervisor.RunHelper()
+//
+//		s := supervisor.New("/etc/app/services.toml")
+//		if err := s.Start(); err != nil {
+//			log.Fatal(err)
+//		}
+//		...
+//		os.Exit(s.Stop())
+//	}
+//
+// The state of the services is kept in the package, as in the daemon, so a
+// program runs one Supervisor at a time: Start fails while another one runs.
+package supervisor
+
+import (
+	"errors"
+	"sync"
+)
+
+// Supervisor runs the services of a config file in the calling program. It
+// serves the control socket, so the go-overlay CLI controls its services,
+// and the HTTP API when the config enables it. Signals are left to the
+// program: it calls Stop when it wants the services down.
+type Supervisor struct {
+	configFile string
+
+	mu      sync.Mutex
+	config  *Config
+	started bool
+	done    chan struct{} // Closed once the services stopped
+}
+
+// runningSupervisor is the Supervisor running the services of the program,
+// from Start until its services stopped
+var (
+	runningSupervisorMu sync.Mutex
+	runningSupervisor   *Supervisor
+)
+
+// claimServices makes s the Supervisor running the services of the program,
+// unless another one already is
+func claimServices(s *Supervisor) error {
+	runningSupervisorMu.Lock()
+	defer runningSupervisorMu.Unlock()
+	if runningSupervisor != nil {
+		return errors.New("another supervisor is running: a program runs one at a time")
+	}
+	runningSupervisor = s
+	return nil
+}
+
+// releaseServices lets another Supervisor run the services of the program
+func releaseServices() {
+	runningSupervisorMu.Lock()
+	runningSupervisor = nil
+	runningSupervisorMu.Unlock()
+}
+
+// New returns a supervisor of the services of configFile
+func New(configFile string) *Supervisor {
+	return &Supervisor{configFile: configFile, done: make(chan struct{})}
+}
+
+// Load reads and validates the config file. Start loads it unless Load
+// already did.
+func (s *Supervisor) Load() error {
+	config, err := loadAndValidateConfig(s.configFile)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = &config
+	return nil
+}
+
+// Start runs the init scripts and starts the services in the background,
+// stage by stage. A Supervisor starts once; another one starts after it
+// stopped.
+func (s *Supervisor) Start() error {
+	s.mu.Lock()
+	loaded, started := s.config != nil, s.started
+	s.mu.Unlock()
+	if started {
+		return errors.New("supervisor already started")
+	}
+	if !loaded {
+		if err := s.Load(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("supervisor already started")
+	}
+	if err := claimServices(s); err != nil {
+		return err
+	}
+	resetShutdown()
+	if err := prepareServices(s.config); err != nil {
+		releaseServices()
+		return err
+	}
+	s.started = true
+
+	config := *s.config
+	go func() {
+		_ = startAllServices(config)
+		// Restarts and services started by hand are supervised outside the stages
+		supervisions.Wait()
+		releaseServices()
+		close(s.done)
+	}()
+	return nil
+}
+
+// Stop stops the services as the daemon does on SIGTERM, in shutdown order,
+// and returns the exit code the daemon would exit with
+func (s *Supervisor) Stop() int {
+	gracefulShutdown()
+	return s.Wait()
+}
+
+// Wait waits until the supervisor shut down, on Stop or when a service it
+// depends on failed, and returns the exit code the daemon would exit with
+func (s *Supervisor) Wait() int {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		<-s.done
+	}
+	return supervisorExitCode()
+}
+
+// Restart stops a service and starts it again, and returns once it started
+func (s *Supervisor) Restart(name string) error {
+	if _, ok := findServiceConfig(name); !ok {
+		if _, running := getActiveService(name); !running {
+			return errServiceNotFound(name)
+		}
+	}
+	return runRestart(newOperation("restart", name), name)
+}
+
+// Services returns the state of every running service, by name
+func (s *Supervisor) Services() []ServiceInfo {
+	return snapshotServices()
+}
+
+// Subscribe returns the lifecycle events of the services from now on, and
+// the function ending the subscription. Like followers of `go-overlay
+// events`, a subscriber that doesn't keep up misses events.
+func (s *Supervisor) Subscribe() (<-chan Event, func()) {
+	return events.subscribe()
+}
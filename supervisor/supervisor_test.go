@@ -0,0 +1,99 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test a program embedding a Supervisor runs, restarts, watches and stops its
+// services
+func TestSupervisor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "services.toml")
+	config := `[control]
+listen = "` + filepath.Join(dir, "control.sock") + `"
+
+[timeouts]
+service_shutdown = 2
+
+[[services]]
+name = "sleeper"
+command = "/bin/sleep"
+args = ["30"]
+`
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	previous := controlAddress
+	t.Cleanup(func() {
+		controlAddress = previous
+		setConfig(nil)
+	})
+
+	s := New(configFile)
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := s.Start(); err == nil {
+		t.Error("a second Start() succeeded")
+	}
+	if err := New(configFile).Start(); err == nil {
+		t.Error("another Supervisor started while one runs")
+	}
+	if !waitFor(t, 10*time.Second, func() bool {
+		_, running := getActiveService("sleeper")
+		return running
+	}) {
+		t.Fatal("sleeper did not start")
+	}
+	first, _ := getActiveService("sleeper")
+	firstPID := first.GetPID()
+
+	if services := s.Services(); len(services) != 1 || services[0].Name != "sleeper" {
+		t.Errorf("Services() = %+v", services)
+	}
+
+	events, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+	if err := s.Restart("sleeper"); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	if current, ok := getActiveService("sleeper"); !ok || current.GetPID() == firstPID {
+		t.Error("Restart() did not run a new process")
+	}
+	select {
+	case event := <-events:
+		if event.Service != "sleeper" {
+			t.Errorf("Subscribe() got an event of %s", event.Service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Subscribe() got no event of the restart")
+	}
+	if err := s.Restart("missing"); errorCode(err) != ErrCodeServiceNotFound {
+		t.Errorf("Restart(missing) error = %v, want %s", err, ErrCodeServiceNotFound)
+	}
+
+	if code := s.Stop(); code != 0 {
+		t.Errorf("Stop() = %d, want 0", code)
+	}
+	if _, running := getActiveService("sleeper"); running {
+		t.Error("sleeper still running after Stop()")
+	}
+
+	// The services of the program are free for another Supervisor
+	next := New(configFile)
+	if err := next.Start(); err != nil {
+		t.Fatalf("Start() after Stop() error = %v", err)
+	}
+	if code := next.Stop(); code != 0 {
+		t.Errorf("Stop() = %d, want 0", code)
+	}
+}
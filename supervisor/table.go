@@ -0,0 +1,72 @@
+package supervisor
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ansiPattern matches ANSI SGR escape sequences
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// stripANSI removes color escape codes from s
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// visibleWidth returns the number of terminal cells s occupies, ignoring escape codes
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(stripANSI(s))
+}
+
+// padRight pads s with spaces to width visible cells
+func padRight(s string, width int) string {
+	if pad := width - visibleWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+// renderTable lays out rows under bold headers, computing column widths from
+// the visible content of every cell so colored cells stay aligned.
+func renderTable(headers []string, rows [][]string) string {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = visibleWidth(header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) && visibleWidth(cell) > widths[i] {
+				widths[i] = visibleWidth(cell)
+			}
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			if i == len(cells)-1 {
+				b.WriteString(cell)
+				break
+			}
+			b.WriteString(padRight(cell, widths[i]))
+			b.WriteString("  ")
+		}
+		b.WriteString("\n")
+	}
+
+	boldHeaders := make([]string, len(headers))
+	total := 0
+	for i, header := range headers {
+		boldHeaders[i] = colorize(ColorBoldWhite, header)
+		total += widths[i] + 2
+	}
+	writeRow(boldHeaders)
+	b.WriteString(colorize(ColorGray, strings.Repeat("─", total-2)))
+	b.WriteString("\n")
+
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test column widths ignore ANSI escape codes
+func TestRenderTableAlignsColoredCells(t *testing.T) {
+	rows := [][]string{
+		{colorize(ColorCyan, "nginx"), colorize(ColorGreen, "RUNNING"), "1"},
+		{"worker-long", colorize(ColorRed, "FAILED"), "2"},
+	}
+
+	lines := strings.Split(strings.TrimRight(stripANSI(renderTable([]string{"NAME", "STATE", "PID"}, rows)), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("renderTable() produced %d lines, want 4", len(lines))
+	}
+
+	column := strings.Index(lines[0], "STATE")
+	for _, line := range []string{lines[2], lines[3]} {
+		if idx := strings.IndexAny(line, "RF"); idx != column {
+			t.Errorf("STATE cell at column %d, want %d in %q", idx, column, line)
+		}
+	}
+}
+
+// Test visible width of colored text
+func TestVisibleWidth(t *testing.T) {
+	if got := visibleWidth(colorize(ColorRed, "FAILED")); got != 6 {
+		t.Errorf("visibleWidth() = %d, want 6", got)
+	}
+	if got := visibleWidth("─→"); got != 2 {
+		t.Errorf("visibleWidth() = %d, want 2", got)
+	}
+}
+
+// Test sorting services by name, state, uptime, usage and restarts
+func TestSortServices(t *testing.T) {
+	services := func() []ServiceInfo {
+		low, high := 2.5, 40.0
+		return []ServiceInfo{
+			{Name: "web", State: ServiceStateRunning, Uptime: time.Minute, CPUPercent: &high, RSS: 10 << 20, TotalRestarts: 2},
+			{Name: "api", State: ServiceStateFailed, TotalRestarts: 5},
+			{Name: "db", State: ServiceStateRunning, Uptime: time.Hour, CPUPercent: &low, RSS: 200 << 20},
+		}
+	}
+	names := func(list []ServiceInfo) string {
+		out := make([]string, len(list))
+		for i, s := range list {
+			out[i] = s.Name
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{SortByName, "api,db,web", false},
+		{SortByState, "db,web,api", false},
+		{SortByUptime, "db,web,api", false},
+		{SortByCPU, "web,db,api", false},
+		{SortByMemory, "db,web,api", false},
+		{SortByRestarts, "api,web,db", false},
+		{"pid", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			list := services()
+			err := sortServices(list, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortServices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && names(list) != tt.want {
+				t.Errorf("sortServices() = %s, want %s", names(list), tt.want)
+			}
+		})
+	}
+}
+
+// Test key=value filters
+func TestFilterServices(t *testing.T) {
+	services := []ServiceInfo{
+		{Name: "worker-1", State: ServiceStateFailed},
+		{Name: "worker-2", State: ServiceStateRunning, Required: true, Health: HealthUnhealthy},
+		{Name: "web", State: ServiceStateFailed, Required: true},
+	}
+
+	tests := []struct {
+		name    string
+		filters []string
+		want    int
+		wantErr bool
+	}{
+		{"No filters", nil, 3, false},
+		{"State", []string{"state=failed"}, 2, false},
+		{"Name glob", []string{"name=worker-*"}, 2, false},
+		{"Combined", []string{"state=FAILED", "required=true"}, 1, false},
+		{"Health", []string{"health=unhealthy"}, 1, false},
+		{"Missing value", []string{"state"}, 0, true},
+		{"Unknown key", []string{"pid=1"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filterServices(services, tt.filters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("filterServices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("filterServices() returned %d services, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package supervisor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/template"
+)
+
+// defaultTemplateMode are the permissions of rendered files without a mode
+const defaultTemplateMode = 0o644
+
+// Template is a Go text/template rendered to a file before every start of a
+// service, so configs can use its environment without an envsubst pre_script
+type Template struct {
+	Source string `toml:"source"`
+	Target string `toml:"target"`
+	Mode   string `toml:"mode,omitempty"` // Octal permissions of the target, e.g. "0640"
+}
+
+// templateData is what templates see as "."
+type templateData struct {
+	Name     string            // Service name
+	Instance string            // Instance identifier, as in GO_OVERLAY_INSTANCE
+	Env      map[string]string // Environment of the service process
+	Labels   map[string]string
+	Tags     []string
+}
+
+// templateFuncs are the functions available to templates. Unlike .Env, env
+// returns an empty string for unset variables, e.g. for {{ env "PORT" | default "80" }}.
+func templateFuncs(env map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"env": func(key string) string { return env[key] },
+		"default": func(fallback, value string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		},
+		"required": func(key string) (string, error) {
+			if env[key] == "" {
+				return "", fmt.Errorf("variable %s is required", key)
+			}
+			return env[key], nil
+		},
+		"split": strings.Split,
+		"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+	}
+}
+
+// parseTemplate parses a template file; a missing key of .Env or .Labels
+// fails the rendering instead of writing "<no value>"
+func parseTemplate(path string, env map[string]string) (*template.Template, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).
+		Option("missingkey=error").
+		Funcs(templateFuncs(env)).
+		Parse(string(source))
+}
+
+// parseFileMode parses octal permissions such as "0640", or returns fallback
+// when mode is empty
+func parseFileMode(mode string, fallback os.FileMode) (os.FileMode, error) {
+	if mode == "" {
+		return fallback, nil
+	}
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > 0o7777 {
+		return 0, fmt.Errorf("invalid mode '%s' (use octal permissions like \"0640\")", mode)
+	}
+	return os.FileMode(perm), nil
+}
+
+// renderTemplates renders the templates of a service with the environment
+// its process gets. Targets are owned by the user of the service, if any.
+func renderTemplates(service *Service, env []string, cred *syscall.Credential) error {
+	data := templateData{
+		Name:     service.Name,
+		Instance: serviceInstance(service),
+		Env:      make(map[string]string, len(env)),
+		Labels:   service.Labels,
+		Tags:     service.Tags,
+	}
+	for _, entry := range env {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			data.Env[key] = value
+		}
+	}
+
+	for _, t := range service.Templates {
+		if err := renderTemplate(t, data, cred); err != nil {
+			return fmt.Errorf("template %s: %w", t.Source, err)
+		}
+	}
+	return nil
+}
+
+// renderTemplate renders one template and replaces its target
+func renderTemplate(t Template, data templateData, cred *syscall.Credential) error {
+	mode, err := parseFileMode(t.Mode, defaultTemplateMode)
+	if err != nil {
+		return err
+	}
+	tmpl, err := parseTemplate(t.Source, data.Env)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return err
+	}
+	return writeServiceFile(t.Target, out.Bytes(), mode, cred)
+}
+
+// writeServiceFile replaces path atomically with a file owned by the user of
+// a service (cred, nil to keep the supervisor's), so readers never see it
+// partially written and a failure keeps the previous file
+func writeServiceFile(path string, data []byte, mode os.FileMode, cred *syscall.Credential) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // No-op once renamed
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Chmod(mode); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if cred != nil {
+		if err := file.Chown(int(cred.Uid), int(cred.Gid)); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func validateTemplates(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	targets := make(map[string]bool, len(service.Templates))
+	for i, t := range service.Templates {
+		field := fmt.Sprintf("templates[%d]", i)
+		if t.Source == "" || t.Target == "" {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Service: service.Name,
+				Message: "a template requires a source and a target",
+			})
+			continue
+		}
+		if !filepath.IsAbs(t.Target) {
+			errors = append(errors, ValidationError{
+				Field:   field + ".target",
+				Service: service.Name,
+				Message: fmt.Sprintf("target '%s' must be an absolute path", t.Target),
+			})
+		}
+		if targets[t.Target] {
+			errors = append(errors, ValidationError{
+				Field:   field + ".target",
+				Service: service.Name,
+				Message: fmt.Sprintf("target '%s' is rendered more than once", t.Target),
+			})
+		}
+		targets[t.Target] = true
+		if _, err := parseFileMode(t.Mode, defaultTemplateMode); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   field + ".mode",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		}
+
+		// Like scripts, sources may be provided by a volume or a pre_script
+		if !shouldValidateCommands(service) {
+			continue
+		}
+		if _, err := parseTemplate(rootPath(t.Source), nil); os.IsNotExist(err) {
+			errors = append(errors, ValidationError{
+				Field:   field + ".source",
+				Service: service.Name,
+				Message: fmt.Sprintf("template file '%s' does not exist", t.Source),
+			})
+		} else if err != nil {
+			errors = append(errors, ValidationError{
+				Field:   field + ".source",
+				Service: service.Name,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,87 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test templates see the service environment and metadata, get their mode
+// and keep the previous file when rendering fails
+func TestRenderTemplates(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "app.conf.tmpl")
+	target := filepath.Join(dir, "etc", "app.conf")
+	content := `name={{ .Name }} tier={{ .Labels.tier }}
+port={{ .Env.PORT }} host={{ env "HOST" | default "0.0.0.0" }}
+{{ range split "a,b" "," }}[{{ upper . }}]{{ end }}
+`
+	if err := os.WriteFile(source, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	service := &Service{
+		Name:      "api",
+		Labels:    map[string]string{"tier": "web"},
+		Templates: []Template{{Source: source, Target: target, Mode: "0640"}},
+	}
+	if err := renderTemplates(service, []string{"PORT=8080"}, nil); err != nil {
+		t.Fatalf("renderTemplates() error = %v", err)
+	}
+	want := "name=api tier=web\nport=8080 host=0.0.0.0\n[A][B]\n"
+	if data, _ := os.ReadFile(target); string(data) != want {
+		t.Errorf("rendered %q, want %q", data, want)
+	}
+	if info, err := os.Stat(target); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("target mode = %v (%v), want 0640", info.Mode().Perm(), err)
+	}
+
+	// PORT is unset: .Env.PORT fails and the previous file is kept
+	err := renderTemplates(service, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "PORT") {
+		t.Errorf("renderTemplates() error = %v, want a missing PORT", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != want {
+		t.Errorf("failed rendering replaced the target with %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(target)); len(entries) != 1 {
+		t.Errorf("temporary files left next to the target: %v", entries)
+	}
+}
+
+// Test template validation
+func TestValidateTemplates(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.tmpl")
+	broken := filepath.Join(dir, "broken.tmpl")
+	_ = os.WriteFile(valid, []byte(`{{ env "PORT" }}`), 0o644)
+	_ = os.WriteFile(broken, []byte(`{{ if }}`), 0o644)
+	off := false
+
+	tests := []struct {
+		name    string
+		service Service
+		wantErr bool
+	}{
+		{"Valid", Service{Name: "web", Templates: []Template{{Source: valid, Target: "/etc/web.conf", Mode: "600"}}}, false},
+		{"Missing target", Service{Name: "web", Templates: []Template{{Source: valid}}}, true},
+		{"Relative target", Service{Name: "web", Templates: []Template{{Source: valid, Target: "web.conf"}}}, true},
+		{"Same target", Service{Name: "web", Templates: []Template{
+			{Source: valid, Target: "/etc/web.conf"}, {Source: valid, Target: "/etc/web.conf"}}}, true},
+		{"Invalid mode", Service{Name: "web", Templates: []Template{{Source: valid, Target: "/etc/web.conf", Mode: "rw"}}}, true},
+		{"Missing source", Service{Name: "web", Templates: []Template{{Source: filepath.Join(dir, "none"), Target: "/etc/web.conf"}}}, true},
+		{"Unchecked source", Service{Name: "web", ValidateCommands: &off,
+			Templates: []Template{{Source: filepath.Join(dir, "none"), Target: "/etc/web.conf"}}}, false},
+		{"Syntax error", Service{Name: "web", Templates: []Template{{Source: broken, Target: "/etc/web.conf"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateTemplates(&tt.service)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateTemplates() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// sleepContext waits for d and reports whether it elapsed before ctx was done
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// delayStart waits out the start_delay of a service, returning false if the
+// supervisor shuts down meanwhile
+func delayStart(service *Service) bool {
+	if service.StartDelay <= 0 {
+		return true
+	}
+	logger.Info(fmt.Sprintf("Service '%s' starts in %ds (start_delay)",
+		colorize(ColorCyan, service.Name), service.StartDelay))
+	return sleepContext(shutdownContext(), time.Duration(service.StartDelay)*time.Second)
+}
+
+// schedulePeriodicRestart restarts a service once its process has been up
+// for restart_every, like `go-overlay restart`. ctx is the context of the run:
+// a stop, an exit or the shutdown cancel the timer, and the next run
+// schedules its own.
+func schedulePeriodicRestart(ctx context.Context, sp *ServiceProcess) {
+	every := time.Duration(sp.Config.RestartEvery) * time.Second
+	// An adopted process keeps its start time across an upgrade
+	if !sleepContext(ctx, every-time.Since(sp.StartTime)) {
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Service '%s' has been up for %s, restarting (restart_every)",
+		colorize(ColorCyan, sp.Name), every))
+	if _, err := requestRestart(sp.Name); err != nil {
+		logger.Error(fmt.Sprintf("Periodic restart of service '%s' failed: %v", colorize(ColorCyan, sp.Name), err))
+	}
+}
+
+func validateTimers(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	if service.StartDelay < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "start_delay",
+			Service: service.Name,
+			Message: fmt.Sprintf("start_delay %d is negative", service.StartDelay),
+		})
+	}
+	if service.RestartEvery < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "restart_every",
+			Service: service.Name,
+			Message: fmt.Sprintf("restart_every %d is negative", service.RestartEvery),
+		})
+	}
+	if service.RestartEvery > 0 && service.LogFile != "" {
+		errors = append(errors, ValidationError{
+			Field:   "restart_every",
+			Service: service.Name,
+			Message: "restart_every has no process to restart with log_file",
+		})
+	}
+
+	return errors
+}
@@ -0,0 +1,73 @@
+package supervisor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Test sleepContext returns early once its context is done
+func TestSleepContext(t *testing.T) {
+	if !sleepContext(context.Background(), time.Millisecond) {
+		t.Error("sleepContext() = false after the delay elapsed")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if sleepContext(ctx, time.Minute) || time.Since(start) > time.Second {
+		t.Error("sleepContext() did not return early for a done context")
+	}
+}
+
+// Test restart_every restarts the service on every interval and stopping it
+// cancels the timer
+func TestPeriodicRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	setupServiceConfig(t, Service{Name: "leaky", Command: "/bin/sleep", Args: []string{"30"}, RestartEvery: 1})
+
+	if err := startService("leaky"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	first, _ := getActiveService("leaky")
+	restarted := func() bool {
+		sp, ok := getActiveService("leaky")
+		return ok && sp != first && sp.GetPID() > 0
+	}
+	if !waitFor(t, 5*time.Second, restarted) {
+		t.Fatal("service not restarted after restart_every")
+	}
+
+	if err := stopService("leaky"); err != nil {
+		t.Fatalf("stopService() error = %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if _, running := getActiveService("leaky"); running {
+		t.Error("stopped service restarted by its timer")
+	}
+}
+
+// Test timer validation
+func TestValidateTimers(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		wantErr bool
+	}{
+		{"Not set", Service{Name: "web"}, false},
+		{"Valid", Service{Name: "web", StartDelay: 10, RestartEvery: 86400}, false},
+		{"Negative delay", Service{Name: "web", StartDelay: -1}, true},
+		{"Negative interval", Service{Name: "web", RestartEvery: -1}, true},
+		{"With log_file", Service{Name: "web", LogFile: "/var/log/web.log", RestartEvery: 60}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateTimers(&tt.service)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("validateTimers() = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// envUpgradeState points the re-exec'd supervisor at the serialized state
+const envUpgradeState = "GO_OVERLAY_UPGRADE_STATE"
+
+// upgradeState is handed from the old supervisor process to the new one
+type upgradeState struct {
+	Version     string                `json:"version"`
+	Services    []upgradeServiceState `json:"services"`
+	ListenerFD  int                   `json:"listener_fd"`
+	StatusDir   string                `json:"status_dir,omitempty"`
+	GeneratedAt time.Time             `json:"generated_at"`
+}
+
+// upgradeServiceState describes a running service whose process survives the upgrade
+type upgradeServiceState struct {
+	StartTime time.Time `json:"start_time"`
+	Name      string    `json:"name"`
+	PID       int       `json:"pid"`
+	PTYFD     int       `json:"pty_fd"`
+	StdoutFD  int       `json:"stdout_fd,omitempty"` // Output pipes of services run without a PTY
+	StderrFD  int       `json:"stderr_fd,omitempty"`
+	ReadyFD   int       `json:"ready_fd,omitempty"` // Notification fd of a service not ready yet
+	Ready     bool      `json:"ready,omitempty"`
+}
+
+// inheritedState is the state received from a previous supervisor, if any
+var inheritedState *upgradeState
+
+// clearCloseOnExec makes fd survive execve
+func clearCloseOnExec(fd uintptr) error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_FCNTL, fd, syscall.F_SETFD, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// snapshotUpgradeState collects running services and marks their PTYs (and the
+// IPC listener) inheritable so the next supervisor image can adopt them.
+func snapshotUpgradeState() (*upgradeState, error) {
+	state := &upgradeState{Version: version, ListenerFD: -1, StatusDir: statusDir, GeneratedAt: time.Now()}
+
+	// Unix and TCP listeners both hand over their file descriptor
+	if listener, ok := currentIPCServer().(interface{ File() (*os.File, error) }); ok {
+		file, err := listener.File()
+		if err != nil {
+			return nil, fmt.Errorf("could not duplicate IPC listener: %w", err)
+		}
+		if err := clearCloseOnExec(file.Fd()); err != nil {
+			return nil, fmt.Errorf("could not pass IPC listener: %w", err)
+		}
+		state.ListenerFD = int(file.Fd())
+	}
+
+	servicesMutex.RLock()
+	defer servicesMutex.RUnlock()
+
+	for name, serviceProc := range activeServices {
+		pid := serviceProc.GetPID()
+		if pid == 0 || serviceProc.GetState() != ServiceStateRunning {
+			continue
+		}
+
+		entry := upgradeServiceState{Name: name, PID: pid, PTYFD: -1, StartTime: serviceProc.StartTime}
+		if serviceProc.PTY != nil {
+			fd := serviceProc.PTY.Fd()
+			if err := clearCloseOnExec(fd); err != nil {
+				return nil, fmt.Errorf("could not pass PTY of service %s: %w", name, err)
+			}
+			entry.PTYFD = int(fd)
+		}
+		if serviceProc.Stdout != nil && serviceProc.Stderr != nil {
+			for _, pipe := range []*os.File{serviceProc.Stdout, serviceProc.Stderr} {
+				if err := clearCloseOnExec(pipe.Fd()); err != nil {
+					return nil, fmt.Errorf("could not pass output of service %s: %w", name, err)
+				}
+			}
+			entry.StdoutFD, entry.StderrFD = int(serviceProc.Stdout.Fd()), int(serviceProc.Stderr.Fd())
+		}
+		entry.Ready = serviceProc.IsReady()
+		if serviceProc.readyPipe != nil && !entry.Ready {
+			fd := serviceProc.readyPipe.Fd()
+			if err := clearCloseOnExec(fd); err != nil {
+				return nil, fmt.Errorf("could not pass notification fd of service %s: %w", name, err)
+			}
+			entry.ReadyFD = int(fd)
+		}
+		state.Services = append(state.Services, entry)
+	}
+	return state, nil
+}
+
+// performUpgrade re-execs binary in place of the running supervisor. Services
+// keep running: the process ID is unchanged, so they remain our children.
+func performUpgrade(binary string) error {
+	if binary == "" {
+		self, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("could not determine executable path: %w", err)
+		}
+		binary = self
+	}
+
+	info, err := os.Stat(binary)
+	if err != nil {
+		return fmt.Errorf("new binary not usable: %w", err)
+	}
+	if info.IsDir() || info.Mode()&0o111 == 0 {
+		return fmt.Errorf("new binary %s is not executable", binary)
+	}
+
+	state, err := snapshotUpgradeState()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// A random name keeps other users from planting the file, or a symlink, in advance
+	stateFile, err := os.CreateTemp("", "go-overlay-upgrade-*.json")
+	if err != nil {
+		return fmt.Errorf("could not create upgrade state: %w", err)
+	}
+	statePath := stateFile.Name()
+	_, err = stateFile.Write(payload)
+	if closeErr := stateFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(statePath)
+		return fmt.Errorf("could not write upgrade state: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Upgrading supervisor to %s, handing over %d running service(s)",
+		colorize(ColorCyan, binary), len(state.Services)))
+
+	env := mergeEnv(os.Environ(), map[string]string{envUpgradeState: statePath})
+	err = syscall.Exec(binary, os.Args, env) // #nosec G204 - re-exec of the supervisor binary
+	_ = os.Remove(statePath)
+	return fmt.Errorf("re-exec failed: %w", err)
+}
+
+// loadUpgradeState reads the state left by a previous supervisor, if this process is an upgrade
+func loadUpgradeState() {
+	statePath := os.Getenv(envUpgradeState)
+	if statePath == "" {
+		return
+	}
+	_ = os.Unsetenv(envUpgradeState)
+	defer os.Remove(statePath)
+
+	payload, err := os.ReadFile(statePath)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Could not read upgrade state: %v", err))
+		return
+	}
+
+	var state upgradeState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		logger.Error(fmt.Sprintf("Could not decode upgrade state: %v", err))
+		return
+	}
+
+	inheritedState = &state
+	logger.Success(fmt.Sprintf("Upgraded from %s to %s, adopting %d service(s)",
+		state.Version, version, len(state.Services)))
+}
+
+// inheritedListener returns the IPC listener passed by a previous supervisor
+func inheritedListener() net.Listener {
+	if inheritedState == nil || inheritedState.ListenerFD < 0 {
+		return nil
+	}
+
+	file := os.NewFile(uintptr(inheritedState.ListenerFD), "ipc-listener")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Could not reuse inherited IPC listener: %v", err))
+		return nil
+	}
+	return listener
+}
+
+// adoptInheritedServices registers services handed over by a previous supervisor
+func adoptInheritedServices(config Config) map[string]bool {
+	adopted := make(map[string]bool)
+	if inheritedState == nil {
+		return adopted
+	}
+
+	maxLength := getLongestServiceNameLength(config.Services)
+	for _, entry := range inheritedState.Services {
+		service, ok := findServiceConfig(entry.Name)
+		if !ok {
+			// The service was removed from the configuration: keep it supervised
+			// under its old name so it can still be stopped cleanly.
+			service = Service{Name: entry.Name}
+		}
+
+		serviceProcess, err := adoptService(service, entry, maxLength)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Could not adopt service '%s' (PID %d): %v",
+				colorize(ColorCyan, entry.Name), entry.PID, err))
+			continue
+		}
+		adopted[entry.Name] = true
+
+		supervisions.Add(1)
+		go func(s Service) {
+			defer supervisions.Done()
+			if err := superviseService(serviceProcess, config.Timeouts); err != nil {
+				handleServiceError(&s, err)
+			}
+		}(service)
+	}
+	return adopted
+}
+
+// adoptService wraps an already running child process in a ServiceProcess
+func adoptService(service Service, entry upgradeServiceState, maxLength int) (*ServiceProcess, error) {
+	process, err := os.FindProcess(entry.PID)
+	if err != nil {
+		return nil, err
+	}
+	if err := process.Signal(syscall.Signal(0)); err != nil {
+		return nil, fmt.Errorf("process is gone: %w", err)
+	}
+
+	var ptmx *os.File
+	if entry.PTYFD >= 0 {
+		ptmx = os.NewFile(uintptr(entry.PTYFD), "pty-"+entry.Name)
+		syscall.CloseOnExec(entry.PTYFD)
+	}
+	var stdout, stderr *os.File
+	if entry.StdoutFD > 0 && entry.StderrFD > 0 {
+		stdout = os.NewFile(uintptr(entry.StdoutFD), "stdout-"+entry.Name)
+		stderr = os.NewFile(uintptr(entry.StderrFD), "stderr-"+entry.Name)
+		syscall.CloseOnExec(entry.StdoutFD)
+		syscall.CloseOnExec(entry.StderrFD)
+	}
+	var readyPipe *os.File
+	if entry.ReadyFD > 0 {
+		readyPipe = os.NewFile(uintptr(entry.ReadyFD), "ready-"+entry.Name)
+		syscall.CloseOnExec(entry.ReadyFD)
+	}
+
+	serviceCtx, serviceCancel := context.WithCancel(shutdownContext())
+	serviceProcess := &ServiceProcess{
+		Name:    service.Name,
+		Process: &exec.Cmd{Process: process},
+		PTY:     ptmx,
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Cancel:  serviceCancel,
+		State:   ServiceStatePending,
+		Config:  service,
+		Ready:   entry.Ready,
+		Exited:  make(chan struct{}),
+		ctx:     serviceCtx,
+		waitErr: make(chan error, 1),
+
+		watchdogPing: make(chan struct{}, 1),
+		readyPipe:    readyPipe,
+	}
+	addActiveService(service.Name, serviceProcess)
+	serviceProcess.StartTime = entry.StartTime
+	serviceProcess.SetState(ServiceStateRunning)
+
+	if service.Register != nil {
+		go runServiceRegistration(serviceCtx, serviceProcess)
+	}
+	if service.HealthCheck != nil {
+		go monitorHealth(serviceCtx, serviceProcess)
+	}
+	if service.Readiness != nil {
+		go monitorReadiness(serviceCtx, serviceProcess)
+	}
+	if service.Watchdog != nil {
+		go monitorWatchdog(serviceCtx, serviceProcess)
+	}
+	if service.RestartEvery > 0 {
+		go schedulePeriodicRestart(serviceCtx, serviceProcess)
+	}
+	if service.Readiness != nil && service.Readiness.Notify || service.Watchdog != nil && service.Watchdog.Notify {
+		// The child keeps its NOTIFY_SOCKET; listen on the same path again
+		uid, gid := -1, -1
+		if cred, _, err := serviceCredential(&service); err == nil && cred != nil {
+			uid, gid = int(cred.Uid), int(cred.Gid)
+		}
+		if conn, err := listenNotifySocket(service.Name, uid, gid); err == nil {
+			go receiveNotify(serviceCtx, serviceProcess, conn)
+		} else {
+			logger.Warn(fmt.Sprintf("Could not reopen notify socket of service '%s': %v", colorize(ColorCyan, service.Name), err))
+		}
+	}
+	if readyPipe != nil {
+		go receiveNotificationFD(serviceCtx, serviceProcess, readyPipe)
+	}
+	if ptmx != nil {
+		serviceProcess.outputDone = make(chan struct{})
+		go func() {
+			prefixLogs(ptmx, newServiceOutput(&service, maxLength))
+			close(serviceProcess.outputDone)
+		}()
+	} else if stdout != nil {
+		serviceProcess.outputDone = make(chan struct{})
+		go func() {
+			pipeLogs(stdout, stderr, newServiceOutput(&service, maxLength))
+			close(serviceProcess.outputDone)
+		}()
+	}
+
+	go func() {
+		serviceProcess.waitErr <- waitAdopted(process)
+	}()
+
+	logger.Success(fmt.Sprintf("Adopted service '%s' (PID: %d)", colorize(ColorCyan, service.Name), entry.PID))
+	return serviceProcess, nil
+}
+
+// waitAdopted waits for an adopted process, converting a failure status into
+// an error like exec.Cmd.Wait returns, so exitStatus reads its exit code
+func waitAdopted(process *os.Process) error {
+	state, err := process.Wait()
+	if err != nil {
+		return err
+	}
+	if !state.Success() {
+		return &exec.ExitError{ProcessState: state}
+	}
+	return nil
+}
+
+func handleUpgrade(binary string) IPCResponse {
+	target := binary
+	if target == "" {
+		target = "current executable"
+	}
+
+	go func() {
+		// Give the IPC response time to reach the client before the exec
+		time.Sleep(200 * time.Millisecond)
+		if err := performUpgrade(binary); err != nil {
+			logger.Error(fmt.Sprintf("Supervisor upgrade failed: %v", err))
+		}
+	}()
+
+	return IPCResponse{
+		Success: true,
+		Message: fmt.Sprintf("Supervisor upgrade to %s initiated", target),
+	}
+}
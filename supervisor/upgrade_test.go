@@ -0,0 +1,154 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Test the upgrade state is read back from the handoff file
+func TestLoadUpgradeState(t *testing.T) {
+	defer func() { inheritedState = nil }()
+
+	state := upgradeState{
+		Version:    "v0.0.1",
+		ListenerFD: -1,
+		Services:   []upgradeServiceState{{Name: "web", PID: 42, PTYFD: 7, StartTime: time.Now()}},
+	}
+	payload, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(statePath, payload, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(envUpgradeState, statePath)
+	loadUpgradeState()
+
+	if inheritedState == nil || len(inheritedState.Services) != 1 || inheritedState.Services[0].PID != 42 {
+		t.Fatalf("inheritedState = %+v", inheritedState)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Error("upgrade state file should be removed once loaded")
+	}
+	if inheritedListener() != nil {
+		t.Error("no listener expected when ListenerFD is -1")
+	}
+}
+
+// Test a running child process can be adopted and stopped
+func TestAdoptService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	resetShutdown()
+	defer stopTestServices("adoptee")
+	config := &Config{Timeouts: Timeouts{ServiceShutdown: 2}}
+	useConfig(t, config)
+
+	child := exec.Command("/bin/sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatalf("starting child: %v", err)
+	}
+
+	started := time.Now().Add(-time.Minute)
+	serviceProcess, err := adoptService(Service{Name: "adoptee"}, upgradeServiceState{
+		Name: "adoptee", PID: child.Process.Pid, PTYFD: -1, StartTime: started,
+	}, 10)
+	if err != nil {
+		t.Fatalf("adoptService() error = %v", err)
+	}
+	supervisions.Add(1)
+	go func() {
+		defer supervisions.Done()
+		_ = superviseService(serviceProcess, config.Timeouts)
+	}()
+
+	if serviceProcess.GetPID() != child.Process.Pid || !serviceProcess.StartTime.Equal(started) {
+		t.Errorf("adopted process pid/start = %d/%v", serviceProcess.GetPID(), serviceProcess.StartTime)
+	}
+
+	if err := stopService("adoptee"); err != nil {
+		t.Fatalf("stopService() error = %v", err)
+	}
+	if _, running := getActiveService("adoptee"); running {
+		t.Error("adopted service still active after stop")
+	}
+}
+
+// Test the exit code of an adopted process is read back like that of a
+// spawned one
+func TestWaitAdoptedExitStatus(t *testing.T) {
+	process, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "exit 3"}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatalf("starting process: %v", err)
+	}
+	if code := exitStatus(waitAdopted(process)); code != 3 {
+		t.Errorf("exitStatus(waitAdopted()) = %d, want 3", code)
+	}
+}
+
+// Test an adopted service keeps its watchdog and its notification fd
+func TestAdoptServiceMonitors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+
+	resetShutdown()
+	setConfig(&Config{Timeouts: Timeouts{ServiceShutdown: 2}})
+	defer func() {
+		cancelShutdown()
+		setConfig(nil)
+	}()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := exec.Command("/bin/sh", "-c", "sleep 0.5; echo >&3; exec sleep 30")
+	child.ExtraFiles = []*os.File{w}
+	if err := child.Start(); err != nil {
+		t.Fatalf("starting child: %v", err)
+	}
+	_ = w.Close()
+	// The supervisor being replaced hands over its read end
+	readyFD, err := syscall.Dup(int(r.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Close()
+
+	service := Service{
+		Name:      "adopted-monitors",
+		Readiness: &ReadinessProbe{NotificationFD: 3},
+		Watchdog:  &Watchdog{Interval: 1, File: filepath.Join(t.TempDir(), "never-touched")},
+	}
+	serviceProcess, err := adoptService(service, upgradeServiceState{
+		Name: service.Name, PID: child.Process.Pid, PTYFD: -1, ReadyFD: readyFD, StartTime: time.Now(),
+	}, 10)
+	if err != nil {
+		t.Fatalf("adoptService() error = %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = superviseService(serviceProcess, Timeouts{ServiceShutdown: 2})
+		close(done)
+	}()
+
+	if !waitFor(t, 5*time.Second, serviceProcess.IsReady) {
+		t.Error("adopted service not ready after writing to its notification fd")
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		_ = stopService(service.Name)
+		t.Fatal("the watchdog of the adopted service did not abort it")
+	}
+}
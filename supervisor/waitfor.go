@@ -0,0 +1,93 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// waitForPollInterval is the delay between two attempts of a wait_for condition
+const waitForPollInterval = 1 * time.Second
+
+// lookupHost resolves a hostname; replaced in tests
+var lookupHost = net.DefaultResolver.LookupHost
+
+// WaitCondition is a precondition that must hold before a service starts
+type WaitCondition struct {
+	DNS     string `toml:"dns,omitempty"`
+	Timeout int    `toml:"timeout,omitempty"` // Seconds, defaults to dependency_wait_timeout
+}
+
+func (w WaitCondition) String() string {
+	return "dns " + w.DNS
+}
+
+// waitForConditions blocks until every wait_for condition of the service holds
+func waitForConditions(s *Service, timeouts Timeouts) error {
+	for _, cond := range s.WaitFor {
+		timeout := cond.Timeout
+		if timeout == 0 {
+			timeout = timeouts.DependencyWait
+		}
+
+		logger.Info(fmt.Sprintf("Service '%s' waiting for %s",
+			colorize(ColorCyan, s.Name), colorize(ColorYellow, cond.String())))
+
+		ctx, cancel := context.WithTimeout(shutdownContext(), time.Duration(timeout)*time.Second)
+		err := waitForDNS(ctx, cond.DNS)
+		cancel()
+		if err != nil {
+			if shutdownContext().Err() != nil {
+				return fmt.Errorf("shutdown requested while waiting for %s", cond)
+			}
+			return fmt.Errorf("timed out after %ds waiting for %s: %w", timeout, cond, err)
+		}
+
+		logger.Success(fmt.Sprintf("Condition %s satisfied for service '%s'",
+			colorize(ColorGreen, cond.String()), colorize(ColorCyan, s.Name)))
+	}
+	return nil
+}
+
+// waitForDNS polls until host resolves or ctx is done, returning the last lookup error
+func waitForDNS(ctx context.Context, host string) error {
+	for {
+		addrs, err := lookupHost(ctx, host)
+		if err == nil && len(addrs) > 0 {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("no addresses found for %s", host)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(waitForPollInterval):
+		}
+	}
+}
+
+func validateWaitFor(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	for i, cond := range service.WaitFor {
+		if cond.DNS == "" {
+			errors = append(errors, ValidationError{
+				Field:   "wait_for",
+				Service: service.Name,
+				Message: fmt.Sprintf("wait_for entry %d must declare a dns hostname", i),
+			})
+		}
+		if cond.Timeout < 0 {
+			errors = append(errors, ValidationError{
+				Field:   "wait_for",
+				Service: service.Name,
+				Message: fmt.Sprintf("wait_for entry %d timeout must be a positive number of seconds", i),
+			})
+		}
+	}
+
+	return errors
+}
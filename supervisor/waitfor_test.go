@@ -0,0 +1,69 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Test waitForDNS retries until the host resolves
+func TestWaitForDNSEventuallyResolves(t *testing.T) {
+	original := lookupHost
+	defer func() { lookupHost = original }()
+
+	var attempts int32
+	lookupHost = func(_ context.Context, _ string) ([]string, error) {
+		if atomic.AddInt32(&attempts, 1) < 2 {
+			return nil, errors.New("no such host")
+		}
+		return []string{"10.0.0.1"}, nil
+	}
+
+	if err := waitForDNS(context.Background(), "db.internal"); err != nil {
+		t.Fatalf("waitForDNS() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("lookup attempts = %d, want 2", got)
+	}
+}
+
+// Test waitForConditions fails once the timeout expires
+func TestWaitForConditionsTimeout(t *testing.T) {
+	original := lookupHost
+	defer func() { lookupHost = original }()
+	lookupHost = func(_ context.Context, _ string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+
+	resetShutdown()
+	defer cancelShutdown()
+
+	service := &Service{Name: "app", WaitFor: []WaitCondition{{DNS: "db.internal", Timeout: 1}}}
+	err := waitForConditions(service, Timeouts{DependencyWait: 300})
+	if err == nil || !strings.Contains(err.Error(), "no such host") {
+		t.Errorf("waitForConditions() error = %v, want timeout wrapping lookup error", err)
+	}
+}
+
+// Test wait_for is decoded from TOML and validated
+func TestParseConfigWaitFor(t *testing.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+[[services]]
+name = "app"
+command = "/bin/true"
+wait_for = [{ dns = "db.internal", timeout = 120 }, { timeout = 5 }]
+`))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	service := &cfg.Services[0]
+	if len(service.WaitFor) != 2 || service.WaitFor[0].DNS != "db.internal" || service.WaitFor[0].Timeout != 120 {
+		t.Fatalf("WaitFor = %+v", service.WaitFor)
+	}
+	if errs := validateWaitFor(service); len(errs) != 1 {
+		t.Errorf("validateWaitFor() = %v, want 1 error for missing dns", errs)
+	}
+}
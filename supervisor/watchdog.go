@@ -0,0 +1,179 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// EnvWatchdogUsec tells notify watchdog services how often to send
+// WATCHDOG=1, in microseconds, as systemd does
+const EnvWatchdogUsec = "WATCHDOG_USEC"
+
+// watchdogPoll is how often the deadline and a watchdog file are checked
+const watchdogPoll = time.Second
+
+// watchdogKillGrace is how long a service aborted by its watchdog has to
+// exit, e.g. to dump core, before it is killed
+const watchdogKillGrace = 5 * time.Second
+
+// Watchdog requires a running service to show a sign of life every Interval
+// seconds, catching services that hang without exiting: exactly one of File,
+// Notify or Exec is set
+type Watchdog struct {
+	Interval    int    `toml:"interval"`               // Seconds allowed between two signs of life
+	File        string `toml:"file,omitempty"`         // Alive when the service touched the file
+	Notify      bool   `toml:"notify,omitempty"`       // Alive when the service sends WATCHDOG=1 to $NOTIFY_SOCKET
+	Exec        string `toml:"exec,omitempty"`         // Shell command run every interval, alive on exit code 0
+	StartPeriod int    `toml:"start_period,omitempty"` // Seconds added to the first deadline
+}
+
+func (w *Watchdog) String() string {
+	var probe string
+	switch {
+	case w.File != "":
+		probe = "file " + w.File
+	case w.Exec != "":
+		probe = "exec " + w.Exec
+	default:
+		probe = "notify"
+	}
+	return fmt.Sprintf("%s every %s", probe, w.interval())
+}
+
+func (w *Watchdog) interval() time.Duration {
+	return time.Duration(w.Interval) * time.Second
+}
+
+// watchdogEnv returns the environment of a service with a notify watchdog
+func watchdogEnv(w *Watchdog) map[string]string {
+	return map[string]string{EnvWatchdogUsec: strconv.FormatInt(w.interval().Microseconds(), 10)}
+}
+
+// pingWatchdog records a WATCHDOG=1 sent by the service
+func (sp *ServiceProcess) pingWatchdog() {
+	select {
+	case sp.watchdogPing <- struct{}{}:
+	default:
+	}
+}
+
+// monitorWatchdog aborts the service once it misses a deadline of its
+// watchdog, so its restart policy applies, until ctx is done
+func monitorWatchdog(ctx context.Context, sp *ServiceProcess) {
+	err := awaitWatchdogTimeout(ctx, sp)
+	if err == nil {
+		return
+	}
+
+	logger.Error(fmt.Sprintf("Service '%s' %v, aborting it", colorize(ColorCyan, sp.Name), err))
+	sp.SetError(err)
+	sp.SetHealth(HealthUnhealthy)
+	events.publish(Event{Type: EventWatchdog, Service: sp.Name, Message: err.Error()})
+
+	// SIGABRT like systemd, so a hung service can leave a core dump behind
+	pid := sp.GetPID()
+	if err := signalProcessGroup(pid, syscall.SIGABRT); err != nil {
+		logger.Error(fmt.Sprintf("Error aborting service '%s': %v", colorize(ColorCyan, sp.Name), err))
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(watchdogKillGrace):
+		logger.Warn(fmt.Sprintf("Force killing service '%s'", colorize(ColorCyan, sp.Name)))
+		if err := signalProcessGroup(pid, syscall.SIGKILL); err != nil {
+			logger.Error(fmt.Sprintf("Error force killing service '%s': %v", colorize(ColorCyan, sp.Name), err))
+		}
+	}
+}
+
+// awaitWatchdogTimeout blocks until the service misses a deadline, returning
+// why, or until ctx is done, returning nil
+func awaitWatchdogTimeout(ctx context.Context, sp *ServiceProcess) error {
+	watchdog := sp.Config.Watchdog
+	interval := watchdog.interval()
+	lastAlive := time.Now().Add(time.Duration(watchdog.StartPeriod) * time.Second)
+	nextProbe := lastAlive.Add(interval)
+
+	poll := min(watchdogPoll, interval)
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sp.watchdogPing:
+			lastAlive = time.Now()
+		case now := <-ticker.C:
+			switch {
+			case watchdog.File != "":
+				if info, err := os.Stat(watchdog.File); err == nil && info.ModTime().After(lastAlive) {
+					lastAlive = info.ModTime()
+				}
+			case watchdog.Exec != "" && !now.Before(nextProbe):
+				probeCtx, cancel := context.WithTimeout(ctx, interval)
+				err := execProbe(probeCtx, watchdog.Exec, buildServiceEnv(&sp.Config))
+				cancel()
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("failed its watchdog probe: %w", err)
+				}
+				// Ticks come a little early or late: the tick closest to the
+				// interval probes again
+				lastAlive = time.Now()
+				nextProbe = now.Add(interval - poll/2)
+				continue
+			case watchdog.Exec != "":
+				// The probe decides, timing out after the interval
+				continue
+			}
+			if time.Since(lastAlive) > interval {
+				return fmt.Errorf("missed its watchdog deadline of %s", interval)
+			}
+		}
+	}
+}
+
+func validateWatchdog(service *Service) ValidationErrors {
+	var errors ValidationErrors
+
+	watchdog := service.Watchdog
+	if watchdog == nil {
+		return errors
+	}
+
+	probes := 0
+	for _, set := range []bool{watchdog.File != "", watchdog.Notify, watchdog.Exec != ""} {
+		if set {
+			probes++
+		}
+	}
+	if probes != 1 {
+		errors = append(errors, ValidationError{
+			Field:   "watchdog",
+			Service: service.Name,
+			Message: "watchdog must set exactly one of file, notify or exec",
+		})
+	}
+
+	if watchdog.Interval <= 0 {
+		errors = append(errors, ValidationError{
+			Field:   "watchdog.interval",
+			Service: service.Name,
+			Message: "watchdog needs an interval of at least 1 second",
+		})
+	}
+	if watchdog.StartPeriod < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "watchdog.start_period",
+			Service: service.Name,
+			Message: "start_period cannot be negative",
+		})
+	}
+
+	return errors
+}
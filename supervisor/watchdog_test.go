@@ -0,0 +1,149 @@
+package supervisor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test watchdog validation
+func TestValidateWatchdog(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		errors  int
+	}{
+		{"none", Service{Name: "web"}, 0},
+		{"file", Service{Name: "web", Watchdog: &Watchdog{Interval: 30, File: "/run/web.alive"}}, 0},
+		{"notify", Service{Name: "web", Watchdog: &Watchdog{Interval: 30, Notify: true, StartPeriod: 60}}, 0},
+		{"exec", Service{Name: "web", Watchdog: &Watchdog{Interval: 30, Exec: "web-ping"}}, 0},
+		{"no probe", Service{Name: "web", Watchdog: &Watchdog{Interval: 30}}, 1},
+		{"two probes", Service{Name: "web", Watchdog: &Watchdog{Interval: 30, Notify: true, Exec: "web-ping"}}, 1},
+		{"no interval", Service{Name: "web", Watchdog: &Watchdog{Notify: true}}, 1},
+		{"negative start period", Service{Name: "web", Watchdog: &Watchdog{Interval: 30, Notify: true, StartPeriod: -1}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateWatchdog(&tt.service); len(got) != tt.errors {
+				t.Errorf("validateWatchdog() returned %d errors, want %d: %v", len(got), tt.errors, got)
+			}
+		})
+	}
+}
+
+// Test each kind of watchdog keeps the service alive while it shows signs of
+// life, and times out once it stops
+func TestAwaitWatchdogTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping watchdog timing test in short mode")
+	}
+	dir := t.TempDir()
+	aliveFile := filepath.Join(dir, "alive")
+	stopFile := filepath.Join(dir, "stop")
+	if err := os.WriteFile(aliveFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		watchdog Watchdog
+		alive    func(sp *ServiceProcess) // Sign of life, given every 500ms
+		want     string
+	}{
+		{
+			name:     "file",
+			watchdog: Watchdog{Interval: 1, File: aliveFile},
+			alive: func(*ServiceProcess) {
+				now := time.Now()
+				_ = os.Chtimes(aliveFile, now, now)
+			},
+			want: "missed its watchdog deadline of 1s",
+		},
+		{
+			name:     "notify",
+			watchdog: Watchdog{Interval: 1, Notify: true},
+			alive:    func(sp *ServiceProcess) { sp.pingWatchdog() },
+			want:     "missed its watchdog deadline of 1s",
+		},
+		{
+			name:     "exec",
+			watchdog: Watchdog{Interval: 1, Exec: "test ! -e " + stopFile},
+			alive:    func(*ServiceProcess) {},
+			want:     "failed its watchdog probe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sp := &ServiceProcess{Name: "web", Config: Service{Name: "web", Watchdog: &tt.watchdog}, watchdogPing: make(chan struct{}, 1)}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			result := make(chan error, 1)
+			start := time.Now()
+			go func() { result <- awaitWatchdogTimeout(ctx, sp) }()
+
+			// Alive for twice the interval
+			for time.Since(start) < 2*time.Second {
+				select {
+				case err := <-result:
+					t.Fatalf("awaitWatchdogTimeout() = %v while the service was alive", err)
+				case <-time.After(500 * time.Millisecond):
+					tt.alive(sp)
+				}
+			}
+			if tt.watchdog.Exec != "" {
+				if err := os.WriteFile(stopFile, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			select {
+			case err := <-result:
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("awaitWatchdogTimeout() = %v, want %q", err, tt.want)
+				}
+			case <-time.After(4 * time.Second):
+				t.Error("awaitWatchdogTimeout() did not time out")
+			}
+		})
+	}
+}
+
+// Test a hung service is aborted by its watchdog and restarted by its policy
+func TestWatchdogRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping process test in short mode")
+	}
+	setupServiceConfig(t, Service{
+		Name: "hung", Command: "/bin/sleep", Args: []string{"30"},
+		Restart: RestartOnFailure, RestartBackoff: 1,
+		Watchdog: &Watchdog{Interval: 1, Notify: true},
+	})
+
+	if err := startService("hung"); err != nil {
+		t.Fatalf("startService() error = %v", err)
+	}
+	first, _ := getActiveService("hung")
+	if env := strings.Join(first.Process.Env, "\n"); !strings.Contains(env, EnvWatchdogUsec+"=1000000") {
+		t.Errorf("service environment misses %s=1000000", EnvWatchdogUsec)
+	}
+
+	if !waitFor(t, 10*time.Second, func() bool { return restartCount("hung") == 1 }) {
+		t.Fatal("service was not restarted after missing its watchdog deadline")
+	}
+	if code, _ := lastExitCode("hung"); code != 134 {
+		t.Errorf("lastExitCode() = %d, want 134 (SIGABRT)", code)
+	}
+	if !waitFor(t, 5*time.Second, func() bool {
+		sp, running := getActiveService("hung")
+		return running && sp != first
+	}) {
+		t.Error("service is not running again")
+	}
+}